@@ -32,10 +32,13 @@ const (
 )
 
 var config struct {
-	cfgFile       string
-	interfaceType string
-	interfaceName string
-	tapPrefix     string
+	cfgFile         string
+	interfaceType   string
+	interfaceName   string
+	tapPrefix       string
+	tapCount        int
+	poolAuditPeriod int
+	ipvlanMode      string
 }
 
 var rootCmd = &cobra.Command{
@@ -82,11 +85,15 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	intfTypeOpts := newFlagOpts([]string{types.SriovPodInterface, types.IpvlanPodInterface, types.TapInterface}, types.SriovPodInterface)
-	rootCmd.PersistentFlags().Var(intfTypeOpts, "interfaceType", "Pod Interface type (sriov|ipvlan|tap)")
+	intfTypeOpts := newFlagOpts([]string{types.SriovPodInterface, types.IpvlanPodInterface, types.TapInterface, types.VethPodInterface}, types.SriovPodInterface)
+	rootCmd.PersistentFlags().Var(intfTypeOpts, "interfaceType", "Pod Interface type (sriov|ipvlan|tap|veth)")
 	rootCmd.PersistentFlags().StringVar(&config.interfaceName, "interface", "", intfFlagHelpMsg)
 	rootCmd.PersistentFlags().StringVar(&config.cfgFile, "config", "/etc/infra/infraagent.yaml", "config file")
 	rootCmd.PersistentFlags().StringVar(&config.tapPrefix, "tapPrefix", types.TapInterfacePrefix, "Host TAP interface prefix for TAP interface type")
+	rootCmd.PersistentFlags().IntVar(&config.tapCount, "tapCount", 0, "Number of TAP ports the agent creates through gNMI for TAP interface type before the pipeline is set, 0 to use the existing ones")
+	rootCmd.PersistentFlags().IntVar(&config.poolAuditPeriod, "poolAuditPeriod", 60, "Period in seconds of the audit reclaiming the TAP interfaces and VFs leaked by pods gone from the node, 0 to disable it")
+	ipvlanModeOpts := newFlagOpts([]string{types.IPVlanModeL2, types.IPVlanModeL3, types.IPVlanModeL3S}, types.IPVlanModeL3)
+	rootCmd.PersistentFlags().Var(ipvlanModeOpts, "ipvlanMode", "IPVLAN mode of the Pod interfaces for IPVLAN interface type (l2|l3|l3s)")
 	if err := viper.BindPFlag("interfaceType", rootCmd.PersistentFlags().Lookup("interfaceType")); err != nil {
 		fmt.Fprintf(os.Stderr, "There was an error while binding flags '%s'", err)
 		os.Exit(1)
@@ -99,6 +106,16 @@ func init() {
 		fmt.Fprintf(os.Stderr, "There was an error while binding flags '%s'", err)
 		os.Exit(1)
 	}
+	for _, name := range []string{"tapCount", "poolAuditPeriod"} {
+		if err := viper.BindPFlag(name, rootCmd.PersistentFlags().Lookup(name)); err != nil {
+			fmt.Fprintf(os.Stderr, "There was an error while binding flags '%s'", err)
+			os.Exit(1)
+		}
+	}
+	if err := viper.BindPFlag("ipvlanMode", rootCmd.PersistentFlags().Lookup("ipvlanMode")); err != nil {
+		fmt.Fprintf(os.Stderr, "There was an error while binding flags '%s'", err)
+		os.Exit(1)
+	}
 }
 
 func initConfig() {
@@ -144,10 +161,23 @@ func validateConfigs() error {
 	var err error
 	// validate interface type
 	interfaceType := viper.GetString("interfaceType")
-	if newErr := newFlagOpts([]string{types.SriovPodInterface, types.IpvlanPodInterface, types.TapInterface}, types.SriovPodInterface).Set(interfaceType); newErr != nil {
+	if newErr := newFlagOpts([]string{types.SriovPodInterface, types.IpvlanPodInterface, types.TapInterface, types.VethPodInterface}, types.SriovPodInterface).Set(interfaceType); newErr != nil {
 		err = fmt.Errorf("error validating interfaceType: %w", newErr)
 	}
 
+	ipvlanMode := viper.GetString("ipvlanMode")
+	if newErr := newFlagOpts([]string{types.IPVlanModeL2, types.IPVlanModeL3, types.IPVlanModeL3S}, types.IPVlanModeL3).Set(ipvlanMode); newErr != nil {
+		err = joinErrors(err, fmt.Errorf("error validating ipvlanMode: %w", newErr))
+	}
+
+	if newErr := validateTapCounts(); newErr != nil {
+		err = joinErrors(err, newErr)
+	}
+
+	if poolAuditPeriod := viper.GetInt("poolAuditPeriod"); poolAuditPeriod < 0 {
+		err = joinErrors(err, fmt.Errorf("error validating poolAuditPeriod: %d is negative", poolAuditPeriod))
+	}
+
 	// When validating other configs wrap add error msgs in one and then return it at the end.
 	// For example:
 	//
@@ -158,3 +188,23 @@ func validateConfigs() error {
 
 	return err
 }
+
+func joinErrors(err, newErr error) error {
+	if err != nil {
+		return fmt.Errorf("%s;\n%w", err, newErr)
+	}
+	return newErr
+}
+
+// The pipeline needs a power of 2 of TAP interfaces
+func isPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+func validateTapCounts() error {
+	tapCount := viper.GetInt("tapCount")
+	if tapCount != 0 && !isPowerOfTwo(tapCount) {
+		return fmt.Errorf("error validating tapCount: %d is not a power of 2", tapCount)
+	}
+	return nil
+}
@@ -53,6 +53,9 @@ type ApiServer struct {
 	log       *log.Entry
 	p4RtC     *client.Client
 	p4RtCConn *grpc.ClientConn
+	// Backends removed from services and still draining
+	drainMutex sync.Mutex
+	drains     map[*serviceDrain]bool
 }
 
 var api *ApiServer
@@ -142,6 +145,37 @@ func SetFwdPipe(ctx context.Context, binPath string,
 	return server.p4RtC.SetFwdPipe(ctx, binPath, p4infoPath, cookie)
 }
 
+// ReconcileStores brings the CNI and service tables of the pipeline in
+// line with the endpoint and service stores. The stores are the source
+// of truth, a crash between a pipeline write and the store sync leaves
+// stale or missing entries behind otherwise.
+func ReconcileStores(ctx context.Context) error {
+	server := NewApiServer()
+
+	endpoints := store.ListEndPoints(nil)
+	endpointMap := make(map[string]store.EndPoint, len(endpoints))
+	for _, ep := range endpoints {
+		endpointMap[ep.PodIpAddress] = ep
+	}
+	services := store.ListServices(nil)
+
+	stats, err := p4.ReconcileCniRules(ctx, server.p4RtC, endpoints)
+	if err != nil {
+		log.Errorf("Failed to reconcile the CNI tables: %v", err)
+		return err
+	}
+	log.Infof("Reconciled the CNI tables with %d endpoints: %s", len(endpoints), stats)
+
+	stats, err = p4.ReconcileServiceRules(ctx, server.p4RtC, services, endpointMap)
+	if err != nil {
+		log.Errorf("Failed to reconcile the service tables: %v", err)
+		return err
+	}
+	log.Infof("Reconciled the service tables with %d services: %s", len(services), stats)
+
+	return nil
+}
+
 func CreateServer(log *log.Entry) *ApiServer {
 	logger := log.WithField("func", "CreateAndStartServer")
 	logger.Infof("Starting infra-manager gRPC server")
@@ -199,48 +233,76 @@ func (s *ApiServer) Stop() {
 	types.InfraManagerServerStatus = types.ServerStatusStopped
 }
 
-func insertRule(log *log.Entry, ctx context.Context, p4RtC *client.Client, macAddr string, ipAddr string, portID int, ifaceType p4.InterfaceType) (bool, error) {
+// Programs the addresses of an interface and stores an endpoint per
+// address. The mac_to_port entry is shared by the addresses of the
+// interface, so it is programmed only along with the first of them.
+func insertRule(log *log.Entry, ctx context.Context, p4RtC *client.Client, macAddr string, ipAddrs []string, portID int, ifaceType p4.InterfaceType) (bool, error) {
 	var err error
 
 	logger := log.WithField("func", "insertRule")
 
-	ep := store.EndPoint{
-		PodIpAddress:  ipAddr,
-		InterfaceID:   uint32(portID),
-		PodMacAddress: macAddr,
-	}
+	var eps []store.EndPoint
+	var newIpAddrs []string
+	for _, ipAddr := range ipAddrs {
+		ep := store.EndPoint{
+			PodIpAddress:  canonicalIP(ipAddr),
+			InterfaceID:   uint32(portID),
+			PodMacAddress: macAddr,
+		}
 
-	entry := ep.GetFromStore()
-	if entry != nil {
-		epEntry := entry.(store.EndPoint)
-		if epEntry.PodIpAddress == ep.PodIpAddress &&
-			epEntry.InterfaceID == ep.InterfaceID &&
-			epEntry.PodMacAddress == ep.PodMacAddress {
+		entry := ep.GetFromStore()
+		if entry != nil {
+			epEntry := entry.(store.EndPoint)
+			if epEntry.PodIpAddress == ep.PodIpAddress &&
+				epEntry.InterfaceID == ep.InterfaceID &&
+				epEntry.PodMacAddress == ep.PodMacAddress {
 
-			logger.Infof("Entry %s %s %d already exists",
-				macAddr, ipAddr, portID)
-			return true, nil
-		} else {
-			err = fmt.Errorf("A different entry for %s already exists in the store", ipAddr)
-			return false, err
+				logger.Infof("Entry %s %s %d already exists",
+					macAddr, ep.PodIpAddress, portID)
+				continue
+			} else {
+				err = fmt.Errorf("A different entry for %s already exists in the store", ep.PodIpAddress)
+				return false, err
+			}
 		}
+		eps = append(eps, ep)
+		newIpAddrs = append(newIpAddrs, ep.PodIpAddress)
 	}
 
-	logger.Infof("Inserting entry into the cni tables")
-	if err = p4.InsertCniRules(ctx, p4RtC, macAddr, ipAddr, portID, ifaceType); err != nil {
-		logger.Errorf("Failed to insert the entries for %s %s", macAddr, ipAddr)
-		return false, err
+	if len(eps) == 0 {
+		return true, nil
+	}
+
+	ruleMacAddr := macAddr
+	if len(store.GetEndPointsByMac(macAddr)) > 0 {
+		ruleMacAddr = ""
 	}
-	logger.Infof("Inserted the entries %s %s %d into the pipeline",
-		macAddr, ipAddr, portID)
 
-	if ep.WriteToStore() != true {
-		err = fmt.Errorf("Failed to add %s %s %d to the store",
-			macAddr, ipAddr, portID)
+	logger.Infof("Inserting entry into the cni tables")
+	if err = p4.InsertCniRules(ctx, p4RtC, ruleMacAddr, newIpAddrs, portID, ifaceType); err != nil {
+		logger.Errorf("Failed to insert the entries for %s %v", macAddr, newIpAddrs)
 		return false, err
 	}
-	logger.Infof("Inserted the entries %s %s %d into the store",
-		macAddr, ipAddr, portID)
+	logger.Infof("Inserted the entries %s %v %d into the pipeline",
+		macAddr, newIpAddrs, portID)
+
+	for i, ep := range eps {
+		if ep.WriteToStore() != true {
+			err = fmt.Errorf("Failed to add %s %s %d to the store",
+				macAddr, ep.PodIpAddress, portID)
+			// Leave neither the entries nor the endpoints behind,
+			// the agent retries the whole request
+			if delErr := p4.DeleteCniRules(ctx, p4RtC, ruleMacAddr, newIpAddrs, portID); delErr != nil {
+				logger.Errorf("Failed to delete the entries for %s %v: %v", macAddr, newIpAddrs, delErr)
+			}
+			for _, written := range eps[:i+1] {
+				written.DeleteFromStore()
+			}
+			return false, err
+		}
+	}
+	logger.Infof("Inserted the entries %s %v %d into the store",
+		macAddr, newIpAddrs, portID)
 
 	return true, err
 }
@@ -258,7 +320,15 @@ func (s *ApiServer) CreateNetwork(ctx context.Context, in *proto.CreateNetworkRe
 
 	server := NewApiServer()
 
-	ipAddr := strings.Split(in.AddRequest.ContainerIps[0].Address, "/")[0]
+	// A dual-stack pod has an address of each family
+	var ipAddrs []string
+	for _, containerIp := range in.AddRequest.ContainerIps {
+		ipAddrs = append(ipAddrs, containerIp.Address)
+	}
+	if len(ipAddrs) == 0 {
+		out.Successful = false
+		return out, fmt.Errorf("No address for %s", in.HostIfName)
+	}
 	macAddr := in.MacAddr
 	//TODO: Extract the portId from mac.
 	// Temporary: Always send to port 0
@@ -273,7 +343,7 @@ func (s *ApiServer) CreateNetwork(ctx context.Context, in *proto.CreateNetworkRe
 	}
 
 	status, err := insertRule(s.log, ctx, server.p4RtC, macAddr,
-		ipAddr, int(portID), p4.ENDPOINT)
+		ipAddrs, int(portID), p4.ENDPOINT)
 	out.Successful = status
 	return out, err
 }
@@ -290,94 +360,573 @@ func (s *ApiServer) DeleteNetwork(ctx context.Context, in *proto.DeleteNetworkRe
 
 	server := NewApiServer()
 
-	ipAddr := strings.Split(in.Ipv4Addr, "/")[0]
 	macAddr := in.MacAddr
 
-	ep := store.EndPoint{
-		PodIpAddress: ipAddr,
-	}
+	var eps []store.EndPoint
+	var ipAddrs []string
+	var portID uint32
+	for _, addr := range []string{in.Ipv4Addr, in.Ipv6Addr} {
+		if addr == "" {
+			continue
+		}
+		ipAddr := canonicalIP(addr)
+		ep := store.EndPoint{
+			PodIpAddress: ipAddr,
+		}
 
-	entry := ep.GetFromStore()
-	if entry == nil {
-		err = fmt.Errorf("Entry for %s does not exist in the store", ipAddr)
+		entry := ep.GetFromStore()
+		if entry == nil {
+			err = fmt.Errorf("Entry for %s does not exist in the store", ipAddr)
+			out.Successful = false
+			return out, err
+		}
+		portID = entry.(store.EndPoint).InterfaceID
+		eps = append(eps, ep)
+		ipAddrs = append(ipAddrs, ipAddr)
+	}
+	if len(eps) == 0 {
 		out.Successful = false
-		return out, err
+		return out, fmt.Errorf("No address to delete for %s", macAddr)
 	}
 
-	if err = p4.DeleteCniRules(ctx, server.p4RtC, macAddr, ipAddr); err != nil {
-		logger.Errorf("Failed to delete the entries for %s %s", macAddr, ipAddr)
-		out.Successful = false
-		return out, err
+	// Keep the mac_to_port entry for the addresses of the interface left
+	ruleMacAddr := macAddr
+	if len(store.GetEndPointsByMac(macAddr)) > len(eps) {
+		ruleMacAddr = ""
 	}
-	logger.Infof("Deleted the entries %s %s from the pipeline", macAddr, ipAddr)
 
-	if ep.DeleteFromStore() != true {
+	if err = p4.DeleteCniRules(ctx, server.p4RtC, ruleMacAddr, ipAddrs, int(portID)); err != nil {
+		logger.Errorf("Failed to delete the entries for %s %v", macAddr, ipAddrs)
 		out.Successful = false
-		err = fmt.Errorf("Failed to delete %s %s from the store", macAddr, ipAddr)
 		return out, err
 	}
-	logger.Infof("Deleted the entries %s %s from the store", macAddr, ipAddr)
+	logger.Infof("Deleted the entries %s %v from the pipeline", macAddr, ipAddrs)
+
+	for _, ep := range eps {
+		if ep.DeleteFromStore() != true {
+			out.Successful = false
+			err = fmt.Errorf("Failed to delete %s %s from the store", macAddr, ep.PodIpAddress)
+			return out, err
+		}
+	}
+	logger.Infof("Deleted the entries %s %v from the store", macAddr, ipAddrs)
 
 	return out, err
 }
 
+func replyError(out *proto.Reply, err error) (*proto.Reply, error) {
+	out.Successful = false
+	out.ErrorMessage = err.Error()
+	return out, err
+}
+
+// Returns the address without its prefix length, in the canonical
+// form the addresses are kept in the stores
+func canonicalIP(addr string) string {
+	addr = strings.Split(addr, "/")[0]
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip.String()
+	}
+	return addr
+}
+
+// Returns the address of the endpoint, whichever its family is
+func natEndpointIP(ep *proto.NatEndpoint) string {
+	if ep.Ipv4Addr != "" {
+		return canonicalIP(ep.Ipv4Addr)
+	}
+	return canonicalIP(ep.Ipv6Addr)
+}
+
+func validateNatTranslation(endpoint *proto.NatEndpoint, protocol string) error {
+	if endpoint == nil {
+		return errors.New("Missing service endpoint")
+	}
+	ip := net.ParseIP(natEndpointIP(endpoint))
+	switch {
+	case endpoint.Ipv4Addr != "" && endpoint.Ipv6Addr != "":
+		return fmt.Errorf("Service address %s set along with %s",
+			endpoint.Ipv4Addr, endpoint.Ipv6Addr)
+	case ip == nil,
+		endpoint.Ipv4Addr != "" && ip.To4() == nil,
+		endpoint.Ipv6Addr != "" && ip.To4() != nil:
+		return fmt.Errorf("Invalid service address %s%s",
+			endpoint.Ipv4Addr, endpoint.Ipv6Addr)
+	}
+	// The pipeline load balances TCP, UDP and SCTP traffic
+	if _, _, err := p4.ServiceProtocol(protocol); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Returns the protocol of the service as it is kept in the store
+func serviceProto(protocol string) string {
+	proto, _, _ := p4.ServiceProtocol(protocol)
+	return proto
+}
+
+// Returns the backend with the given ip of any service in the
+// store other than the one identified by skipKey.
+func findServiceEndPoint(ipAddr string, skipKey string) (store.ServiceEndPoint, bool) {
+	for _, service := range store.GetServicesByBackend(ipAddr) {
+		if store.ServiceKey(service.ClusterIp, service.Proto, service.ClusterPort) == skipKey {
+			continue
+		}
+		return service.ServiceEndPoint[ipAddr], true
+	}
+	return store.ServiceEndPoint{}, false
+}
+
+func isServiceUUIDInUse(id uint32) bool {
+	inUse := store.ListServices(func(service store.Service) bool {
+		if service.GroupID == id {
+			return true
+		}
+		for _, ep := range service.ServiceEndPoint {
+			if ep.MemberID == id || ep.DnatPtr == id {
+				return true
+			}
+		}
+		return false
+	})
+	return len(inUse) > 0
+}
+
+// The id generator restarts along with the server while the
+// services restored from the store keep their ids, so skip
+// the ones still in use.
+func newServiceUUID() uint32 {
+	for {
+		id := p4.NewUUID()
+		if !isServiceUUIDInUse(id) {
+			return id
+		}
+	}
+}
+
+// The traffic of the pods leaving the cluster is masqueraded behind the
+// SNAT addresses of the node
 func (s *ApiServer) SetSnatAddress(ctx context.Context, in *proto.SetSnatAddressRequest) (*proto.Reply, error) {
 	logger := log.WithField("func", "SetSnatAddress")
 	logger.Infof("Incomming SetSnatAddress %+v", in)
-	return &proto.Reply{Successful: true}, nil
+
+	out := &proto.Reply{
+		Successful: true,
+	}
+
+	server := NewApiServer()
+
+	if err := p4.SetSnatAddress(ctx, server.p4RtC, in.SnatIpv4, in.SnatIpv6); err != nil {
+		logger.Errorf("Failed to set the SNAT addresses %s %s", in.SnatIpv4, in.SnatIpv6)
+		return replyError(out, err)
+	}
+	logger.Infof("Set the SNAT addresses %s %s", in.SnatIpv4, in.SnatIpv6)
+
+	return out, nil
+}
+
+// Allocates the members for the local backends and records them in the
+// service. Backends already part of the service are skipped. A backend
+// of another service reuses its DNAT entry.
+func (s *ApiServer) addServiceBackends(service *store.Service, backends []*proto.NatEndpointTuple) p4.ServiceMembers {
+	var res p4.ServiceMembers
+
+	logger := s.log.WithField("func", "addServiceBackends")
+	key := store.ServiceKey(service.ClusterIp, service.Proto, service.ClusterPort)
+
+	for _, backend := range backends {
+		if backend.DstEp == nil {
+			continue
+		}
+		ipAddr := natEndpointIP(backend.DstEp)
+
+		// The translation does not change the address family
+		if p4.IsIPv6(ipAddr) != p4.IsIPv6(service.ClusterIp) {
+			logger.Warnf("Backend %s is not of the family of service %s, skipping",
+				ipAddr, service.ClusterIp)
+			continue
+		}
+
+		if _, ok := service.ServiceEndPoint[ipAddr]; ok {
+			logger.Infof("Backend %s already exists", ipAddr)
+			continue
+		}
+
+		// Only the pods running on this node are reachable
+		// through the pipeline, the remote backends are not
+		// programmed and the traffic to them is not load balanced
+		ep := store.EndPoint{
+			PodIpAddress: ipAddr,
+		}
+		entry := ep.GetFromStore()
+		if entry == nil {
+			logger.Infof("Backend %s is not a local endpoint, skipping", ipAddr)
+			continue
+		}
+		epEntry := entry.(store.EndPoint)
+
+		// TODO: The pipeline does not translate the L4 port,
+		// the target port is expected to match the service port
+		if backend.DstEp.Port != service.ClusterPort {
+			logger.Warnf("Backend %s port %d differs from service port %d",
+				ipAddr, backend.DstEp.Port, service.ClusterPort)
+		}
+
+		id := newServiceUUID()
+		res.PodIpAddr = append(res.PodIpAddr, ipAddr)
+		res.PodMacAddr = append(res.PodMacAddr, epEntry.PodMacAddress)
+		res.MemberID = append(res.MemberID, id)
+		res.InterfaceID = append(res.InterfaceID, epEntry.InterfaceID)
+
+		var dnatPtr uint32
+		if other, found := findServiceEndPoint(ipAddr, key); found {
+			logger.Infof("Backend %s already serves another service, not updating rx_src_ip", ipAddr)
+			dnatPtr = other.DnatPtr
+		} else {
+			res.SnatPodIpAddr = append(res.SnatPodIpAddr, ipAddr)
+			res.SnatMemberID = append(res.SnatMemberID, id)
+			dnatPtr = newServiceUUID()
+			res.NewDnatPtr = append(res.NewDnatPtr, dnatPtr)
+		}
+		res.DnatPtr = append(res.DnatPtr, dnatPtr)
+
+		service.ServiceEndPoint[ipAddr] = store.ServiceEndPoint{
+			IpAddress: ipAddr,
+			Port:      backend.DstEp.Port,
+			MemberID:  id,
+			DnatPtr:   dnatPtr,
+		}
+	}
+	return res
+}
+
+// Removes the given backends from the service and collects their members.
+// The rx_src_ip entry of a backend still used by another service is handed
+// over to that service, the DNAT entry is deleted along with the last
+// service referring to it, once the backend is drained.
+func (s *ApiServer) removeServiceBackends(service *store.Service, podIpAddr []string) p4.ServiceMembers {
+	var res p4.ServiceMembers
+
+	key := store.ServiceKey(service.ClusterIp, service.Proto, service.ClusterPort)
+
+	for _, ipAddr := range podIpAddr {
+		ep, ok := service.ServiceEndPoint[ipAddr]
+		if !ok {
+			continue
+		}
+		res.PodIpAddr = append(res.PodIpAddr, ipAddr)
+		res.MemberID = append(res.MemberID, ep.MemberID)
+		res.DnatPtr = append(res.DnatPtr, ep.DnatPtr)
+		// The member is restored on the port of the backend on rollback
+		var interfaceID uint32
+		if entry := (store.EndPoint{PodIpAddress: ipAddr}).GetFromStore(); entry != nil {
+			interfaceID = entry.(store.EndPoint).InterfaceID
+		}
+		res.InterfaceID = append(res.InterfaceID, interfaceID)
+
+		if other, found := findServiceEndPoint(ipAddr, key); found {
+			res.UpdPodIpAddr = append(res.UpdPodIpAddr, ipAddr)
+			res.UpdMemberID = append(res.UpdMemberID, other.MemberID)
+		} else {
+			res.SnatPodIpAddr = append(res.SnatPodIpAddr, ipAddr)
+		}
+		// The service itself is still in the store
+		if store.DnatRefCount(ipAddr, ep.DnatPtr) <= 1 {
+			res.StaleDnatPtr = append(res.StaleDnatPtr, ep.DnatPtr)
+		}
+		delete(service.ServiceEndPoint, ipAddr)
+	}
+	// The flows pinned to the backends are left to end by themselves
+	// for the drain period, unless there is none
+	res.Drain = len(res.MemberID) != 0 && drainPeriod() > 0
+	return res
+}
+
+// Returns the backends of the service in the store which are not among
+// the given ones
+func staleBackends(service store.Service, backends []*proto.NatEndpointTuple) []*proto.NatEndpointTuple {
+	keep := make(map[string]bool, len(backends))
+	for _, backend := range backends {
+		if backend.DstEp != nil {
+			keep[natEndpointIP(backend.DstEp)] = true
+		}
+	}
+	var stale []*proto.NatEndpointTuple
+	for ipAddr, ep := range service.ServiceEndPoint {
+		if keep[ipAddr] {
+			continue
+		}
+		dstEp := &proto.NatEndpoint{Ipv4Addr: ipAddr, Port: ep.Port}
+		if p4.IsIPv6(ipAddr) {
+			dstEp = &proto.NatEndpoint{Ipv6Addr: ipAddr, Port: ep.Port}
+		}
+		stale = append(stale, &proto.NatEndpointTuple{DstEp: dstEp})
+	}
+	return stale
+}
+
+func copyServiceEndPoints(service store.Service) map[string]store.ServiceEndPoint {
+	eps := make(map[string]store.ServiceEndPoint, len(service.ServiceEndPoint))
+	for ipAddr, ep := range service.ServiceEndPoint {
+		eps[ipAddr] = ep
+	}
+	return eps
 }
 
 func (s *ApiServer) NatTranslationAdd(ctx context.Context, in *proto.NatTranslation) (*proto.Reply, error) {
-	logger := log.WithField("func", "NatTranslationUpdate")
-	logger.Infof("Incomming NatTranslationUpdate %+v", in)
-	logger.Infof("Service ip %v and port %v proto %v", in.Endpoint.Ipv4Addr, in.Endpoint.Port, in.Proto)
-	logger.Infof("Endpoints num %v", len(in.Backends))
-	for i, e := range in.Backends {
-		logger.Infof("Endpoint %v, src ip: %v dst ip %v", i, e.SrcEp, e.DstEp)
+	var err error
+
+	logger := s.log.WithField("func", "NatTranslationAdd")
+	logger.Infof("Incoming NatTranslationAdd %+v", in)
+
+	out := &proto.Reply{
+		Successful: true,
 	}
-	return &proto.Reply{Successful: true}, nil
+
+	server := NewApiServer()
+
+	if err = validateNatTranslation(in.Endpoint, in.Proto); err != nil {
+		logger.Errorf("Invalid NAT translation: %v", err)
+		return replyError(out, err)
+	}
+
+	serviceIpAddr := natEndpointIP(in.Endpoint)
+	service := store.Service{
+		ClusterIp:       serviceIpAddr,
+		ClusterPort:     in.Endpoint.Port,
+		Proto:           serviceProto(in.Proto),
+		ServiceEndPoint: make(map[string]store.ServiceEndPoint),
+		AffinityTimeout: in.SessionAffinityTimeout,
+	}
+
+	if entry := service.GetFromStore(); entry != nil {
+		existing := entry.(store.Service)
+		if existing.AffinityTimeout == in.SessionAffinityTimeout {
+			// The agent adds the service again when it restarts, with
+			// backends which may have changed since
+			logger.Infof("Service %s:%d already exists, updating its backends",
+				serviceIpAddr, in.Endpoint.Port)
+			return s.NatTranslationUpdate(ctx, &proto.NatTranslationUpdateRequest{
+				Endpoint:        in.Endpoint,
+				Proto:           in.Proto,
+				IsRealIp:        in.IsRealIp,
+				AddedBackends:   in.Backends,
+				RemovedBackends: staleBackends(existing, in.Backends),
+
+				SessionAffinityTimeout: in.SessionAffinityTimeout,
+			})
+		}
+		// The affinity is set along with the translation only
+		if reply, err := s.NatTranslationDelete(ctx, in); err != nil {
+			return reply, err
+		}
+	}
+
+	s.purgeDrainedBackends(backendIPs(in.Backends))
+	added := s.addServiceBackends(&service, in.Backends)
+	if len(added.MemberID) == 0 {
+		// A service address without members would drop the traffic,
+		// nothing is programmed until a local backend is added
+		logger.Infof("Service %s:%d has no local backends, it is not load balanced on this node",
+			serviceIpAddr, in.Endpoint.Port)
+		return out, nil
+	}
+
+	service.GroupID = newServiceUUID()
+
+	if err = p4.InsertServiceRules(ctx, server.p4RtC, added, service); err != nil {
+		logger.Errorf("Failed to insert the service entries for %s:%d",
+			serviceIpAddr, in.Endpoint.Port)
+		return replyError(out, err)
+	}
+	logger.Infof("Inserted the service entries for %s:%d with %d backends into the pipeline",
+		serviceIpAddr, in.Endpoint.Port, len(added.MemberID))
+
+	if service.WriteToStore() != true {
+		err = fmt.Errorf("Failed to add service %s:%d to the store",
+			serviceIpAddr, in.Endpoint.Port)
+		return replyError(out, err)
+	}
+	logger.Infof("Inserted the service %s:%d into the store",
+		serviceIpAddr, in.Endpoint.Port)
+
+	return out, nil
 }
 
+// The prefixes are the destinations the pods reach without SNAT
 func (s *ApiServer) AddDelSnatPrefix(ctx context.Context, in *proto.AddDelSnatPrefixRequest) (*proto.Reply, error) {
 	logger := log.WithField("func", "AddDelSnatPrefix")
 	logger.Infof("Incomming AddDelSnatPrefix %+v", in)
-	return &proto.Reply{Successful: true}, nil
+
+	out := &proto.Reply{
+		Successful: true,
+	}
+
+	server := NewApiServer()
+
+	if err := p4.AddDelSnatPrefix(ctx, server.p4RtC, in.Prefix, in.IsAdd); err != nil {
+		logger.Errorf("Failed to update the SNAT prefix %s", in.Prefix)
+		return replyError(out, err)
+	}
+	logger.Infof("Updated the SNAT prefix %s, added: %v", in.Prefix, in.IsAdd)
+
+	return out, nil
 }
 
 func (s *ApiServer) NatTranslationDelete(ctx context.Context, in *proto.NatTranslation) (*proto.Reply, error) {
-	logger := log.WithField("func", "NatTranslationDelete")
-	logger.Infof("Incomming NatTranslationDelete %+v", in)
-	return &proto.Reply{Successful: true}, nil
-}
+	var err error
 
-func (s *ApiServer) ActivePolicyUpdate(ctx context.Context, in *proto.ActivePolicyUpdate) (*proto.Reply, error) {
-	logger := log.WithField("func", "updatePolicy")
-	logger.Infof("Incoming updatePolicy Request %+v", in)
-	return &proto.Reply{Successful: true}, nil
-}
+	logger := s.log.WithField("func", "NatTranslationDelete")
+	logger.Infof("Incoming NatTranslationDelete %+v", in)
 
-func (s *ApiServer) ActivePolicyRemove(ctx context.Context, in *proto.ActivePolicyRemove) (*proto.Reply, error) {
-	logger := log.WithField("func", "DeletePolicy")
-	logger.Infof("Incoming DeletePolicy Request %+v", in)
-	return &proto.Reply{Successful: true}, nil
-}
+	out := &proto.Reply{
+		Successful: true,
+	}
 
-func (s *ApiServer) UpdateIPSet(ctx context.Context, in *proto.IPSetUpdate) (*proto.Reply, error) {
-	logger := log.WithField("func", "UpdateIPSet")
-	logger.Infof("Incoming UpdateIPSet Request %+v", in)
-	return &proto.Reply{Successful: true}, nil
-}
+	server := NewApiServer()
 
-func (s *ApiServer) UpdateIPSetDelta(ctx context.Context, in *proto.IPSetDeltaUpdate) (*proto.Reply, error) {
-	logger := log.WithField("func", "UpdateIPSetDelta")
-	logger.Infof("Incoming UpdateIPSetDelta Request %+v", in)
-	return &proto.Reply{Successful: true}, nil
+	if in.Endpoint == nil {
+		err = errors.New("Missing service endpoint")
+		return replyError(out, err)
+	}
+
+	serviceIpAddr := natEndpointIP(in.Endpoint)
+	service := store.Service{
+		ClusterIp:   serviceIpAddr,
+		ClusterPort: in.Endpoint.Port,
+		Proto:       serviceProto(in.Proto),
+	}
+
+	entry := service.GetFromStore()
+	if entry == nil {
+		// Services without local backends are never programmed
+		logger.Infof("Service %s:%d does not exist in the store",
+			serviceIpAddr, in.Endpoint.Port)
+		return out, nil
+	}
+	service = entry.(store.Service)
+	service.ServiceEndPoint = copyServiceEndPoints(service)
+
+	var podIpAddr []string
+	for ipAddr := range service.ServiceEndPoint {
+		podIpAddr = append(podIpAddr, ipAddr)
+	}
+	removed := s.removeServiceBackends(&service, podIpAddr)
+
+	if err = p4.DeleteServiceRules(ctx, server.p4RtC, removed, service); err != nil {
+		logger.Errorf("Failed to delete the service entries for %s:%d",
+			serviceIpAddr, in.Endpoint.Port)
+		return replyError(out, err)
+	}
+	logger.Infof("Deleted the service entries for %s:%d from the pipeline",
+		serviceIpAddr, in.Endpoint.Port)
+	s.drainBackends(removed, service)
+
+	if service.DeleteFromStore() != true {
+		err = fmt.Errorf("Failed to delete service %s:%d from the store",
+			serviceIpAddr, in.Endpoint.Port)
+		return replyError(out, err)
+	}
+	logger.Infof("Deleted the service %s:%d from the store",
+		serviceIpAddr, in.Endpoint.Port)
+
+	return out, nil
 }
 
-func (s *ApiServer) RemoveIPSet(ctx context.Context, in *proto.IPSetRemove) (*proto.Reply, error) {
-	logger := log.WithField("func", "RemoveIPSet")
-	logger.Infof("Incoming RemoveIPSet Request %+v", in)
-	return &proto.Reply{Successful: true}, nil
+func (s *ApiServer) NatTranslationUpdate(ctx context.Context, in *proto.NatTranslationUpdateRequest) (*proto.Reply, error) {
+	var err error
+
+	logger := s.log.WithField("func", "NatTranslationUpdate")
+	logger.Infof("Incoming NatTranslationUpdate %+v", in)
+
+	out := &proto.Reply{
+		Successful: true,
+	}
+
+	server := NewApiServer()
+
+	if err = validateNatTranslation(in.Endpoint, in.Proto); err != nil {
+		logger.Errorf("Invalid NAT translation: %v", err)
+		return replyError(out, err)
+	}
+
+	serviceIpAddr := natEndpointIP(in.Endpoint)
+	service := store.Service{
+		ClusterIp:   serviceIpAddr,
+		ClusterPort: in.Endpoint.Port,
+		Proto:       serviceProto(in.Proto),
+	}
+
+	entry := service.GetFromStore()
+	if entry == nil {
+		// Nothing is programmed when a service has no local backends,
+		// so the update boils down to adding the new ones.
+		return s.NatTranslationAdd(ctx, &proto.NatTranslation{
+			Endpoint: in.Endpoint,
+			Proto:    in.Proto,
+			IsRealIp: in.IsRealIp,
+			Backends: in.AddedBackends,
+
+			SessionAffinityTimeout: in.SessionAffinityTimeout,
+		})
+	}
+	service = entry.(store.Service)
+	service.ServiceEndPoint = copyServiceEndPoints(service)
+
+	// The pipeline state of a backend does not depend on its port,
+	// so a backend both removed and added has nothing to update.
+	addedIpAddr := make(map[string]bool)
+	for _, backend := range in.AddedBackends {
+		if backend.DstEp != nil {
+			addedIpAddr[natEndpointIP(backend.DstEp)] = true
+		}
+	}
+	var podIpAddr []string
+	for _, backend := range in.RemovedBackends {
+		if backend.DstEp == nil {
+			continue
+		}
+		ipAddr := natEndpointIP(backend.DstEp)
+		if !addedIpAddr[ipAddr] {
+			podIpAddr = append(podIpAddr, ipAddr)
+		}
+	}
+
+	s.purgeDrainedBackends(backendIPs(in.AddedBackends))
+	removed := s.removeServiceBackends(&service, podIpAddr)
+	added := s.addServiceBackends(&service, in.AddedBackends)
+
+	if len(service.ServiceEndPoint) == 0 {
+		logger.Infof("No local backends left for %s:%d", serviceIpAddr, in.Endpoint.Port)
+		return s.NatTranslationDelete(ctx, &proto.NatTranslation{
+			Endpoint: in.Endpoint,
+			Proto:    in.Proto,
+			IsRealIp: in.IsRealIp,
+		})
+	}
+
+	if len(added.MemberID) == 0 && len(removed.MemberID) == 0 {
+		logger.Infof("No change in the local backends of %s:%d", serviceIpAddr, in.Endpoint.Port)
+		return out, nil
+	}
+
+	if err = p4.UpdateServiceRules(ctx, server.p4RtC, added, removed, service); err != nil {
+		logger.Errorf("Failed to update the service entries for %s:%d",
+			serviceIpAddr, in.Endpoint.Port)
+		return replyError(out, err)
+	}
+	logger.Infof("Updated the service entries for %s:%d, %d backends added, %d removed",
+		serviceIpAddr, in.Endpoint.Port, len(added.MemberID), len(removed.MemberID))
+	s.drainBackends(removed, service)
+
+	if service.UpdateToStore() != true {
+		err = fmt.Errorf("Failed to update service %s:%d in the store",
+			serviceIpAddr, in.Endpoint.Port)
+		return replyError(out, err)
+	}
+
+	return out, nil
 }
 
 func (s *ApiServer) UpdateActiveProfile(ctx context.Context, in *proto.ActiveProfileUpdate) (*proto.Reply, error) {
@@ -404,18 +953,6 @@ func (s *ApiServer) RemoveHostEndpoint(ctx context.Context, in *proto.HostEndpoi
 	return &proto.Reply{Successful: true}, nil
 }
 
-func (s *ApiServer) UpdateLocalEndpoint(ctx context.Context, in *proto.WorkloadEndpointUpdate) (*proto.Reply, error) {
-	logger := log.WithField("func", "UpdateLocalEndpoint")
-	logger.Infof("Incoming UpdateLocalEndpoint Request %+v", in)
-	return &proto.Reply{Successful: true}, nil
-}
-
-func (s *ApiServer) RemoveLocalEndpoint(ctx context.Context, in *proto.WorkloadEndpointRemove) (*proto.Reply, error) {
-	logger := log.WithField("func", "RemoveLocalEndpoint")
-	logger.Infof("Incoming RemoveLocalEndpoint Request %+v", in)
-	return &proto.Reply{Successful: true}, nil
-}
-
 func (s *ApiServer) UpdateHostMetaData(ctx context.Context, in *proto.HostMetadataUpdate) (*proto.Reply, error) {
 	logger := log.WithField("func", "UpdateHostMetaData")
 	logger.Infof("Incoming UpdateHostMetaData Request %+v", in)
@@ -518,7 +1055,6 @@ func (s *ApiServer) SetupHostInterface(ctx context.Context, in *proto.SetupHostI
 
 	server := NewApiServer()
 
-	ipAddr := strings.Split(in.Ipv4Addr, "/")[0]
 	macAddr := in.MacAddr
 	//TODO: Extract the portId from mac.
 	// Temporary: Always send to port 0
@@ -533,7 +1069,7 @@ func (s *ApiServer) SetupHostInterface(ctx context.Context, in *proto.SetupHostI
 	}
 
 	status, err := insertRule(s.log, ctx, server.p4RtC, macAddr,
-		ipAddr, int(portID), p4.HOST)
+		[]string{in.Ipv4Addr}, int(portID), p4.HOST)
 	out.Successful = status
 	return out, err
 }
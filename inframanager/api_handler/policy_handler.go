@@ -0,0 +1,552 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api_handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	p4 "github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/p4"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/store"
+	"github.com/ipdk-io/k8s-infra-offload/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+var protocolNumbers = map[string]uint8{
+	"icmp":   1,
+	"tcp":    6,
+	"udp":    17,
+	"icmpv6": 58,
+	"sctp":   132,
+}
+
+func policyName(tier string, name string) string {
+	return tier + "/" + name
+}
+
+func workloadID(id *proto.WorkloadEndpointID) string {
+	return id.OrchestratorId + "/" + id.WorkloadId + "/" + id.EndpointId
+}
+
+func convertPortRanges(ports []*proto.PortRange) []store.PortRange {
+	res := make([]store.PortRange, 0, len(ports))
+	for _, p := range ports {
+		res = append(res, store.PortRange{First: uint16(p.First), Last: uint16(p.Last)})
+	}
+	return res
+}
+
+// Reduces a felix rule to the matches supported by the pipeline, the
+// rules with negated or named port matches are not supported. Neither are
+// the pass (next-tier) actions, the ACL rules table cannot jump to the
+// next tier.
+func convertRule(r *proto.Rule) (store.PolicyRule, error) {
+	res := store.PolicyRule{
+		Action:      r.Action,
+		SrcNet:      r.SrcNet,
+		DstNet:      r.DstNet,
+		SrcPorts:    convertPortRanges(r.SrcPorts),
+		DstPorts:    convertPortRanges(r.DstPorts),
+		SrcIpSetIDs: r.SrcIpSetIds,
+		DstIpSetIDs: r.DstIpSetIds,
+	}
+
+	switch strings.ToLower(r.Action) {
+	case p4.AclActionAllow, p4.AclActionDeny:
+	default:
+		return res, fmt.Errorf("%s actions are not supported", r.Action)
+	}
+	if r.IpVersion == proto.IPVersion_IPV6 {
+		return res, errors.New("IPv6 rules are not supported")
+	}
+	if r.NotProtocol != nil || len(r.NotSrcNet) > 0 || len(r.NotDstNet) > 0 ||
+		len(r.NotSrcPorts) > 0 || len(r.NotDstPorts) > 0 ||
+		len(r.NotSrcIpSetIds) > 0 || len(r.NotDstIpSetIds) > 0 ||
+		len(r.NotSrcNamedPortIpSetIds) > 0 || len(r.NotDstNamedPortIpSetIds) > 0 ||
+		r.NotIcmp != nil {
+		return res, errors.New("negated matches are not supported")
+	}
+	if len(r.SrcNamedPortIpSetIds) > 0 || len(r.DstNamedPortIpSetIds) > 0 ||
+		len(r.DstIpPortSetIds) > 0 {
+		return res, errors.New("named ports are not supported")
+	}
+
+	if r.Protocol != nil {
+		switch p := r.Protocol.NumberOrName.(type) {
+		case *proto.Protocol_Number:
+			res.Protocol = uint8(p.Number)
+		case *proto.Protocol_Name:
+			number, ok := protocolNumbers[strings.ToLower(p.Name)]
+			if !ok {
+				return res, fmt.Errorf("unknown protocol %s", p.Name)
+			}
+			res.Protocol = number
+		}
+	}
+	return res, nil
+}
+
+const aclActionLog = "log"
+
+// The rule denying all the traffic of a direction
+var denyAllRules = []store.PolicyRule{{Action: p4.AclActionDeny}}
+
+// Converts the rules of one direction of a policy. A rule the pipeline
+// cannot express is left out when it allows traffic, which only denies
+// more of it. One denying traffic cannot be left out without letting
+// through what it denies, the policy then denies all the traffic of the
+// direction and an error is returned.
+func convertRules(logger *log.Entry, rules []*proto.Rule) ([]store.PolicyRule, error) {
+	res := make([]store.PolicyRule, 0, len(rules))
+	for _, r := range rules {
+		if strings.EqualFold(r.Action, aclActionLog) {
+			// Logging does not decide the fate of the packet
+			continue
+		}
+		allow := strings.EqualFold(r.Action, p4.AclActionAllow)
+		rule, err := convertRule(r)
+		if err != nil {
+			if !allow {
+				return denyAllRules, fmt.Errorf("rule %s: %v", r.RuleId, err)
+			}
+			logger.Warnf("Skipping allow rule %s: %v", r.RuleId, err)
+			continue
+		}
+		if r.Icmp != nil {
+			if allow {
+				logger.Warnf("Skipping allow rule %s: ICMP type matches are not supported", r.RuleId)
+				continue
+			}
+			logger.Warnf("Rule %s: ICMP type matches are not supported, denying the whole protocol", r.RuleId)
+		}
+		res = append(res, rule)
+	}
+	return res, nil
+}
+
+func isAclIDInUse(id uint32) bool {
+	return len(store.ListWorkloads(func(w store.Workload) bool {
+		return w.IngressAclID == id || w.EgressAclID == id
+	})) > 0
+}
+
+func newAclID() uint32 {
+	for {
+		id := p4.NewUUID()
+		if !isAclIDInUse(id) {
+			return id
+		}
+	}
+}
+
+// The rules deciding the traffic of the workload. The policies come tier
+// by tier and the traffic matching no rule of a tier is denied at its end.
+// The rules holding no pass action, no packet goes past the first tier,
+// whose end is the default action of the ACL rules table.
+func workloadRules(policies []string, ingress bool) []store.PolicyRule {
+	rules := make([]store.PolicyRule, 0)
+	for _, name := range policies {
+		if parsePolicyName(name).Tier != parsePolicyName(policies[0]).Tier {
+			break
+		}
+		entry := store.Policy{Name: name}.GetFromStore()
+		if entry == nil {
+			// The rules of the policy are not known yet,
+			// the traffic is denied until they are.
+			continue
+		}
+		policy := entry.(store.Policy)
+		if ingress {
+			rules = append(rules, policy.IngressRules...)
+		} else {
+			rules = append(rules, policy.EgressRules...)
+		}
+	}
+	return rules
+}
+
+// The number of entries of the ACL rules table of the direction used by
+// the workloads other than the given one, the table is shared by all
+func aclTableUsage(ingress bool, skip string) int {
+	used := 0
+	for _, w := range store.ListWorkloads(nil) {
+		if w.WorkloadID == skip {
+			continue
+		}
+		if ingress {
+			used += len(w.IngressEntries)
+		} else {
+			used += len(w.EgressEntries)
+		}
+	}
+	return used
+}
+
+func ipSetsSnapshot() map[string]store.IpSet {
+	ipSets := store.ListIpSets(nil)
+	res := make(map[string]store.IpSet, len(ipSets))
+	for _, ipSet := range ipSets {
+		res[ipSet.IpSetID] = ipSet
+	}
+	return res
+}
+
+// Compiles the policies of the workload and replaces its ACL entries.
+// A workload with no policy in a direction is not bound in that direction
+// and all its traffic is allowed. When its rules cannot be compiled or
+// their entries do not fit in the table, the workload is bound to deny all
+// the traffic of the direction and an error is returned along with it.
+func (s *ApiServer) programWorkload(ctx context.Context, w store.Workload, old store.Workload) (store.Workload, error) {
+	var denied error
+
+	server := NewApiServer()
+	ipSets := ipSetsSnapshot()
+
+	for _, ingress := range []bool{true, false} {
+		policies := w.EgressPolicies
+		oldID, oldEntries := old.EgressAclID, old.EgressEntries
+		if ingress {
+			policies = w.IngressPolicies
+			oldID, oldEntries = old.IngressAclID, old.IngressEntries
+		}
+
+		var newID uint32
+		var entries []store.AclEntry
+		if len(policies) > 0 && (w.PodIpAddress != "" || w.PodIpv6Address != "") {
+			var err error
+			entries, err = p4.CompileAclRules(workloadRules(policies, ingress), ipSets)
+			if err != nil {
+				denied = fmt.Errorf("ACL rules of %s: %v", w.WorkloadID, err)
+				entries, _ = p4.CompileAclRules(denyAllRules, nil)
+			}
+			// The new entries are inserted before the old ones are deleted
+			used := aclTableUsage(ingress, w.WorkloadID) + len(oldEntries)
+			if used+len(entries) > p4.AclMaxEntries {
+				denied = fmt.Errorf("%d ACL entries of %s exceed the %d entries left in the table",
+					len(entries), w.WorkloadID, p4.AclMaxEntries-used)
+				entries, _ = p4.CompileAclRules(denyAllRules, nil)
+				if used+len(entries) > p4.AclMaxEntries {
+					return old, denied
+				}
+			}
+			newID = newAclID()
+		}
+
+		// The bindings are keyed by the pod addresses, drop them
+		// first when the workload moved to new ones
+		if oldID != 0 && (old.PodIpAddress != w.PodIpAddress || old.PodIpv6Address != w.PodIpv6Address) {
+			if err := p4.ProgramAclRules(ctx, server.p4RtC, old.PodIpAddress, old.PodIpv6Address, ingress,
+				oldID, oldEntries, 0, nil); err != nil {
+				return old, err
+			}
+			oldID, oldEntries = 0, nil
+		}
+
+		if err := p4.ProgramAclRules(ctx, server.p4RtC, w.PodIpAddress, w.PodIpv6Address, ingress,
+			oldID, oldEntries, newID, entries); err != nil {
+			return old, err
+		}
+
+		if ingress {
+			w.IngressAclID, w.IngressEntries = newID, entries
+			old.IngressAclID, old.IngressEntries = newID, entries
+		} else {
+			w.EgressAclID, w.EgressEntries = newID, entries
+			old.EgressAclID, old.EgressEntries = newID, entries
+		}
+	}
+	return w, denied
+}
+
+func (s *ApiServer) reprogramWorkloads(ctx context.Context, policies map[string]bool) error {
+	var err error
+
+	logger := s.log.WithField("func", "reprogramWorkloads")
+
+	for _, w := range store.GetPolicyWorkloads(policies) {
+		updated, e := s.programWorkload(ctx, w, w)
+		if e != nil {
+			logger.Errorf("Failed to program the policies of %s: %v", w.WorkloadID, e)
+			err = e
+		}
+		updated.WriteToStore()
+	}
+	return err
+}
+
+func (s *ApiServer) ActivePolicyUpdate(ctx context.Context, in *proto.ActivePolicyUpdate) (*proto.Reply, error) {
+	logger := s.log.WithField("func", "ActivePolicyUpdate")
+	logger.Infof("Incoming ActivePolicyUpdate Request %+v", in)
+
+	out := &proto.Reply{
+		Successful: true,
+	}
+
+	if in.Id == nil || in.Policy == nil {
+		return replyError(out, errors.New("Missing policy"))
+	}
+
+	ingressRules, ingressErr := convertRules(logger, in.Policy.InboundRules)
+	egressRules, egressErr := convertRules(logger, in.Policy.OutboundRules)
+	policy := store.Policy{
+		Name:         policyName(in.Id.Tier, in.Id.Name),
+		IngressRules: ingressRules,
+		EgressRules:  egressRules,
+	}
+	policy.WriteToStore()
+
+	if err := s.reprogramWorkloads(ctx, map[string]bool{policy.Name: true}); err != nil {
+		return replyError(out, err)
+	}
+	// The policy is in place, denying the traffic its rules cannot decide
+	if ingressErr != nil {
+		return replyError(out, fmt.Errorf("Policy %s denies all the ingress traffic, %v", policy.Name, ingressErr))
+	}
+	if egressErr != nil {
+		return replyError(out, fmt.Errorf("Policy %s denies all the egress traffic, %v", policy.Name, egressErr))
+	}
+	return out, nil
+}
+
+func (s *ApiServer) ActivePolicyRemove(ctx context.Context, in *proto.ActivePolicyRemove) (*proto.Reply, error) {
+	logger := s.log.WithField("func", "ActivePolicyRemove")
+	logger.Infof("Incoming ActivePolicyRemove Request %+v", in)
+
+	out := &proto.Reply{
+		Successful: true,
+	}
+
+	if in.Id == nil {
+		return replyError(out, errors.New("Missing policy id"))
+	}
+
+	policy := store.Policy{
+		Name: policyName(in.Id.Tier, in.Id.Name),
+	}
+	policy.DeleteFromStore()
+
+	if err := s.reprogramWorkloads(ctx, map[string]bool{policy.Name: true}); err != nil {
+		return replyError(out, err)
+	}
+	return out, nil
+}
+
+func (s *ApiServer) updateIpSet(ctx context.Context, ipSet store.IpSet, remove bool) error {
+	// The policies have to be looked up before the ip set is gone
+	policies := store.GetIpSetPolicies(ipSet.IpSetID)
+	if remove {
+		ipSet.DeleteFromStore()
+	} else {
+		ipSet.WriteToStore()
+	}
+	return s.reprogramWorkloads(ctx, policies)
+}
+
+func (s *ApiServer) UpdateIPSet(ctx context.Context, in *proto.IPSetUpdate) (*proto.Reply, error) {
+	logger := s.log.WithField("func", "UpdateIPSet")
+	logger.Infof("Incoming UpdateIPSet Request %+v", in)
+
+	out := &proto.Reply{
+		Successful: true,
+	}
+
+	if in.Type == proto.IPSetUpdate_IP_AND_PORT {
+		logger.Warnf("IP set %s: named ports are not supported", in.Id)
+	}
+
+	ipSet := store.IpSet{
+		IpSetID: in.Id,
+		Members: in.Members,
+	}
+	if err := s.updateIpSet(ctx, ipSet, false); err != nil {
+		return replyError(out, err)
+	}
+	return out, nil
+}
+
+func (s *ApiServer) UpdateIPSetDelta(ctx context.Context, in *proto.IPSetDeltaUpdate) (*proto.Reply, error) {
+	logger := s.log.WithField("func", "UpdateIPSetDelta")
+	logger.Infof("Incoming UpdateIPSetDelta Request %+v", in)
+
+	out := &proto.Reply{
+		Successful: true,
+	}
+
+	ipSet := store.IpSet{
+		IpSetID: in.Id,
+	}
+	if entry := ipSet.GetFromStore(); entry != nil {
+		ipSet = entry.(store.IpSet)
+	}
+
+	removed := make(map[string]bool)
+	for _, m := range in.RemovedMembers {
+		removed[m] = true
+	}
+	members := make([]string, 0, len(ipSet.Members)+len(in.AddedMembers))
+	for _, m := range ipSet.Members {
+		if !removed[m] {
+			members = append(members, m)
+		}
+	}
+	ipSet.Members = append(members, in.AddedMembers...)
+
+	if err := s.updateIpSet(ctx, ipSet, false); err != nil {
+		return replyError(out, err)
+	}
+	return out, nil
+}
+
+func (s *ApiServer) RemoveIPSet(ctx context.Context, in *proto.IPSetRemove) (*proto.Reply, error) {
+	logger := s.log.WithField("func", "RemoveIPSet")
+	logger.Infof("Incoming RemoveIPSet Request %+v", in)
+
+	out := &proto.Reply{
+		Successful: true,
+	}
+
+	if err := s.updateIpSet(ctx, store.IpSet{IpSetID: in.Id}, true); err != nil {
+		return replyError(out, err)
+	}
+	return out, nil
+}
+
+func (s *ApiServer) UpdateLocalEndpoint(ctx context.Context, in *proto.WorkloadEndpointUpdate) (*proto.Reply, error) {
+	logger := s.log.WithField("func", "UpdateLocalEndpoint")
+	logger.Infof("Incoming UpdateLocalEndpoint Request %+v", in)
+
+	out := &proto.Reply{
+		Successful: true,
+	}
+
+	if in.Id == nil || in.Endpoint == nil {
+		return replyError(out, errors.New("Missing workload endpoint"))
+	}
+
+	w := store.Workload{
+		WorkloadID: workloadID(in.Id),
+	}
+	old := w
+	if entry := w.GetFromStore(); entry != nil {
+		old = entry.(store.Workload)
+	}
+
+	if len(in.Endpoint.Ipv4Nets) > 0 {
+		w.PodIpAddress = strings.Split(in.Endpoint.Ipv4Nets[0], "/")[0]
+	}
+	if len(in.Endpoint.Ipv6Nets) > 0 {
+		w.PodIpv6Address = canonicalIP(in.Endpoint.Ipv6Nets[0])
+	}
+	// The policies are evaluated tier by tier, in the order given
+	for _, tier := range in.Endpoint.Tiers {
+		for _, name := range tier.IngressPolicies {
+			w.IngressPolicies = append(w.IngressPolicies, policyName(tier.Name, name))
+		}
+		for _, name := range tier.EgressPolicies {
+			w.EgressPolicies = append(w.EgressPolicies, policyName(tier.Name, name))
+		}
+	}
+
+	updated, err := s.programWorkload(ctx, w, old)
+	if err != nil {
+		logger.Errorf("Failed to program the policies of %s: %v", w.WorkloadID, err)
+		if updated.WorkloadID != "" {
+			updated.WriteToStore()
+		}
+		return replyError(out, err)
+	}
+	updated.WriteToStore()
+	logger.Infof("Programmed %d ingress and %d egress ACL entries for %s",
+		len(updated.IngressEntries), len(updated.EgressEntries), w.WorkloadID)
+
+	return out, nil
+}
+
+func (s *ApiServer) RemoveLocalEndpoint(ctx context.Context, in *proto.WorkloadEndpointRemove) (*proto.Reply, error) {
+	logger := s.log.WithField("func", "RemoveLocalEndpoint")
+	logger.Infof("Incoming RemoveLocalEndpoint Request %+v", in)
+
+	out := &proto.Reply{
+		Successful: true,
+	}
+
+	if in.Id == nil {
+		return replyError(out, errors.New("Missing workload endpoint id"))
+	}
+
+	w := store.Workload{
+		WorkloadID: workloadID(in.Id),
+	}
+	entry := w.GetFromStore()
+	if entry == nil {
+		logger.Infof("Workload %s does not exist in the store", w.WorkloadID)
+		return out, nil
+	}
+	old := entry.(store.Workload)
+
+	// Keep the addresses so that the bindings get removed
+	w.PodIpAddress = old.PodIpAddress
+	w.PodIpv6Address = old.PodIpv6Address
+	updated, err := s.programWorkload(ctx, w, old)
+	if err != nil {
+		updated.WriteToStore()
+		return replyError(out, err)
+	}
+	w.DeleteFromStore()
+
+	return out, nil
+}
+
+func parsePolicyName(name string) *proto.PolicyID {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 {
+		return &proto.PolicyID{Name: name}
+	}
+	return &proto.PolicyID{Tier: parts[0], Name: parts[1]}
+}
+
+// The workload id of kubernetes endpoints is namespace/pod,
+// so only the first and the last separators are significant.
+func parseWorkloadID(id string) *proto.WorkloadEndpointID {
+	first := strings.Index(id, "/")
+	last := strings.LastIndex(id, "/")
+	if first < 0 || first == last {
+		return &proto.WorkloadEndpointID{WorkloadId: id}
+	}
+	return &proto.WorkloadEndpointID{
+		OrchestratorId: id[:first],
+		WorkloadId:     id[first+1 : last],
+		EndpointId:     id[last+1:],
+	}
+}
+
+func (s *ApiServer) GetPolicyState(ctx context.Context, in *proto.PolicyStateRequest) (*proto.PolicyStateReply, error) {
+	logger := s.log.WithField("func", "GetPolicyState")
+	logger.Infof("Incoming GetPolicyState Request %+v", in)
+
+	out := &proto.PolicyStateReply{}
+	for _, p := range store.ListPolicies(nil) {
+		out.PolicyIds = append(out.PolicyIds, parsePolicyName(p.Name))
+	}
+	for _, ipSet := range store.ListIpSets(nil) {
+		out.IpSetIds = append(out.IpSetIds, ipSet.IpSetID)
+	}
+	for _, w := range store.ListWorkloads(nil) {
+		out.WorkloadEndpointIds = append(out.WorkloadEndpointIds, parseWorkloadID(w.WorkloadID))
+	}
+	return out, nil
+}
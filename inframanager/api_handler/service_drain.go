@@ -0,0 +1,130 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api_handler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	p4 "github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/p4"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/store"
+	"github.com/ipdk-io/k8s-infra-offload/proto"
+)
+
+// The time the purge of drained backends is given to complete
+const drainPurgeTimeout = 10 * time.Second
+
+// The backends removed at once from a service. They do not get new
+// flows anymore, but the flows pinned to them are still served until
+// the drain period is over. The drains do not survive a restart, the
+// reconciliation of the stores purges what is left of them.
+type serviceDrain struct {
+	members p4.ServiceMembers
+	service store.Service
+	timer   *time.Timer
+	purge   sync.Once
+}
+
+func drainPeriod() time.Duration {
+	if config == nil {
+		return 0
+	}
+	return time.Duration(config.DrainPeriod) * time.Second
+}
+
+func backendIPs(backends []*proto.NatEndpointTuple) []string {
+	var podIpAddr []string
+	for _, backend := range backends {
+		if backend.DstEp != nil {
+			podIpAddr = append(podIpAddr, natEndpointIP(backend.DstEp))
+		}
+	}
+	return podIpAddr
+}
+
+// Has the backends purged once the drain period is over, the pipeline
+// only deleted their members
+func (s *ApiServer) drainBackends(m p4.ServiceMembers, service store.Service) {
+	if !m.Drain {
+		return
+	}
+	d := &serviceDrain{
+		members: m,
+		service: service,
+	}
+
+	s.drainMutex.Lock()
+	defer s.drainMutex.Unlock()
+	if s.drains == nil {
+		s.drains = make(map[*serviceDrain]bool)
+	}
+	s.drains[d] = true
+	d.timer = time.AfterFunc(drainPeriod(), func() {
+		s.purgeDrain(d)
+	})
+	s.log.Infof("Draining %d backends of service %s:%d for %v",
+		len(m.MemberID), service.ClusterIp, service.ClusterPort, drainPeriod())
+}
+
+// A backend still draining is purged before it gets added to a service
+// again, its rx_src_ip entry is keyed by its address only and would be
+// in the way. The whole drain it is part of is purged along with it.
+func (s *ApiServer) purgeDrainedBackends(podIpAddr []string) {
+	isAdded := make(map[string]bool, len(podIpAddr))
+	for _, ipAddr := range podIpAddr {
+		isAdded[ipAddr] = true
+	}
+
+	var purged []*serviceDrain
+	s.drainMutex.Lock()
+	for d := range s.drains {
+		for _, ipAddr := range d.members.PodIpAddr {
+			if isAdded[ipAddr] {
+				d.timer.Stop()
+				purged = append(purged, d)
+				break
+			}
+		}
+	}
+	s.drainMutex.Unlock()
+
+	for _, d := range purged {
+		s.purgeDrain(d)
+	}
+}
+
+// Purges the drained backends once. The drain is only forgotten once
+// purged, so that a caller finding it while the purge is running waits
+// for the purge to complete.
+func (s *ApiServer) purgeDrain(d *serviceDrain) {
+	d.purge.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), drainPurgeTimeout)
+		defer cancel()
+		err := p4.PurgeServiceMembers(ctx, s.p4RtC, d.members, d.service)
+
+		s.drainMutex.Lock()
+		delete(s.drains, d)
+		s.drainMutex.Unlock()
+
+		if err != nil {
+			s.log.WithError(err).Errorf("Failed to purge the drained backends %v of service %s:%d",
+				d.members.PodIpAddr, d.service.ClusterIp, d.service.ClusterPort)
+			return
+		}
+		s.log.Infof("Purged the drained backends %v of service %s:%d",
+			d.members.PodIpAddr, d.service.ClusterIp, d.service.ClusterPort)
+	})
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/antoninbas/p4runtime-go-client/pkg/signals"
 	api "github.com/ipdk-io/k8s-infra-offload/inframanager/api_handler"
 	mgr "github.com/ipdk-io/k8s-infra-offload/pkg/inframanager"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/p4"
 )
 
 func main() {
@@ -55,7 +56,10 @@ func main() {
 	stopCh := signals.RegisterSignalHandlers()
 
 	api.NewApiServer()
-	store.NewEndPoint()
+	if err := store.OpenBackend(config.StoreBackend, config.StoreDir); err != nil {
+		log.Fatalf("Failed to open the %s store backend: %v", config.StoreBackend, err)
+	}
+	defer store.CloseBackend()
 
 	if err := api.OpenP4RtC(ctx, 0, 1, stopCh); err != nil {
 		log.Errorf("Failed to open p4 runtime client connection")
@@ -72,11 +76,34 @@ func main() {
 			api.CloseCon()
 			os.Exit(1)
 		}
+		if err := p4.CheckPipelineP4Info(pipelineConfig.P4Info,
+			p4InfoPath); err != nil {
+			log.Errorf("Stale pipeline: %v", err)
+			api.CloseCon()
+			os.Exit(1)
+		}
 		store.InitEndPointStore(false)
+		store.InitServiceStore(false)
+		store.InitPolicyStore(false)
+
+		/*
+			The pipeline kept its entries from the previous
+			run, which may have stopped before syncing the
+			stores. Fix up the pipeline according to the stores.
+		*/
+		if err := api.ReconcileStores(ctx); err != nil {
+			log.Errorf("Failed to reconcile the pipeline with the stores: %v", err)
+		}
 	} else {
 		// Setting fwding pipeline
 		log.Infof("Setting the pipeline")
 
+		if err := p4.CheckPipelineBinary(p4BinPath, p4InfoPath); err != nil {
+			log.Errorf("Stale pipeline binary: %v", err)
+			api.CloseCon()
+			os.Exit(1)
+		}
+
 		if _, err := api.SetFwdPipe(ctx, p4BinPath, p4InfoPath,
 			0); err != nil {
 			log.Errorf("Error when setting forwarding pipe: %v", err)
@@ -84,6 +111,8 @@ func main() {
 			os.Exit(1)
 		}
 		store.InitEndPointStore(true)
+		store.InitServiceStore(true)
+		store.InitPolicyStore(true)
 	}
 
 	// Starting inframanager gRPC server
@@ -17,17 +17,17 @@ package cni
 import (
 	"context"
 	"errors"
-	"fmt"
+	"io"
 	"net"
 	"time"
 
 	"github.com/containernetworking/plugins/pkg/ns"
+	managerclient "github.com/ipdk-io/k8s-infra-offload/pkg/manager_client"
 	"github.com/ipdk-io/k8s-infra-offload/pkg/netconf"
 	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
 	pb "github.com/ipdk-io/k8s-infra-offload/proto"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 	"gopkg.in/tomb.v2"
@@ -50,7 +50,7 @@ type CniServer struct {
 
 var (
 	newPodInterface     = netconf.NewPodInterface
-	grpcDial            = grpc.Dial
+	getManagerConn      = managerclient.GetConn
 	newInfraAgentClient = pb.NewInfraAgentClient
 
 	listenFunc = net.Listen
@@ -125,6 +125,12 @@ func (s *CniServer) serve() error {
 func (s *CniServer) StopServer() {
 	s.grpc.GracefulStop()
 	s.listener.Close()
+	// the pod interface may have set up resources of its own on the host
+	if c, ok := s.podInterface.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			s.log.WithError(err).Error("failed to close the pod interface")
+		}
+	}
 	types.CNIServerStatus = types.ServerStatusStopped
 }
 
@@ -137,10 +143,7 @@ func (s *CniServer) Add(ctx context.Context, in *pb.AddRequest) (*pb.AddReply, e
 		out.ErrorMessage = err.Error()
 		return out, nil
 	}
-	managerAddr := fmt.Sprintf("%s:%s", types.InfraManagerAddr, types.InfraManagerPort)
-	conn, err := grpcDial(managerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	defer grpcClose(conn, s.log, "failed to close connnection, not fatal")
-
+	conn, err := getManagerConn()
 	if err != nil {
 		out.ErrorMessage = err.Error()
 		return out, nil
@@ -149,7 +152,9 @@ func (s *CniServer) Add(ctx context.Context, in *pb.AddRequest) (*pb.AddReply, e
 
 	in.DesiredHostInterfaceName = intfInfo.InterfaceName
 
-	mgrRply, err := s.podInterface.SetupNetwork(ctx, c, intfInfo, in)
+	callCtx, cancel := managerclient.NewCallContext(ctx)
+	defer cancel()
+	mgrRply, err := s.podInterface.SetupNetwork(callCtx, c, intfInfo, in)
 	if err != nil || !mgrRply.Successful {
 		// We should release allocated interface here on error
 		s.log.WithError(err).Error("Failed to configure interface via infra-manager, releasing allocated Pod interface")
@@ -187,17 +192,16 @@ func (s *CniServer) Del(ctx context.Context, in *pb.DelRequest) (*pb.DelReply, e
 		}
 	}
 
-	managerAddr := fmt.Sprintf("%s:%s", types.InfraManagerAddr, types.InfraManagerPort)
-	conn, err := grpcDial(managerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	defer grpcClose(conn, s.log, "failed to close connnection")
-
+	conn, err := getManagerConn()
 	if err != nil {
 		out.Successful = false
 		out.ErrorMessage = err.Error()
 		return out, nil
 	}
 	c := newInfraAgentClient(conn)
-	out, err = s.podInterface.ReleaseNetwork(ctx, c, in)
+	callCtx, cancel := managerclient.NewCallContext(ctx)
+	defer cancel()
+	out, err = s.podInterface.ReleaseNetwork(callCtx, c, in)
 	if err != nil || !out.Successful {
 		s.log.WithError(err).Error("Failed to clean up interface config via infra-manager")
 		return out, err
@@ -224,12 +228,3 @@ func (s *CniServer) Check(ctx context.Context, in *healthgrpc.HealthCheckRequest
 func (s *CniServer) Watch(in *healthgrpc.HealthCheckRequest, _ healthgrpc.Health_WatchServer) error {
 	return errors.New("Unimplemented")
 }
-
-func grpcClose(conn *grpc.ClientConn, log *log.Entry, errorMsg string) {
-	if conn == nil {
-		return
-	}
-	if err := conn.Close(); err != nil {
-		log.WithError(err).Error(errorMsg)
-	}
-}
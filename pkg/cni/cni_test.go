@@ -61,6 +61,7 @@ type podInterfaceMock struct {
 	addReply               *proto.AddReply
 	addReplyErr            error
 	delReply               *proto.DelReply
+	closed                 bool
 }
 
 func (pi *podInterfaceMock) CreatePodInterface(in *proto.AddRequest) (*types.InterfaceInfo, error) {
@@ -79,6 +80,11 @@ func (pi *podInterfaceMock) ReleaseNetwork(context.Context, proto.InfraAgentClie
 	return pi.delReply, pi.addReplyErr
 }
 
+func (pi *podInterfaceMock) Close() error {
+	pi.closed = true
+	return nil
+}
+
 func bufDialer(context.Context, string) (net.Conn, error) {
 	return listener.Dial()
 }
@@ -145,7 +151,7 @@ var _ = Describe("CNI backend server", func() {
 	})
 
 	var _ = BeforeEach(func() {
-		grpcDial = func(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+		getManagerConn = func() (*grpc.ClientConn, error) {
 			return grpc.DialContext(context.TODO(), "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
 		}
 	})
@@ -262,7 +268,7 @@ var _ = Describe("CNI backend server", func() {
 					},
 					log: logrus.NewEntry(logrus.New()),
 				}
-				grpcDial = func(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+				getManagerConn = func() (*grpc.ClientConn, error) {
 					return nil, fmt.Errorf("dummy error")
 				}
 				out, err := server.Add(context.TODO(), in)
@@ -360,7 +366,7 @@ var _ = Describe("CNI backend server", func() {
 					},
 					log: logrus.NewEntry(logrus.New()),
 				}
-				grpcDial = func(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+				getManagerConn = func() (*grpc.ClientConn, error) {
 					return nil, fmt.Errorf("dummy error")
 				}
 				getNSFunc = func(nspath string) (ns.NetNS, error) {
@@ -449,6 +455,20 @@ var _ = Describe("CNI backend server", func() {
 			server.StopServer()
 			listenFunc = listenFuncBack
 		})
+		var _ = It("StopServer is called, closing the pod interface", func() {
+			listenFuncBack = listenFunc
+			listenFunc = mockListen
+			newPodInterfaceBackup := newPodInterface
+			pi := &podInterfaceMock{}
+			newPodInterface = func(t string, log *logrus.Entry) (types.PodInterface, error) { return pi, nil }
+			agentAddr := fmt.Sprintf("%s:%s", types.InfraAgentAddr, randomPort())
+			server, err := NewCniServer(logrus.New().WithContext(context.TODO()), "dummy", agentAddr, nil)
+			Expect(err).ToNot(HaveOccurred())
+			server.StopServer()
+			Expect(pi.closed).To(BeTrue())
+			newPodInterface = newPodInterfaceBackup
+			listenFunc = listenFuncBack
+		})
 	})
 
 	var _ = Context("Creation and deletion of the server should return error when", func() {
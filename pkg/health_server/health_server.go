@@ -17,6 +17,7 @@ package healthserver
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
 	"time"
@@ -30,6 +31,9 @@ import (
 
 var (
 	grpcDial = grpc.Dial
+	// The variables served at /pools. The other variables of the
+	// agent, like its command line, are not for the health port.
+	poolVars = []string{"resourcePools", "resourcePoolChanges"}
 )
 
 type httpHealthServer interface {
@@ -52,6 +56,13 @@ func getCheck(hs *healtServer) func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
+		// check status of the connection to infra manager
+		if ok := hs.checkInfraManagerConnection(); !ok {
+			hs.log.Infof("infra manager connection is %s", types.InfraManagerConnStatus)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		// check status of cni grpc server status
 		if ok := hs.checkCniServerLiveness(); !ok {
 			hs.log.Infof("CNI reports failure")
@@ -70,12 +81,33 @@ func getCheck(hs *healtServer) func(http.ResponseWriter, *http.Request) {
 	}
 }
 
+// getPools serves the published pool variables as a JSON object, in
+// the format of expvar
+func getPools(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{")
+	first := true
+	for _, name := range poolVars {
+		v := expvar.Get(name)
+		if v == nil {
+			continue
+		}
+		if !first {
+			fmt.Fprintf(w, ",")
+		}
+		first = false
+		fmt.Fprintf(w, "%q: %s", name, v.String())
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
 func NewHealthCheckServer(l *logrus.Entry) (types.Server, error) {
 	hs := &healtServer{
 		log: l,
 	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/check", getCheck(hs))
+	mux.HandleFunc("/pools", getPools)
 	hs.srv = &http.Server{
 		Addr:    ":" + types.DefaultHealthServerPort,
 		Handler: mux,
@@ -96,6 +128,10 @@ func (s *healtServer) checkInfraManagerLiveness() bool {
 	return s.checkGrpcServerStatus(managerAddr)
 }
 
+func (s *healtServer) checkInfraManagerConnection() bool {
+	return types.InfraManagerConnStatus == types.ServerStatusOK
+}
+
 func (s *healtServer) checkCniServerLiveness() bool {
 	// TODO change this to UDS when grpc start working using it
 	agentAddr := fmt.Sprintf("%s:%s", types.InfraAgentAddr, types.InfraAgentPort)
@@ -2,9 +2,12 @@ package healthserver
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"expvar"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
@@ -125,6 +128,20 @@ var _ = Describe("HealthServer", func() {
 		})
 	})
 
+	var _ = Context("getPools() should", func() {
+		var _ = It("serve the pool variables only", func() {
+			expvar.NewMap("resourcePools").Add("tap", 1)
+			rec := httptest.NewRecorder()
+			getPools(rec, httptest.NewRequest(http.MethodGet, "/pools", nil))
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			vars := map[string]interface{}{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &vars)).To(Succeed())
+			Expect(vars).To(HaveKey("resourcePools"))
+			Expect(vars).ToNot(HaveKey("cmdline"))
+			Expect(vars).ToNot(HaveKey("memstats"))
+		})
+	})
+
 	var _ = Context("getCheck() should", func() {
 		var _ = It("return HTTP_200 for check request", func() {
 			var t tomb.Tomb
@@ -140,6 +157,7 @@ var _ = Describe("HealthServer", func() {
 			})
 			// fake services running
 			types.ServiceServerStatus = types.ServerStatusOK
+			types.InfraManagerConnStatus = types.ServerStatusOK
 			c := hs.srv.(*inMemoryServer).newHttpClient()
 			res, err := c.Get("http://in-memory-server/check")
 			Expect(err).ToNot(HaveOccurred())
@@ -152,6 +170,34 @@ var _ = Describe("HealthServer", func() {
 			Expect(err).To(HaveOccurred())
 		})
 
+		var _ = It("return HTTP_500 if connection to infra manager is down", func() {
+			var t tomb.Tomb
+			hs := &healtServer{log: logrus.NewEntry(logrus.StandardLogger())}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/check", getCheck(hs))
+			hs.srv = newInMemoryServer(mux)
+			t.Go(func() error {
+				defer GinkgoRecover()
+				err := hs.Start(&t)
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+			// fake services running, connection reconnecting
+			types.ServiceServerStatus = types.ServerStatusOK
+			types.InfraManagerConnStatus = "TRANSIENT_FAILURE"
+			gRPChs.setRes([]*grpc_health_v1.HealthCheckResponse{{Status: grpc_health_v1.HealthCheckResponse_SERVING}})
+			c := hs.srv.(*inMemoryServer).newHttpClient()
+			res, err := c.Get("http://in-memory-server/check")
+			Expect(err).To(BeNil())
+			Expect(res).ToNot(BeNil())
+			Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
+			res.Body.Close()
+
+			t.Kill(errors.New("stop"))
+			err = t.Wait()
+			Expect(err).To(HaveOccurred())
+		})
+
 		var _ = It("return HTTP_500 if policy server not running", func() {
 			var t tomb.Tomb
 			hs := &healtServer{log: logrus.NewEntry(logrus.StandardLogger())}
@@ -192,6 +238,7 @@ var _ = Describe("HealthServer", func() {
 			})
 			// fake services running
 			types.ServiceServerStatus = types.ServerStatusOK
+			types.InfraManagerConnStatus = types.ServerStatusOK
 			// grpc return error
 			response := []*grpc_health_v1.HealthCheckResponse{{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}}
 			gRPChs.setRes(response)
@@ -221,6 +268,7 @@ var _ = Describe("HealthServer", func() {
 			})
 			// fake services running
 			types.ServiceServerStatus = types.ServerStatusOK
+			types.InfraManagerConnStatus = types.ServerStatusOK
 			// grpc return error
 			response := []*grpc_health_v1.HealthCheckResponse{{Status: grpc_health_v1.HealthCheckResponse_SERVING}, {Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}}
 			gRPChs.setRes(response)
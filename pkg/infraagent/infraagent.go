@@ -25,6 +25,7 @@ import (
 
 	"github.com/ipdk-io/k8s-infra-offload/pkg/cni"
 	healthserver "github.com/ipdk-io/k8s-infra-offload/pkg/health_server"
+	managerclient "github.com/ipdk-io/k8s-infra-offload/pkg/manager_client"
 	"github.com/ipdk-io/k8s-infra-offload/pkg/policy"
 	"github.com/ipdk-io/k8s-infra-offload/pkg/services"
 	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
@@ -206,6 +207,12 @@ func (a *agent) Run() {
 		logger.WithError(err).Fatal("Failed to get cluster configuration")
 	}
 
+	// dial the connection shared by all servers up front,
+	// so that its state is reported from the start
+	if _, err := managerclient.GetConn(); err != nil {
+		logger.WithError(err).Fatal("Failed to create connection to infra manager")
+	}
+
 	servers, err := a.prepareServers()
 	if err != nil {
 		log.Errorf("failed to initialize one or more server(s): %s", err)
@@ -220,4 +227,5 @@ func (a *agent) Run() {
 	<-signalChannel
 	logger.Infof("SIGINT received, exiting")
 	a.stopServers()
+	managerclient.Close()
 }
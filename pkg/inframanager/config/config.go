@@ -39,6 +39,9 @@ func ReadConfig(conf *Configuration, cfgFileName string) {
 	viper.SetDefault("DefaultDevice", 0)
 	viper.SetDefault("EnableService", 0)
 	viper.SetDefault("EnableRouting", 0)
+	viper.SetDefault("StoreDir", "/opt/inframanager")
+	viper.SetDefault("StoreBackend", "json")
+	viper.SetDefault("DrainPeriod", 30)
 
 	err := viper.Unmarshal(conf)
 	if err != nil {
@@ -54,4 +57,7 @@ func ReadConfig(conf *Configuration, cfgFileName string) {
 	fmt.Println("P4 bin path \t", viper.GetString("P4BinPath"))
 	fmt.Println("EnableServices:\t", viper.GetInt(""))
 	fmt.Println("HostName:\t", viper.GetString("HostName"))
+	fmt.Println("Store dir:\t", viper.GetString("StoreDir"))
+	fmt.Println("Store backend:\t", viper.GetString("StoreBackend"))
+	fmt.Println("Drain period:\t", viper.GetInt("DrainPeriod"))
 }
@@ -27,6 +27,9 @@ type Configuration struct {
 	EnableService bool
 	EnableRouting bool
 	DefaultDevice int
+	StoreDir      string
+	StoreBackend  string
+	DrainPeriod   int
 	EXAMPLE_PATH  string
 	EXAMPLE_VAR   string
 }
@@ -87,5 +87,7 @@ func Run(stopCh <-chan struct{}, waitCh chan<- struct{}) {
 
 	manager.stopServer()
 	store.RunSyncEndPointInfo()
+	store.RunSyncServiceInfo()
+	store.RunSyncPolicyInfo()
 	close(waitCh)
 }
@@ -0,0 +1,204 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/store"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Size of the ingress_acl_rules and egress_acl_rules tables
+	AclMaxEntries = 4096
+
+	AclActionAllow = "allow"
+	AclActionDeny  = "deny"
+)
+
+type ipMatch struct {
+	ip   uint32
+	mask uint32
+}
+
+type portMatch struct {
+	port uint16
+	mask uint16
+}
+
+// A nil list matches any address, an empty one matches none
+func parseNets(nets []string) []ipMatch {
+	res := make([]ipMatch, 0, len(nets))
+	for _, n := range nets {
+		if !strings.Contains(n, "/") {
+			n = n + "/32"
+		}
+		_, ipNet, err := net.ParseCIDR(n)
+		if err != nil || ipNet.IP.To4() == nil {
+			// Only IPv4 is supported by the pipeline
+			continue
+		}
+		res = append(res, ipMatch{
+			ip:   uint32(IP4toInt(ipNet.IP)),
+			mask: uint32(IP4toInt(net.IP(ipNet.Mask))),
+		})
+	}
+	return res
+}
+
+// The intersection of two prefixes is the longer one,
+// if it is contained in the other
+func intersectNets(a []ipMatch, b []ipMatch) []ipMatch {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	res := make([]ipMatch, 0)
+	for _, x := range a {
+		for _, y := range b {
+			mask := x.mask & y.mask
+			if x.ip&mask != y.ip&mask {
+				continue
+			}
+			if x.mask > y.mask {
+				res = append(res, x)
+			} else {
+				res = append(res, y)
+			}
+		}
+	}
+	return res
+}
+
+// A packet matches the rule nets and all the ip sets of the rule
+func ruleNets(nets []string, ipSetIDs []string, ipSets map[string]store.IpSet) []ipMatch {
+	var res []ipMatch
+	if len(nets) > 0 {
+		res = parseNets(nets)
+	}
+	for _, id := range ipSetIDs {
+		members := make([]ipMatch, 0)
+		if ipSet, ok := ipSets[id]; ok {
+			members = parseNets(ipSet.Members)
+		} else {
+			log.Warnf("IP set %s does not exist", id)
+		}
+		res = intersectNets(res, members)
+	}
+	return res
+}
+
+// Splits the range into the prefixes covering it
+func portRangeToTernary(first uint16, last uint16) []portMatch {
+	res := make([]portMatch, 0)
+	lo := uint32(first)
+	hi := uint32(last)
+	for lo <= hi {
+		size := uint32(1)
+		for lo&(size*2-1) == 0 && lo+size*2-1 <= hi {
+			size *= 2
+		}
+		res = append(res, portMatch{
+			port: uint16(lo),
+			mask: uint16(^(size - 1)),
+		})
+		lo += size
+	}
+	return res
+}
+
+func rulePorts(ports []store.PortRange) []portMatch {
+	if len(ports) == 0 {
+		return []portMatch{{}}
+	}
+	res := make([]portMatch, 0)
+	for _, r := range ports {
+		res = append(res, portRangeToTernary(r.First, r.Last)...)
+	}
+	return res
+}
+
+func anyIfNil(m []ipMatch) []ipMatch {
+	if m == nil {
+		return []ipMatch{{}}
+	}
+	return m
+}
+
+// CompileAclRules turns the ordered policy rules into ternary entries
+// of the ACL rules tables. The priority of the entries follows the order
+// of the rules, the first rule matching a packet decides its fate and
+// the packets matching no rule are dropped by the table default action.
+func CompileAclRules(rules []store.PolicyRule, ipSets map[string]store.IpSet) ([]store.AclEntry, error) {
+	entries := make([]store.AclEntry, 0)
+
+	for _, r := range rules {
+		var allow bool
+		switch strings.ToLower(r.Action) {
+		case AclActionAllow:
+			allow = true
+		case AclActionDeny:
+			allow = false
+		default:
+			// Skipping the rule would let another one decide the
+			// traffic it matches
+			return nil, fmt.Errorf("unsupported rule action %s", r.Action)
+		}
+
+		var protocolMask uint8
+		if r.Protocol != 0 {
+			protocolMask = 0xff
+		}
+
+		for _, src := range anyIfNil(ruleNets(r.SrcNet, r.SrcIpSetIDs, ipSets)) {
+			for _, dst := range anyIfNil(ruleNets(r.DstNet, r.DstIpSetIDs, ipSets)) {
+				for _, sp := range rulePorts(r.SrcPorts) {
+					for _, dp := range rulePorts(r.DstPorts) {
+						entries = append(entries, store.AclEntry{
+							SrcIp:        src.ip,
+							SrcIpMask:    src.mask,
+							DstIp:        dst.ip,
+							DstIpMask:    dst.mask,
+							Protocol:     r.Protocol,
+							ProtocolMask: protocolMask,
+							SrcPort:      sp.port,
+							SrcPortMask:  sp.mask,
+							DstPort:      dp.port,
+							DstPortMask:  dp.mask,
+							Allow:        allow,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	if len(entries) > AclMaxEntries {
+		return nil, fmt.Errorf("%d ACL entries exceed the table size %d",
+			len(entries), AclMaxEntries)
+	}
+
+	for i := range entries {
+		entries[i].Priority = int32(len(entries) - i)
+	}
+	return entries, nil
+}
@@ -23,7 +23,9 @@ import (
 	"net"
 )
 
-func insertMacToPortTableEntry(ctx context.Context, p4RtC *client.Client, macAddr string, port uint32) error {
+// The entries are always built with their action, so that a deleted
+// entry can be inserted back on rollback.
+func macToPortTableEntry(ctx context.Context, tx *Transaction, macAddr string, port uint32, action OperationType) error {
 	var err error
 	mac, err := net.ParseMAC(macAddr)
 	if err != nil {
@@ -31,118 +33,83 @@ func insertMacToPortTableEntry(ctx context.Context, p4RtC *client.Client, macAdd
 		return err
 	}
 
-	entry := p4RtC.NewTableEntry(
+	entry := tx.p4RtC.NewTableEntry(
 		"k8s_dp_control.mac_to_port_table",
 		map[string]client.MatchInterface{
 			"hdr.ethernet.dst_mac": &client.ExactMatch{
 				Value: mac,
 			},
 		},
-		p4RtC.NewTableActionDirect("k8s_dp_control.set_dest_vport", [][]byte{valueToBytes(port)}),
+		tx.p4RtC.NewTableActionDirect("k8s_dp_control.set_dest_vport", [][]byte{valueToBytes(port)}),
 		nil,
 	)
-	if err = p4RtC.InsertTableEntry(ctx, entry); err != nil {
-		log.Errorf("Cannot insert entry in 'mac_to_port_table': %v", err)
+	if err = tx.Write(ctx, tableEntity(entry), action, tableEntity(entry)); err != nil {
+		log.Errorf("Cannot %s entry in 'mac_to_port_table': %v", action, err)
 	}
 
 	return err
 }
 
-func deleteMacToPortTableEntry(ctx context.Context, p4RtC *client.Client, macAddr string) error {
+// The entry of the ARP table for an IPv4 address, of the Neighbor
+// Solicitation table for an IPv6 one
+func ipToPortTableEntry(ctx context.Context, tx *Transaction, ipAddr string, port uint32, action OperationType) error {
 	var err error
-	mac, err := net.ParseMAC(macAddr)
-	if err != nil {
-		log.Errorf("Failed to parse mac address %s", macAddr)
-		return err
-	}
-
-	entry := p4RtC.NewTableEntry(
-		"k8s_dp_control.mac_to_port_table",
+	family := familyOf(ipAddr)
+	entry := tx.p4RtC.NewTableEntry(
+		family.toPortTable,
 		map[string]client.MatchInterface{
-			"hdr.ethernet.dst_mac": &client.ExactMatch{
-				Value: mac,
-			},
-		},
-		nil,
-		nil,
-	)
-	if err = p4RtC.DeleteTableEntry(ctx, entry); err != nil {
-		log.Errorf("Cannot delete entry from 'mac_to_port_table': %v", err)
-	}
-
-	return err
-}
-
-func insertIpv4ToPortTableEntry(ctx context.Context, p4RtC *client.Client, arpTpa string, port uint32) error {
-	var err error
-	entry := p4RtC.NewTableEntry(
-		"k8s_dp_control.ipv4_to_port_table",
-		map[string]client.MatchInterface{
-			"hdr.arp.tpa": &client.LpmMatch{
-				Value: Pack32BinaryIP4(arpTpa),
-				PLen:  int32(32),
+			family.toPortTarget: &client.LpmMatch{
+				Value: PackBinaryIP(ipAddr),
+				PLen:  family.hostPrefixLen(),
 			},
 		},
 		//TODO: properly handle k8s_dp_control.send
-		p4RtC.NewTableActionDirect("k8s_dp_control.set_dest_vport", [][]byte{valueToBytes(port)}),
+		tx.p4RtC.NewTableActionDirect("k8s_dp_control.set_dest_vport", [][]byte{valueToBytes(port)}),
 		nil,
 	)
-	if err = p4RtC.InsertTableEntry(ctx, entry); err != nil {
-		log.Errorf("Cannot insert entry in ipv4_to_port_table table: %v", err)
+	if err = tx.Write(ctx, tableEntity(entry), action, tableEntity(entry)); err != nil {
+		log.Errorf("Cannot %s entry in %s table: %v", action, family.toPortTable, err)
 	}
 
 	return err
 }
 
-func deleteIpv4ToPortTableEntry(ctx context.Context, p4RtC *client.Client, arpTpa string) error {
-	var err error
-	entry := p4RtC.NewTableEntry(
-		"k8s_dp_control.ipv4_to_port_table",
-		map[string]client.MatchInterface{
-			"hdr.arp.tpa": &client.LpmMatch{
-				Value: Pack32BinaryIP4(arpTpa),
-				PLen:  int32(32),
-			},
-		},
-		nil,
-		nil,
-	)
-	if err = p4RtC.DeleteTableEntry(ctx, entry); err != nil {
-		log.Errorf("Cannot delete entry from ipv4_to_port_table table: %v", err)
-	}
-
-	return err
-}
-
-func InsertCniRules(ctx context.Context, p4RtC *client.Client, macAddr string, ipAddr string, portId int, ifaceType InterfaceType) error {
+// InsertCniRules inserts the entries of all the addresses of the
+// interface and its mac_to_port entry, or none of them. The mac_to_port
+// entry is left out when macAddr is empty, which is the case when it
+// has been programmed along with another address of the interface.
+func InsertCniRules(ctx context.Context, p4RtC *client.Client, macAddr string, ipAddr []string, portId int, ifaceType InterfaceType) error {
 	/*
 		TODO. Distinguish for interface type
 		and program the rules accordingly.
 	*/
 
-	err := insertIpv4ToPortTableEntry(ctx, p4RtC, ipAddr, uint32(portId))
-	if err != nil {
-		return err
-	}
-
-	err = insertMacToPortTableEntry(ctx, p4RtC, macAddr, uint32(portId))
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+		for _, ip := range ipAddr {
+			if err := ipToPortTableEntry(ctx, tx, ip, uint32(portId), Insert); err != nil {
+				return err
+			}
+		}
+		if macAddr == "" {
+			return nil
+		}
+		return macToPortTableEntry(ctx, tx, macAddr, uint32(portId), Insert)
+	})
 }
 
-func DeleteCniRules(ctx context.Context, p4RtC *client.Client, macAddr string, ipAddr string) error {
-	err := deleteIpv4ToPortTableEntry(ctx, p4RtC, ipAddr)
-	if err != nil {
-		return err
-	}
-
-	err = deleteMacToPortTableEntry(ctx, p4RtC, macAddr)
-	if err != nil {
-		return err
-	}
-
-	return nil
+// DeleteCniRules deletes the entries of the given addresses and the
+// mac_to_port entry, or none of them. The mac_to_port entry is kept
+// when macAddr is empty, for the addresses of the interface left.
+func DeleteCniRules(ctx context.Context, p4RtC *client.Client, macAddr string, ipAddr []string, portId int) error {
+	return RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+		for _, ip := range ipAddr {
+			if err := ipToPortTableEntry(ctx, tx, ip, uint32(portId), Delete); err != nil {
+				return err
+			}
+		}
+		if macAddr == "" {
+			return nil
+		}
+		return macToPortTableEntry(ctx, tx, macAddr, uint32(portId), Delete)
+	})
 }
@@ -0,0 +1,87 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CNI rules", func() {
+	ctx := context.Background()
+	mac := "00:00:00:00:00:01"
+	ip := []string{"10.10.10.1"}
+	dualStack := []string{"10.10.10.1", "fd00::1"}
+
+	var _ = Context("InsertCniRules() should", func() {
+		var _ = It("program both entries", func() {
+			Expect(InsertCniRules(ctx, p4RtC, mac, ip, 1, ENDPOINT)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(2))
+		})
+
+		var _ = It("remove the ipv4_to_port entry if the mac_to_port insert fails", func() {
+			fakeServer.failWrite = failTable(p4RtC, "k8s_dp_control.mac_to_port_table")
+			Expect(InsertCniRules(ctx, p4RtC, mac, ip, 1, ENDPOINT)).ToNot(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+
+		var _ = It("remove the ipv4_to_port entry if the mac address is invalid", func() {
+			Expect(InsertCniRules(ctx, p4RtC, "invalid", ip, 1, ENDPOINT)).ToNot(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+
+		var _ = It("program an entry per address and a single mac_to_port entry", func() {
+			Expect(InsertCniRules(ctx, p4RtC, mac, dualStack, 1, ENDPOINT)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(3))
+		})
+
+		var _ = It("leave out the mac_to_port entry without a mac address", func() {
+			Expect(InsertCniRules(ctx, p4RtC, mac, ip, 1, ENDPOINT)).To(Succeed())
+			Expect(InsertCniRules(ctx, p4RtC, "", []string{"fd00::1"}, 1, ENDPOINT)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(3))
+		})
+
+		var _ = It("remove the entries of all the addresses if one insert fails", func() {
+			fakeServer.failWrite = failTable(p4RtC, "k8s_dp_control.ipv6_to_port_table")
+			Expect(InsertCniRules(ctx, p4RtC, mac, dualStack, 1, ENDPOINT)).ToNot(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+	})
+
+	var _ = Context("DeleteCniRules() should", func() {
+		var _ = It("delete both entries", func() {
+			Expect(InsertCniRules(ctx, p4RtC, mac, ip, 1, ENDPOINT)).To(Succeed())
+			Expect(DeleteCniRules(ctx, p4RtC, mac, ip, 1)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+
+		var _ = It("restore the ipv4_to_port entry if the mac_to_port delete fails", func() {
+			Expect(InsertCniRules(ctx, p4RtC, mac, ip, 1, ENDPOINT)).To(Succeed())
+			fakeServer.failWrite = failTable(p4RtC, "k8s_dp_control.mac_to_port_table")
+			Expect(DeleteCniRules(ctx, p4RtC, mac, ip, 1)).ToNot(Succeed())
+			Expect(fakeServer.count()).To(Equal(2))
+		})
+
+		var _ = It("keep the mac_to_port entry without a mac address", func() {
+			Expect(InsertCniRules(ctx, p4RtC, mac, dualStack, 1, ENDPOINT)).To(Succeed())
+			Expect(DeleteCniRules(ctx, p4RtC, "", []string{"fd00::1"}, 1)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(2))
+		})
+	})
+})
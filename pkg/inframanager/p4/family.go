@@ -0,0 +1,108 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+// ipFamily names the tables of the pipeline handling the packets of
+// one address family, along with the fields they match on. The as_sl3
+// members and groups are shared by both families.
+type ipFamily struct {
+	name string
+	// Forwarding of the ARP requests or of the Neighbor Solicitations
+	toPortTable  string
+	toPortTarget string
+	// Service load balancing, DNAT and SNAT
+	txBalance      string
+	txBalanceDst   string
+	txBalanceProto string
+	rxSrcIp        string
+	rxSrcIpSrc     string
+	writeDestIp    string
+	updateDstIpMac string
+	writeSourceIp  string
+	updateSrcIpMac string
+	// Flows learnt from the first packet load balanced to a backend
+	pinnedFlows      string
+	pinnedFlowsDst   string
+	pinnedFlowsProto string
+	// Masquerading of the traffic leaving the cluster
+	snatPrefix    string
+	snatPrefixDst string
+	masquerade    string
+	// Converts a value read from the target back to an address
+	bytesToIP func([]byte) string
+}
+
+var ipv4Family = &ipFamily{
+	name:             "IPv4",
+	toPortTable:      "k8s_dp_control.ipv4_to_port_table",
+	toPortTarget:     "hdr.arp.tpa",
+	txBalance:        "k8s_dp_control.tx_balance",
+	txBalanceDst:     "hdr.ipv4.dst_addr",
+	txBalanceProto:   "hdr.ipv4.protocol",
+	rxSrcIp:          "k8s_dp_control.rx_src_ip",
+	rxSrcIpSrc:       "hdr.ipv4.src_addr",
+	writeDestIp:      "k8s_dp_control.write_dest_ip_table",
+	updateDstIpMac:   "k8s_dp_control.update_dst_ip_mac",
+	writeSourceIp:    "k8s_dp_control.write_source_ip_table",
+	updateSrcIpMac:   "k8s_dp_control.update_src_ip_mac",
+	pinnedFlows:      "k8s_dp_control.pinned_flows",
+	pinnedFlowsDst:   "hdr.ipv4.dst_addr",
+	pinnedFlowsProto: "hdr.ipv4.protocol",
+	snatPrefix:       "k8s_dp_control.snat_prefix",
+	snatPrefixDst:    "hdr.ipv4.dst_addr",
+	masquerade:       "k8s_dp_control.masquerade",
+	bytesToIP:        bytesToIPv4,
+}
+
+var ipv6Family = &ipFamily{
+	name:             "IPv6",
+	toPortTable:      "k8s_dp_control.ipv6_to_port_table",
+	toPortTarget:     "hdr.ndp.target_addr",
+	txBalance:        "k8s_dp_control.tx_balance_ipv6",
+	txBalanceDst:     "hdr.ipv6.dst_addr",
+	txBalanceProto:   "hdr.ipv6.next_hdr",
+	rxSrcIp:          "k8s_dp_control.rx_src_ipv6",
+	rxSrcIpSrc:       "hdr.ipv6.src_addr",
+	writeDestIp:      "k8s_dp_control.write_dest_ipv6_table",
+	updateDstIpMac:   "k8s_dp_control.update_dst_ipv6_mac",
+	writeSourceIp:    "k8s_dp_control.write_source_ipv6_table",
+	updateSrcIpMac:   "k8s_dp_control.update_src_ipv6_mac",
+	pinnedFlows:      "k8s_dp_control.pinned_flows_ipv6",
+	pinnedFlowsDst:   "hdr.ipv6.dst_addr",
+	pinnedFlowsProto: "hdr.ipv6.next_hdr",
+	snatPrefix:       "k8s_dp_control.snat_prefix_ipv6",
+	snatPrefixDst:    "hdr.ipv6.dst_addr",
+	masquerade:       "k8s_dp_control.masquerade_ipv6",
+	bytesToIP:        bytesToIPv6,
+}
+
+var ipFamilies = []*ipFamily{ipv4Family, ipv6Family}
+
+func familyOf(ipAddr string) *ipFamily {
+	if IsIPv6(ipAddr) {
+		return ipv6Family
+	}
+	return ipv4Family
+}
+
+// Full length prefix of the addresses of the family
+func (f *ipFamily) hostPrefixLen() int32 {
+	if f == ipv6Family {
+		return 128
+	}
+	return 32
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/antoninbas/p4runtime-go-client/pkg/client"
+	"github.com/golang/protobuf/proto"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	p4_v1 "github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	bufSize      = 1024 * 1024
+	p4InfoPath   = "../../../k8s_dp/p4Info.txt"
+	p4SourcePath = "../../../k8s_dp/k8s_dp.p4"
+)
+
+// fakeP4RuntimeServer keeps the written entities in memory and
+// rejects the updates a real target would reject
+type fakeP4RuntimeServer struct {
+	p4_v1.UnimplementedP4RuntimeServer
+	lock     sync.Mutex
+	entities map[string]*p4_v1.Entity
+	// failWrite makes the matching updates fail
+	failWrite func(update *p4_v1.Update) bool
+}
+
+func newFakeP4RuntimeServer() *fakeP4RuntimeServer {
+	return &fakeP4RuntimeServer{
+		entities: make(map[string]*p4_v1.Entity),
+	}
+}
+
+func entityKey(entity *p4_v1.Entity) string {
+	switch e := entity.Entity.(type) {
+	case *p4_v1.Entity_TableEntry:
+		matches := make([]*p4_v1.FieldMatch, len(e.TableEntry.Match))
+		copy(matches, e.TableEntry.Match)
+		sort.Slice(matches, func(i, j int) bool {
+			return matches[i].FieldId < matches[j].FieldId
+		})
+		key := fmt.Sprintf("table/%d/%d", e.TableEntry.TableId, e.TableEntry.Priority)
+		for _, m := range matches {
+			bs, _ := proto.Marshal(m)
+			key += fmt.Sprintf("/%x", bs)
+		}
+		return key
+	case *p4_v1.Entity_ActionProfileMember:
+		return fmt.Sprintf("member/%d/%d", e.ActionProfileMember.ActionProfileId, e.ActionProfileMember.MemberId)
+	case *p4_v1.Entity_ActionProfileGroup:
+		return fmt.Sprintf("group/%d/%d", e.ActionProfileGroup.ActionProfileId, e.ActionProfileGroup.GroupId)
+	}
+	return ""
+}
+
+// Returns the key prefix of the entities a wildcard read selects
+func wildcardPrefix(entity *p4_v1.Entity) (string, bool) {
+	switch e := entity.Entity.(type) {
+	case *p4_v1.Entity_TableEntry:
+		if len(e.TableEntry.Match) == 0 && !e.TableEntry.IsDefaultAction {
+			return fmt.Sprintf("table/%d/", e.TableEntry.TableId), true
+		}
+	case *p4_v1.Entity_ActionProfileMember:
+		if e.ActionProfileMember.MemberId == 0 {
+			return fmt.Sprintf("member/%d/", e.ActionProfileMember.ActionProfileId), true
+		}
+	case *p4_v1.Entity_ActionProfileGroup:
+		if e.ActionProfileGroup.GroupId == 0 {
+			return fmt.Sprintf("group/%d/", e.ActionProfileGroup.ActionProfileId), true
+		}
+	}
+	return "", false
+}
+
+func (s *fakeP4RuntimeServer) Write(ctx context.Context, req *p4_v1.WriteRequest) (*p4_v1.WriteResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, update := range req.Updates {
+		if s.failWrite != nil && s.failWrite(update) {
+			return nil, status.Error(codes.Unavailable, "write failed")
+		}
+		key := entityKey(update.Entity)
+		_, exists := s.entities[key]
+		switch update.Type {
+		case p4_v1.Update_INSERT:
+			if exists {
+				return nil, status.Error(codes.AlreadyExists, key)
+			}
+			s.entities[key] = update.Entity
+		case p4_v1.Update_MODIFY:
+			// the default entry of a table always exists
+			if !exists && !update.Entity.GetTableEntry().GetIsDefaultAction() {
+				return nil, status.Error(codes.NotFound, key)
+			}
+			s.entities[key] = update.Entity
+		case p4_v1.Update_DELETE:
+			if !exists {
+				return nil, status.Error(codes.NotFound, key)
+			}
+			delete(s.entities, key)
+		}
+	}
+	return &p4_v1.WriteResponse{}, nil
+}
+
+func (s *fakeP4RuntimeServer) Read(req *p4_v1.ReadRequest, stream p4_v1.P4Runtime_ReadServer) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rep := &p4_v1.ReadResponse{}
+	for _, entity := range req.Entities {
+		if prefix, ok := wildcardPrefix(entity); ok {
+			for key, e := range s.entities {
+				// as for a target, the default entries are left out
+				if strings.HasPrefix(key, prefix) && !e.GetTableEntry().GetIsDefaultAction() {
+					rep.Entities = append(rep.Entities, e)
+				}
+			}
+			continue
+		}
+		if e, ok := s.entities[entityKey(entity)]; ok {
+			rep.Entities = append(rep.Entities, e)
+		}
+	}
+	return stream.Send(rep)
+}
+
+func (s *fakeP4RuntimeServer) SetForwardingPipelineConfig(ctx context.Context, req *p4_v1.SetForwardingPipelineConfigRequest) (*p4_v1.SetForwardingPipelineConfigResponse, error) {
+	return &p4_v1.SetForwardingPipelineConfigResponse{}, nil
+}
+
+func (s *fakeP4RuntimeServer) count() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.entities)
+}
+
+func (s *fakeP4RuntimeServer) get(entity *p4_v1.Entity) *p4_v1.Entity {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.entities[entityKey(entity)]
+}
+
+func failTable(p4RtC *client.Client, table string) func(update *p4_v1.Update) bool {
+	id := p4RtC.NewTableEntry(table, map[string]client.MatchInterface{}, nil, nil).TableId
+	return func(update *p4_v1.Update) bool {
+		return update.Entity.GetTableEntry().GetTableId() == id
+	}
+}
+
+var (
+	fakeServer *fakeP4RuntimeServer
+	grpcServer *grpc.Server
+	listener   *bufconn.Listener
+	conn       *grpc.ClientConn
+	p4RtC      *client.Client
+)
+
+func TestP4(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "P4 Test Suite")
+}
+
+var _ = BeforeEach(func() {
+	fakeServer = newFakeP4RuntimeServer()
+	grpcServer = grpc.NewServer()
+	p4_v1.RegisterP4RuntimeServer(grpcServer, fakeServer)
+	listener = bufconn.Listen(bufSize)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+
+	var err error
+	conn, err = grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	Expect(err).ToNot(HaveOccurred())
+
+	p4RtC = client.NewClient(p4_v1.NewP4RuntimeClient(conn), 1, &p4_v1.Uint128{High: 0, Low: 1})
+	p4Info, err := os.ReadFile(p4InfoPath)
+	Expect(err).ToNot(HaveOccurred())
+	_, err = p4RtC.SetFwdPipeFromBytes(context.Background(), nil, p4Info, 0)
+	Expect(err).ToNot(HaveOccurred())
+})
+
+var _ = AfterEach(func() {
+	conn.Close()
+	grpcServer.Stop()
+	listener.Close()
+})
@@ -0,0 +1,127 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	p4_config_v1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+)
+
+// The objects of the pipeline the inframanager programs
+func pipelineObjects(p4Info *p4_config_v1.P4Info) []string {
+	var names []string
+	for _, t := range p4Info.Tables {
+		names = append(names, t.Preamble.Name)
+	}
+	for _, a := range p4Info.Actions {
+		names = append(names, a.Preamble.Name)
+	}
+	for _, p := range p4Info.ActionProfiles {
+		names = append(names, p.Preamble.Name)
+	}
+	return names
+}
+
+func readP4Info(p4InfoPath string) (*p4_config_v1.P4Info, error) {
+	data, err := os.ReadFile(p4InfoPath)
+	if err != nil {
+		return nil, err
+	}
+	p4Info := &p4_config_v1.P4Info{}
+	if err := proto.UnmarshalText(string(data), p4Info); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", p4InfoPath, err)
+	}
+	return p4Info, nil
+}
+
+// Whether the name appears in data on its own, not as the prefix of a
+// longer name
+func containsName(data []byte, name string) bool {
+	for i := bytes.Index(data, []byte(name)); i >= 0; {
+		end := i + len(name)
+		if end == len(data) || !isNameByte(data[end]) {
+			return true
+		}
+		next := bytes.Index(data[end:], []byte(name))
+		if next < 0 {
+			break
+		}
+		i = end + next
+	}
+	return false
+}
+
+func isNameByte(b byte) bool {
+	return b == '_' || b == '.' || (b >= '0' && b <= '9') ||
+		(b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// CheckPipelineBinary checks that the pipeline binary was built from the
+// same program as the P4Info, the binary embeds the names of the tables
+// and actions of the program. A binary left behind a change of k8s_dp.p4
+// lacks the new ones, which the writes of the inframanager would fail on.
+func CheckPipelineBinary(p4BinPath string, p4InfoPath string) error {
+	p4Info, err := readP4Info(p4InfoPath)
+	if err != nil {
+		return err
+	}
+	bin, err := os.ReadFile(p4BinPath)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, name := range pipelineObjects(p4Info) {
+		if name != "NoAction" && !containsName(bin, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s lacks %s of %s, rebuild it with scripts/build_pipeline.sh",
+			p4BinPath, strings.Join(missing, ", "), p4InfoPath)
+	}
+	return nil
+}
+
+// CheckPipelineP4Info checks that the pipeline set on the target has all
+// the tables and actions of the P4Info
+func CheckPipelineP4Info(set *p4_config_v1.P4Info, p4InfoPath string) error {
+	p4Info, err := readP4Info(p4InfoPath)
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool)
+	for _, name := range pipelineObjects(set) {
+		present[name] = true
+	}
+	var missing []string
+	for _, name := range pipelineObjects(p4Info) {
+		if !present[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the pipeline set lacks %s of %s, it has to be set again",
+			strings.Join(missing, ", "), p4InfoPath)
+	}
+	return nil
+}
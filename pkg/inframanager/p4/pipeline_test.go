@@ -0,0 +1,67 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pipeline", func() {
+	var names []string
+
+	BeforeEach(func() {
+		p4Info, err := readP4Info(p4InfoPath)
+		Expect(err).ToNot(HaveOccurred())
+		names = pipelineObjects(p4Info)
+		Expect(names).To(ContainElement("k8s_dp_control.acl_ct"))
+	})
+
+	writeBin := func(names []string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "k8s_dp.pb.bin")
+		Expect(os.WriteFile(path, []byte(strings.Join(names, "\x00")), 0644)).To(Succeed())
+		return path
+	}
+
+	It("accepts a binary built from the P4Info", func() {
+		Expect(CheckPipelineBinary(writeBin(names), p4InfoPath)).To(Succeed())
+	})
+
+	It("refuses a binary missing a table of the P4Info", func() {
+		var stale []string
+		for _, name := range names {
+			if name != "k8s_dp_control.acl_ct" {
+				stale = append(stale, name)
+			}
+		}
+		err := CheckPipelineBinary(writeBin(stale), p4InfoPath)
+		Expect(err).To(MatchError(ContainSubstring("k8s_dp_control.acl_ct")))
+	})
+
+	It("refuses a set pipeline missing a table of the P4Info", func() {
+		set, err := readP4Info(p4InfoPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(CheckPipelineP4Info(set, p4InfoPath)).To(Succeed())
+
+		set.Tables = set.Tables[1:]
+		Expect(CheckPipelineP4Info(set, p4InfoPath)).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,204 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/antoninbas/p4runtime-go-client/pkg/client"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/store"
+	p4_v1 "github.com/p4lang/p4runtime/go/p4/v1"
+	log "github.com/sirupsen/logrus"
+)
+
+func aclTableNames(ingress bool) (string, string) {
+	if ingress {
+		return "k8s_dp_control.ingress_acl_pod_ip_table", "k8s_dp_control.ingress_acl_rules"
+	}
+	return "k8s_dp_control.egress_acl_pod_ip_table", "k8s_dp_control.egress_acl_rules"
+}
+
+func newAclPodIpEntry(p4RtC *client.Client, podIpAddr string, aclID uint32, ingress bool) *p4_v1.TableEntry {
+	table, _ := aclTableNames(ingress)
+	key := "hdr.ipv4.src_addr"
+	if ingress {
+		key = "hdr.ipv4.dst_addr"
+	}
+
+	return p4RtC.NewTableEntry(
+		table,
+		map[string]client.MatchInterface{
+			key: &client.ExactMatch{
+				Value: Pack32BinaryIP4(podIpAddr),
+			},
+		},
+		p4RtC.NewTableActionDirect("k8s_dp_control.set_acl_id",
+			[][]byte{valueToBytes(aclID)}),
+		nil,
+	)
+}
+
+// The old id is the one the pod was bound to before an update,
+// the binding is switched back to it on rollback.
+func AclPodIpTableEntry(ctx context.Context, tx *Transaction, podIpAddr string, aclID uint32, oldAclID uint32, ingress bool, action OperationType) error {
+	table, _ := aclTableNames(ingress)
+	entry := newAclPodIpEntry(tx.p4RtC, podIpAddr, aclID, ingress)
+
+	var old *p4_v1.Entity
+	switch action {
+	case Update:
+		old = tableEntity(newAclPodIpEntry(tx.p4RtC, podIpAddr, oldAclID, ingress))
+	case Delete:
+		old = tableEntity(entry)
+	}
+
+	if err := tx.Write(ctx, tableEntity(entry), action, old); err != nil {
+		log.Errorf("Cannot %s entry in '%s': %v", action, table, err)
+		return err
+	}
+	return nil
+}
+
+// The policies are only compiled for IPv4, the IPv6 traffic of a pod
+// bound to one is denied
+func AclPodIpv6TableEntry(ctx context.Context, tx *Transaction, podIpv6Addr string, ingress bool, action OperationType) error {
+	table := "k8s_dp_control.egress_acl_pod_ipv6_table"
+	key := "hdr.ipv6.src_addr"
+	if ingress {
+		table = "k8s_dp_control.ingress_acl_pod_ipv6_table"
+		key = "hdr.ipv6.dst_addr"
+	}
+
+	entry := tx.p4RtC.NewTableEntry(
+		table,
+		map[string]client.MatchInterface{
+			key: &client.ExactMatch{
+				Value: PackBinaryIP(podIpv6Addr),
+			},
+		},
+		tx.p4RtC.NewTableActionDirect("k8s_dp_control.acl_deny", nil),
+		nil,
+	)
+	if err := tx.Write(ctx, tableEntity(entry), action, tableEntity(entry)); err != nil {
+		log.Errorf("Cannot %s entry in '%s': %v", action, table, err)
+		return err
+	}
+	return nil
+}
+
+func uint16ToBytes(value uint16) []byte {
+	buf := make([]byte, 2)
+	binary.BigEndian.PutUint16(buf, value)
+	return buf
+}
+
+// The don't care ternary fields are omitted from the match,
+// as required by P4Runtime
+func addTernaryMatch(mfs map[string]client.MatchInterface, name string, value []byte, mask []byte) {
+	for _, b := range mask {
+		if b != 0 {
+			mfs[name] = &client.TernaryMatch{
+				Value: value,
+				Mask:  mask,
+			}
+			return
+		}
+	}
+}
+
+func AclRulesTableEntry(ctx context.Context, tx *Transaction, entries []store.AclEntry, aclID uint32, ingress bool, action OperationType) error {
+	_, table := aclTableNames(ingress)
+
+	for _, e := range entries {
+		mfs := map[string]client.MatchInterface{
+			"meta.acl_id": &client.ExactMatch{
+				Value: valueToBytes(aclID),
+			},
+		}
+		addTernaryMatch(mfs, "hdr.ipv4.src_addr", valueToBytes(e.SrcIp), valueToBytes(e.SrcIpMask))
+		addTernaryMatch(mfs, "hdr.ipv4.dst_addr", valueToBytes(e.DstIp), valueToBytes(e.DstIpMask))
+		addTernaryMatch(mfs, "hdr.ipv4.protocol", []byte{e.Protocol}, []byte{e.ProtocolMask})
+		addTernaryMatch(mfs, "meta.l4_src_port", uint16ToBytes(e.SrcPort), uint16ToBytes(e.SrcPortMask))
+		addTernaryMatch(mfs, "meta.l4_dst_port", uint16ToBytes(e.DstPort), uint16ToBytes(e.DstPortMask))
+
+		aclAction := "k8s_dp_control.acl_deny"
+		if e.Allow {
+			aclAction = "k8s_dp_control.acl_allow"
+		}
+
+		entry := tx.p4RtC.NewTableEntry(
+			table,
+			mfs,
+			tx.p4RtC.NewTableActionDirect(aclAction, nil),
+			&client.TableEntryOptions{
+				Priority: e.Priority,
+			},
+		)
+		if err := tx.Write(ctx, tableEntity(entry), action, tableEntity(entry)); err != nil {
+			log.Errorf("Cannot %s entry in '%s': %v", action, table, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// ProgramAclRules replaces the ACL entries of a pod for one direction.
+// The new entries are inserted under a new ACL id and the pod is then
+// bound to it, before the old entries are deleted, so that the pod never
+// goes unprotected. A zero new id unbinds the pod. On failure the pod is
+// left with its old entries. The pipeline matches the rules over IPv4
+// only, the IPv6 traffic of a bound pod is denied rather than let through.
+func ProgramAclRules(ctx context.Context, p4RtC *client.Client, podIpAddr string, podIpv6Addr string, ingress bool, oldID uint32, oldEntries []store.AclEntry, newID uint32, newEntries []store.AclEntry) error {
+	return RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+		if newID != 0 {
+			if err := AclRulesTableEntry(ctx, tx, newEntries, newID, ingress, Insert); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case newID != 0 && podIpAddr != "":
+			action := Insert
+			if oldID != 0 {
+				action = Update
+			}
+			if err := AclPodIpTableEntry(ctx, tx, podIpAddr, newID, oldID, ingress, action); err != nil {
+				return err
+			}
+		case oldID != 0 && podIpAddr != "":
+			if err := AclPodIpTableEntry(ctx, tx, podIpAddr, oldID, 0, ingress, Delete); err != nil {
+				return err
+			}
+		}
+
+		if podIpv6Addr != "" && (newID == 0) != (oldID == 0) {
+			action := Insert
+			if newID == 0 {
+				action = Delete
+			}
+			if err := AclPodIpv6TableEntry(ctx, tx, podIpv6Addr, ingress, action); err != nil {
+				return err
+			}
+		}
+
+		if oldID != 0 {
+			return AclRulesTableEntry(ctx, tx, oldEntries, oldID, ingress, Delete)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"context"
+
+	"github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/store"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACL rules", func() {
+	ctx := context.Background()
+
+	compile := func(action string) []store.AclEntry {
+		entries, err := CompileAclRules([]store.PolicyRule{{Action: action}}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		return entries
+	}
+
+	var _ = Context("CompileAclRules() should", func() {
+		var _ = It("fail on the actions the table cannot express", func() {
+			rules := []store.PolicyRule{{Action: "pass"}, {Action: AclActionAllow}}
+			_, err := CompileAclRules(rules, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	var _ = Context("ProgramAclRules() should", func() {
+		var _ = It("deny the IPv6 traffic of a bound dual-stack pod", func() {
+			entries := compile(AclActionAllow)
+			Expect(ProgramAclRules(ctx, p4RtC, "10.10.10.1", "fd00:10::1", true, 0, nil, 1, entries)).To(Succeed())
+
+			res, err := p4RtC.ReadTableEntryWildcard(ctx, "k8s_dp_control.ingress_acl_pod_ipv6_table")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+			Expect(fakeServer.count()).To(Equal(len(entries) + 2))
+
+			// Replacing the rules keeps the IPv6 binding
+			newEntries := compile(AclActionDeny)
+			Expect(ProgramAclRules(ctx, p4RtC, "10.10.10.1", "fd00:10::1", true, 1, entries, 2, newEntries)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(len(newEntries) + 2))
+
+			Expect(ProgramAclRules(ctx, p4RtC, "10.10.10.1", "fd00:10::1", true, 2, newEntries, 0, nil)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+
+		var _ = It("bind an IPv6 only pod", func() {
+			entries := compile(AclActionAllow)
+			Expect(ProgramAclRules(ctx, p4RtC, "", "fd00:10::1", false, 0, nil, 1, entries)).To(Succeed())
+
+			res, err := p4RtC.ReadTableEntryWildcard(ctx, "k8s_dp_control.egress_acl_pod_ip_table")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(BeEmpty())
+			Expect(fakeServer.count()).To(Equal(len(entries) + 1))
+
+			Expect(ProgramAclRules(ctx, p4RtC, "", "fd00:10::1", false, 1, entries, 0, nil)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,510 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/antoninbas/p4runtime-go-client/pkg/client"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/store"
+	p4_v1 "github.com/p4lang/p4runtime/go/p4/v1"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReconcileStats counts the changes a reconciliation made to the pipeline
+type ReconcileStats struct {
+	Removed    int
+	Programmed int
+	Failed     int
+}
+
+func (s ReconcileStats) String() string {
+	return fmt.Sprintf("%d removed, %d programmed, %d failed",
+		s.Removed, s.Programmed, s.Failed)
+}
+
+func readTable(ctx context.Context, p4RtC *client.Client, table string) ([]*p4_v1.TableEntry, error) {
+	entries, err := p4RtC.ReadTableEntryWildcard(ctx, table)
+	if err != nil {
+		log.Errorf("Cannot read the entries of '%s': %v", table, err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+func readActionProfile(ctx context.Context, p4RtC *client.Client, profile string) ([]*p4_v1.Entity, error) {
+	profileID := p4RtC.NewActionProfileGroup(profile, 0, nil, 0).ActionProfileId
+	var entities []*p4_v1.Entity
+
+	for _, wildcard := range []*p4_v1.Entity{
+		memberEntity(&p4_v1.ActionProfileMember{ActionProfileId: profileID}),
+		groupEntity(&p4_v1.ActionProfileGroup{ActionProfileId: profileID}),
+	} {
+		ch := make(chan *p4_v1.Entity, 100)
+		done := make(chan struct{})
+		go func() {
+			for e := range ch {
+				entities = append(entities, e)
+			}
+			close(done)
+		}()
+		err := p4RtC.ReadEntityWildcard(ctx, wildcard, ch)
+		<-done
+		if err != nil {
+			log.Errorf("Cannot read the entries of '%s': %v", profile, err)
+			return nil, err
+		}
+	}
+	return entities, nil
+}
+
+// Returns the value the entry matches on the given field
+func matchValue(p4RtC *client.Client, entry *p4_v1.TableEntry, table string, field string) []byte {
+	ref := p4RtC.NewTableEntry(table,
+		map[string]client.MatchInterface{field: &client.ExactMatch{}}, nil, nil)
+	for _, m := range entry.Match {
+		if m.FieldId != ref.Match[0].FieldId {
+			continue
+		}
+		switch v := m.FieldMatchType.(type) {
+		case *p4_v1.FieldMatch_Exact_:
+			return v.Exact.Value
+		case *p4_v1.FieldMatch_Lpm:
+			return v.Lpm.Value
+		}
+	}
+	return nil
+}
+
+// Returns the first parameter of the direct action of the entry
+func actionParam(entry *p4_v1.TableEntry) uint32 {
+	params := entry.GetAction().GetAction().GetParams()
+	if len(params) == 0 {
+		return 0
+	}
+	return bytesToUint32(params[0].Value)
+}
+
+// Returns the parameter of the direct action of the entry with the given id
+func actionParamByID(entry *p4_v1.TableEntry, id uint32) uint32 {
+	for _, p := range entry.GetAction().GetAction().GetParams() {
+		if p.ParamId == id {
+			return bytesToUint32(p.Value)
+		}
+	}
+	return 0
+}
+
+func deleteEntities(ctx context.Context, p4RtC *client.Client, entities []*p4_v1.Entity, stats *ReconcileStats) {
+	for _, e := range entities {
+		err := p4RtC.WriteUpdate(ctx, &p4_v1.Update{
+			Type:   p4_v1.Update_DELETE,
+			Entity: e,
+		})
+		if err != nil {
+			log.Errorf("Cannot delete the stale entry %v: %v", e, err)
+			stats.Failed++
+			continue
+		}
+		stats.Removed++
+	}
+}
+
+// ReconcileCniRules makes the mac_to_port, ipv4_to_port and ipv6_to_port
+// tables hold exactly the entries of the given endpoints. The entries matching no
+// endpoint or sending to a different port are removed, the missing ones
+// are programmed.
+func ReconcileCniRules(ctx context.Context, p4RtC *client.Client, endpoints []store.EndPoint) (ReconcileStats, error) {
+	var stats ReconcileStats
+
+	macPort := make(map[string]uint32)
+	ipPort := make(map[string]uint32)
+	for _, ep := range endpoints {
+		mac, err := net.ParseMAC(ep.PodMacAddress)
+		if err != nil {
+			log.Errorf("Invalid mac address %s of endpoint %s", ep.PodMacAddress, ep.PodIpAddress)
+			continue
+		}
+		macPort[mac.String()] = ep.InterfaceID
+		// The addresses are compared in their canonical text form
+		ipPort[net.ParseIP(ep.PodIpAddress).String()] = ep.InterfaceID
+	}
+
+	macEntries, err := readTable(ctx, p4RtC, "k8s_dp_control.mac_to_port_table")
+	if err != nil {
+		return stats, err
+	}
+
+	var stale []*p4_v1.Entity
+	macFound := make(map[string]bool)
+	for _, entry := range macEntries {
+		mac := bytesToMAC(matchValue(p4RtC, entry, "k8s_dp_control.mac_to_port_table", "hdr.ethernet.dst_mac"))
+		if port, ok := macPort[mac]; ok && port == actionParam(entry) {
+			macFound[mac] = true
+			continue
+		}
+		stale = append(stale, tableEntity(entry))
+	}
+	ipFound := make(map[string]bool)
+	for _, family := range ipFamilies {
+		ipEntries, err := readTable(ctx, p4RtC, family.toPortTable)
+		if err != nil {
+			return stats, err
+		}
+		for _, entry := range ipEntries {
+			ip := family.bytesToIP(matchValue(p4RtC, entry, family.toPortTable, family.toPortTarget))
+			if port, ok := ipPort[ip]; ok && port == actionParam(entry) {
+				ipFound[ip] = true
+				continue
+			}
+			stale = append(stale, tableEntity(entry))
+		}
+	}
+	deleteEntities(ctx, p4RtC, stale, &stats)
+
+	for _, ep := range endpoints {
+		mac, err := net.ParseMAC(ep.PodMacAddress)
+		if err != nil {
+			continue
+		}
+		programmed := 0
+		err = RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+			if !ipFound[net.ParseIP(ep.PodIpAddress).String()] {
+				if err := ipToPortTableEntry(ctx, tx, ep.PodIpAddress, ep.InterfaceID, Insert); err != nil {
+					return err
+				}
+				programmed++
+			}
+			if !macFound[mac.String()] {
+				if err := macToPortTableEntry(ctx, tx, ep.PodMacAddress, ep.InterfaceID, Insert); err != nil {
+					return err
+				}
+				programmed++
+			}
+			return nil
+		})
+		if err != nil {
+			log.Errorf("Cannot program the entries of endpoint %s: %v", ep.PodIpAddress, err)
+			stats.Failed++
+			continue
+		}
+		// The other addresses of the interface share the entry
+		macFound[mac.String()] = true
+		stats.Programmed += programmed
+	}
+
+	return stats, nil
+}
+
+// serviceTables is the content of the service load balancing tables
+type serviceTables struct {
+	txBalance map[string]*p4_v1.TableEntry
+	groups    map[uint32]*p4_v1.ActionProfileGroup
+	members   map[uint32]*p4_v1.ActionProfileMember
+	destIp    map[uint32]*p4_v1.TableEntry
+	sourceIp  map[uint32]*p4_v1.TableEntry
+	rxSrcIp   map[string]*p4_v1.TableEntry
+	// The pinned_flows entries, by the service of their flow
+	pinnedFlows map[string][]*p4_v1.TableEntry
+}
+
+func readServiceTables(ctx context.Context, p4RtC *client.Client) (*serviceTables, error) {
+	t := &serviceTables{
+		txBalance:   make(map[string]*p4_v1.TableEntry),
+		groups:      make(map[uint32]*p4_v1.ActionProfileGroup),
+		members:     make(map[uint32]*p4_v1.ActionProfileMember),
+		destIp:      make(map[uint32]*p4_v1.TableEntry),
+		sourceIp:    make(map[uint32]*p4_v1.TableEntry),
+		rxSrcIp:     make(map[string]*p4_v1.TableEntry),
+		pinnedFlows: make(map[string][]*p4_v1.TableEntry),
+	}
+
+	for _, family := range ipFamilies {
+		if err := t.readFamily(ctx, p4RtC, family); err != nil {
+			return nil, err
+		}
+	}
+
+	entities, err := readActionProfile(ctx, p4RtC, "k8s_dp_control.as_sl3")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entities {
+		if m := e.GetActionProfileMember(); m != nil {
+			t.members[m.MemberId] = m
+		}
+		if g := e.GetActionProfileGroup(); g != nil {
+			t.groups[g.GroupId] = g
+		}
+	}
+
+	return t, nil
+}
+
+// Keys a service as its tx_balance entry read back is, with the
+// protocol in its canonical form
+func serviceKey(s store.Service) string {
+	proto, _, _ := ServiceProtocol(s.Proto)
+	return store.ServiceKey(s.ClusterIp, proto, s.ClusterPort)
+}
+
+// Reads the tables of one family. The DNAT and SNAT entries of both
+// families are keyed by member ids, which are unique across them.
+func (t *serviceTables) readFamily(ctx context.Context, p4RtC *client.Client, family *ipFamily) error {
+	entries, err := readTable(ctx, p4RtC, family.txBalance)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		ip := family.bytesToIP(matchValue(p4RtC, e, family.txBalance, family.txBalanceDst))
+		proto := protocolName(uint8(bytesToUint32(matchValue(p4RtC, e, family.txBalance, family.txBalanceProto))))
+		port := bytesToUint32(matchValue(p4RtC, e, family.txBalance, "meta.l4_dst_port"))
+		t.txBalance[store.ServiceKey(ip, proto, port)] = e
+	}
+
+	for table, m := range map[string]map[uint32]*p4_v1.TableEntry{
+		family.writeDestIp:   t.destIp,
+		family.writeSourceIp: t.sourceIp,
+	} {
+		entries, err := readTable(ctx, p4RtC, table)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			m[bytesToUint32(matchValue(p4RtC, e, table, "meta.mod_blob_ptr"))] = e
+		}
+	}
+
+	entries, err = readTable(ctx, p4RtC, family.rxSrcIp)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		t.rxSrcIp[family.bytesToIP(matchValue(p4RtC, e, family.rxSrcIp, family.rxSrcIpSrc))] = e
+	}
+
+	entries, err = readTable(ctx, p4RtC, family.pinnedFlows)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		ip := family.bytesToIP(matchValue(p4RtC, e, family.pinnedFlows, family.pinnedFlowsDst))
+		proto := protocolName(uint8(bytesToUint32(matchValue(p4RtC, e, family.pinnedFlows, family.pinnedFlowsProto))))
+		port := bytesToUint32(matchValue(p4RtC, e, family.pinnedFlows, "meta.l4_dst_port"))
+		key := store.ServiceKey(ip, proto, port)
+		t.pinnedFlows[key] = append(t.pinnedFlows[key], e)
+	}
+	return nil
+}
+
+// A service is intact when its tx_balance entry points to its group,
+// the group holds exactly its members and the SNAT entries of all the
+// members exist. The content of the SNAT entries is not compared. The
+// DNAT entries are shared by the services and reconciled on their own.
+func (t *serviceTables) isIntact(s store.Service) bool {
+	tx, ok := t.txBalance[serviceKey(s)]
+	if !ok || tx.GetAction().GetActionProfileGroupId() != s.GroupID {
+		return false
+	}
+	group, ok := t.groups[s.GroupID]
+	if !ok || len(group.Members) != len(s.ServiceEndPoint) {
+		return false
+	}
+	inGroup := make(map[uint32]bool)
+	for _, m := range group.Members {
+		inGroup[m.MemberId] = true
+	}
+	for _, ep := range s.ServiceEndPoint {
+		if !inGroup[ep.MemberID] || t.members[ep.MemberID] == nil ||
+			t.sourceIp[ep.MemberID] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ReconcileServiceRules makes the service load balancing tables hold
+// exactly the state of the given services. The objects no service refers
+// to are removed, along with the flows pinned to a backend which is not
+// one of their service anymore, like the backends still draining when the
+// inframanager stopped. The services missing any of their entries are
+// cleared and programmed again with the ids recorded in the store, the
+// endpoints provide the mac addresses of the backends.
+func ReconcileServiceRules(ctx context.Context, p4RtC *client.Client, services []store.Service, endpoints map[string]store.EndPoint) (ReconcileStats, error) {
+	var stats ReconcileStats
+
+	t, err := readServiceTables(ctx, p4RtC)
+	if err != nil {
+		return stats, err
+	}
+
+	intact := make(map[string]bool)
+	groups := make(map[uint32]bool)
+	members := make(map[uint32]bool)
+	// backend ip to the members it is reachable through
+	backends := make(map[string]map[uint32]bool)
+	// DNAT entries referred to by any service, to their backend
+	dnat := make(map[uint32]string)
+	// DNAT entries referred to by each service
+	serviceDnat := make(map[string]map[uint32]bool)
+	for _, s := range services {
+		key := serviceKey(s)
+		intact[key] = t.isIntact(s)
+		serviceDnat[key] = make(map[uint32]bool)
+		if intact[key] {
+			groups[s.GroupID] = true
+		}
+		for ip, ep := range s.ServiceEndPoint {
+			if intact[key] {
+				members[ep.MemberID] = true
+			}
+			if backends[ip] == nil {
+				backends[ip] = make(map[uint32]bool)
+			}
+			backends[ip][ep.MemberID] = true
+			dnat[ep.DnatPtr] = ip
+			serviceDnat[key][ep.DnatPtr] = true
+		}
+	}
+
+	// Remove whatever is not part of an intact service, the entries
+	// referring to an object go before the object
+	var stale []*p4_v1.Entity
+	for key, entries := range t.pinnedFlows {
+		for _, e := range entries {
+			// The ptr parameter of pinned_flows_hit
+			if !serviceDnat[key][actionParamByID(e, 2)] {
+				stale = append(stale, tableEntity(e))
+			}
+		}
+	}
+	for key, e := range t.txBalance {
+		if !intact[key] {
+			stale = append(stale, tableEntity(e))
+		}
+	}
+	for id, g := range t.groups {
+		if !groups[id] {
+			stale = append(stale, groupEntity(g))
+		}
+	}
+	for id, m := range t.members {
+		if !members[id] {
+			stale = append(stale, memberEntity(m))
+		}
+	}
+	for id, e := range t.sourceIp {
+		if !members[id] {
+			stale = append(stale, tableEntity(e))
+		}
+	}
+	for ptr, e := range t.destIp {
+		if _, ok := dnat[ptr]; !ok {
+			stale = append(stale, tableEntity(e))
+		}
+	}
+	for ip, e := range t.rxSrcIp {
+		if backends[ip] == nil {
+			stale = append(stale, tableEntity(e))
+			delete(t.rxSrcIp, ip)
+		}
+	}
+	deleteEntities(ctx, p4RtC, stale, &stats)
+
+	for ptr, ip := range dnat {
+		if t.destIp[ptr] != nil {
+			continue
+		}
+		endpoint, ok := endpoints[ip]
+		if !ok {
+			log.Errorf("Cannot program the DNAT entry of %s: not a known endpoint", ip)
+			stats.Failed++
+			continue
+		}
+		err := RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+			return WriteDestIpTableEntry(ctx, tx, []string{ip},
+				[]string{endpoint.PodMacAddress}, []uint32{ptr}, Insert)
+		})
+		if err != nil {
+			log.Errorf("Cannot program the DNAT entry of %s: %v", ip, err)
+			stats.Failed++
+			continue
+		}
+		stats.Programmed++
+	}
+
+	for _, s := range services {
+		if intact[serviceKey(s)] {
+			continue
+		}
+		if err := reprogramService(ctx, p4RtC, s, endpoints); err != nil {
+			log.Errorf("Cannot program service %s: %v", serviceKey(s), err)
+			stats.Failed++
+			continue
+		}
+		stats.Programmed++
+	}
+
+	// The rx_src_ip entry of a backend must point to one of its members
+	for ip, ids := range backends {
+		var id uint32
+		for id = range ids {
+			break
+		}
+		action := Insert
+		var old []uint32
+		if e, ok := t.rxSrcIp[ip]; ok {
+			if ids[actionParam(e)] {
+				continue
+			}
+			action = Update
+			old = []uint32{actionParam(e)}
+		}
+		err := RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+			return RxSrcIpTableEntry(ctx, tx, []string{ip}, []uint32{id}, old, action)
+		})
+		if err != nil {
+			log.Errorf("Cannot program the rx_src_ip entry of %s: %v", ip, err)
+			stats.Failed++
+			continue
+		}
+		stats.Programmed++
+	}
+
+	return stats, nil
+}
+
+func reprogramService(ctx context.Context, p4RtC *client.Client, s store.Service, endpoints map[string]store.EndPoint) error {
+	var m ServiceMembers
+	for ip, ep := range s.ServiceEndPoint {
+		endpoint, ok := endpoints[ip]
+		if !ok {
+			return fmt.Errorf("backend %s is not a known endpoint", ip)
+		}
+		m.PodIpAddr = append(m.PodIpAddr, ip)
+		m.PodMacAddr = append(m.PodMacAddr, endpoint.PodMacAddress)
+		m.MemberID = append(m.MemberID, ep.MemberID)
+		m.DnatPtr = append(m.DnatPtr, ep.DnatPtr)
+		m.InterfaceID = append(m.InterfaceID, endpoint.InterfaceID)
+	}
+
+	// The rx_src_ip and the DNAT entries are shared by the services
+	// of a backend, the caller takes care of them
+	return InsertServiceRules(ctx, p4RtC, m, s)
+}
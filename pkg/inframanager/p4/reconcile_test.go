@@ -0,0 +1,301 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"context"
+
+	"github.com/antoninbas/p4runtime-go-client/pkg/client"
+	"github.com/golang/protobuf/proto"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/store"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reconciliation", func() {
+	ctx := context.Background()
+	epA := store.EndPoint{PodIpAddress: "10.10.10.1", InterfaceID: 1, PodMacAddress: "00:00:00:00:00:01"}
+	epB := store.EndPoint{PodIpAddress: "10.10.10.2", InterfaceID: 2, PodMacAddress: "00:00:00:00:00:02"}
+
+	insertEndPoint := func(ep store.EndPoint) {
+		Expect(InsertCniRules(ctx, p4RtC, ep.PodMacAddress, []string{ep.PodIpAddress}, int(ep.InterfaceID), ENDPOINT)).To(Succeed())
+	}
+
+	var _ = Context("ReconcileCniRules() should", func() {
+		var _ = It("leave the entries of the endpoints untouched", func() {
+			insertEndPoint(epA)
+			stats, err := ReconcileCniRules(ctx, p4RtC, []store.EndPoint{epA})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{}))
+			Expect(fakeServer.count()).To(Equal(2))
+		})
+
+		var _ = It("remove the entries of unknown endpoints", func() {
+			insertEndPoint(epA)
+			insertEndPoint(epB)
+			stats, err := ReconcileCniRules(ctx, p4RtC, []store.EndPoint{epA})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{Removed: 2}))
+			Expect(fakeServer.count()).To(Equal(2))
+		})
+
+		var _ = It("program the missing entries", func() {
+			insertEndPoint(epA)
+			stats, err := ReconcileCniRules(ctx, p4RtC, []store.EndPoint{epA, epB})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{Programmed: 2}))
+			Expect(fakeServer.count()).To(Equal(4))
+		})
+
+		var _ = It("replace the entries sending to another port", func() {
+			insertEndPoint(epA)
+			moved := epA
+			moved.InterfaceID = 5
+			stats, err := ReconcileCniRules(ctx, p4RtC, []store.EndPoint{moved})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{Removed: 2, Programmed: 2}))
+			Expect(fakeServer.count()).To(Equal(2))
+			entry := macEntry([]byte{0, 0, 0, 0, 0, 1}, 5)
+			Expect(proto.Equal(fakeServer.get(entry), entry)).To(BeTrue())
+		})
+
+		var _ = It("share the mac_to_port entry between the addresses of an interface", func() {
+			epA6 := epA
+			epA6.PodIpAddress = "fd00::1"
+			stats, err := ReconcileCniRules(ctx, p4RtC, []store.EndPoint{epA, epA6})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{Programmed: 3}))
+
+			stats, err = ReconcileCniRules(ctx, p4RtC, []store.EndPoint{epA, epA6})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{}))
+			Expect(fakeServer.count()).To(Equal(3))
+		})
+	})
+
+	var _ = Context("ReconcileServiceRules() should", func() {
+		podIp := []string{epA.PodIpAddress, epB.PodIpAddress}
+		podMac := []string{epA.PodMacAddress, epB.PodMacAddress}
+		memberID := []uint32{1, 2}
+		dnatPtr := []uint32{11, 12}
+		service := store.Service{
+			ClusterIp:   "10.96.0.10",
+			ClusterPort: 80,
+			GroupID:     100,
+			ServiceEndPoint: map[string]store.ServiceEndPoint{
+				epA.PodIpAddress: {IpAddress: epA.PodIpAddress, Port: 80, MemberID: 1, DnatPtr: 11},
+				epB.PodIpAddress: {IpAddress: epB.PodIpAddress, Port: 80, MemberID: 2, DnatPtr: 12},
+			},
+		}
+		endpoints := map[string]store.EndPoint{
+			epA.PodIpAddress: epA,
+			epB.PodIpAddress: epB,
+		}
+		entries := 4*len(memberID) + 2
+
+		var _ = BeforeEach(func() {
+			Expect(InsertServiceRules(ctx, p4RtC, ServiceMembers{
+				PodIpAddr:     podIp,
+				PodMacAddr:    podMac,
+				MemberID:      memberID,
+				DnatPtr:       dnatPtr,
+				NewDnatPtr:    dnatPtr,
+				SnatPodIpAddr: podIp,
+				SnatMemberID:  memberID,
+			}, service)).To(Succeed())
+		})
+
+		var _ = It("leave an intact service untouched", func() {
+			stats, err := ReconcileServiceRules(ctx, p4RtC, []store.Service{service}, endpoints)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{}))
+			Expect(fakeServer.count()).To(Equal(entries))
+		})
+
+		var _ = It("remove the entries of unknown services", func() {
+			stats, err := ReconcileServiceRules(ctx, p4RtC, nil, endpoints)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{Removed: entries}))
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+
+		var _ = It("program again a service missing its tx_balance entry", func() {
+			Expect(RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+				return TxBalanceIpTableEntry(ctx, tx, service.ClusterIp, service.Proto, service.ClusterPort, service.GroupID, Delete)
+			})).To(Succeed())
+
+			stats, err := ReconcileServiceRules(ctx, p4RtC, []store.Service{service}, endpoints)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats.Programmed).To(Equal(1))
+			Expect(stats.Failed).To(Equal(0))
+			Expect(fakeServer.count()).To(Equal(entries))
+		})
+
+		var _ = It("program the missing rx_src_ip entries", func() {
+			Expect(RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+				return RxSrcIpTableEntry(ctx, tx, podIp, nil, nil, Delete)
+			})).To(Succeed())
+
+			stats, err := ReconcileServiceRules(ctx, p4RtC, []store.Service{service}, endpoints)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{Programmed: 2}))
+			Expect(fakeServer.count()).To(Equal(entries))
+		})
+
+		var _ = It("report a service whose backend is not a known endpoint", func() {
+			Expect(RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+				return TxBalanceIpTableEntry(ctx, tx, service.ClusterIp, service.Proto, service.ClusterPort, service.GroupID, Delete)
+			})).To(Succeed())
+
+			stats, err := ReconcileServiceRules(ctx, p4RtC, []store.Service{service},
+				map[string]store.EndPoint{epA.PodIpAddress: epA})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats.Failed).To(Equal(1))
+		})
+
+		var _ = It("tell the services of a port apart by protocol", func() {
+			udp := service
+			udp.Proto = "UDP"
+			stats, err := ReconcileServiceRules(ctx, p4RtC, []store.Service{udp}, endpoints)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats.Programmed).To(Equal(1))
+			Expect(stats.Failed).To(Equal(0))
+			Expect(fakeServer.count()).To(Equal(entries))
+
+			stats, err = ReconcileServiceRules(ctx, p4RtC, []store.Service{udp}, endpoints)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{}))
+		})
+
+		var _ = It("reconcile the services of both families", func() {
+			epA6 := store.EndPoint{PodIpAddress: "fd00::1", InterfaceID: 1, PodMacAddress: epA.PodMacAddress}
+			service6 := store.Service{
+				ClusterIp:   "fd00:96::10",
+				ClusterPort: 80,
+				GroupID:     101,
+				ServiceEndPoint: map[string]store.ServiceEndPoint{
+					epA6.PodIpAddress: {IpAddress: epA6.PodIpAddress, Port: 80, MemberID: 3, DnatPtr: 13},
+				},
+			}
+			Expect(InsertServiceRules(ctx, p4RtC, ServiceMembers{
+				PodIpAddr:     []string{epA6.PodIpAddress},
+				PodMacAddr:    podMac[:1],
+				MemberID:      []uint32{3},
+				DnatPtr:       []uint32{13},
+				NewDnatPtr:    []uint32{13},
+				SnatPodIpAddr: []string{epA6.PodIpAddress},
+				SnatMemberID:  []uint32{3},
+			}, service6)).To(Succeed())
+			endpoints6 := map[string]store.EndPoint{epA6.PodIpAddress: epA6}
+			for ip, ep := range endpoints {
+				endpoints6[ip] = ep
+			}
+
+			stats, err := ReconcileServiceRules(ctx, p4RtC, []store.Service{service, service6}, endpoints6)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{}))
+
+			stats, err = ReconcileServiceRules(ctx, p4RtC, []store.Service{service}, endpoints6)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{Removed: 6}))
+			Expect(fakeServer.count()).To(Equal(entries))
+		})
+
+		var _ = It("keep the DNAT entries shared with a remaining service", func() {
+			https := service
+			https.ClusterPort = 443
+			https.GroupID = 102
+			https.ServiceEndPoint = map[string]store.ServiceEndPoint{
+				epA.PodIpAddress: {IpAddress: epA.PodIpAddress, Port: 443, MemberID: 5, DnatPtr: 11},
+				epB.PodIpAddress: {IpAddress: epB.PodIpAddress, Port: 443, MemberID: 6, DnatPtr: 12},
+			}
+			Expect(InsertServiceRules(ctx, p4RtC, ServiceMembers{
+				PodIpAddr:  podIp,
+				PodMacAddr: podMac,
+				MemberID:   []uint32{5, 6},
+				DnatPtr:    dnatPtr,
+			}, https)).To(Succeed())
+
+			stats, err := ReconcileServiceRules(ctx, p4RtC, []store.Service{service, https}, endpoints)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{}))
+
+			// Only the members, SNAT entries, group and tx_balance entry of
+			// the first service go, the rx_src_ip entries move to the second
+			stats, err = ReconcileServiceRules(ctx, p4RtC, []store.Service{https}, endpoints)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{Removed: 2*len(memberID) + 2, Programmed: 2}))
+			Expect(fakeServer.count()).To(Equal(entries))
+		})
+
+		var _ = It("purge the backends still draining when the inframanager stopped", func() {
+			pinFlow := func(ptr uint32) {
+				Expect(p4RtC.InsertTableEntry(ctx, p4RtC.NewTableEntry(
+					"k8s_dp_control.pinned_flows",
+					map[string]client.MatchInterface{
+						"hdr.ipv4.src_addr": &client.ExactMatch{Value: PackBinaryIP("192.168.0.1")},
+						"hdr.ipv4.dst_addr": &client.ExactMatch{Value: PackBinaryIP(service.ClusterIp)},
+						"hdr.ipv4.protocol": &client.ExactMatch{Value: []byte{6}},
+						"meta.l4_src_port":  &client.ExactMatch{Value: valueToBytes(40000 + ptr)},
+						"meta.l4_dst_port":  &client.ExactMatch{Value: valueToBytes(service.ClusterPort)},
+					},
+					p4RtC.NewTableActionDirect("k8s_dp_control.pinned_flows_hit",
+						[][]byte{valueToBytes(0), valueToBytes(ptr)}),
+					nil,
+				))).To(Succeed())
+			}
+			pinFlow(dnatPtr[0])
+			pinFlow(dnatPtr[1])
+
+			updated := service
+			updated.ServiceEndPoint = map[string]store.ServiceEndPoint{
+				epA.PodIpAddress: service.ServiceEndPoint[epA.PodIpAddress],
+			}
+			Expect(UpdateServiceRules(ctx, p4RtC, ServiceMembers{}, ServiceMembers{
+				PodIpAddr:     podIp[1:],
+				MemberID:      memberID[1:],
+				DnatPtr:       dnatPtr[1:],
+				StaleDnatPtr:  dnatPtr[1:],
+				SnatPodIpAddr: podIp[1:],
+				Drain:         true,
+			}, updated)).To(Succeed())
+
+			// The pinned flow, write_source_ip, write_dest_ip and
+			// rx_src_ip entries of the drained backend
+			stats, err := ReconcileServiceRules(ctx, p4RtC, []store.Service{updated}, endpoints)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{Removed: 4}))
+			Expect(fakeServer.count()).To(Equal(entries - 4 + 1))
+
+			res, err := p4RtC.ReadTableEntryWildcard(ctx, "k8s_dp_control.pinned_flows")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+			Expect(actionParamByID(res[0], 2)).To(Equal(dnatPtr[0]))
+		})
+
+		var _ = It("program the missing DNAT entries of a service", func() {
+			Expect(RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+				return WriteDestIpTableEntry(ctx, tx, podIp[:1], nil, dnatPtr[:1], Delete)
+			})).To(Succeed())
+
+			stats, err := ReconcileServiceRules(ctx, p4RtC, []store.Service{service}, endpoints)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(ReconcileStats{Programmed: 1}))
+			Expect(fakeServer.count()).To(Equal(entries))
+		})
+	})
+})
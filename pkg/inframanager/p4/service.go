@@ -18,167 +18,523 @@ package p4
 
 import (
 	"context"
+	"net"
+
 	"github.com/antoninbas/p4runtime-go-client/pkg/client"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/store"
 	p4_v1 "github.com/p4lang/p4runtime/go/p4/v1"
 	log "github.com/sirupsen/logrus"
-	"net"
 )
 
-func WriteDestIpTableEntry(ctx context.Context, p4RtC *client.Client, podIpAddr []string, podMacAddr []string, modBlobPtr []uint32) error {
-	var err error
+const (
+	// Size of the as_sl3 action selector, AS_NUM_MEMBERS in k8s_dp.p4
+	asSl3MaxGroupSize = 128
+)
+
+// Idle timeouts in seconds of the expire time profiles of the tables
+// learning entries, as set by the DPDK target. They are the
+// EXPIRE_TIME_PROFILE_<n> constants of k8s_dp.p4, which must be kept
+// in sync. Profile 2 is used for the pinned flows.
+var expireTimeProfiles = []uint32{10, 30, 60, 120, 300, 43200, 120, 120}
+
+// Returns the expire time profile of the client_affinity entries of a
+// service, the shortest one lasting at least the affinity timeout. The
+// profiles are fixed, so the affinity may last longer than asked.
+func affinityProfile(timeout uint32) uint32 {
+	var profile uint32
+	found := false
+	for i, t := range expireTimeProfiles {
+		if t >= timeout && (!found || t < expireTimeProfiles[profile]) {
+			profile, found = uint32(i), true
+		}
+	}
+	if found {
+		return profile
+	}
+	// None lasts long enough, the longest one is the closest
+	for i, t := range expireTimeProfiles {
+		if t > expireTimeProfiles[profile] {
+			profile = uint32(i)
+		}
+	}
+	return profile
+}
+
+// Returns the entity to write back when the entry gets deleted. An
+// entry without an action does not carry its content, the transaction
+// reads it from the target instead.
+func restorable(entry *p4_v1.TableEntry) *p4_v1.Entity {
+	if entry.Action == nil {
+		return nil
+	}
+	return tableEntity(entry)
+}
+
+// The DNAT entries of the backends go to the table of their family,
+// which is the family of the service. The entry of a backend is shared
+// by all its services.
+func WriteDestIpTableEntry(ctx context.Context, tx *Transaction, podIpAddr []string, podMacAddr []string, modBlobPtr []uint32, action OperationType) error {
+	p4RtC := tx.p4RtC
 	for i := 0; i < len(modBlobPtr); i++ {
-		dstMac, err := net.ParseMAC(podMacAddr[i])
-		if err != nil {
-			log.Errorf("Failed to parse mac address %s", podMacAddr[i])
-			return err
+		family := familyOf(podIpAddr[i])
+		var tableAction *p4_v1.TableAction
+		if i < len(podMacAddr) {
+			dstMac, err := net.ParseMAC(podMacAddr[i])
+			if err != nil {
+				log.Errorf("Failed to parse mac address %s", podMacAddr[i])
+				return err
+			}
+			tableAction = p4RtC.NewTableActionDirect(family.updateDstIpMac,
+				[][]byte{dstMac, PackBinaryIP(podIpAddr[i])})
 		}
 
-		entry1 := p4RtC.NewTableEntry(
-			"k8s_dp_control.write_dest_ip_table",
+		entry := p4RtC.NewTableEntry(
+			family.writeDestIp,
 			map[string]client.MatchInterface{
 				"meta.mod_blob_ptr": &client.ExactMatch{
 					Value: valueToBytes(modBlobPtr[i]),
 				},
 			},
-			p4RtC.NewTableActionDirect("k8s_dp_control.update_dst_ip_mac", [][]byte{dstMac, net.ParseIP(podIpAddr[i])}),
+			tableAction,
 			nil,
 		)
-		if err := p4RtC.InsertTableEntry(ctx, entry1); err != nil {
-			log.Errorf("Cannot insert entry in 'direction_table table': %v", err)
+		if err := tx.Write(ctx, tableEntity(entry), action, restorable(entry)); err != nil {
+			log.Errorf("Cannot %s entry in '%s': %v", action, family.writeDestIp, err)
+			return err
 		}
 	}
-	return err
+	return nil
 }
 
-func AsSl3TableEntry(ctx context.Context, p4RtC *client.Client, memberID []uint32, modBlobPtr []uint32, interfaceID []uint32, groupID uint32) error {
-	/*»	var memberList []*p4_v1.ActionProfileGroup_Member = []*p4_v1.ActionProfileGroup_Member{&p4_v1.ActionProfileGroup_Member{
-	  »	»	MemberId: uint32(1),
-	  »	},
-	  »	»	&p4_v1.ActionProfileGroup_Member{
-	  »	»	»	MemberId: uint32(2),
-	  »	»	}}*/
-	var err error
-	var memberList []*p4_v1.ActionProfileGroup_Member
-	for j := 0; j < len(memberID); j++ {
-		member := &p4_v1.ActionProfileGroup_Member{
-			MemberId: memberID[j],
-		}
-		memberList = append(memberList, member)
-	}
-
+// The members of a service with ClientIP affinity, a non zero affinity
+// timeout, have its clients learnt by the client_affinity table.
+func AsSl3MemberEntry(ctx context.Context, tx *Transaction, memberID []uint32, modBlobPtr []uint32, interfaceID []uint32, affinityTimeout uint32, action OperationType) error {
 	for i := 0; i < len(memberID); i++ {
-		member := &p4_v1.ActionProfileGroup_Member{
-			MemberId: memberID[i],
+		actionName := "k8s_dp_control.set_default_lb_dest"
+		params := [][]byte{valueToBytes(interfaceID[i]), valueToBytes(modBlobPtr[i])}
+		if affinityTimeout != 0 {
+			actionName = "k8s_dp_control.set_affinity_lb_dest"
+			params = append(params, []byte{uint8(affinityProfile(affinityTimeout))})
 		}
-		memberList = append(memberList, member)
-
-		entry1 := p4RtC.NewActionProfileMember(
+		member := tx.p4RtC.NewActionProfileMember(
 			"k8s_dp_control.as_sl3",
 			memberID[i],
-			"k8s_dp_control.set_default_lb_dest",
-			[][]byte{valueToBytes(interfaceID[i]), valueToBytes(modBlobPtr[i])},
+			actionName,
+			params,
 		)
-		if err := p4RtC.InsertActionProfileMember(ctx, entry1); err != nil {
-			log.Errorf("Cannot insert member entry in 'as_sl3 table': %v", err)
+
+		if err := tx.Write(ctx, memberEntity(member), action, memberEntity(member)); err != nil {
+			log.Errorf("Cannot %s member entry in 'as_sl3 table': %v", action, err)
+			return err
 		}
 	}
+	return nil
+}
+
+func newAsSl3Group(p4RtC *client.Client, memberID []uint32, groupID uint32) *p4_v1.ActionProfileGroup {
+	var memberList []*p4_v1.ActionProfileGroup_Member
+	for i := 0; i < len(memberID); i++ {
+		memberList = append(memberList, &p4_v1.ActionProfileGroup_Member{
+			MemberId: memberID[i],
+			Weight:   1,
+		})
+	}
 
-	entry2 := p4RtC.NewActionProfileGroup(
+	return p4RtC.NewActionProfileGroup(
 		"k8s_dp_control.as_sl3",
 		groupID,
 		memberList,
-		int32(124),
+		int32(asSl3MaxGroupSize),
 	)
-	if err := p4RtC.InsertActionProfileGroup(ctx, entry2); err != nil {
-		log.Errorf("Cannot insert group entry in 'as_sl3 table': %v", err)
-	}
-
-	return err
 }
 
-func TxBalanceIpTableEntry(ctx context.Context, p4RtC *client.Client, serviceIpAddr string, servicePort uint32, groupID uint32) error {
-	var err error
-	mfs := map[string]client.MatchInterface{
-		"hdr.ipv4.dst_addr": &client.ExactMatch{
-			Value: net.ParseIP(serviceIpAddr),
-		},
-		"hdr.tcp.dst_port": &client.ExactMatch{
-			Value: valueToBytes(servicePort),
-		},
+// The old members are the members of the group before an update,
+// the group is switched back to them on rollback.
+func AsSl3GroupEntry(ctx context.Context, tx *Transaction, memberID []uint32, oldMemberID []uint32, groupID uint32, action OperationType) error {
+	group := newAsSl3Group(tx.p4RtC, memberID, groupID)
+
+	var old *p4_v1.Entity
+	switch action {
+	case Update:
+		if oldMemberID != nil {
+			old = groupEntity(newAsSl3Group(tx.p4RtC, oldMemberID, groupID))
+		}
+	case Delete:
+		old = groupEntity(group)
 	}
-	entry1 := p4RtC.NewTableEntry(
-		"k8s_dp_control.tx_balance",
-		mfs,
-		p4RtC.NewTableActionGroup(groupID),
-		nil,
-	)
-	if err := p4RtC.InsertTableEntry(ctx, entry1); err != nil {
-		log.Errorf("Cannot insert entry in 'tx_balance table': %v", err)
+
+	if err := tx.Write(ctx, groupEntity(group), action, old); err != nil {
+		log.Errorf("Cannot %s group entry in 'as_sl3 table': %v", action, err)
+		return err
 	}
-	return err
+	return nil
 }
 
-func WriteSourceIpTableEntry(ctx context.Context, p4RtC *client.Client, rxModBlobPtr uint32, serviceIpAddr string, serviceMacAddr string) error {
-	var err error
-	srcMac, err := net.ParseMAC(serviceMacAddr)
+// TxBalanceIpTableEntry programs the load balancing of the packets to
+// the virtual ip and port of a service over the given protocol
+func TxBalanceIpTableEntry(ctx context.Context, tx *Transaction, serviceIpAddr string, proto string, servicePort uint32, groupID uint32, action OperationType) error {
+	family := familyOf(serviceIpAddr)
+	_, protoNum, err := ServiceProtocol(proto)
 	if err != nil {
-		log.Errorf("Failed to parse mac address %s", serviceMacAddr)
+		log.Errorf("Cannot %s entry in '%s table': %v", action, family.txBalance, err)
 		return err
 	}
-
-	entry1 := p4RtC.NewTableEntry(
-		"k8s_dp_control.write_source_ip_table",
+	entry := tx.p4RtC.NewTableEntry(
+		family.txBalance,
 		map[string]client.MatchInterface{
-			"meta.mod_blob_ptr": &client.ExactMatch{
-				Value: valueToBytes(rxModBlobPtr),
+			family.txBalanceDst: &client.ExactMatch{
+				Value: PackBinaryIP(serviceIpAddr),
+			},
+			family.txBalanceProto: &client.ExactMatch{
+				Value: []byte{protoNum},
+			},
+			"meta.l4_dst_port": &client.ExactMatch{
+				Value: valueToBytes(servicePort),
 			},
 		},
-		p4RtC.NewTableActionDirect("k8s_dp_control.update_src_ip_mac", [][]byte{srcMac, net.ParseIP(serviceIpAddr)}),
+		tx.p4RtC.NewTableActionGroup(groupID),
 		nil,
 	)
-	if err := p4RtC.InsertTableEntry(ctx, entry1); err != nil {
-		log.Errorf("Cannot insert entry in 'write_source_ip_table table': %v", err)
+	if err := tx.Write(ctx, tableEntity(entry), action, restorable(entry)); err != nil {
+		log.Errorf("Cannot %s entry in '%s table': %v", action, family.txBalance, err)
+		return err
 	}
-	return err
+	return nil
 }
 
-func RxSrcIpTableEntry(ctx context.Context, p4RtC *client.Client, podIpAddr []string, rxModBlobPtr uint32) error {
-	var err error
-	for i := 0; i < len(podIpAddr); i++ {
-		entry1 := p4RtC.NewTableEntry(
-			"k8s_dp_control.rx_src_ip",
+func WriteSourceIpTableEntry(ctx context.Context, tx *Transaction, podMacAddr []string, rxModBlobPtr []uint32, serviceIpAddr string, action OperationType) error {
+	p4RtC := tx.p4RtC
+	family := familyOf(serviceIpAddr)
+	for i := 0; i < len(rxModBlobPtr); i++ {
+		var tableAction *p4_v1.TableAction
+		if i < len(podMacAddr) {
+			srcMac, err := net.ParseMAC(podMacAddr[i])
+			if err != nil {
+				log.Errorf("Failed to parse mac address %s", podMacAddr[i])
+				return err
+			}
+			tableAction = p4RtC.NewTableActionDirect(family.updateSrcIpMac,
+				[][]byte{srcMac, PackBinaryIP(serviceIpAddr)})
+		}
+
+		entry := p4RtC.NewTableEntry(
+			family.writeSourceIp,
 			map[string]client.MatchInterface{
-				"hdr.ipv4.src_addr": &client.ExactMatch{
-					Value: net.ParseIP(podIpAddr[i]),
+				"meta.mod_blob_ptr": &client.ExactMatch{
+					Value: valueToBytes(rxModBlobPtr[i]),
 				},
 			},
-			p4RtC.NewTableActionDirect("k8s_dp_control.set_source_ip", [][]byte{valueToBytes(rxModBlobPtr)}),
+			tableAction,
 			nil,
 		)
-		if err := p4RtC.InsertTableEntry(ctx, entry1); err != nil {
-			log.Errorf("Cannot insert entry in 'rx_src_ip table': %v", err)
+		if err := tx.Write(ctx, tableEntity(entry), action, restorable(entry)); err != nil {
+			log.Errorf("Cannot %s entry in '%s': %v", action, family.writeSourceIp, err)
+			return err
 		}
 	}
-	return err
+	return nil
 }
 
-func insertServiceRules(ctx context.Context, p4RtC *client.Client, podIpAddr []string, podMacAddr []string, serviceIpAddr string, serviceMacAddr string, servicePort uint32) error {
-	var err error
-	memberID := make([]uint32, len(podIpAddr))
-	interfaceID := make([]uint32, len(podIpAddr))
-
-	groupID := uuidFactory.getUUID()
+func newRxSrcIpEntry(p4RtC *client.Client, podIpAddr string, rxModBlobPtr uint32) *p4_v1.TableEntry {
+	family := familyOf(podIpAddr)
+	return p4RtC.NewTableEntry(
+		family.rxSrcIp,
+		map[string]client.MatchInterface{
+			family.rxSrcIpSrc: &client.ExactMatch{
+				Value: PackBinaryIP(podIpAddr),
+			},
+		},
+		p4RtC.NewTableActionDirect("k8s_dp_control.set_source_ip",
+			[][]byte{valueToBytes(rxModBlobPtr)}),
+		nil,
+	)
+}
 
+// The old pointers are the ones the entries held before an update or
+// a delete, the entries are restored to them on rollback.
+func RxSrcIpTableEntry(ctx context.Context, tx *Transaction, podIpAddr []string, rxModBlobPtr []uint32, oldRxModBlobPtr []uint32, action OperationType) error {
 	for i := 0; i < len(podIpAddr); i++ {
-		memberID = append(memberID, uint32(i+1))
-		interfaceID = append(interfaceID, 0)
+		var ptr uint32
+		if i < len(rxModBlobPtr) {
+			ptr = rxModBlobPtr[i]
+		}
+		entry := newRxSrcIpEntry(tx.p4RtC, podIpAddr[i], ptr)
+
+		var old *p4_v1.Entity
+		if action != Insert && i < len(oldRxModBlobPtr) {
+			old = tableEntity(newRxSrcIpEntry(tx.p4RtC, podIpAddr[i], oldRxModBlobPtr[i]))
+		}
+
+		if err := tx.Write(ctx, tableEntity(entry), action, old); err != nil {
+			log.Errorf("Cannot %s entry in '%s table': %v", action, familyOf(podIpAddr[i]).rxSrcIp, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// ServiceMembers describes the backends of a service being added to or
+// removed from the pipeline. PodIpAddr, PodMacAddr, MemberID, DnatPtr,
+// and InterfaceID are indexed by backend.
+type ServiceMembers struct {
+	PodIpAddr  []string
+	PodMacAddr []string
+	MemberID   []uint32
+	// The ports the backends are reached through
+	InterfaceID []uint32
+	// Pointers of the DNAT entries of the backends, an entry is shared
+	// by all the services load balancing to the backend
+	DnatPtr []uint32
+	// The DNAT entries first referred to by these backends, inserted
+	// along with the members
+	NewDnatPtr []uint32
+	// The DNAT entries no other service refers to anymore, deleted
+	// along with the members
+	StaleDnatPtr []uint32
+	// The rx_src_ip entries to insert, or to delete
+	SnatPodIpAddr []string
+	SnatMemberID  []uint32
+	// The rx_src_ip entries to repoint to the member of another service
+	UpdPodIpAddr []string
+	UpdMemberID  []uint32
+	// The backends being removed keep serving the flows pinned to them
+	Drain bool
+}
+
+// Returns the backends whose DNAT entry is one of the given ones
+func (m ServiceMembers) dnatEntries(ptrs []uint32) ([]string, []string, []uint32) {
+	wanted := make(map[uint32]bool, len(ptrs))
+	for _, ptr := range ptrs {
+		wanted[ptr] = true
+	}
+	var podIpAddr, podMacAddr []string
+	var dnatPtr []uint32
+	for i := 0; i < len(m.DnatPtr) && i < len(m.PodIpAddr); i++ {
+		if !wanted[m.DnatPtr[i]] {
+			continue
+		}
+		// A pointer shows up once per service
+		delete(wanted, m.DnatPtr[i])
+		podIpAddr = append(podIpAddr, m.PodIpAddr[i])
+		if i < len(m.PodMacAddr) {
+			podMacAddr = append(podMacAddr, m.PodMacAddr[i])
+		}
+		dnatPtr = append(dnatPtr, m.DnatPtr[i])
+	}
+	return podIpAddr, podMacAddr, dnatPtr
+}
+
+// Each backend of a service owns one member in the as_sl3 selector.
+// The member id is also used as the mod_blob_ptr of the SNAT entry in
+// write_source_ip_table, which holds the address of the service. The
+// DNAT entry in write_dest_ip_table only depends on the backend, so the
+// services of a backend share it and the members point to it.
+// The rx_src_ip table is keyed by the pod ip only, hence the entries
+// are programmed just for the backends listed in SnatPodIpAddr, which
+// are not yet serving as a backend for any other service.
+func InsertServiceMembers(ctx context.Context, tx *Transaction, m ServiceMembers, s store.Service) error {
+	interfaceID := m.interfaceIDs()
+
+	podIpAddr, podMacAddr, dnatPtr := m.dnatEntries(m.NewDnatPtr)
+	if err := WriteDestIpTableEntry(ctx, tx, podIpAddr, podMacAddr, dnatPtr, Insert); err != nil {
+		return err
+	}
+
+	if err := AsSl3MemberEntry(ctx, tx, m.MemberID, m.DnatPtr, interfaceID, s.AffinityTimeout, Insert); err != nil {
+		return err
 	}
 
-	err = WriteDestIpTableEntry(ctx, p4RtC, podIpAddr, podMacAddr, memberID)
-	err = AsSl3TableEntry(ctx, p4RtC, memberID, memberID, interfaceID, groupID)
-	err = TxBalanceIpTableEntry(ctx, p4RtC, serviceIpAddr, servicePort, groupID)
-	err = WriteSourceIpTableEntry(ctx, p4RtC, groupID, serviceIpAddr, serviceMacAddr)
-	err = RxSrcIpTableEntry(ctx, p4RtC, podIpAddr, groupID)
+	if err := WriteSourceIpTableEntry(ctx, tx, m.PodMacAddr, m.MemberID, s.ClusterIp, Insert); err != nil {
+		return err
+	}
+
+	return RxSrcIpTableEntry(ctx, tx, m.SnatPodIpAddr, m.SnatMemberID, nil, Insert)
+}
+
+// Returns the port of each member, 0 when it is not known
+func (m ServiceMembers) interfaceIDs() []uint32 {
+	interfaceID := make([]uint32, len(m.MemberID))
+	copy(interfaceID, m.InterfaceID)
+	return interfaceID
+}
+
+// Returns the members of the given backends
+func (m ServiceMembers) podMemberIDs(podIpAddr []string) []uint32 {
+	podMemberID := make(map[string]uint32)
+	for i := 0; i < len(m.PodIpAddr) && i < len(m.MemberID); i++ {
+		podMemberID[m.PodIpAddr[i]] = m.MemberID[i]
+	}
+	memberID := make([]uint32, 0, len(podIpAddr))
+	for _, ip := range podIpAddr {
+		memberID = append(memberID, podMemberID[ip])
+	}
+	return memberID
+}
+
+// The rx_src_ip entries of the backends which are still used by other
+// services are repointed to the SNAT entries of those services, the
+// remaining ones are listed in SnatPodIpAddr and get deleted.
+// The members must not be referenced by the as_sl3 group anymore.
+// Draining backends only lose their members, so that no new flow is
+// load balanced to them, their other entries are left to the flows
+// pinned to them until PurgeServiceMembers is called.
+func DeleteServiceMembers(ctx context.Context, tx *Transaction, m ServiceMembers, s store.Service) error {
+	interfaceID := m.interfaceIDs()
+
+	// The rx_src_ip entries being changed point to the members
+	// being deleted, this is what they are restored to on rollback
+	if err := RxSrcIpTableEntry(ctx, tx, m.UpdPodIpAddr, m.UpdMemberID, m.podMemberIDs(m.UpdPodIpAddr), Update); err != nil {
+		return err
+	}
+
+	if err := AsSl3MemberEntry(ctx, tx, m.MemberID, m.DnatPtr, interfaceID, s.AffinityTimeout, Delete); err != nil {
+		return err
+	}
+
+	if m.Drain {
+		return nil
+	}
+	return purgeServiceMembers(ctx, tx, m, s)
+}
+
+// Deletes the pinned_flows entries of the flows to the service which
+// were load balanced to one of the given DNAT entries. The entries are
+// learnt by the pipeline, they would otherwise keep sending the packets
+// of their flow to a removed backend until they expire.
+func purgePinnedFlows(ctx context.Context, tx *Transaction, s store.Service, dnatPtr []uint32) error {
+	if len(dnatPtr) == 0 {
+		return nil
+	}
+	family := familyOf(s.ClusterIp)
+	_, protoNum, err := ServiceProtocol(s.Proto)
+	if err != nil {
+		return err
+	}
+	isPurged := make(map[uint32]bool, len(dnatPtr))
+	for _, ptr := range dnatPtr {
+		isPurged[ptr] = true
+	}
+
+	entries, err := readTable(ctx, tx.p4RtC, family.pinnedFlows)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		// The ptr parameter of pinned_flows_hit
+		if !isPurged[actionParamByID(e, 2)] ||
+			family.bytesToIP(matchValue(tx.p4RtC, e, family.pinnedFlows, family.pinnedFlowsDst)) != s.ClusterIp ||
+			bytesToUint32(matchValue(tx.p4RtC, e, family.pinnedFlows, family.pinnedFlowsProto)) != uint32(protoNum) ||
+			bytesToUint32(matchValue(tx.p4RtC, e, family.pinnedFlows, "meta.l4_dst_port")) != s.ClusterPort {
+			continue
+		}
+		if err := tx.Write(ctx, tableEntity(e), Delete, tableEntity(e)); err != nil {
+			log.Errorf("Cannot delete entry in '%s table': %v", family.pinnedFlows, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Deletes the entries the flows pinned to the backends still use, once
+// their members are deleted
+func purgeServiceMembers(ctx context.Context, tx *Transaction, m ServiceMembers, s store.Service) error {
+	if err := purgePinnedFlows(ctx, tx, s, m.DnatPtr); err != nil {
+		return err
+	}
+
+	snatMemberID := m.podMemberIDs(m.SnatPodIpAddr)
+	if err := RxSrcIpTableEntry(ctx, tx, m.SnatPodIpAddr, snatMemberID, snatMemberID, Delete); err != nil {
+		return err
+	}
+
+	if err := WriteSourceIpTableEntry(ctx, tx, nil, m.MemberID, s.ClusterIp, Delete); err != nil {
+		return err
+	}
+
+	podIpAddr, _, dnatPtr := m.dnatEntries(m.StaleDnatPtr)
+	return WriteDestIpTableEntry(ctx, tx, podIpAddr, nil, dnatPtr, Delete)
+}
+
+func serviceMemberIDs(s store.Service) []uint32 {
+	memberID := make([]uint32, 0, len(s.ServiceEndPoint))
+	for _, ep := range s.ServiceEndPoint {
+		memberID = append(memberID, ep.MemberID)
+	}
+	return memberID
+}
+
+// The members of the backends are inserted first, then the as_sl3
+// group referencing them and last the tx_balance entry referencing
+// the group. Either all of them get programmed or none.
+func InsertServiceRules(ctx context.Context, p4RtC *client.Client, m ServiceMembers, s store.Service) error {
+	return RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+		if err := InsertServiceMembers(ctx, tx, m, s); err != nil {
+			return err
+		}
+
+		if err := AsSl3GroupEntry(ctx, tx, m.MemberID, nil, s.GroupID, Insert); err != nil {
+			return err
+		}
+
+		return TxBalanceIpTableEntry(ctx, tx, s.ClusterIp, s.Proto, s.ClusterPort, s.GroupID, Insert)
+	})
+}
+
+// Updates the backends of a programmed service in place, the tx_balance
+// entry is left untouched. The service passed in holds the resulting
+// set of backends, so the group is switched to the new members before
+// the removed ones are deleted.
+func UpdateServiceRules(ctx context.Context, p4RtC *client.Client, added ServiceMembers, removed ServiceMembers, s store.Service) error {
+	memberID := serviceMemberIDs(s)
+
+	isAdded := make(map[uint32]bool)
+	for _, id := range added.MemberID {
+		isAdded[id] = true
+	}
+	oldMemberID := make([]uint32, 0, len(memberID)+len(removed.MemberID))
+	for _, id := range memberID {
+		if !isAdded[id] {
+			oldMemberID = append(oldMemberID, id)
+		}
+	}
+	oldMemberID = append(oldMemberID, removed.MemberID...)
+
+	return RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+		if err := InsertServiceMembers(ctx, tx, added, s); err != nil {
+			return err
+		}
+
+		if err := AsSl3GroupEntry(ctx, tx, memberID, oldMemberID, s.GroupID, Update); err != nil {
+			return err
+		}
+
+		return DeleteServiceMembers(ctx, tx, removed, s)
+	})
+}
+
+// PurgeServiceMembers deletes what is left of backends removed from a
+// service while draining, along with the flows still pinned to them
+func PurgeServiceMembers(ctx context.Context, p4RtC *client.Client, m ServiceMembers, s store.Service) error {
+	return RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+		return purgeServiceMembers(ctx, tx, m, s)
+	})
+}
+
+func DeleteServiceRules(ctx context.Context, p4RtC *client.Client, m ServiceMembers, s store.Service) error {
+	return RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+		if err := TxBalanceIpTableEntry(ctx, tx, s.ClusterIp, s.Proto, s.ClusterPort, s.GroupID, Delete); err != nil {
+			return err
+		}
+
+		if err := AsSl3GroupEntry(ctx, tx, m.MemberID, nil, s.GroupID, Delete); err != nil {
+			return err
+		}
+
+		return DeleteServiceMembers(ctx, tx, m, s)
+	})
+}
 
-	return err
+// NewUUID returns an id for the as_sl3 groups and members
+func NewUUID() uint32 {
+	return uuidFactory.getUUID()
 }
@@ -0,0 +1,356 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/antoninbas/p4runtime-go-client/pkg/client"
+	"github.com/golang/protobuf/proto"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/inframanager/store"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Service rules", func() {
+	ctx := context.Background()
+	podIp := []string{"10.10.10.1", "10.10.10.2"}
+	podMac := []string{"00:00:00:00:00:01", "00:00:00:00:00:02"}
+	memberID := []uint32{1, 2}
+	dnatPtr := []uint32{11, 12}
+	service := store.Service{
+		ClusterIp:   "10.96.0.10",
+		ClusterPort: 80,
+		GroupID:     100,
+		ServiceEndPoint: map[string]store.ServiceEndPoint{
+			"10.10.10.1": {IpAddress: "10.10.10.1", Port: 8080, MemberID: 1, DnatPtr: 11},
+			"10.10.10.2": {IpAddress: "10.10.10.2", Port: 8080, MemberID: 2, DnatPtr: 12},
+		},
+	}
+	// The first service of the backends
+	added := ServiceMembers{
+		PodIpAddr:     podIp,
+		PodMacAddr:    podMac,
+		MemberID:      memberID,
+		DnatPtr:       dnatPtr,
+		NewDnatPtr:    dnatPtr,
+		SnatPodIpAddr: podIp,
+		SnatMemberID:  memberID,
+	}
+	// The last service of the backends
+	removed := ServiceMembers{
+		PodIpAddr:     podIp,
+		MemberID:      memberID,
+		DnatPtr:       dnatPtr,
+		StaleDnatPtr:  dnatPtr,
+		SnatPodIpAddr: podIp,
+	}
+
+	// Per backend: write_dest_ip, as_sl3 member, write_source_ip, rx_src_ip
+	// and for the service: as_sl3 group, tx_balance
+	entries := 4*len(memberID) + 2
+
+	// Learns a flow from a client to the service port of the given address
+	pinFlow := func(serviceIp string, ptr uint32) {
+		entry := p4RtC.NewTableEntry(
+			"k8s_dp_control.pinned_flows",
+			map[string]client.MatchInterface{
+				"hdr.ipv4.src_addr": &client.ExactMatch{Value: PackBinaryIP("192.168.0.1")},
+				"hdr.ipv4.dst_addr": &client.ExactMatch{Value: PackBinaryIP(serviceIp)},
+				"hdr.ipv4.protocol": &client.ExactMatch{Value: []byte{6}},
+				"meta.l4_src_port":  &client.ExactMatch{Value: valueToBytes(40000 + ptr)},
+				"meta.l4_dst_port":  &client.ExactMatch{Value: valueToBytes(service.ClusterPort)},
+			},
+			p4RtC.NewTableActionDirect("k8s_dp_control.pinned_flows_hit",
+				[][]byte{valueToBytes(0), valueToBytes(ptr)}),
+			nil,
+		)
+		Expect(p4RtC.InsertTableEntry(ctx, entry)).To(Succeed())
+	}
+
+	podIp6 := []string{"fd00::1", "fd00::2"}
+	memberID6 := []uint32{3, 4}
+	dnatPtr6 := []uint32{13, 14}
+	service6 := store.Service{
+		ClusterIp:   "fd00:96::10",
+		ClusterPort: 80,
+		GroupID:     101,
+		ServiceEndPoint: map[string]store.ServiceEndPoint{
+			"fd00::1": {IpAddress: "fd00::1", Port: 8080, MemberID: 3, DnatPtr: 13},
+			"fd00::2": {IpAddress: "fd00::2", Port: 8080, MemberID: 4, DnatPtr: 14},
+		},
+	}
+	added6 := ServiceMembers{
+		PodIpAddr:     podIp6,
+		PodMacAddr:    podMac,
+		MemberID:      memberID6,
+		DnatPtr:       dnatPtr6,
+		NewDnatPtr:    dnatPtr6,
+		SnatPodIpAddr: podIp6,
+		SnatMemberID:  memberID6,
+	}
+	removed6 := ServiceMembers{
+		PodIpAddr:     podIp6,
+		MemberID:      memberID6,
+		DnatPtr:       dnatPtr6,
+		StaleDnatPtr:  dnatPtr6,
+		SnatPodIpAddr: podIp6,
+	}
+
+	var _ = Context("InsertServiceRules() should", func() {
+		var _ = It("program all the entries", func() {
+			Expect(InsertServiceRules(ctx, p4RtC, added, service)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(entries))
+		})
+
+		var _ = It("leave nothing programmed if the tx_balance insert fails", func() {
+			fakeServer.failWrite = failTable(p4RtC, "k8s_dp_control.tx_balance")
+			Expect(InsertServiceRules(ctx, p4RtC, added, service)).ToNot(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+
+		var _ = It("program an IPv6 service into the IPv6 tables", func() {
+			Expect(InsertServiceRules(ctx, p4RtC, added6, service6)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(entries))
+
+			for _, table := range []string{"k8s_dp_control.tx_balance_ipv6", "k8s_dp_control.rx_src_ipv6",
+				"k8s_dp_control.write_dest_ipv6_table", "k8s_dp_control.write_source_ipv6_table"} {
+				res, err := p4RtC.ReadTableEntryWildcard(ctx, table)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res).ToNot(BeEmpty(), table)
+			}
+			res, err := p4RtC.ReadTableEntryWildcard(ctx, "k8s_dp_control.tx_balance")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(BeEmpty())
+		})
+
+		var _ = It("program the services of both families side by side", func() {
+			Expect(InsertServiceRules(ctx, p4RtC, added, service)).To(Succeed())
+			Expect(InsertServiceRules(ctx, p4RtC, added6, service6)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(2 * entries))
+		})
+
+		var _ = It("share the DNAT entries of the backends between the ports of a service", func() {
+			https := service
+			https.ClusterPort = 443
+			https.GroupID = 102
+			Expect(InsertServiceRules(ctx, p4RtC, added, service)).To(Succeed())
+			Expect(InsertServiceRules(ctx, p4RtC, ServiceMembers{
+				PodIpAddr:  podIp,
+				PodMacAddr: podMac,
+				MemberID:   []uint32{5, 6},
+				DnatPtr:    dnatPtr,
+			}, https)).To(Succeed())
+
+			// The second service only adds its members, SNAT entries, group and tx_balance
+			Expect(fakeServer.count()).To(Equal(entries + 2*len(memberID) + 2))
+			member := memberEntity(p4RtC.NewActionProfileMember("k8s_dp_control.as_sl3", 5,
+				"k8s_dp_control.set_default_lb_dest", [][]byte{valueToBytes(0), valueToBytes(11)}))
+			Expect(proto.Equal(fakeServer.get(member), member)).To(BeTrue())
+		})
+	})
+
+	var _ = Context("InsertServiceRules() with the ports of the backends should", func() {
+		var _ = It("program the members on the ports of the backends", func() {
+			m := added
+			m.InterfaceID = []uint32{7, 8}
+			Expect(InsertServiceRules(ctx, p4RtC, m, service)).To(Succeed())
+
+			member := memberEntity(p4RtC.NewActionProfileMember("k8s_dp_control.as_sl3", 2,
+				"k8s_dp_control.set_default_lb_dest", [][]byte{valueToBytes(8), valueToBytes(12)}))
+			Expect(proto.Equal(fakeServer.get(member), member)).To(BeTrue())
+		})
+	})
+
+	var _ = Context("InsertServiceRules() with ClientIP affinity should", func() {
+		var _ = It("have the clients of the members learnt for the profile covering the timeout", func() {
+			sticky := service
+			sticky.AffinityTimeout = 10800
+			Expect(InsertServiceRules(ctx, p4RtC, added, sticky)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(entries))
+
+			member := memberEntity(p4RtC.NewActionProfileMember("k8s_dp_control.as_sl3", 1,
+				"k8s_dp_control.set_affinity_lb_dest", [][]byte{valueToBytes(0), valueToBytes(11), {5}}))
+			Expect(proto.Equal(fakeServer.get(member), member)).To(BeTrue())
+		})
+
+		var _ = It("pick the shortest expire time profile lasting long enough", func() {
+			Expect(affinityProfile(1)).To(Equal(uint32(0)))
+			Expect(affinityProfile(10)).To(Equal(uint32(0)))
+			Expect(affinityProfile(100)).To(Equal(uint32(3)))
+			Expect(affinityProfile(10800)).To(Equal(uint32(5)))
+			Expect(affinityProfile(86400)).To(Equal(uint32(5)))
+		})
+
+		var _ = It("use the expire time profiles of the pipeline", func() {
+			source, err := os.ReadFile(p4SourcePath)
+			Expect(err).ToNot(HaveOccurred())
+			re := regexp.MustCompile(`const bit<32> EXPIRE_TIME_PROFILE_(\d+) = (\d+);`)
+			profiles := make([]uint32, len(re.FindAllSubmatch(source, -1)))
+			for _, m := range re.FindAllSubmatch(source, -1) {
+				id, _ := strconv.Atoi(string(m[1]))
+				timeout, _ := strconv.ParseUint(string(m[2]), 10, 32)
+				Expect(id).To(BeNumerically("<", len(profiles)))
+				profiles[id] = uint32(timeout)
+			}
+			Expect(profiles).To(Equal(expireTimeProfiles))
+		})
+	})
+
+	var _ = Context("TxBalanceIpTableEntry() should", func() {
+		var _ = It("program the TCP and UDP services of a port side by side", func() {
+			Expect(RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+				if err := TxBalanceIpTableEntry(ctx, tx, service.ClusterIp, "TCP", 53, 100, Insert); err != nil {
+					return err
+				}
+				if err := TxBalanceIpTableEntry(ctx, tx, service.ClusterIp, "udp", 53, 101, Insert); err != nil {
+					return err
+				}
+				return TxBalanceIpTableEntry(ctx, tx, service6.ClusterIp, "SCTP", 53, 102, Insert)
+			})).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(3))
+		})
+
+		var _ = It("reject an unsupported protocol", func() {
+			Expect(RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+				return TxBalanceIpTableEntry(ctx, tx, service.ClusterIp, "ICMP", 53, 100, Insert)
+			})).ToNot(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+	})
+
+	var _ = Context("UpdateServiceRules() should", func() {
+		var _ = It("restore the group members if deleting a backend fails", func() {
+			Expect(InsertServiceRules(ctx, p4RtC, added, service)).To(Succeed())
+			group := groupEntity(newAsSl3Group(p4RtC, memberID, service.GroupID))
+
+			updated := store.Service{
+				ClusterIp:   service.ClusterIp,
+				ClusterPort: service.ClusterPort,
+				GroupID:     service.GroupID,
+				ServiceEndPoint: map[string]store.ServiceEndPoint{
+					"10.10.10.1": service.ServiceEndPoint["10.10.10.1"],
+				},
+			}
+			fakeServer.failWrite = failTable(p4RtC, "k8s_dp_control.write_dest_ip_table")
+			Expect(UpdateServiceRules(ctx, p4RtC, ServiceMembers{}, ServiceMembers{
+				PodIpAddr:     podIp[1:],
+				MemberID:      memberID[1:],
+				DnatPtr:       dnatPtr[1:],
+				StaleDnatPtr:  dnatPtr[1:],
+				SnatPodIpAddr: podIp[1:],
+			}, updated)).ToNot(Succeed())
+
+			Expect(fakeServer.count()).To(Equal(entries))
+			Expect(proto.Equal(fakeServer.get(group), group)).To(BeTrue())
+		})
+	})
+
+	var _ = Context("UpdateServiceRules() with draining backends should", func() {
+		updated := store.Service{
+			ClusterIp:   service.ClusterIp,
+			ClusterPort: service.ClusterPort,
+			GroupID:     service.GroupID,
+			ServiceEndPoint: map[string]store.ServiceEndPoint{
+				"10.10.10.1": service.ServiceEndPoint["10.10.10.1"],
+			},
+		}
+		drained := ServiceMembers{
+			PodIpAddr:     podIp[1:],
+			MemberID:      memberID[1:],
+			DnatPtr:       dnatPtr[1:],
+			StaleDnatPtr:  dnatPtr[1:],
+			SnatPodIpAddr: podIp[1:],
+			Drain:         true,
+		}
+
+		var _ = It("only delete the members, until the backends are purged", func() {
+			Expect(InsertServiceRules(ctx, p4RtC, added, service)).To(Succeed())
+			pinFlow(service.ClusterIp, dnatPtr[1])
+
+			Expect(UpdateServiceRules(ctx, p4RtC, ServiceMembers{}, drained, updated)).To(Succeed())
+			// The pinned flow is still served
+			Expect(fakeServer.count()).To(Equal(entries))
+
+			Expect(PurgeServiceMembers(ctx, p4RtC, drained, updated)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(entries - 4))
+		})
+	})
+
+	var _ = Context("DeleteServiceRules() should", func() {
+		var _ = It("delete all the entries", func() {
+			Expect(InsertServiceRules(ctx, p4RtC, added, service)).To(Succeed())
+			Expect(DeleteServiceRules(ctx, p4RtC, removed, service)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+
+		var _ = It("purge the flows pinned to the backends of the service only", func() {
+			Expect(InsertServiceRules(ctx, p4RtC, added, service)).To(Succeed())
+			pinFlow(service.ClusterIp, dnatPtr[0])
+			pinFlow(service.ClusterIp, dnatPtr[1])
+			pinFlow("10.96.0.20", dnatPtr[0])
+
+			Expect(DeleteServiceRules(ctx, p4RtC, removed, service)).To(Succeed())
+			res, err := p4RtC.ReadTableEntryWildcard(ctx, "k8s_dp_control.pinned_flows")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+			dst := matchValue(p4RtC, res[0], "k8s_dp_control.pinned_flows", "hdr.ipv4.dst_addr")
+			Expect(bytesToIPv4(dst)).To(Equal("10.96.0.20"))
+		})
+
+		var _ = It("delete all the entries of an IPv6 service", func() {
+			Expect(InsertServiceRules(ctx, p4RtC, added6, service6)).To(Succeed())
+			Expect(DeleteServiceRules(ctx, p4RtC, removed6, service6)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+
+		var _ = It("keep the DNAT entries still referred to by another service", func() {
+			https := service
+			https.ClusterPort = 443
+			https.GroupID = 102
+			Expect(InsertServiceRules(ctx, p4RtC, added, service)).To(Succeed())
+			Expect(InsertServiceRules(ctx, p4RtC, ServiceMembers{
+				PodIpAddr:  podIp,
+				PodMacAddr: podMac,
+				MemberID:   []uint32{5, 6},
+				DnatPtr:    dnatPtr,
+			}, https)).To(Succeed())
+
+			Expect(DeleteServiceRules(ctx, p4RtC, ServiceMembers{
+				PodIpAddr:    podIp,
+				MemberID:     memberID,
+				DnatPtr:      dnatPtr,
+				UpdPodIpAddr: podIp,
+				UpdMemberID:  []uint32{5, 6},
+			}, service)).To(Succeed())
+			// The DNAT and rx_src_ip entries of the backends are left
+			Expect(fakeServer.count()).To(Equal(entries))
+
+			Expect(DeleteServiceRules(ctx, p4RtC, ServiceMembers{
+				PodIpAddr:     podIp,
+				MemberID:      []uint32{5, 6},
+				DnatPtr:       dnatPtr,
+				StaleDnatPtr:  dnatPtr,
+				SnatPodIpAddr: podIp,
+			}, https)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,109 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/antoninbas/p4runtime-go-client/pkg/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// The address is set by the default action of the snat_prefix table of
+// its family, which always exists and is only modified. No address
+// turns masquerading off for the family.
+func snatAddressEntry(ctx context.Context, tx *Transaction, family *ipFamily, ipAddr string) error {
+	action := tx.p4RtC.NewTableActionDirect("NoAction", nil)
+	if ipAddr != "" {
+		ip := net.ParseIP(ipAddr)
+		if ip == nil || familyOf(ipAddr) != family {
+			return fmt.Errorf("Invalid %s SNAT address %s", family.name, ipAddr)
+		}
+		action = tx.p4RtC.NewTableActionDirect(family.masquerade, [][]byte{PackBinaryIP(ipAddr)})
+	}
+
+	entry := tx.p4RtC.NewTableEntry(family.snatPrefix, nil, action, nil)
+	if err := tx.Write(ctx, tableEntity(entry), Update, nil); err != nil {
+		log.Errorf("Cannot set the %s SNAT address in %s table: %v", family.name, family.snatPrefix, err)
+		return err
+	}
+	return nil
+}
+
+// SetSnatAddress sets the addresses of the node the traffic of the pods
+// leaving the cluster is masqueraded behind, for both families at once
+func SetSnatAddress(ctx context.Context, p4RtC *client.Client, ipv4Addr, ipv6Addr string) error {
+	return RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+		if err := snatAddressEntry(ctx, tx, ipv4Family, ipv4Addr); err != nil {
+			return err
+		}
+		return snatAddressEntry(ctx, tx, ipv6Family, ipv6Addr)
+	})
+}
+
+// A prefix the pods reach with their own address
+func snatPrefixEntry(ctx context.Context, tx *Transaction, prefix *net.IPNet, action OperationType) error {
+	family := familyOf(prefix.IP.String())
+	plen, _ := prefix.Mask.Size()
+	entry := tx.p4RtC.NewTableEntry(
+		family.snatPrefix,
+		map[string]client.MatchInterface{
+			family.snatPrefixDst: &client.LpmMatch{
+				Value: PackBinaryIP(prefix.IP.String()),
+				PLen:  int32(plen),
+			},
+		},
+		tx.p4RtC.NewTableActionDirect("k8s_dp_control.no_snat", nil),
+		nil,
+	)
+
+	// The agent pushes its prefixes again when it restarts
+	_, err := tx.p4RtC.ReadEntitySingle(ctx, tableEntity(entry))
+	exists := err == nil
+	if (action == Insert && exists) || (action == Delete && !exists) {
+		return nil
+	}
+
+	if err = tx.Write(ctx, tableEntity(entry), action, tableEntity(entry)); err != nil {
+		log.Errorf("Cannot %s entry in %s table: %v", action, family.snatPrefix, err)
+	}
+	return err
+}
+
+// AddDelSnatPrefix adds or deletes a prefix the pods reach without
+// being masqueraded, like the pod and service CIDRs of the cluster
+func AddDelSnatPrefix(ctx context.Context, p4RtC *client.Client, prefix string, isAdd bool) error {
+	_, ipNet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return err
+	}
+	// A /0 match has to be left out of an entry, turning masquerading
+	// off is done by clearing the SNAT address instead
+	if plen, _ := ipNet.Mask.Size(); plen == 0 {
+		return fmt.Errorf("Invalid SNAT prefix %s", prefix)
+	}
+
+	action := Insert
+	if !isAdd {
+		action = Delete
+	}
+	return RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+		return snatPrefixEntry(ctx, tx, ipNet, action)
+	})
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SNAT rules", func() {
+	ctx := context.Background()
+
+	defaultEntry := func(family *ipFamily, action string, params ...[]byte) bool {
+		entry := tableEntity(p4RtC.NewTableEntry(family.snatPrefix, nil,
+			p4RtC.NewTableActionDirect(action, params), nil))
+		return proto.Equal(fakeServer.get(entry), entry)
+	}
+
+	var _ = Context("SetSnatAddress() should", func() {
+		var _ = It("masquerade behind the node address of each family", func() {
+			Expect(SetSnatAddress(ctx, p4RtC, "192.168.111.66", "fd00:10::66")).To(Succeed())
+			Expect(defaultEntry(ipv4Family, "k8s_dp_control.masquerade", PackBinaryIP("192.168.111.66"))).To(BeTrue())
+			Expect(defaultEntry(ipv6Family, "k8s_dp_control.masquerade_ipv6", PackBinaryIP("fd00:10::66"))).To(BeTrue())
+
+			Expect(SetSnatAddress(ctx, p4RtC, "192.168.111.67", "")).To(Succeed())
+			Expect(defaultEntry(ipv4Family, "k8s_dp_control.masquerade", PackBinaryIP("192.168.111.67"))).To(BeTrue())
+			Expect(defaultEntry(ipv6Family, "NoAction")).To(BeTrue())
+		})
+
+		var _ = It("reject an address of the wrong family", func() {
+			Expect(SetSnatAddress(ctx, p4RtC, "fd00:10::66", "")).ToNot(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+	})
+
+	var _ = Context("AddDelSnatPrefix() should", func() {
+		var _ = It("program the prefixes reached without SNAT once", func() {
+			Expect(AddDelSnatPrefix(ctx, p4RtC, "10.244.0.0/16", true)).To(Succeed())
+			Expect(AddDelSnatPrefix(ctx, p4RtC, "10.244.0.0/16", true)).To(Succeed())
+			Expect(AddDelSnatPrefix(ctx, p4RtC, "fd00:96::/108", true)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(2))
+
+			res, err := p4RtC.ReadTableEntryWildcard(ctx, "k8s_dp_control.snat_prefix_ipv6")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+
+			Expect(AddDelSnatPrefix(ctx, p4RtC, "10.244.0.0/16", false)).To(Succeed())
+			Expect(AddDelSnatPrefix(ctx, p4RtC, "10.244.0.0/16", false)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(1))
+		})
+
+		var _ = It("reject an invalid prefix", func() {
+			Expect(AddDelSnatPrefix(ctx, p4RtC, "10.244.0.1", true)).ToNot(Succeed())
+			Expect(AddDelSnatPrefix(ctx, p4RtC, "0.0.0.0/0", true)).ToNot(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+	})
+})
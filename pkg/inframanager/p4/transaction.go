@@ -0,0 +1,145 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antoninbas/p4runtime-go-client/pkg/client"
+	p4_v1 "github.com/p4lang/p4runtime/go/p4/v1"
+	log "github.com/sirupsen/logrus"
+)
+
+// Transaction writes a sequence of updates to the pipeline and records
+// for each of them the update reverting it. The target does not support
+// atomic batches, so a failed sequence is undone by writing the recorded
+// updates in the reverse order.
+type Transaction struct {
+	p4RtC *client.Client
+	undo  []*p4_v1.Update
+}
+
+func NewTransaction(p4RtC *client.Client) *Transaction {
+	return &Transaction{
+		p4RtC: p4RtC,
+	}
+}
+
+// The time the rollback of a failed transaction is given to complete
+var rollbackTimeout = 10 * time.Second
+
+// RunTransaction calls fn with a new transaction and reverts the
+// updates fn wrote if it fails. The rollback runs on its own context,
+// fn may have failed because ctx expired.
+func RunTransaction(ctx context.Context, p4RtC *client.Client, fn func(tx *Transaction) error) error {
+	tx := NewTransaction(p4RtC)
+	if err := fn(tx); err != nil {
+		rbCtx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+		defer cancel()
+		if rbErr := tx.Rollback(rbCtx); rbErr != nil {
+			return fmt.Errorf("%v, rollback failed: %v", err, rbErr)
+		}
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+func tableEntity(entry *p4_v1.TableEntry) *p4_v1.Entity {
+	return &p4_v1.Entity{
+		Entity: &p4_v1.Entity_TableEntry{TableEntry: entry},
+	}
+}
+
+func memberEntity(member *p4_v1.ActionProfileMember) *p4_v1.Entity {
+	return &p4_v1.Entity{
+		Entity: &p4_v1.Entity_ActionProfileMember{ActionProfileMember: member},
+	}
+}
+
+func groupEntity(group *p4_v1.ActionProfileGroup) *p4_v1.Entity {
+	return &p4_v1.Entity{
+		Entity: &p4_v1.Entity_ActionProfileGroup{ActionProfileGroup: group},
+	}
+}
+
+// Write applies the operation to the entity. The old entity is the
+// content the entity had before an update or a delete, it is written
+// back on rollback. When it is not known it is read from the target,
+// if that fails too the operation cannot be reverted.
+func (t *Transaction) Write(ctx context.Context, entity *p4_v1.Entity, action OperationType, old *p4_v1.Entity) error {
+	update := &p4_v1.Update{
+		Entity: entity,
+	}
+	if old == nil && (action == Update || action == Delete) {
+		var err error
+		if old, err = t.p4RtC.ReadEntitySingle(ctx, entity); err != nil {
+			log.Warnf("Cannot read the entity before the %s, it will not be reverted: %v", action, err)
+			old = nil
+		}
+	}
+	var undo *p4_v1.Update
+
+	switch action {
+	case Insert:
+		update.Type = p4_v1.Update_INSERT
+		undo = &p4_v1.Update{Type: p4_v1.Update_DELETE, Entity: entity}
+	case Update:
+		update.Type = p4_v1.Update_MODIFY
+		if old != nil {
+			undo = &p4_v1.Update{Type: p4_v1.Update_MODIFY, Entity: old}
+		}
+	case Delete:
+		update.Type = p4_v1.Update_DELETE
+		if old != nil {
+			undo = &p4_v1.Update{Type: p4_v1.Update_INSERT, Entity: old}
+		}
+	default:
+		return fmt.Errorf("Invalid operation type %d", action)
+	}
+
+	if err := t.p4RtC.WriteUpdate(ctx, update); err != nil {
+		return err
+	}
+	if undo != nil {
+		t.undo = append(t.undo, undo)
+	}
+	return nil
+}
+
+// Rollback reverts the updates written so far, latest first. All the
+// updates are tried even if some fail, the first error is returned.
+func (t *Transaction) Rollback(ctx context.Context) error {
+	var err error
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		if e := t.p4RtC.WriteUpdate(ctx, t.undo[i]); e != nil {
+			log.Errorf("Failed to revert the %s: %v", t.undo[i].Type, e)
+			if err == nil {
+				err = e
+			}
+		}
+	}
+	t.undo = nil
+	return err
+}
+
+// Commit drops the recorded updates, the written ones are kept
+func (t *Transaction) Commit() {
+	t.undo = nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build dpdk
+
+package p4
+
+import (
+	"context"
+	"errors"
+
+	"github.com/antoninbas/p4runtime-go-client/pkg/client"
+	"github.com/golang/protobuf/proto"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	p4_v1 "github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+func macEntry(mac []byte, port uint32) *p4_v1.Entity {
+	return tableEntity(p4RtC.NewTableEntry(
+		"k8s_dp_control.mac_to_port_table",
+		map[string]client.MatchInterface{
+			"hdr.ethernet.dst_mac": &client.ExactMatch{
+				Value: mac,
+			},
+		},
+		p4RtC.NewTableActionDirect("k8s_dp_control.set_dest_vport", [][]byte{valueToBytes(port)}),
+		nil,
+	))
+}
+
+var _ = Describe("Transaction", func() {
+	ctx := context.Background()
+	mac1 := []byte{0, 0, 0, 0, 0, 1}
+	mac2 := []byte{0, 0, 0, 0, 0, 2}
+
+	var _ = Context("Rollback() should", func() {
+		var _ = It("delete the inserted entries", func() {
+			tx := NewTransaction(p4RtC)
+			Expect(tx.Write(ctx, macEntry(mac1, 1), Insert, nil)).To(Succeed())
+			Expect(tx.Write(ctx, macEntry(mac2, 2), Insert, nil)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(2))
+
+			Expect(tx.Rollback(ctx)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+
+		var _ = It("restore the modified and deleted entries", func() {
+			Expect(NewTransaction(p4RtC).Write(ctx, macEntry(mac1, 1), Insert, nil)).To(Succeed())
+			Expect(NewTransaction(p4RtC).Write(ctx, macEntry(mac2, 2), Insert, nil)).To(Succeed())
+
+			tx := NewTransaction(p4RtC)
+			Expect(tx.Write(ctx, macEntry(mac1, 10), Update, macEntry(mac1, 1))).To(Succeed())
+			Expect(tx.Write(ctx, macEntry(mac2, 2), Delete, macEntry(mac2, 2))).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(1))
+
+			Expect(tx.Rollback(ctx)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(2))
+			Expect(proto.Equal(fakeServer.get(macEntry(mac1, 1)), macEntry(mac1, 1))).To(BeTrue())
+		})
+
+		var _ = It("restore the entries read from the target if the old content is not given", func() {
+			Expect(NewTransaction(p4RtC).Write(ctx, macEntry(mac1, 1), Insert, nil)).To(Succeed())
+
+			tx := NewTransaction(p4RtC)
+			Expect(tx.Write(ctx, macEntry(mac1, 1), Delete, nil)).To(Succeed())
+			Expect(fakeServer.count()).To(Equal(0))
+
+			Expect(tx.Rollback(ctx)).To(Succeed())
+			Expect(proto.Equal(fakeServer.get(macEntry(mac1, 1)), macEntry(mac1, 1))).To(BeTrue())
+		})
+	})
+
+	var _ = Context("RunTransaction() should", func() {
+		var _ = It("keep the entries when the function succeeds", func() {
+			err := RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+				return tx.Write(ctx, macEntry(mac1, 1), Insert, nil)
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeServer.count()).To(Equal(1))
+		})
+
+		var _ = It("revert the entries when the function fails", func() {
+			err := RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+				if err := tx.Write(ctx, macEntry(mac1, 1), Insert, nil); err != nil {
+					return err
+				}
+				return errors.New("failed")
+			})
+			Expect(err).To(MatchError("failed"))
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+
+		var _ = It("revert the entries when the context expired", func() {
+			txCtx, cancel := context.WithCancel(ctx)
+			err := RunTransaction(txCtx, p4RtC, func(tx *Transaction) error {
+				if err := tx.Write(txCtx, macEntry(mac1, 1), Insert, nil); err != nil {
+					return err
+				}
+				cancel()
+				return tx.Write(txCtx, macEntry(mac2, 2), Insert, nil)
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).ToNot(ContainSubstring("rollback failed"))
+			Expect(fakeServer.count()).To(Equal(0))
+		})
+
+		var _ = It("report a failed rollback", func() {
+			err := RunTransaction(ctx, p4RtC, func(tx *Transaction) error {
+				if err := tx.Write(ctx, macEntry(mac1, 1), Insert, nil); err != nil {
+					return err
+				}
+				fakeServer.failWrite = func(*p4_v1.Update) bool { return true }
+				return errors.New("failed")
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("rollback failed"))
+		})
+	})
+})
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math/big"
 	"net"
+	"strings"
 )
 
 type InterfaceType int
@@ -31,6 +32,27 @@ const (
 	EXCEPTION
 )
 
+type OperationType int
+
+const (
+	Insert OperationType = iota
+	Update
+	Delete
+)
+
+func (op OperationType) String() string {
+	switch op {
+	case Insert:
+		return "insert"
+	case Update:
+		return "update"
+	case Delete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
 const (
 	MAXUINT32              = 4294967295
 	DEFAULT_UUID_CNT_CACHE = 512
@@ -101,3 +123,82 @@ func Pack32BinaryIP4(ip4Address string) []byte {
 	//fmt.Sprintf("%x", buf.Bytes())
 	return buf.Bytes()
 }
+
+// IsIPv6 tells whether the address, with or without a prefix length,
+// is an IPv6 one
+func IsIPv6(ipAddress string) bool {
+	ip := net.ParseIP(strings.Split(ipAddress, "/")[0])
+	return ip != nil && ip.To4() == nil
+}
+
+// PackBinaryIP packs an address of either family, on 32 bits for
+// IPv4 and on 128 bits for IPv6
+func PackBinaryIP(ipAddress string) []byte {
+	if !IsIPv6(ipAddress) {
+		return Pack32BinaryIP4(ipAddress)
+	}
+	return net.ParseIP(ipAddress).To16()
+}
+
+// IP protocol numbers of the Kubernetes service protocols
+var serviceProtocols = map[string]uint8{
+	"TCP":  6,
+	"UDP":  17,
+	"SCTP": 132,
+}
+
+// ServiceProtocol returns the Kubernetes protocol of a service in its
+// canonical form along with its IP protocol number. As for Kubernetes,
+// the protocol is TCP when none is given.
+func ServiceProtocol(proto string) (string, uint8, error) {
+	if proto == "" {
+		proto = "TCP"
+	}
+	proto = strings.ToUpper(proto)
+	num, ok := serviceProtocols[proto]
+	if !ok {
+		return "", 0, fmt.Errorf("Unsupported protocol %s", proto)
+	}
+	return proto, num, nil
+}
+
+func protocolName(num uint8) string {
+	for name, n := range serviceProtocols {
+		if n == num {
+			return name
+		}
+	}
+	return fmt.Sprint(num)
+}
+
+// The values read from the target are in the canonical form, without
+// the leading zero bytes
+
+func bytesToUint32(value []byte) uint32 {
+	var res uint32
+	for _, b := range value {
+		res = res<<8 | uint32(b)
+	}
+	return res
+}
+
+func bytesToIPv4(value []byte) string {
+	return net.IP(leftPad(value, net.IPv4len)).String()
+}
+
+func bytesToIPv6(value []byte) string {
+	return net.IP(leftPad(value, net.IPv6len)).String()
+}
+
+func bytesToMAC(value []byte) string {
+	return net.HardwareAddr(leftPad(value, 6)).String()
+}
+
+func leftPad(value []byte, size int) []byte {
+	if len(value) >= size {
+		return value[len(value)-size:]
+	}
+	res := make([]byte, size)
+	copy(res[size-len(value):], value)
+	return res
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultStoreDir = "/opt/inframanager"
+
+	BackendJSON   = "json"
+	BackendMemory = "memory"
+	BackendBolt   = "bolt"
+)
+
+// Backend saves the entries of the stores. The entries of a store
+// are kept in a bucket of their own, keyed by the same key as in the
+// store map, and are saved as soon as they change.
+type Backend interface {
+	// Load returns the entries of the bucket, empty if there are none
+	Load(bucket string) (map[string][]byte, error)
+	// Put saves the entry, replacing the previous one with the same key
+	Put(bucket, key string, value []byte) error
+	// Delete removes the entry, if there is one
+	Delete(bucket, key string) error
+	// Replace discards the entries of the bucket and saves the given ones
+	Replace(bucket string, entries map[string][]byte) error
+	Close() error
+}
+
+var errNoBackend = errors.New("no store backend opened")
+
+// OpenBackend opens the backend of the given kind, keeping its data
+// in dir, and puts an empty store saved to it in use. It has to be
+// called before the stores are initialized.
+func OpenBackend(kind, dir string) error {
+	if dir == "" {
+		dir = DefaultStoreDir
+	}
+
+	var b Backend
+	var err error
+	switch kind {
+	case "", BackendJSON:
+		b, err = NewJSONFileBackend(dir)
+	case BackendMemory:
+		b = NewMemoryBackend()
+	case BackendBolt:
+		b, err = NewBoltBackend(dir)
+	default:
+		err = fmt.Errorf("unknown store backend %q", kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	Use(NewStore(b))
+	return nil
+}
+
+// CloseBackend closes the backend of the store in use, which is
+// replaced by an empty store without a backend
+func CloseBackend() error {
+	s := Use(NewStore(nil))
+	if s.backend == nil {
+		return nil
+	}
+	return s.backend.Close()
+}
+
+// Loads the entries of the bucket into m, which points to the map of
+// a store. The entries are discarded instead when reset is set.
+func (s *Store) loadEntries(bucket string, reset bool, m interface{}) bool {
+	backend := s.backend
+	if backend == nil {
+		log.Errorf("Failed to load the %s store: %s", bucket, errNoBackend)
+		return false
+	}
+	if reset {
+		if err := backend.Replace(bucket, nil); err != nil {
+			log.Errorf("Failed to reset the %s store: %s", bucket, err)
+			return false
+		}
+		return true
+	}
+
+	entries, err := backend.Load(bucket)
+	if err != nil {
+		log.Errorf("Failed to load the %s store: %s", bucket, err)
+		return false
+	}
+	if len(entries) == 0 {
+		return true
+	}
+
+	raw := make(map[string]json.RawMessage, len(entries))
+	for k, v := range entries {
+		raw[k] = v
+	}
+	data, err := json.Marshal(raw)
+	if err == nil {
+		err = json.Unmarshal(data, m)
+	}
+	if err != nil {
+		log.Errorf("Failed to decode the %s store: %s", bucket, err)
+		return false
+	}
+	return true
+}
+
+func (s *Store) saveEntry(bucket, key string, v interface{}) bool {
+	backend := s.backend
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Errorf("Failed to encode %s entry %s: %s", bucket, key, err)
+		return false
+	}
+	if backend == nil {
+		log.Errorf("Failed to save %s entry %s: %s", bucket, key, errNoBackend)
+		return false
+	}
+	if err = backend.Put(bucket, key, data); err != nil {
+		log.Errorf("Failed to save %s entry %s: %s", bucket, key, err)
+		return false
+	}
+	return true
+}
+
+func (s *Store) deleteEntry(bucket, key string) bool {
+	backend := s.backend
+	if backend == nil {
+		log.Errorf("Failed to delete %s entry %s: %s", bucket, key, errNoBackend)
+		return false
+	}
+	if err := backend.Delete(bucket, key); err != nil {
+		log.Errorf("Failed to delete %s entry %s: %s", bucket, key, err)
+		return false
+	}
+	return true
+}
+
+// Saves all the entries of m, the map of a store, in place of the
+// entries of the bucket. The caller holds the lock of the store.
+func (s *Store) replaceEntries(bucket string, m interface{}) bool {
+	backend := s.backend
+	data, err := json.Marshal(m)
+	raw := make(map[string]json.RawMessage)
+	if err == nil {
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		log.Errorf("Failed to encode the %s store: %s", bucket, err)
+		return false
+	}
+
+	entries := make(map[string][]byte, len(raw))
+	for k, v := range raw {
+		entries[k] = v
+	}
+	if backend == nil {
+		log.Errorf("Failed to save the %s store: %s", bucket, errNoBackend)
+		return false
+	}
+	if err = backend.Replace(bucket, entries); err != nil {
+		log.Errorf("Failed to save the %s store: %s", bucket, err)
+		return false
+	}
+	return true
+}
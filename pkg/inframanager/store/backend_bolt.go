@@ -0,0 +1,107 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	boltFile    = "inframanager.db"
+	boltTimeout = time.Second
+)
+
+// BoltBackend keeps the buckets in a bbolt database, so that a
+// change only writes the entry that changed. It suits clusters with
+// many endpoints and services per node.
+type BoltBackend struct {
+	db *bolt.DB
+}
+
+func NewBoltBackend(dir string) (*BoltBackend, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	// The timeout keeps a second instance from blocking on the file lock
+	db, err := bolt.Open(filepath.Join(dir, boltFile), 0600, &bolt.Options{Timeout: boltTimeout})
+	if err != nil {
+		return nil, err
+	}
+	return &BoltBackend{db: db}, nil
+}
+
+func (b *BoltBackend) Load(bucket string) (map[string][]byte, error) {
+	res := make(map[string][]byte)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(bucket))
+		if bkt == nil {
+			return nil
+		}
+		return bkt.ForEach(func(k, v []byte) error {
+			// Values are only valid for the life of the transaction
+			res[string(k)] = append([]byte{}, v...)
+			return nil
+		})
+	})
+	return res, err
+}
+
+func (b *BoltBackend) Put(bucket, key string, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(key), value)
+	})
+}
+
+func (b *BoltBackend) Delete(bucket, key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(bucket))
+		if bkt == nil {
+			return nil
+		}
+		return bkt.Delete([]byte(key))
+	})
+}
+
+func (b *BoltBackend) Replace(bucket string, entries map[string][]byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket([]byte(bucket))
+		if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		bkt, err := tx.CreateBucket([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		for k, v := range entries {
+			if err = bkt.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltBackend) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	storeFileSuffix = "_db.json"
+	tmpFileSuffix   = ".tmp"
+)
+
+// JSONFileBackend keeps each bucket in a JSON file of the store
+// directory, named after the bucket. The whole file is written again
+// on every change, which is fine for the size of a node.
+type JSONFileBackend struct {
+	dir     string
+	lock    sync.Mutex
+	buckets map[string]map[string]json.RawMessage
+}
+
+func NewJSONFileBackend(dir string) (*JSONFileBackend, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &JSONFileBackend{
+		dir:     dir,
+		buckets: make(map[string]map[string]json.RawMessage),
+	}, nil
+}
+
+func (b *JSONFileBackend) path(bucket string) string {
+	return filepath.Join(b.dir, bucket+storeFileSuffix)
+}
+
+// Returns the content of the bucket, reading it from its file the
+// first time. Temporary files left by an interrupted write are removed.
+// The caller holds the lock.
+func (b *JSONFileBackend) bucket(name string) (map[string]json.RawMessage, error) {
+	if entries, ok := b.buckets[name]; ok {
+		return entries, nil
+	}
+
+	path := b.path(name)
+	stale, _ := filepath.Glob(path + tmpFileSuffix + "*")
+	for _, f := range stale {
+		os.Remove(f)
+	}
+
+	entries := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+	}
+
+	b.buckets[name] = entries
+	return entries, nil
+}
+
+// Writes the new content of the bucket to its file, the cached content
+// is only replaced once it is written so that it always matches the file.
+// The caller holds the lock.
+func (b *JSONFileBackend) write(name string, entries map[string]json.RawMessage) error {
+	data, err := json.MarshalIndent(entries, "", " ")
+	if err != nil {
+		return err
+	}
+	if err = writeFileAtomic(b.path(name), data); err != nil {
+		return err
+	}
+	b.buckets[name] = entries
+	return nil
+}
+
+// Returns a copy of the bucket to be changed and written
+func (b *JSONFileBackend) modify(name string) (map[string]json.RawMessage, error) {
+	entries, err := b.bucket(name)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]json.RawMessage, len(entries)+1)
+	for k, v := range entries {
+		res[k] = v
+	}
+	return res, nil
+}
+
+func (b *JSONFileBackend) Load(bucket string) (map[string][]byte, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	entries, err := b.bucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string][]byte, len(entries))
+	for k, v := range entries {
+		res[k] = append([]byte{}, v...)
+	}
+	return res, nil
+}
+
+func (b *JSONFileBackend) Put(bucket, key string, value []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	entries, err := b.modify(bucket)
+	if err != nil {
+		return err
+	}
+	entries[key] = append(json.RawMessage{}, value...)
+	return b.write(bucket, entries)
+}
+
+func (b *JSONFileBackend) Delete(bucket, key string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	entries, err := b.modify(bucket)
+	if err != nil {
+		return err
+	}
+	delete(entries, key)
+	return b.write(bucket, entries)
+}
+
+func (b *JSONFileBackend) Replace(bucket string, entries map[string][]byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	stored := make(map[string]json.RawMessage, len(entries))
+	for k, v := range entries {
+		stored[k] = append(json.RawMessage{}, v...)
+	}
+	return b.write(bucket, stored)
+}
+
+func (b *JSONFileBackend) Close() error {
+	return nil
+}
+
+// Writes the data to a temporary file next to the store file and
+// renames it over the store file once it is synced, so the store file
+// always holds either the previous or the new content, even if the
+// process is killed while writing.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+tmpFileSuffix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0600)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Persist the rename itself
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
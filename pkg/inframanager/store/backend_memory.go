@@ -0,0 +1,76 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"sync"
+)
+
+// MemoryBackend keeps the buckets in memory only, so nothing
+// survives a restart. It is meant for tests.
+type MemoryBackend struct {
+	lock    sync.Mutex
+	buckets map[string]map[string][]byte
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{buckets: make(map[string]map[string][]byte)}
+}
+
+func (b *MemoryBackend) Load(bucket string) (map[string][]byte, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	res := make(map[string][]byte, len(b.buckets[bucket]))
+	for k, v := range b.buckets[bucket] {
+		res[k] = append([]byte{}, v...)
+	}
+	return res, nil
+}
+
+func (b *MemoryBackend) Put(bucket, key string, value []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.buckets[bucket] == nil {
+		b.buckets[bucket] = make(map[string][]byte)
+	}
+	b.buckets[bucket][key] = append([]byte{}, value...)
+	return nil
+}
+
+func (b *MemoryBackend) Delete(bucket, key string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.buckets[bucket], key)
+	return nil
+}
+
+func (b *MemoryBackend) Replace(bucket string, entries map[string][]byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	stored := make(map[string][]byte, len(entries))
+	for k, v := range entries {
+		stored[k] = append([]byte{}, v...)
+	}
+	b.buckets[bucket] = stored
+	return nil
+}
+
+func (b *MemoryBackend) Close() error {
+	return nil
+}
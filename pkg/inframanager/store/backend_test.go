@@ -0,0 +1,250 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func readEndPoints() map[string]EndPoint {
+	data, err := os.ReadFile(filepath.Join(tmpDir, endPointBucket+storeFileSuffix))
+	Expect(err).ToNot(HaveOccurred())
+	res := make(map[string]EndPoint)
+	Expect(json.Unmarshal(data, &res)).To(Succeed())
+	return res
+}
+
+var _ = Describe("Store backend", func() {
+	ep := EndPoint{
+		PodIpAddress:  "10.10.10.1",
+		InterfaceID:   1,
+		PodMacAddress: "00:00:00:aa:aa:aa",
+	}
+
+	var _ = Context("EndPoint", func() {
+		var _ = It("should be written to the store file on every change", func() {
+			Expect(ep.WriteToStore()).To(BeTrue())
+			Expect(readEndPoints()).To(Equal(map[string]EndPoint{ep.PodIpAddress: ep}))
+
+			Expect(ep.DeleteFromStore()).To(BeTrue())
+			Expect(readEndPoints()).To(BeEmpty())
+		})
+
+		var _ = It("should be loaded from the store file", func() {
+			Expect(ep.WriteToStore()).To(BeTrue())
+			reopenStore()
+
+			Expect(InitEndPointStore(false)).To(BeTrue())
+			Expect(inUse().endPoints.EndPointMap).To(Equal(map[string]EndPoint{ep.PodIpAddress: ep}))
+		})
+
+		var _ = It("should be dropped when the pipeline gets set", func() {
+			Expect(ep.WriteToStore()).To(BeTrue())
+			reopenStore()
+
+			Expect(InitEndPointStore(true)).To(BeTrue())
+			Expect(inUse().endPoints.EndPointMap).To(BeEmpty())
+			Expect(readEndPoints()).To(BeEmpty())
+		})
+	})
+
+	var _ = Context("Service", func() {
+		var _ = It("should be written to the store file on every change", func() {
+			s := Service{
+				ClusterIp:   "10.96.0.10",
+				ClusterPort: 80,
+				Proto:       "TCP",
+				GroupID:     1,
+				ServiceEndPoint: map[string]ServiceEndPoint{
+					"10.10.10.1": {IpAddress: "10.10.10.1", Port: 80, MemberID: 2, DnatPtr: 2},
+				},
+			}
+			Expect(s.WriteToStore()).To(BeTrue())
+			reopenStore()
+			Expect(InitServiceStore(false)).To(BeTrue())
+			Expect(inUse().services.ServiceMap).To(HaveKeyWithValue(ServiceKey(s.ClusterIp, s.Proto, s.ClusterPort), s))
+
+			Expect(s.DeleteFromStore()).To(BeTrue())
+			reopenStore()
+			Expect(InitServiceStore(false)).To(BeTrue())
+			Expect(inUse().services.ServiceMap).To(BeEmpty())
+		})
+
+		var _ = It("should be keyed by protocol once loaded from an older store", func() {
+			s := Service{ClusterIp: "10.96.0.10", ClusterPort: 53, GroupID: 1}
+			Expect(inUse().saveEntry(serviceBucket, "10.96.0.10:53", s)).To(BeTrue())
+
+			reopenStore()
+			Expect(InitServiceStore(false)).To(BeTrue())
+			s.Proto = "TCP"
+			Expect(inUse().services.ServiceMap).To(HaveLen(1))
+			Expect(inUse().services.ServiceMap).To(HaveKeyWithValue("TCP/10.96.0.10:53", s))
+
+			entries, err := inUse().backend.Load(serviceBucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries).To(HaveKey("TCP/10.96.0.10:53"))
+		})
+
+		var _ = It("should point its older backends at the DNAT entries of their members", func() {
+			s := Service{
+				ClusterIp:   "10.96.0.10",
+				ClusterPort: 80,
+				Proto:       "TCP",
+				GroupID:     1,
+				ServiceEndPoint: map[string]ServiceEndPoint{
+					"10.10.10.1": {IpAddress: "10.10.10.1", Port: 80, MemberID: 2},
+				},
+			}
+			Expect(inUse().saveEntry(serviceBucket, s.key(), s)).To(BeTrue())
+
+			reopenStore()
+			Expect(InitServiceStore(false)).To(BeTrue())
+			Expect(inUse().services.ServiceMap[s.key()].ServiceEndPoint["10.10.10.1"].DnatPtr).To(Equal(uint32(2)))
+			Expect(DnatRefCount("10.10.10.1", 2)).To(Equal(1))
+		})
+	})
+
+	var _ = Context("Policy", func() {
+		var _ = It("should be written to the store file on every change", func() {
+			p := Policy{Name: "default/allow"}
+			i := IpSet{IpSetID: "s:1", Members: []string{"10.10.10.1/32"}}
+			Expect(p.WriteToStore()).To(BeTrue())
+			Expect(i.WriteToStore()).To(BeTrue())
+
+			reopenStore()
+			Expect(InitPolicyStore(false)).To(BeTrue())
+			Expect(inUse().policies.PolicyMap).To(HaveKey(p.Name))
+			Expect(inUse().policies.IpSetMap).To(HaveKeyWithValue(i.IpSetID, i))
+		})
+	})
+
+	var _ = Context("InitEndPointStore()", func() {
+		var _ = It("should remove the files of an interrupted write", func() {
+			tmp := filepath.Join(tmpDir, endPointBucket+storeFileSuffix+tmpFileSuffix+"123")
+			Expect(os.WriteFile(tmp, []byte("{"), 0600)).To(Succeed())
+			Expect(InitEndPointStore(false)).To(BeTrue())
+			Expect(tmp).ToNot(BeAnExistingFile())
+		})
+	})
+
+	var _ = Context("Use()", func() {
+		var _ = It("should keep the stores apart", func() {
+			ep := EndPoint{PodIpAddress: "10.10.10.1", InterfaceID: 1}
+			Expect(ep.WriteToStore()).To(BeTrue())
+
+			old := Use(NewStore(NewMemoryBackend()))
+			Expect(ep.GetFromStore()).To(BeNil())
+			Use(old)
+			Expect(ep.GetFromStore()).To(Equal(ep))
+		})
+	})
+
+	var _ = Context("OpenBackend()", func() {
+		var _ = It("should create the store directory", func() {
+			Expect(CloseBackend()).To(Succeed())
+			for _, kind := range []string{BackendJSON, BackendBolt} {
+				dir := filepath.Join(tmpDir, kind)
+				Expect(OpenBackend(kind, dir)).To(Succeed())
+				Expect(dir).To(BeADirectory())
+				Expect(CloseBackend()).To(Succeed())
+			}
+			Expect(OpenBackend(BackendMemory, "")).To(Succeed())
+		})
+
+		var _ = It("should fail for an unknown backend", func() {
+			Expect(OpenBackend("xml", tmpDir)).ToNot(Succeed())
+		})
+
+		var _ = It("should be called before the stores are initialized", func() {
+			Expect(CloseBackend()).To(Succeed())
+			Expect(InitEndPointStore(false)).To(BeFalse())
+			Expect(InitServiceStore(false)).To(BeFalse())
+			Expect(InitPolicyStore(false)).To(BeFalse())
+			Expect(EndPoint{PodIpAddress: "10.10.10.1"}.WriteToStore()).To(BeFalse())
+		})
+	})
+
+	for _, kind := range []string{BackendJSON, BackendMemory, BackendBolt} {
+		kind := kind
+		var _ = Context(kind+" backend", func() {
+			var b Backend
+
+			BeforeEach(func() {
+				var err error
+				switch kind {
+				case BackendJSON:
+					b, err = NewJSONFileBackend(filepath.Join(tmpDir, kind))
+				case BackendMemory:
+					b = NewMemoryBackend()
+				case BackendBolt:
+					b, err = NewBoltBackend(filepath.Join(tmpDir, kind))
+				}
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				Expect(b.Close()).To(Succeed())
+			})
+
+			var _ = It("should save, delete and replace entries", func() {
+				Expect(b.Load("cni")).To(BeEmpty())
+				Expect(b.Put("cni", "a", []byte(`1`))).To(Succeed())
+				Expect(b.Put("cni", "b", []byte(`2`))).To(Succeed())
+				Expect(b.Put("services", "a", []byte(`3`))).To(Succeed())
+				Expect(b.Delete("cni", "a")).To(Succeed())
+				Expect(b.Delete("policies", "a")).To(Succeed())
+				Expect(b.Load("cni")).To(Equal(map[string][]byte{"b": []byte(`2`)}))
+
+				Expect(b.Replace("cni", map[string][]byte{"c": []byte(`4`)})).To(Succeed())
+				Expect(b.Load("cni")).To(Equal(map[string][]byte{"c": []byte(`4`)}))
+				Expect(b.Replace("services", nil)).To(Succeed())
+				Expect(b.Load("services")).To(BeEmpty())
+			})
+
+			if kind != BackendMemory {
+				var _ = It("should keep the entries across restarts", func() {
+					Expect(b.Put("cni", "a", []byte(`{"x":1}`))).To(Succeed())
+					Expect(b.Close()).To(Succeed())
+
+					var err error
+					if kind == BackendJSON {
+						b, err = NewJSONFileBackend(filepath.Join(tmpDir, kind))
+					} else {
+						b, err = NewBoltBackend(filepath.Join(tmpDir, kind))
+					}
+					Expect(err).ToNot(HaveOccurred())
+					Expect(b.Load("cni")).To(HaveKey("a"))
+				})
+			}
+
+			if kind == BackendJSON {
+				var _ = It("should keep the entries it failed to write unchanged", func() {
+					Expect(b.Put("cni", "a", []byte(`1`))).To(Succeed())
+					Expect(os.RemoveAll(filepath.Join(tmpDir, kind))).To(Succeed())
+
+					Expect(b.Put("cni", "b", []byte(`2`))).ToNot(Succeed())
+					Expect(b.Delete("cni", "a")).ToNot(Succeed())
+					Expect(b.Load("cni")).To(Equal(map[string][]byte{"a": []byte(`1`)}))
+				})
+			}
+		})
+	}
+})
@@ -0,0 +1,48 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var tmpDir string
+
+func TestStore(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Store Test Suite")
+}
+
+var _ = BeforeEach(func() {
+	var err error
+	tmpDir, err = os.MkdirTemp("", "store")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(OpenBackend(BackendJSON, tmpDir)).To(Succeed())
+})
+
+// Puts an empty store in use on the backend of the current one,
+// as after a restart
+func reopenStore() {
+	Use(NewStore(inUse().backend))
+}
+
+var _ = AfterEach(func() {
+	Expect(CloseBackend()).To(Succeed())
+	os.RemoveAll(tmpDir)
+})
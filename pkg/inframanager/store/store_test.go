@@ -0,0 +1,146 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store queries", func() {
+	ep1 := EndPoint{PodIpAddress: "10.10.10.1", InterfaceID: 1, PodMacAddress: "00:00:00:AA:AA:AA"}
+	ep2 := EndPoint{PodIpAddress: "10.10.10.2", InterfaceID: 2, PodMacAddress: "00:00:00:bb:bb:bb"}
+
+	newService := func(port uint32, backends ...string) Service {
+		s := Service{
+			ClusterIp:       "10.96.0.10",
+			ClusterPort:     port,
+			GroupID:         port,
+			ServiceEndPoint: make(map[string]ServiceEndPoint),
+		}
+		for i, ip := range backends {
+			s.ServiceEndPoint[ip] = ServiceEndPoint{IpAddress: ip, Port: 8080, MemberID: port + uint32(i) + 1}
+		}
+		return s
+	}
+
+	var _ = Context("EndPoint", func() {
+		BeforeEach(func() {
+			Expect(ep1.WriteToStore()).To(BeTrue())
+			Expect(ep2.WriteToStore()).To(BeTrue())
+		})
+
+		var _ = It("should be found by MAC address and by interface", func() {
+			Expect(GetEndPointsByMac("00:00:00:aa:aa:aa")).To(ConsistOf(ep1))
+			Expect(GetEndPointsByInterface(2)).To(ConsistOf(ep2))
+			Expect(GetEndPointsByInterface(3)).To(BeEmpty())
+		})
+
+		var _ = It("should be listed through a filter", func() {
+			Expect(ListEndPoints(nil)).To(ConsistOf(ep1, ep2))
+			Expect(ListEndPoints(func(ep EndPoint) bool { return ep.InterfaceID > 1 })).To(ConsistOf(ep2))
+		})
+
+		var _ = It("should be updated only when it exists", func() {
+			moved := ep1
+			moved.InterfaceID = 3
+			Expect(moved.UpdateToStore()).To(BeTrue())
+			Expect(moved.GetFromStore()).To(Equal(moved))
+			Expect(GetEndPointsByInterface(1)).To(BeEmpty())
+			Expect(GetEndPointsByInterface(3)).To(ConsistOf(moved))
+
+			unknown := EndPoint{PodIpAddress: "10.10.10.3", InterfaceID: 3}
+			Expect(unknown.UpdateToStore()).To(BeFalse())
+			Expect(unknown.GetFromStore()).To(BeNil())
+		})
+
+		var _ = It("should leave the indexes when deleted", func() {
+			Expect(ep1.DeleteFromStore()).To(BeTrue())
+			Expect(GetEndPointsByMac(ep1.PodMacAddress)).To(BeEmpty())
+			Expect(GetEndPointsByInterface(ep1.InterfaceID)).To(BeEmpty())
+		})
+
+		var _ = It("should be indexed once loaded from the backend", func() {
+			reopenStore()
+			Expect(InitEndPointStore(false)).To(BeTrue())
+			Expect(GetEndPointsByInterface(1)).To(ConsistOf(ep1))
+		})
+	})
+
+	var _ = Context("Service", func() {
+		var _ = It("should be found by backend", func() {
+			s1 := newService(80, "10.10.10.1", "10.10.10.2")
+			s2 := newService(443, "10.10.10.2")
+			Expect(s1.WriteToStore()).To(BeTrue())
+			Expect(s2.WriteToStore()).To(BeTrue())
+
+			Expect(GetServicesByBackend("10.10.10.1")).To(ConsistOf(s1))
+			Expect(GetServicesByBackend("10.10.10.2")).To(ConsistOf(s1, s2))
+			Expect(ListServices(func(s Service) bool { return s.ClusterPort == 443 })).To(ConsistOf(s2))
+		})
+
+		var _ = It("should be updated only when it exists", func() {
+			s := newService(80, "10.10.10.1")
+			Expect(s.UpdateToStore()).To(BeFalse())
+			Expect(s.WriteToStore()).To(BeTrue())
+
+			s = newService(80, "10.10.10.2")
+			Expect(s.UpdateToStore()).To(BeTrue())
+			Expect(GetServicesByBackend("10.10.10.1")).To(BeEmpty())
+			Expect(GetServicesByBackend("10.10.10.2")).To(ConsistOf(s))
+		})
+
+		var _ = It("should be told apart by protocol", func() {
+			tcp := newService(53, "10.10.10.1")
+			tcp.Proto = "TCP"
+			udp := newService(53, "10.10.10.2")
+			udp.Proto = "UDP"
+			Expect(tcp.WriteToStore()).To(BeTrue())
+			Expect(udp.WriteToStore()).To(BeTrue())
+
+			Expect(tcp.GetFromStore()).To(Equal(tcp))
+			Expect(udp.GetFromStore()).To(Equal(udp))
+			Expect(udp.DeleteFromStore()).To(BeTrue())
+			Expect(tcp.GetFromStore()).To(Equal(tcp))
+		})
+
+		var _ = It("should count the services referring to a DNAT entry", func() {
+			s1 := newService(80, "10.10.10.1")
+			s2 := newService(443, "10.10.10.1")
+			for _, s := range []Service{s1, s2} {
+				ep := s.ServiceEndPoint["10.10.10.1"]
+				ep.DnatPtr = 7
+				s.ServiceEndPoint["10.10.10.1"] = ep
+				Expect(s.WriteToStore()).To(BeTrue())
+			}
+
+			Expect(DnatRefCount("10.10.10.1", 7)).To(Equal(2))
+			Expect(DnatRefCount("10.10.10.1", 8)).To(Equal(0))
+			Expect(s1.DeleteFromStore()).To(BeTrue())
+			Expect(DnatRefCount("10.10.10.1", 7)).To(Equal(1))
+		})
+
+		var _ = It("should not share its backends with the store", func() {
+			s := newService(80, "10.10.10.1")
+			Expect(s.WriteToStore()).To(BeTrue())
+			delete(s.ServiceEndPoint, "10.10.10.1")
+
+			res := s.GetFromStore().(Service)
+			Expect(res.ServiceEndPoint).To(HaveKey("10.10.10.1"))
+			delete(res.ServiceEndPoint, "10.10.10.1")
+			Expect(GetServicesByBackend("10.10.10.1")).To(HaveLen(1))
+		})
+	})
+})
@@ -34,38 +34,141 @@ type EndPoint struct {
 type EndPointCollection struct {
 	EndPointMap  map[string]EndPoint
 	EndPointLock *sync.Mutex
+	// ip addresses of the endpoints by MAC address and by port
+	macIndex       map[string]map[string]bool
+	interfaceIndex map[uint32]map[string]bool
 }
 
 type Service struct {
-	ClusterIp       string
-	ClusterPort     uint32
+	ClusterIp   string
+	ClusterPort uint32
+	// Kubernetes protocol of the service port: TCP, UDP or SCTP
+	Proto           string
 	GroupID         uint32
 	ServiceEndPoint map[string]ServiceEndPoint
+	// Idle timeout in seconds of the ClientIP affinity, zero for none
+	AffinityTimeout uint32
 }
 
 type ServiceEndPoint struct {
 	IpAddress string
-	port      uint32
+	Port      uint32
 	MemberID  uint32
+	// Pointer of the DNAT entry of the backend, shared by its services
+	DnatPtr uint32
 }
 
 type ServiceCollection struct {
 	ServiceMap  map[string]Service
 	ServiceLock *sync.Mutex
+	// keys of the services by backend ip address
+	backendIndex map[string]map[string]bool
 }
 
-var ServiceMap *ServiceCollection
-var EndPointSet *EndPointCollection
-var once sync.Once
+type PortRange struct {
+	First uint16
+	Last  uint16
+}
+
+// PolicyRule is a felix policy rule reduced to the matches
+// the ACL stage of the pipeline supports
+type PolicyRule struct {
+	Action      string
+	Protocol    uint8
+	SrcNet      []string
+	DstNet      []string
+	SrcPorts    []PortRange
+	DstPorts    []PortRange
+	SrcIpSetIDs []string
+	DstIpSetIDs []string
+}
+
+type Policy struct {
+	Name         string
+	IngressRules []PolicyRule
+	EgressRules  []PolicyRule
+}
+
+type IpSet struct {
+	IpSetID string
+	Members []string
+}
+
+// AclEntry is a ternary entry of the ingress or egress ACL rules table
+type AclEntry struct {
+	SrcIp        uint32
+	SrcIpMask    uint32
+	DstIp        uint32
+	DstIpMask    uint32
+	Protocol     uint8
+	ProtocolMask uint8
+	SrcPort      uint16
+	SrcPortMask  uint16
+	DstPort      uint16
+	DstPortMask  uint16
+	Allow        bool
+	Priority     int32
+}
+
+// Workload is a local workload endpoint along with the
+// ACL entries programmed for its policies
+type Workload struct {
+	WorkloadID      string
+	PodIpAddress    string
+	PodIpv6Address  string
+	IngressPolicies []string
+	EgressPolicies  []string
+	IngressAclID    uint32
+	EgressAclID     uint32
+	IngressEntries  []AclEntry
+	EgressEntries   []AclEntry
+}
+
+type PolicyCollection struct {
+	PolicyMap   map[string]Policy
+	IpSetMap    map[string]IpSet
+	WorkloadMap map[string]Workload
+	PolicyLock  *sync.Mutex
+}
+
+// Store holds the endpoints, services and policies of the inframanager
+// and the backend they are saved to. The entry types and the functions
+// of the package work on the store in use, tests put one of their own
+// in use rather than share it.
+type Store struct {
+	backend   Backend
+	endPoints *EndPointCollection
+	services  *ServiceCollection
+	policies  *PolicyCollection
+}
+
+// NewStore returns an empty store saving its entries to the backend.
+// Without a backend the store fails to initialize and to save entries.
+func NewStore(b Backend) *Store {
+	return &Store{
+		backend:   b,
+		endPoints: newEndPointCollection(),
+		services:  newServiceCollection(),
+		policies:  newPolicyCollection(),
+	}
+}
+
+var (
+	inUseLock  sync.Mutex
+	inUseStore = NewStore(nil)
+)
 
-func NewEndPoint() {
-	once.Do(func() {
-		EndPointSet = &EndPointCollection{EndPointMap: make(map[string]EndPoint),
-			EndPointLock: &sync.Mutex{}} //call this from core manager before setting pipeline
-	})
+// Use puts the store in use and returns the one it replaces
+func Use(s *Store) *Store {
+	inUseLock.Lock()
+	defer inUseLock.Unlock()
+	old := inUseStore
+	inUseStore = s
+	return old
 }
 
-func NewServiceAddMap() {
-	ServiceMap = &ServiceCollection{ServiceMap: make(map[string]Service),
-		ServiceLock: &sync.Mutex{}}
+func inUse() *Store {
+	inUseLock.Lock()
+	defer inUseLock.Unlock()
+	return inUseStore
 }
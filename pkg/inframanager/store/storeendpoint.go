@@ -15,20 +15,20 @@
 package store
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
+	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 )
 
 const (
-	storeFile = "/opt/inframanager/cni_db.json"
+	endPointBucket = "cni"
 )
 
 func isEndPointStoreEmpty() bool {
-	if len(EndPointSet.EndPointMap) == 0 {
+	st := inUse()
+	if len(st.endPoints.EndPointMap) == 0 {
 		return true
 	} else {
 		return false
@@ -36,8 +36,6 @@ func isEndPointStoreEmpty() bool {
 }
 
 func InitEndPointStore(setFwdPipe bool) bool {
-	flags := os.O_CREATE
-
 	/*
 		Initialize the store to empty while setting the
 		forwarding pipeline. It indicates that the p4-ovs
@@ -45,85 +43,179 @@ func InitEndPointStore(setFwdPipe bool) bool {
 		And no stale forwarding rules should exist in the store.
 		Truncate if any entries from previous server runs.
 	*/
-	if setFwdPipe {
-		flags = flags | os.O_TRUNC
-	}
+	st := inUse()
+	st.endPoints.EndPointLock.Lock()
+	defer st.endPoints.EndPointLock.Unlock()
 
-	/* Create the store file if it doesn't exist */
-	file, err := os.OpenFile(storeFile, flags, 0600)
-	if err != nil {
-		log.Error("Failed to open", storeFile)
+	if !st.loadEntries(endPointBucket, setFwdPipe, &st.endPoints.EndPointMap) {
 		return false
 	}
-	file.Close()
+	st.endPoints.reindex()
 
-	data, err := os.ReadFile(storeFile)
-	if err != nil {
-		log.Error("Error reading ", storeFile, err)
-		return false
+	log.Infof("Map: " + fmt.Sprint(st.endPoints.EndPointMap))
+	return true
+}
+
+func newEndPointCollection() *EndPointCollection {
+	return &EndPointCollection{
+		EndPointMap:    make(map[string]EndPoint),
+		EndPointLock:   &sync.Mutex{},
+		macIndex:       make(map[string]map[string]bool),
+		interfaceIndex: make(map[uint32]map[string]bool),
 	}
+}
 
-	if len(data) == 0 {
-		return true
+// MAC addresses are indexed in lower case, the way the CNI reports them
+func macKey(mac string) string {
+	return strings.ToLower(mac)
+}
+
+// The caller holds the lock of the store, as for all the index helpers
+func (c *EndPointCollection) index(ep EndPoint) {
+	mac := macKey(ep.PodMacAddress)
+	if c.macIndex[mac] == nil {
+		c.macIndex[mac] = make(map[string]bool)
 	}
+	c.macIndex[mac][ep.PodIpAddress] = true
 
-	err = json.Unmarshal(data, &EndPointSet.EndPointMap)
-	if err != nil {
-		log.Error("Error unmarshalling data from ", storeFile, err)
-		return false
+	if c.interfaceIndex[ep.InterfaceID] == nil {
+		c.interfaceIndex[ep.InterfaceID] = make(map[string]bool)
+	}
+	c.interfaceIndex[ep.InterfaceID][ep.PodIpAddress] = true
+}
+
+func (c *EndPointCollection) unindex(ep EndPoint) {
+	mac := macKey(ep.PodMacAddress)
+	delete(c.macIndex[mac], ep.PodIpAddress)
+	if len(c.macIndex[mac]) == 0 {
+		delete(c.macIndex, mac)
 	}
 
-	log.Infof("Map: " + fmt.Sprint(EndPointSet.EndPointMap))
-	return true
+	delete(c.interfaceIndex[ep.InterfaceID], ep.PodIpAddress)
+	if len(c.interfaceIndex[ep.InterfaceID]) == 0 {
+		delete(c.interfaceIndex, ep.InterfaceID)
+	}
+}
+
+func (c *EndPointCollection) reindex() {
+	c.macIndex = make(map[string]map[string]bool)
+	c.interfaceIndex = make(map[uint32]map[string]bool)
+	for _, ep := range c.EndPointMap {
+		c.index(ep)
+	}
+}
+
+func (c *EndPointCollection) lookup(ips map[string]bool) []EndPoint {
+	res := make([]EndPoint, 0, len(ips))
+	for ip := range ips {
+		res = append(res, c.EndPointMap[ip])
+	}
+	return res
 }
 
+// The caller holds the lock
+func (c *EndPointCollection) set(ep EndPoint) {
+	if old, ok := c.EndPointMap[ep.PodIpAddress]; ok {
+		c.unindex(old)
+	}
+	c.EndPointMap[ep.PodIpAddress] = ep
+	c.index(ep)
+}
+
+// The entry is saved to the backend on every change, while the lock
+// is held, so that the backend always matches the map.
 func (ep EndPoint) WriteToStore() bool {
+	st := inUse()
 	//aquire lock before adding entry into the map
-	EndPointSet.EndPointLock.Lock()
+	st.endPoints.EndPointLock.Lock()
+	defer st.endPoints.EndPointLock.Unlock()
 	//append tmp entry to the map
-	EndPointSet.EndPointMap[ep.PodIpAddress] = ep
-	//release lock after updating the map
-	EndPointSet.EndPointLock.Unlock()
+	st.endPoints.set(ep)
 
-	return true
+	return st.saveEntry(endPointBucket, ep.PodIpAddress, ep)
 }
 
 func (ep EndPoint) DeleteFromStore() bool {
+	st := inUse()
 	//aquire lock before adding entry into the map
-	EndPointSet.EndPointLock.Lock()
+	st.endPoints.EndPointLock.Lock()
+	defer st.endPoints.EndPointLock.Unlock()
 	//delete tmp entry from the map
-	delete(EndPointSet.EndPointMap, ep.PodIpAddress)
-	//release lock after updating the map
-	EndPointSet.EndPointLock.Unlock()
-	return true
+	if old, ok := st.endPoints.EndPointMap[ep.PodIpAddress]; ok {
+		st.endPoints.unindex(old)
+		delete(st.endPoints.EndPointMap, ep.PodIpAddress)
+	}
+
+	return st.deleteEntry(endPointBucket, ep.PodIpAddress)
 }
 
 func (ep EndPoint) GetFromStore() store {
-	res := EndPointSet.EndPointMap[ep.PodIpAddress]
-	if (res == EndPoint{}) {
+	st := inUse()
+	st.endPoints.EndPointLock.Lock()
+	defer st.endPoints.EndPointLock.Unlock()
+
+	res, ok := st.endPoints.EndPointMap[ep.PodIpAddress]
+	if !ok {
 		return nil
-	} else {
-		return res
 	}
+	return res
 }
 
+// UpdateToStore replaces the endpoint with the same ip address.
+// It fails when there is no such endpoint in the store.
 func (ep EndPoint) UpdateToStore() bool {
-	fmt.Println("not implemented")
-	return true
-}
+	st := inUse()
+	st.endPoints.EndPointLock.Lock()
+	defer st.endPoints.EndPointLock.Unlock()
 
-func RunSyncEndPointInfo() bool {
-	jsonStr, err := json.MarshalIndent(EndPointSet.EndPointMap, "", " ")
-	if err != nil {
-		log.Errorf("Failed to marshal endpoint entries map %s", err)
+	if _, ok := st.endPoints.EndPointMap[ep.PodIpAddress]; !ok {
+		log.Errorf("No endpoint %s to update in the store", ep.PodIpAddress)
 		return false
 	}
+	st.endPoints.set(ep)
 
-	if err = ioutil.WriteFile(storeFile, jsonStr, 0600); err != nil {
-		log.Errorf("Failed to write entries to %s, err %s",
-			storeFile, err)
-		return false
+	return st.saveEntry(endPointBucket, ep.PodIpAddress, ep)
+}
+
+// GetEndPointsByMac returns the endpoints with the given MAC address,
+// one per ip address of the pod interface
+func GetEndPointsByMac(mac string) []EndPoint {
+	st := inUse()
+	st.endPoints.EndPointLock.Lock()
+	defer st.endPoints.EndPointLock.Unlock()
+
+	return st.endPoints.lookup(st.endPoints.macIndex[macKey(mac)])
+}
+
+// GetEndPointsByInterface returns the endpoints behind the given port
+func GetEndPointsByInterface(id uint32) []EndPoint {
+	st := inUse()
+	st.endPoints.EndPointLock.Lock()
+	defer st.endPoints.EndPointLock.Unlock()
+
+	return st.endPoints.lookup(st.endPoints.interfaceIndex[id])
+}
+
+// ListEndPoints returns the endpoints matching the filter, all of
+// them when the filter is nil
+func ListEndPoints(filter func(EndPoint) bool) []EndPoint {
+	st := inUse()
+	st.endPoints.EndPointLock.Lock()
+	defer st.endPoints.EndPointLock.Unlock()
+
+	res := make([]EndPoint, 0, len(st.endPoints.EndPointMap))
+	for _, ep := range st.endPoints.EndPointMap {
+		if filter == nil || filter(ep) {
+			res = append(res, ep)
+		}
 	}
+	return res
+}
 
-	return true
+func RunSyncEndPointInfo() bool {
+	st := inUse()
+	st.endPoints.EndPointLock.Lock()
+	defer st.endPoints.EndPointLock.Unlock()
+
+	return st.replaceEntries(endPointBucket, st.endPoints.EndPointMap)
 }
@@ -0,0 +1,244 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	policyBucket   = "policies"
+	ipSetBucket    = "ipsets"
+	workloadBucket = "workloads"
+)
+
+func newPolicyCollection() *PolicyCollection {
+	return &PolicyCollection{
+		PolicyMap:   make(map[string]Policy),
+		IpSetMap:    make(map[string]IpSet),
+		WorkloadMap: make(map[string]Workload),
+		PolicyLock:  &sync.Mutex{},
+	}
+}
+
+func InitPolicyStore(setFwdPipe bool) bool {
+	/*
+		The ACL entries of the previous server runs are
+		stale once the forwarding pipeline has been set.
+	*/
+	st := inUse()
+	if !st.loadEntries(policyBucket, setFwdPipe, &st.policies.PolicyMap) ||
+		!st.loadEntries(ipSetBucket, setFwdPipe, &st.policies.IpSetMap) ||
+		!st.loadEntries(workloadBucket, setFwdPipe, &st.policies.WorkloadMap) {
+		return false
+	}
+
+	log.Infof("Map: " + fmt.Sprint(st.policies.WorkloadMap))
+	return true
+}
+
+func (p Policy) WriteToStore() bool {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+	st.policies.PolicyMap[p.Name] = p
+	return st.saveEntry(policyBucket, p.Name, p)
+}
+
+func (p Policy) DeleteFromStore() bool {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+	delete(st.policies.PolicyMap, p.Name)
+	return st.deleteEntry(policyBucket, p.Name)
+}
+
+func (p Policy) GetFromStore() store {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	res, ok := st.policies.PolicyMap[p.Name]
+	st.policies.PolicyLock.Unlock()
+	if !ok {
+		return nil
+	}
+	return res
+}
+
+func (p Policy) UpdateToStore() bool {
+	return p.WriteToStore()
+}
+
+func (i IpSet) WriteToStore() bool {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+	st.policies.IpSetMap[i.IpSetID] = i
+	return st.saveEntry(ipSetBucket, i.IpSetID, i)
+}
+
+func (i IpSet) DeleteFromStore() bool {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+	delete(st.policies.IpSetMap, i.IpSetID)
+	return st.deleteEntry(ipSetBucket, i.IpSetID)
+}
+
+func (i IpSet) GetFromStore() store {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	res, ok := st.policies.IpSetMap[i.IpSetID]
+	st.policies.PolicyLock.Unlock()
+	if !ok {
+		return nil
+	}
+	return res
+}
+
+func (i IpSet) UpdateToStore() bool {
+	return i.WriteToStore()
+}
+
+func (w Workload) WriteToStore() bool {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+	st.policies.WorkloadMap[w.WorkloadID] = w
+	return st.saveEntry(workloadBucket, w.WorkloadID, w)
+}
+
+func (w Workload) DeleteFromStore() bool {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+	delete(st.policies.WorkloadMap, w.WorkloadID)
+	return st.deleteEntry(workloadBucket, w.WorkloadID)
+}
+
+func (w Workload) GetFromStore() store {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	res := st.policies.WorkloadMap[w.WorkloadID]
+	st.policies.PolicyLock.Unlock()
+	if reflect.DeepEqual(res, Workload{}) {
+		return nil
+	}
+	return res
+}
+
+func (w Workload) UpdateToStore() bool {
+	return w.WriteToStore()
+}
+
+// GetPolicyWorkloads returns the workloads with any of the given
+// policies applied in either direction
+func GetPolicyWorkloads(policies map[string]bool) []Workload {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+
+	res := make([]Workload, 0)
+	for _, w := range st.policies.WorkloadMap {
+		for _, name := range append(append([]string{}, w.IngressPolicies...), w.EgressPolicies...) {
+			if policies[name] {
+				res = append(res, w)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// GetIpSetPolicies returns the names of the policies with rules
+// referencing the given ip set
+func GetIpSetPolicies(ipSetID string) map[string]bool {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+
+	res := make(map[string]bool)
+	for name, p := range st.policies.PolicyMap {
+		for _, r := range append(append([]PolicyRule{}, p.IngressRules...), p.EgressRules...) {
+			for _, id := range append(append([]string{}, r.SrcIpSetIDs...), r.DstIpSetIDs...) {
+				if id == ipSetID {
+					res[name] = true
+				}
+			}
+		}
+	}
+	return res
+}
+
+// ListPolicies returns the policies matching the filter, all of
+// them when the filter is nil
+func ListPolicies(filter func(Policy) bool) []Policy {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+
+	res := make([]Policy, 0, len(st.policies.PolicyMap))
+	for _, p := range st.policies.PolicyMap {
+		if filter == nil || filter(p) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// ListIpSets returns the ip sets matching the filter, all of
+// them when the filter is nil
+func ListIpSets(filter func(IpSet) bool) []IpSet {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+
+	res := make([]IpSet, 0, len(st.policies.IpSetMap))
+	for _, i := range st.policies.IpSetMap {
+		if filter == nil || filter(i) {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// ListWorkloads returns the workloads matching the filter, all of
+// them when the filter is nil
+func ListWorkloads(filter func(Workload) bool) []Workload {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+
+	res := make([]Workload, 0, len(st.policies.WorkloadMap))
+	for _, w := range st.policies.WorkloadMap {
+		if filter == nil || filter(w) {
+			res = append(res, w)
+		}
+	}
+	return res
+}
+
+func RunSyncPolicyInfo() bool {
+	st := inUse()
+	st.policies.PolicyLock.Lock()
+	defer st.policies.PolicyLock.Unlock()
+
+	return st.replaceEntries(policyBucket, st.policies.PolicyMap) &&
+		st.replaceEntries(ipSetBucket, st.policies.IpSetMap) &&
+		st.replaceEntries(workloadBucket, st.policies.WorkloadMap)
+}
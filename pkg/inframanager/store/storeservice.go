@@ -15,72 +15,257 @@
 package store
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"reflect"
+	"net"
+	"strconv"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 )
 
 const (
-	servicesFile = "/opt/inframanager/services_db.json"
+	serviceBucket = "services"
 )
 
+const (
+	// Protocol of the services stored before it was recorded
+	defaultServiceProto = "TCP"
+)
+
+// A service is identified by its protocol, virtual ip and port, as the
+// same ip is shared by all the NodePort services of a node and a port
+// can be served over both TCP and UDP.
+func ServiceKey(ip string, proto string, port uint32) string {
+	return proto + "/" + net.JoinHostPort(ip, strconv.FormatUint(uint64(port), 10))
+}
+
+func (s Service) key() string {
+	return ServiceKey(s.ClusterIp, s.Proto, s.ClusterPort)
+}
+
+// Keys the services stored before their protocol was recorded by
+// their full key. The backends stored before the DNAT entries were
+// shared own the entry programmed under their member id.
+// Reports whether any service was changed. The caller holds the lock.
+func (c *ServiceCollection) migrate() bool {
+	changed := false
+	for key, s := range c.ServiceMap {
+		migrated := false
+		if s.Proto == "" {
+			s.Proto = defaultServiceProto
+			migrated = true
+		}
+		for ip, ep := range s.ServiceEndPoint {
+			if ep.DnatPtr == 0 {
+				ep.DnatPtr = ep.MemberID
+				s.ServiceEndPoint[ip] = ep
+				migrated = true
+			}
+		}
+		if !migrated && key == s.key() {
+			continue
+		}
+		delete(c.ServiceMap, key)
+		c.ServiceMap[s.key()] = s
+		changed = true
+	}
+	return changed
+}
+
+// DnatRefCount returns the number of services whose backend with the
+// given ip refers to the DNAT entry
+func DnatRefCount(ip string, ptr uint32) int {
+	st := inUse()
+	st.services.ServiceLock.Lock()
+	defer st.services.ServiceLock.Unlock()
+
+	refs := 0
+	for key := range st.services.backendIndex[ip] {
+		if st.services.ServiceMap[key].ServiceEndPoint[ip].DnatPtr == ptr {
+			refs++
+		}
+	}
+	return refs
+}
+
 func isServiceStoreEmpty() bool {
-	if len(ServiceMap.ServiceMap) == 0 {
+	st := inUse()
+	if len(st.services.ServiceMap) == 0 {
 		return true
 	} else {
 		return false
 	}
 }
 
+func InitServiceStore(setFwdPipe bool) bool {
+	/*
+		As for the endpoint store, the services programmed
+		by the previous server runs are stale once the
+		forwarding pipeline has been set again.
+	*/
+	st := inUse()
+	st.services.ServiceLock.Lock()
+	defer st.services.ServiceLock.Unlock()
+
+	if !st.loadEntries(serviceBucket, setFwdPipe, &st.services.ServiceMap) {
+		return false
+	}
+	if st.services.migrate() && !st.replaceEntries(serviceBucket, st.services.ServiceMap) {
+		return false
+	}
+	st.services.reindex()
+
+	log.Infof("Map: " + fmt.Sprint(st.services.ServiceMap))
+	return true
+}
+
+func newServiceCollection() *ServiceCollection {
+	return &ServiceCollection{
+		ServiceMap:   make(map[string]Service),
+		ServiceLock:  &sync.Mutex{},
+		backendIndex: make(map[string]map[string]bool),
+	}
+}
+
+// Returns a copy of the service not sharing its backends with the store
+func (s Service) clone() Service {
+	eps := s.ServiceEndPoint
+	if eps != nil {
+		s.ServiceEndPoint = make(map[string]ServiceEndPoint, len(eps))
+		for ip, ep := range eps {
+			s.ServiceEndPoint[ip] = ep
+		}
+	}
+	return s
+}
+
+// The caller holds the lock of the store, as for all the index helpers
+func (c *ServiceCollection) index(key string, s Service) {
+	for ip := range s.ServiceEndPoint {
+		if c.backendIndex[ip] == nil {
+			c.backendIndex[ip] = make(map[string]bool)
+		}
+		c.backendIndex[ip][key] = true
+	}
+}
+
+func (c *ServiceCollection) unindex(key string, s Service) {
+	for ip := range s.ServiceEndPoint {
+		delete(c.backendIndex[ip], key)
+		if len(c.backendIndex[ip]) == 0 {
+			delete(c.backendIndex, ip)
+		}
+	}
+}
+
+func (c *ServiceCollection) reindex() {
+	c.backendIndex = make(map[string]map[string]bool)
+	for key, s := range c.ServiceMap {
+		c.index(key, s)
+	}
+}
+
+// The caller holds the lock
+func (c *ServiceCollection) set(key string, s Service) {
+	if old, ok := c.ServiceMap[key]; ok {
+		c.unindex(key, old)
+	}
+	c.ServiceMap[key] = s
+	c.index(key, s)
+}
+
 func (s Service) WriteToStore() bool {
+	st := inUse()
 	//aquire lock before adding entry into the map
-	ServiceMap.ServiceLock.Lock()
+	st.services.ServiceLock.Lock()
+	defer st.services.ServiceLock.Unlock()
 	//append tmp entry to the map
-	ServiceMap.ServiceMap[s.ClusterIp] = s
-	//release lock after updating the map
-	ServiceMap.ServiceLock.Unlock()
-	return true
+	key := s.key()
+	st.services.set(key, s.clone())
+
+	return st.saveEntry(serviceBucket, key, s)
 }
 
 func (s Service) DeleteFromStore() bool {
+	st := inUse()
 	//aquire lock before adding entry into the map
-	ServiceMap.ServiceLock.Lock()
+	st.services.ServiceLock.Lock()
+	defer st.services.ServiceLock.Unlock()
 	//delete tmp entry from the map
-	delete(ServiceMap.ServiceMap, s.ClusterIp)
-	//release lock after updating the map
-	ServiceMap.ServiceLock.Unlock()
-	return true
+	key := s.key()
+	if old, ok := st.services.ServiceMap[key]; ok {
+		st.services.unindex(key, old)
+		delete(st.services.ServiceMap, key)
+	}
+
+	return st.deleteEntry(serviceBucket, key)
 }
 
+// GetFromStore returns a copy of the service, which can be changed
+// and written back without affecting the store in between.
 func (s Service) GetFromStore() store {
+	st := inUse()
+	st.services.ServiceLock.Lock()
+	defer st.services.ServiceLock.Unlock()
 
-	res := ServiceMap.ServiceMap[s.ClusterIp]
-	if reflect.DeepEqual(res, Service{}) {
+	res, ok := st.services.ServiceMap[s.key()]
+	if !ok {
 		return nil
-	} else {
-		return res
 	}
+	return res.clone()
 }
 
+// UpdateToStore replaces the service with the same protocol, virtual
+// ip and port. It fails when there is no such service in the store.
 func (s Service) UpdateToStore() bool {
-	fmt.Println("not implemented")
-	return true
-}
+	st := inUse()
+	st.services.ServiceLock.Lock()
+	defer st.services.ServiceLock.Unlock()
 
-func RunSyncServiceInfo() bool {
-	jsonStr, err := json.MarshalIndent(ServiceMap.ServiceMap, "", " ")
-	if err != nil {
-		log.Errorf("Failed to marshal service entries map %s", err)
+	key := s.key()
+	if _, ok := st.services.ServiceMap[key]; !ok {
+		log.Errorf("No service %s to update in the store", key)
 		return false
 	}
+	st.services.set(key, s.clone())
 
-	if err = ioutil.WriteFile(servicesFile, jsonStr, 0600); err != nil {
-		log.Errorf("Failed to write entries to %s, err %s",
-			servicesFile, err)
-		return false
+	return st.saveEntry(serviceBucket, key, s)
+}
+
+// GetServicesByBackend returns the services load balancing
+// to the given pod ip address
+func GetServicesByBackend(ip string) []Service {
+	st := inUse()
+	st.services.ServiceLock.Lock()
+	defer st.services.ServiceLock.Unlock()
+
+	res := make([]Service, 0, len(st.services.backendIndex[ip]))
+	for key := range st.services.backendIndex[ip] {
+		res = append(res, st.services.ServiceMap[key].clone())
 	}
-	return true
+	return res
+}
+
+// ListServices returns the services matching the filter, all of
+// them when the filter is nil
+func ListServices(filter func(Service) bool) []Service {
+	st := inUse()
+	st.services.ServiceLock.Lock()
+	defer st.services.ServiceLock.Unlock()
+
+	res := make([]Service, 0, len(st.services.ServiceMap))
+	for _, s := range st.services.ServiceMap {
+		if filter == nil || filter(s) {
+			res = append(res, s.clone())
+		}
+	}
+	return res
+}
+
+func RunSyncServiceInfo() bool {
+	st := inUse()
+	st.services.ServiceLock.Lock()
+	defer st.services.ServiceLock.Unlock()
+
+	return st.replaceEntries(serviceBucket, st.services.ServiceMap)
 }
@@ -0,0 +1,137 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package managerclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var (
+	grpcDial = grpc.Dial
+
+	// CallTimeout is the deadline of every call to inframanager
+	CallTimeout = 30 * time.Second
+)
+
+var (
+	connLock sync.Mutex
+	conn     *grpc.ClientConn
+)
+
+func dialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  1 * time.Second,
+				Multiplier: 1.6,
+				Jitter:     0.2,
+				MaxDelay:   30 * time.Second,
+			},
+			MinConnectTimeout: 5 * time.Second,
+		}),
+	}
+}
+
+// GetConn returns the connection to inframanager shared by all the
+// agent components. It is dialed on first use, grpc re-establishes it
+// with backoff whenever it is lost, so the callers must not close it.
+func GetConn() (*grpc.ClientConn, error) {
+	connLock.Lock()
+	defer connLock.Unlock()
+
+	if conn != nil {
+		return conn, nil
+	}
+
+	managerAddr := fmt.Sprintf("%s:%s", types.InfraManagerAddr, types.InfraManagerPort)
+	c, err := grpcDial(managerAddr, dialOptions()...)
+	if err != nil {
+		log.WithField("func", "GetConn").Errorf("unable to dial Infra Manager. err %v", err)
+		return nil, err
+	}
+	conn = c
+	go watchState(c)
+	return conn, nil
+}
+
+// NewCallContext returns the context for a single call to inframanager
+func NewCallContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, CallTimeout)
+}
+
+// Close closes the shared connection, the next GetConn dials a new one
+func Close() {
+	connLock.Lock()
+	defer connLock.Unlock()
+
+	if conn == nil {
+		return
+	}
+	if err := conn.Close(); err != nil {
+		log.WithField("func", "Close").WithError(err).Warn("failed to close connection to Infra Manager")
+	}
+	conn = nil
+}
+
+func connStatus(state connectivity.State) string {
+	switch state {
+	case connectivity.Ready:
+		return types.ServerStatusOK
+	case connectivity.Shutdown:
+		return types.ServerStatusStopped
+	}
+	return state.String()
+}
+
+// Only the current connection reports its state, a closed one
+// may still be reporting after it got replaced.
+func setConnStatus(c *grpc.ClientConn, state connectivity.State) {
+	connLock.Lock()
+	defer connLock.Unlock()
+
+	if conn == c || conn == nil {
+		types.InfraManagerConnStatus = connStatus(state)
+	}
+}
+
+// Reports the state of the connection to the health server and keeps the
+// connection up when it goes idle, so that failures are noticed before
+// the next call.
+func watchState(c *grpc.ClientConn) {
+	logger := log.WithField("func", "watchState")
+	for {
+		state := c.GetState()
+		setConnStatus(c, state)
+		logger.Infof("Infra Manager connection state: %s", state)
+		switch state {
+		case connectivity.Shutdown:
+			return
+		case connectivity.Idle:
+			c.Connect()
+		}
+		c.WaitForStateChange(context.Background(), state)
+	}
+}
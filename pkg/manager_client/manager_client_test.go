@@ -0,0 +1,127 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package managerclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1024 * 1024
+
+var (
+	listener *bufconn.Listener
+	server   *grpc.Server
+	dialed   int
+)
+
+func TestManagerClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Manager Client Test Suite")
+}
+
+func bufDialer(context.Context, string) (net.Conn, error) {
+	return listener.Dial()
+}
+
+var _ = Describe("manager client", func() {
+	var _ = BeforeEach(func() {
+		listener = bufconn.Listen(bufSize)
+		server = grpc.NewServer()
+		go func() {
+			_ = server.Serve(listener)
+		}()
+		dialed = 0
+		grpcDial = func(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+			dialed++
+			return grpc.Dial("bufnet", append(opts, grpc.WithContextDialer(bufDialer))...)
+		}
+	})
+
+	var _ = AfterEach(func() {
+		Close()
+		server.Stop()
+		listener.Close()
+	})
+
+	var _ = Context("GetConn() should", func() {
+		var _ = It("return the same connection on every call", func() {
+			first, err := GetConn()
+			Expect(err).ToNot(HaveOccurred())
+			second, err := GetConn()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(second).To(BeIdenticalTo(first))
+			Expect(dialed).To(Equal(1))
+		})
+
+		var _ = It("dial again on the next call if dial failed", func() {
+			dial := grpcDial
+			grpcDial = func(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+				return nil, errors.New("dial error")
+			}
+			_, err := GetConn()
+			Expect(err).To(HaveOccurred())
+
+			grpcDial = dial
+			conn, err := GetConn()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn).ToNot(BeNil())
+		})
+
+		var _ = It("report the connection state", func() {
+			_, err := GetConn()
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(func() string {
+				return types.InfraManagerConnStatus
+			}, "3s").Should(Equal(types.ServerStatusOK))
+
+			Close()
+			Eventually(func() string {
+				return types.InfraManagerConnStatus
+			}, "3s").Should(Equal(types.ServerStatusStopped))
+		})
+	})
+
+	var _ = Context("Close() should", func() {
+		var _ = It("make the next GetConn dial a new connection", func() {
+			first, err := GetConn()
+			Expect(err).ToNot(HaveOccurred())
+			Close()
+			second, err := GetConn()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(second).ToNot(BeIdenticalTo(first))
+			Expect(dialed).To(Equal(2))
+		})
+	})
+
+	var _ = Context("NewCallContext() should", func() {
+		var _ = It("set the call deadline", func() {
+			ctx, cancel := NewCallContext(context.Background())
+			defer cancel()
+			deadline, ok := ctx.Deadline()
+			Expect(ok).To(BeTrue())
+			Expect(time.Until(deadline)).To(BeNumerically("~", CallTimeout, time.Second))
+		})
+	})
+})
@@ -136,6 +136,26 @@ func (mr *MockInfraAgentClientMockRecorder) DeleteNetwork(ctx, in interface{}, o
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNetwork", reflect.TypeOf((*MockInfraAgentClient)(nil).DeleteNetwork), varargs...)
 }
 
+// GetPolicyState mocks base method.
+func (m *MockInfraAgentClient) GetPolicyState(ctx context.Context, in *proto.PolicyStateRequest, opts ...grpc.CallOption) (*proto.PolicyStateReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetPolicyState", varargs...)
+	ret0, _ := ret[0].(*proto.PolicyStateReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPolicyState indicates an expected call of GetPolicyState.
+func (mr *MockInfraAgentClientMockRecorder) GetPolicyState(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPolicyState", reflect.TypeOf((*MockInfraAgentClient)(nil).GetPolicyState), varargs...)
+}
+
 // NatTranslationAdd mocks base method.
 func (m *MockInfraAgentClient) NatTranslationAdd(ctx context.Context, in *proto.NatTranslation, opts ...grpc.CallOption) (*proto.Reply, error) {
 	m.ctrl.T.Helper()
@@ -176,6 +196,26 @@ func (mr *MockInfraAgentClientMockRecorder) NatTranslationDelete(ctx, in interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NatTranslationDelete", reflect.TypeOf((*MockInfraAgentClient)(nil).NatTranslationDelete), varargs...)
 }
 
+// NatTranslationUpdate mocks base method.
+func (m *MockInfraAgentClient) NatTranslationUpdate(ctx context.Context, in *proto.NatTranslationUpdateRequest, opts ...grpc.CallOption) (*proto.Reply, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "NatTranslationUpdate", varargs...)
+	ret0, _ := ret[0].(*proto.Reply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NatTranslationUpdate indicates an expected call of NatTranslationUpdate.
+func (mr *MockInfraAgentClientMockRecorder) NatTranslationUpdate(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NatTranslationUpdate", reflect.TypeOf((*MockInfraAgentClient)(nil).NatTranslationUpdate), varargs...)
+}
+
 // RemoveActiveProfile mocks base method.
 func (m *MockInfraAgentClient) RemoveActiveProfile(ctx context.Context, in *proto.ActiveProfileRemove, opts ...grpc.CallOption) (*proto.Reply, error) {
 	m.ctrl.T.Helper()
@@ -754,6 +794,21 @@ func (mr *MockInfraAgentServerMockRecorder) DeleteNetwork(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNetwork", reflect.TypeOf((*MockInfraAgentServer)(nil).DeleteNetwork), arg0, arg1)
 }
 
+// GetPolicyState mocks base method.
+func (m *MockInfraAgentServer) GetPolicyState(arg0 context.Context, arg1 *proto.PolicyStateRequest) (*proto.PolicyStateReply, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPolicyState", arg0, arg1)
+	ret0, _ := ret[0].(*proto.PolicyStateReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPolicyState indicates an expected call of GetPolicyState.
+func (mr *MockInfraAgentServerMockRecorder) GetPolicyState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPolicyState", reflect.TypeOf((*MockInfraAgentServer)(nil).GetPolicyState), arg0, arg1)
+}
+
 // NatTranslationAdd mocks base method.
 func (m *MockInfraAgentServer) NatTranslationAdd(arg0 context.Context, arg1 *proto.NatTranslation) (*proto.Reply, error) {
 	m.ctrl.T.Helper()
@@ -784,6 +839,21 @@ func (mr *MockInfraAgentServerMockRecorder) NatTranslationDelete(arg0, arg1 inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NatTranslationDelete", reflect.TypeOf((*MockInfraAgentServer)(nil).NatTranslationDelete), arg0, arg1)
 }
 
+// NatTranslationUpdate mocks base method.
+func (m *MockInfraAgentServer) NatTranslationUpdate(arg0 context.Context, arg1 *proto.NatTranslationUpdateRequest) (*proto.Reply, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NatTranslationUpdate", arg0, arg1)
+	ret0, _ := ret[0].(*proto.Reply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NatTranslationUpdate indicates an expected call of NatTranslationUpdate.
+func (mr *MockInfraAgentServerMockRecorder) NatTranslationUpdate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NatTranslationUpdate", reflect.TypeOf((*MockInfraAgentServer)(nil).NatTranslationUpdate), arg0, arg1)
+}
+
 // RemoveActiveProfile mocks base method.
 func (m *MockInfraAgentServer) RemoveActiveProfile(arg0 context.Context, arg1 *proto.ActiveProfileRemove) (*proto.Reply, error) {
 	m.ctrl.T.Helper()
@@ -122,15 +122,24 @@ func configureIpvlanNamespace(netns ns.NetNS, mv *netlink.IPVlan, in *pb.AddRequ
 	return ipList, contMac, err
 }
 
+// Removes the route set up by setupRouting
+func teardownRouting(ipList []netlink.Addr) error {
+	infraHostLink, err := linkByName(types.InfraHost)
+	if err != nil {
+		return err
+	}
+	return routeDel(&netlink.Route{LinkIndex: infraHostLink.Attrs().Index, Dst: ipList[0].IPNet, Scope: netlink.SCOPE_LINK})
+}
+
 func ReleaseIpvlanNetwork(in *pb.DelRequest) error {
-	var podIp netlink.Addr
+	logger := log.WithField("func", "ReleaseIpvlanNetwork").WithField("pkg", "netconf")
+	var ipList []netlink.Addr
 	err := withNetNSPath(in.GetNetns(), func(_ ns.NetNS) error {
 		// don't return an error if the device is already removed
 		link, err := linkByName(in.GetInterfaceName())
 		if err == nil {
-			al, err := addrList(link, netlink.FAMILY_V4)
-			if err == nil || len(al) > 0 {
-				podIp = al[0]
+			if al, err := addrList(link, netlink.FAMILY_V4); err == nil {
+				ipList = al
 			}
 		}
 		if err := delLinkByName(in.GetInterfaceName()); err != nil {
@@ -141,11 +150,10 @@ func ReleaseIpvlanNetwork(in *pb.DelRequest) error {
 		return nil
 	})
 	// delete route on host
-	if podIp.IPNet != nil {
-		infraHost, err := linkByName(types.InfraHost)
-		if err == nil {
+	if len(ipList) > 0 && ipList[0].IPNet != nil {
+		if routeErr := teardownRouting(ipList); routeErr != nil {
 			// ignore errors
-			_ = routeDel(&netlink.Route{LinkIndex: infraHost.Attrs().Index, Dst: podIp.IPNet, Scope: netlink.SCOPE_LINK})
+			logger.WithError(routeErr).Warnf("Failed to delete the host route to %s", ipList[0].IPNet)
 		}
 	}
 
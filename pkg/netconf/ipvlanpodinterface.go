@@ -16,22 +16,44 @@ package netconf
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 
+	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
 	pb "github.com/ipdk-io/k8s-infra-offload/proto"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"github.com/vishvananda/netlink"
 )
 
+var ipvlanModes = map[string]netlink.IPVlanMode{
+	types.IPVlanModeL2:  netlink.IPVLAN_MODE_L2,
+	types.IPVlanModeL3:  netlink.IPVLAN_MODE_L3,
+	types.IPVlanModeL3S: netlink.IPVLAN_MODE_L3S,
+}
+
+// The pod interfaces are ipvlan slaves of the node interface, they share
+// its mac address. What each pod got is kept in the interface cache, the
+// pod interfaces are created and released concurrently.
 type ipvlanPodInterface struct {
 	log    *logrus.Entry
 	master string
-	podMac string
 	mode   netlink.IPVlanMode
 }
 
 func NewIpvlanPodInterface(log *logrus.Entry) (types.PodInterface, error) {
-	return &ipvlanPodInterface{log: log, master: types.NodeInterfaceName, mode: netlink.IPVLAN_MODE_L3}, nil
+	modeName := viper.GetString("ipvlanMode")
+	if modeName == "" {
+		modeName = types.IPVlanModeL3
+	}
+	mode, ok := ipvlanModes[modeName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported ipvlan mode %s", modeName)
+	}
+	log.Infof("Using ipvlan mode %s", modeName)
+	return &ipvlanPodInterface{log: log, master: types.NodeInterfaceName, mode: mode}, nil
 }
 
 func (p *ipvlanPodInterface) CreatePodInterface(in *pb.AddRequest) (*types.InterfaceInfo, error) {
@@ -41,14 +63,26 @@ func (p *ipvlanPodInterface) CreatePodInterface(in *pb.AddRequest) (*types.Inter
 		p.log.WithError(err).Error("failed to configure network interface")
 		return nil, err
 	}
-	p.podMac = contMac
-	p.log.Infof("host interface name %s mac %s", p.master, p.podMac)
+	p.log.Infof("host interface name %s mac %s", p.master, contMac)
 	intfInfo := &types.InterfaceInfo{MacAddr: contMac, InterfaceName: p.master}
+
+	refid := filepath.Base(in.Netns)
+	if err = saveInterfaceConf(utilsGetDataDirPath(types.IpvlanPodInterface), refid, in.InterfaceName, intfInfo); err != nil {
+		p.log.WithError(err).Error("storing cache failed")
+		return nil, err
+	}
 	return intfInfo, nil
 }
 
 func (p *ipvlanPodInterface) ReleasePodInterface(in *pb.DelRequest) error {
-	return ReleaseIpvlanNetwork(in)
+	if err := ReleaseIpvlanNetwork(in); err != nil {
+		return err
+	}
+	// remove cache, ignore error
+	refid := filepath.Base(in.Netns)
+	path := filepath.Join(utilsGetDataDirPath(types.IpvlanPodInterface), refid+"-"+in.InterfaceName)
+	_ = os.Remove(path)
+	return nil
 }
 
 func (p *ipvlanPodInterface) SetupNetwork(ctx context.Context, c pb.InfraAgentClient, intfInfo *types.InterfaceInfo, in *pb.AddRequest) (*pb.AddReply, error) {
@@ -56,6 +90,9 @@ func (p *ipvlanPodInterface) SetupNetwork(ctx context.Context, c pb.InfraAgentCl
 		AddRequest: in,
 		HostIfName: in.DesiredHostInterfaceName,
 	}
+	if intfInfo != nil {
+		request.MacAddr = intfInfo.MacAddr
+	}
 
 	// Note: We may need to call different InfraAgentClient method for IPVLAN with different payloads
 	out, err := c.CreateNetwork(ctx, request)
@@ -67,6 +104,55 @@ func (p *ipvlanPodInterface) SetupNetwork(ctx context.Context, c pb.InfraAgentCl
 }
 
 func (p *ipvlanPodInterface) ReleaseNetwork(ctx context.Context, c pb.InfraAgentClient, in *pb.DelRequest) (*pb.DelReply, error) {
-	// Stub implementation
-	return nil, nil
+	out := &pb.DelReply{
+		Successful: true,
+	}
+	// get interface config from cache
+	refid := filepath.Base(in.Netns)
+	conf, err := readInterfaceConf(utilsGetDataDirPath(types.IpvlanPodInterface), refid, in.InterfaceName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			p.log.WithError(err).Infof("interface config cache file for refid %s is not found", refid)
+			return out, nil // the pod interface was not created by the agent or is already released
+		}
+		p.log.WithError(err).Errorf("error trying to read interface config from cache file using refid %s", refid)
+		out.Successful = false
+		out.ErrorMessage = err.Error()
+		return out, err
+	}
+	var ip, ip6 string
+	// fetch interface IPs
+	err = withNetNSPath(in.Netns, func(_ ns.NetNS) error {
+		linkObj, err := linkByName(in.InterfaceName)
+		if err != nil {
+			p.log.WithError(err).Errorf("failed to find netlink device with name %s", in.InterfaceName)
+			return err
+		}
+		ip, ip6, err = linkAddresses(linkObj)
+		if err != nil || (ip == "" && ip6 == "") {
+			p.log.WithError(err).Error("Failed to fetch IP address from Pod interface or IP not set")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		_, ok := err.(ns.NSPathNotExistErr)
+		if ok {
+			// namespace already gone do not return error
+			return out, nil
+		}
+		p.log.WithError(err).Errorf("failed to enter Pod network nampespace with id: %s", in.Netns)
+		out.Successful = false
+		out.ErrorMessage = err.Error()
+		return out, err
+	}
+	request := &pb.DeleteNetworkRequest{
+		DelRequest: in,
+		HostIfName: conf.InterfaceName,
+		MacAddr:    conf.MacAddr,
+		Ipv4Addr:   ip,
+		Ipv6Addr:   ip6,
+	}
+
+	return c.DeleteNetwork(ctx, request)
 }
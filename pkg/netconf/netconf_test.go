@@ -3,9 +3,14 @@ package netconf
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/golang/mock/gomock"
@@ -17,10 +22,15 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"github.com/vishvananda/netlink"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/test/bufconn"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
 )
 
 const (
@@ -48,7 +58,7 @@ func TestUtils(t *testing.T) {
 var _ = BeforeSuite(func() {
 	listener = bufconn.Listen(bufSize)
 	utilsGetDataDirPath = func(t string) string {
-		return tempDir
+		return filepath.Join(tempDir, t)
 	}
 })
 
@@ -65,6 +75,7 @@ var _ = Describe("netconf", func() {
 		Expect(err).ShouldNot(HaveOccurred())
 		saveInterfaceConf = fakeSaveInterfaceConf
 		readInterfaceConf = fakeReadInterfaceConf
+		linkSubscribe = fakeLinkSubscribe
 	})
 
 	var _ = AfterEach(func() {
@@ -299,26 +310,26 @@ var _ = Describe("netconf", func() {
 
 	var _ = Context("sendSetupHostInterface() should", func() {
 		var _ = It("return no error", func() {
-			grpcDial = fakeGrpcDialErr
+			getManagerConn = fakeGetManagerConnErr
 			err := sendSetupHostInterface(&proto.SetupHostInterfaceRequest{})
 			Expect(err).To(HaveOccurred())
 		})
 		var _ = It("return error if request to manager returned error", func() {
-			grpcDial = fakeGrpcDial
+			getManagerConn = fakeGetManagerConn
 			newInfraAgentClient = newFakeClient
 			gomock.InOrder(mockClient.EXPECT().SetupHostInterface(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: false}, errors.New("Error")))
 			err := sendSetupHostInterface(&proto.SetupHostInterfaceRequest{})
 			Expect(err).To(HaveOccurred())
 		})
 		var _ = It("return error if request to is not successfull", func() {
-			grpcDial = fakeGrpcDial
+			getManagerConn = fakeGetManagerConn
 			newInfraAgentClient = newFakeClient
 			gomock.InOrder(mockClient.EXPECT().SetupHostInterface(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: false, ErrorMessage: "Fake error message"}, nil))
 			err := sendSetupHostInterface(&proto.SetupHostInterfaceRequest{})
 			Expect(err).To(HaveOccurred())
 		})
 		var _ = It("return no error", func() {
-			grpcDial = fakeGrpcDial
+			getManagerConn = fakeGetManagerConn
 			newInfraAgentClient = newFakeClient
 			gomock.InOrder(mockClient.EXPECT().SetupHostInterface(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil))
 			err := sendSetupHostInterface(&proto.SetupHostInterfaceRequest{})
@@ -1431,12 +1442,53 @@ var _ = Describe("netconf", func() {
 			err := ReleaseIpvlanNetwork(&proto.DelRequest{})
 			Expect(err).ToNot(HaveOccurred())
 		})
+		var _ = It("delete the host route to the pod", func() {
+			podNet := &net.IPNet{IP: net.ParseIP("10.10.10.1"), Mask: net.CIDRMask(32, 32)}
+			withNetNSPath = fakeWithNetNSPath
+			linkByName = fakeLinkByName
+			delLinkByName = fakeDelLinkByName
+			addrList = func(link netlink.Link, family int) ([]netlink.Addr, error) {
+				return []netlink.Addr{{IPNet: podNet}}, nil
+			}
+			var deleted []*netlink.Route
+			routeDel = func(route *netlink.Route) error {
+				deleted = append(deleted, route)
+				return nil
+			}
+			Expect(ReleaseIpvlanNetwork(&proto.DelRequest{})).To(Succeed())
+			Expect(deleted).To(HaveLen(1))
+			Expect(deleted[0].Dst).To(Equal(podNet))
+		})
+		var _ = It("not fail when the pod interface has no address", func() {
+			withNetNSPath = fakeWithNetNSPath
+			linkByName = fakeLinkByName
+			delLinkByName = fakeDelLinkByName
+			addrList = func(link netlink.Link, family int) ([]netlink.Addr, error) {
+				return nil, nil
+			}
+			Expect(ReleaseIpvlanNetwork(&proto.DelRequest{})).To(Succeed())
+		})
 	})
 	var _ = Context("NewIpvlanPodInterface() should", func() {
+		var _ = AfterEach(func() {
+			viper.Set("ipvlanMode", "")
+		})
 		var _ = It("return new ipvlanPodInterface", func() {
 			pi, err := NewIpvlanPodInterface(logrus.NewEntry(logrus.New()))
 			Expect(err).ToNot(HaveOccurred())
 			Expect(pi).ToNot(BeNil())
+			Expect(pi.(*ipvlanPodInterface).mode).To(Equal(netlink.IPVLAN_MODE_L3))
+		})
+		var _ = It("use the configured ipvlan mode", func() {
+			viper.Set("ipvlanMode", types.IPVlanModeL3S)
+			pi, err := NewIpvlanPodInterface(logrus.NewEntry(logrus.New()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pi.(*ipvlanPodInterface).mode).To(Equal(netlink.IPVLAN_MODE_L3S))
+		})
+		var _ = It("return error if the ipvlan mode is unsupported", func() {
+			viper.Set("ipvlanMode", "vepa")
+			_, err := NewIpvlanPodInterface(logrus.NewEntry(logrus.New()))
+			Expect(err).To(HaveOccurred())
 		})
 	})
 	var _ = Context("ReleasePodInterface() should", func() {
@@ -1451,14 +1503,43 @@ var _ = Describe("netconf", func() {
 		})
 	})
 	var _ = Context("ReleaseNetwork() should", func() {
-		var _ = It("return no error", func() {
+		var _ = It("delete the network of the pod with its address and mac", func() {
 			withNetNSPath = fakeWithNetNSPath
 			linkByName = fakeLinkByName
-			delLinkByName = fakeDelLinkByName
+			addrList = func(link netlink.Link, family int) ([]netlink.Addr, error) {
+				return []netlink.Addr{{IPNet: &net.IPNet{IP: net.ParseIP("10.10.10.1"), Mask: net.CIDRMask(32, 32)}}}, nil
+			}
+			readInterfaceConf = func(dataDir, refid, podIface string) (*types.InterfaceInfo, error) {
+				return &types.InterfaceInfo{InterfaceName: "eth0", MacAddr: "00:00:00:aa:aa:aa"}, nil
+			}
 			pi, err := NewIpvlanPodInterface(logrus.NewEntry(logrus.New()))
 			Expect(err).ToNot(HaveOccurred())
-			_, err = pi.ReleaseNetwork(context.TODO(), mockClient, &proto.DelRequest{})
+			gomock.InOrder(mockClient.EXPECT().DeleteNetwork(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, in *proto.DeleteNetworkRequest, _ ...grpc.CallOption) (*proto.DelReply, error) {
+					Expect(in.HostIfName).To(Equal("eth0"))
+					Expect(in.MacAddr).To(Equal("00:00:00:aa:aa:aa"))
+					Expect(in.Ipv4Addr).To(Equal("10.10.10.1/32"))
+					return &proto.DelReply{Successful: true}, nil
+				}))
+			out, err := pi.ReleaseNetwork(context.TODO(), mockClient, &proto.DelRequest{})
 			Expect(err).ToNot(HaveOccurred())
+			Expect(out.Successful).To(BeTrue())
+		})
+		var _ = It("return no error if the pod interface is not in the cache", func() {
+			readInterfaceConf = fakeReadInterfaceConfNotExist
+			pi, err := NewIpvlanPodInterface(logrus.NewEntry(logrus.New()))
+			Expect(err).ToNot(HaveOccurred())
+			out, err := pi.ReleaseNetwork(context.TODO(), mockClient, &proto.DelRequest{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out.Successful).To(BeTrue())
+		})
+		var _ = It("return error if cannot read interface config", func() {
+			readInterfaceConf = fakeReadInterfaceConfErr
+			pi, err := NewIpvlanPodInterface(logrus.NewEntry(logrus.New()))
+			Expect(err).ToNot(HaveOccurred())
+			out, err := pi.ReleaseNetwork(context.TODO(), mockClient, &proto.DelRequest{})
+			Expect(err).To(HaveOccurred())
+			Expect(out.Successful).To(BeFalse())
 		})
 	})
 	var _ = Context("CreatePodInterface() should", func() {
@@ -1501,6 +1582,28 @@ var _ = Describe("netconf", func() {
 			_, err = pi.CreatePodInterface(request)
 			Expect(err).ToNot(HaveOccurred())
 		})
+		var _ = It("return error if cannot store the interface config", func() {
+			linkByName = fakeLinkByName
+			getNS = fakeGetNS
+			linkAdd = fakeLinkSet
+			linkSetName = fakeLinkSetName
+			sysctlFunc = fakeSysctl
+			addrAdd = fakeAddrAddDel
+			linkSetUp = fakeLinkSet
+			ipAddRoute = fakeIPAddRoute
+			addrList = fakeAddrList
+			routeListFiltered = fakeRouteListFilteredExisting
+			routeAdd = fakeRouteHandle
+			saveInterfaceConf = fakeSaveInterfaceConfErr
+			pi, err := NewIpvlanPodInterface(logrus.NewEntry(logrus.New()))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = pi.CreatePodInterface(&proto.AddRequest{
+				DesiredHostInterfaceName: "dummyDesired",
+				Settings:                 &proto.ContainerSettings{},
+			})
+			Expect(err).To(HaveOccurred())
+		})
 	})
 	var _ = Context("SetupNetwork() should", func() {
 		var _ = It("return error if cannot create network", func() {
@@ -1553,6 +1656,611 @@ var _ = Describe("netconf", func() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+	var _ = Context("tapPodInterface lifecycle", func() {
+		var (
+			links    map[string]netlink.Link
+			deleted  []string
+			gnmiErr  error
+			newTapPi = func(count int) *tapPodInterface {
+				viper.Set("tapCount", count)
+				intf, err := NewTapPodInterface(logrus.NewEntry(logrus.New()))
+				Expect(err).ToNot(HaveOccurred())
+				return intf.(*tapPodInterface)
+			}
+		)
+
+		var _ = BeforeEach(func() {
+			links = map[string]netlink.Link{}
+			deleted = nil
+			gnmiErr = nil
+			viper.Set("tapPrefix", types.TapInterfacePrefix)
+			linkByName = func(name string) (netlink.Link, error) {
+				if link, ok := links[name]; ok {
+					return link, nil
+				}
+				return nil, netlink.LinkNotFoundError{}
+			}
+			// The target brings up the host side of the ports it creates
+			runGnmiCliFunc = func(args ...string) error {
+				Expect(args).To(HaveLen(2))
+				name := strings.TrimPrefix(strings.Split(args[1], ",")[1], "name:")
+				switch args[0] {
+				case "set":
+					Expect(args[1]).To(HaveSuffix("port-type:TAP"))
+					if gnmiErr != nil {
+						return gnmiErr
+					}
+					links[name] = &netlink.Tuntap{LinkAttrs: netlink.LinkAttrs{Name: name}}
+				case "del":
+					deleted = append(deleted, name)
+					delete(links, name)
+				}
+				return nil
+			}
+			getTapInterfaces = func(prefix string) ([]*types.InterfaceInfo, error) {
+				var intfs []*types.InterfaceInfo
+				for name := range links {
+					intfs = append(intfs, &types.InterfaceInfo{InterfaceName: name})
+				}
+				sort.Slice(intfs, func(i, j int) bool { return intfs[i].InterfaceName < intfs[j].InterfaceName })
+				return intfs, nil
+			}
+			readInterfaceConf = fakeReadInterfaceConfErr
+			getHostIPfromPodCIDRFunc = fakeGetHostIPfromPodCIDR
+			configureHostInterfaceFunc = fakeConfigureHostInterface
+			sendSetupHostInterfaceFunc = fakeSendSetupHostInterface
+			setHostInterfaceInPodNetnsFunc = fakeSetHostInterfaceInPodNetns
+			createTapPortsFunc = createTapPorts
+			deleteTapPortFunc = deleteTapPort
+		})
+
+		var _ = AfterEach(func() {
+			viper.Set("tapCount", 0)
+			runGnmiCliFunc = runGnmiCli
+		})
+
+		var _ = It("create the missing TAP ports only", func() {
+			links["P4TAP_1"] = &netlink.Tuntap{LinkAttrs: netlink.LinkAttrs{Name: "P4TAP_1"}}
+			created, err := createTapPorts("P4TAP_", 4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created).To(Equal([]string{"P4TAP_0", "P4TAP_2", "P4TAP_3"}))
+			Expect(links).To(HaveLen(4))
+		})
+		var _ = It("return error if the TAP interfaces cannot be looked up", func() {
+			linkByName = fakeLinkByNameErr
+			_, err := createTapPorts("P4TAP_", 4)
+			Expect(err).To(HaveOccurred())
+		})
+		var _ = It("return error if a TAP port cannot be created", func() {
+			gnmiErr = errors.New("Fake error")
+			_, err := createTapPorts("P4TAP_", 4)
+			Expect(err).To(HaveOccurred())
+		})
+		var _ = It("not create any TAP port without their count", func() {
+			links["P4TAP_0"] = &netlink.Tuntap{LinkAttrs: netlink.LinkAttrs{Name: "P4TAP_0"}}
+			links["P4TAP_1"] = &netlink.Tuntap{LinkAttrs: netlink.LinkAttrs{Name: "P4TAP_1"}}
+			gnmiErr = errors.New("Fake error")
+			pi := newTapPi(0)
+			_, err := pi.CreatePodInterface(&proto.AddRequest{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pi.Close()).To(Succeed())
+			Expect(deleted).To(BeEmpty())
+		})
+		var _ = It("create the pool of the given count", func() {
+			pi := newTapPi(4)
+			Expect(links).To(HaveLen(4))
+			inUse, total := pi.pool.Usage()
+			Expect(inUse).To(Equal(1))
+			Expect(total).To(Equal(4))
+		})
+		var _ = It("not grow the pool once it is used up", func() {
+			pi := newTapPi(2)
+			_, err := pi.CreatePodInterface(&proto.AddRequest{})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = pi.CreatePodInterface(&proto.AddRequest{})
+			Expect(err).To(HaveOccurred())
+			Expect(links).To(HaveLen(2))
+		})
+		var _ = It("delete the created TAP ports if the pool cannot be created", func() {
+			links["P4TAP_0"] = &netlink.Tuntap{LinkAttrs: netlink.LinkAttrs{Name: "P4TAP_0"}}
+			viper.Set("tapCount", 4)
+			runGnmiCli := runGnmiCliFunc
+			runGnmiCliFunc = func(args ...string) error {
+				if args[0] == "set" && strings.Contains(args[1], "P4TAP_3") {
+					return errors.New("Fake error")
+				}
+				return runGnmiCli(args...)
+			}
+			_, err := NewTapPodInterface(logrus.NewEntry(logrus.New()))
+			Expect(err).To(HaveOccurred())
+			Expect(deleted).To(ConsistOf("P4TAP_1", "P4TAP_2"))
+			Expect(links).To(HaveLen(1))
+		})
+		var _ = It("keep the TAP ports it created for its next start", func() {
+			links["P4TAP_0"] = &netlink.Tuntap{LinkAttrs: netlink.LinkAttrs{Name: "P4TAP_0"}}
+			pi := newTapPi(4)
+			Expect(pi.Close()).To(Succeed())
+			Expect(deleted).To(BeEmpty())
+			Expect(links).To(HaveLen(4))
+
+			created, err := createTapPorts("P4TAP_", 4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created).To(BeEmpty())
+		})
+	})
+	var _ = Context("poolAuditor", func() {
+		var (
+			p        pool.ResourcePool
+			auditor  *poolAuditor
+			netns    map[string]bool
+			moved    []string
+			nodeName string
+			now      = time.Now()
+			allocate = func(netnsPath, podName string, age time.Duration) *pool.Resource {
+				res, err := p.Allocate(&pool.Allocation{
+					Netns:        netnsPath,
+					PodInterface: "eth0",
+					PodNamespace: "default",
+					PodName:      podName,
+					Time:         now.Add(-age),
+				})
+				Expect(err).ToNot(HaveOccurred())
+				return res
+			}
+			nodePod = func(name string) *v1.Pod {
+				return &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+					Spec:       v1.PodSpec{NodeName: "node1"},
+				}
+			}
+		)
+
+		var _ = BeforeEach(func() {
+			p = pool.NewResourcePool([]*types.InterfaceInfo{
+				{InterfaceName: "P4TAP_0"},
+				{InterfaceName: "P4TAP_1"},
+				{InterfaceName: "P4TAP_2"},
+			}, utilsGetDataDirPath(types.TapInterface))
+			auditor = &poolAuditor{
+				log:     logrus.NewEntry(logrus.New()),
+				pool:    p,
+				dataDir: utilsGetDataDirPath(types.TapInterface),
+				period:  time.Minute,
+			}
+			netns = map[string]bool{}
+			moved = nil
+			nodeName = types.NodeName
+			types.NodeName = "node1"
+			statNetns = func(name string) (os.FileInfo, error) {
+				if netns[name] {
+					return nil, nil
+				}
+				return nil, os.ErrNotExist
+			}
+			timeNow = func() time.Time { return now }
+			movePodInterfaceToHostNetnsFunc = func(netNSPath, interfaceName string, ifInfo *types.InterfaceInfo) error {
+				moved = append(moved, ifInfo.InterfaceName)
+				return nil
+			}
+			linkByName = fakeLinkByName
+			getK8sClientFunc = func() (kubernetes.Interface, error) {
+				return k8sfake.NewSimpleClientset(nodePod("pod1")), nil
+			}
+		})
+
+		var _ = AfterEach(func() {
+			types.NodeName = nodeName
+			statNetns = os.Stat
+			timeNow = time.Now
+		})
+
+		var _ = It("record the pod the resource is allocated to", func() {
+			alloc := podAllocation(&proto.AddRequest{
+				Netns:         "/var/run/netns/cni-1",
+				InterfaceName: "eth0",
+				Workload:      &proto.WorkloadIDs{Namespace: "default", Pod: "pod1", Endpoint: "eth0"},
+			})
+			Expect(*alloc).To(Equal(pool.Allocation{
+				Netns:        "/var/run/netns/cni-1",
+				PodInterface: "eth0",
+				PodNamespace: "default",
+				PodName:      "pod1",
+				Endpoint:     "eth0",
+				Time:         now,
+			}))
+		})
+		var _ = It("not be started without an audit period", func() {
+			viper.Set("poolAuditPeriod", 0)
+			Expect(startPoolAuditor(logrus.NewEntry(logrus.New()), p, "")).To(BeNil())
+		})
+		var _ = It("keep the resources of the pods on the node", func() {
+			netns["/var/run/netns/cni-1"] = true
+			allocate("/var/run/netns/cni-1", "pod1", time.Hour)
+			Expect(auditor.audit()).To(Equal(0))
+			Expect(p.Allocations()).To(HaveLen(1))
+		})
+		var _ = It("reclaim the resources of a network namespace which is gone", func() {
+			netns["/var/run/netns/cni-1"] = true
+			allocate("/var/run/netns/cni-1", "pod1", time.Hour)
+			leaked := allocate("/var/run/netns/cni-2", "pod1", time.Hour)
+			Expect(auditor.audit()).To(Equal(1))
+			Expect(moved).To(BeEmpty())
+			allocations := p.Allocations()
+			Expect(allocations).To(HaveLen(1))
+			Expect(allocations[0].InterfaceInfo.InterfaceName).ToNot(Equal(leaked.InterfaceInfo.InterfaceName))
+		})
+		var _ = It("reclaim the resources of a pod gone from the node, moving them back to the host", func() {
+			netns["/var/run/netns/cni-2"] = true
+			leaked := allocate("/var/run/netns/cni-2", "pod2", time.Hour)
+			Expect(auditor.audit()).To(Equal(1))
+			Expect(moved).To(ConsistOf(leaked.InterfaceInfo.InterfaceName))
+			Expect(p.Allocations()).To(BeEmpty())
+		})
+		var _ = It("give a pod just set up an audit period to be listed", func() {
+			netns["/var/run/netns/cni-2"] = true
+			allocate("/var/run/netns/cni-2", "pod2", time.Second)
+			Expect(auditor.audit()).To(Equal(0))
+		})
+		var _ = It("audit the network namespaces only when the pods cannot be listed", func() {
+			getK8sClientFunc = func() (kubernetes.Interface, error) {
+				return nil, errors.New("Fake error on getK8sClient")
+			}
+			netns["/var/run/netns/cni-2"] = true
+			allocate("/var/run/netns/cni-2", "pod2", time.Hour)
+			allocate("/var/run/netns/cni-3", "pod3", time.Hour)
+			Expect(auditor.audit()).To(Equal(1))
+		})
+		var _ = It("keep the resource which cannot be moved back to the host", func() {
+			movePodInterfaceToHostNetnsFunc = fakeMovePodInterfaceToHostNetnsErr
+			netns["/var/run/netns/cni-2"] = true
+			allocate("/var/run/netns/cni-2", "pod2", time.Hour)
+			Expect(auditor.audit()).To(Equal(0))
+			Expect(p.Allocations()).To(HaveLen(1))
+		})
+		var _ = It("remove the reclaimed interface which does not exist anymore", func() {
+			linkByName = fakeLinkByNameErr
+			allocate("/var/run/netns/cni-2", "pod2", time.Hour)
+			Expect(auditor.audit()).To(Equal(1))
+			_, total := p.Usage()
+			Expect(total).To(Equal(2))
+		})
+	})
+	var _ = Context("poolWatcher", func() {
+		var (
+			p       pool.ResourcePool
+			watcher *poolWatcher
+			vfs     []*types.InterfaceInfo
+			present []string
+			vf      = func(i int) *types.InterfaceInfo {
+				return &types.InterfaceInfo{
+					InterfaceName: fmt.Sprintf("ens801f0v%d", i),
+					PciAddr:       fmt.Sprintf("0000:af:00.%d", i),
+					VfID:          i,
+				}
+			}
+			poolNames = func() []string {
+				var names []string
+				for _, r := range p.Resources() {
+					names = append(names, r.InterfaceInfo.InterfaceName)
+				}
+				return names
+			}
+		)
+
+		var _ = BeforeEach(func() {
+			vfs = []*types.InterfaceInfo{vf(0), vf(1)}
+			present = []string{vf(0).PciAddr, vf(1).PciAddr}
+			p = pool.NewResourcePool([]*types.InterfaceInfo{vf(0), vf(1)}, utilsGetDataDirPath(types.SriovPodInterface))
+			watcher = &poolWatcher{
+				log:  logrus.NewEntry(logrus.New()),
+				pool: p,
+				done: make(chan struct{}),
+			}
+			// the VFs moved into the pods are left out of the list
+			getVFList = func(pf string, prefix string) ([]*types.InterfaceInfo, error) {
+				return vfs, nil
+			}
+			getVFPciAddrsFunc = func(pf string, prefix string) ([]string, error) {
+				return present, nil
+			}
+		})
+
+		var _ = It("not be started when the link updates cannot be received", func() {
+			linkSubscribe = func(ch chan<- netlink.LinkUpdate, done <-chan struct{}) error {
+				return errors.New("Fake error on linkSubscribe")
+			}
+			w := startPoolWatcher(logrus.NewEntry(logrus.New()), p)
+			Expect(w).To(BeNil())
+			w.stop()
+		})
+		var _ = It("add the VFs created since the last sync", func() {
+			vfs = append(vfs, vf(2))
+			present = append(present, vf(2).PciAddr)
+			watcher.sync()
+			Expect(poolNames()).To(ConsistOf("ens801f0v0", "ens801f0v1", "ens801f0v2"))
+		})
+		var _ = It("remove the free VFs which are gone", func() {
+			vfs = vfs[:1]
+			present = present[:1]
+			watcher.sync()
+			Expect(poolNames()).To(ConsistOf("ens801f0v0"))
+		})
+		var _ = It("keep the VFs moved into the pods", func() {
+			res, err := p.Get()
+			Expect(err).ToNot(HaveOccurred())
+			vfs = []*types.InterfaceInfo{vf(1)}
+			watcher.sync()
+			Expect(poolNames()).To(ConsistOf("ens801f0v0", "ens801f0v1"))
+			for _, r := range p.Resources() {
+				Expect(r.InUse).To(Equal(r.InterfaceInfo.InterfaceName == res.InterfaceInfo.InterfaceName))
+				Expect(r.Degraded).To(BeFalse())
+			}
+		})
+		var _ = It("degrade the VFs gone while in use until they are released", func() {
+			res, err := p.Get()
+			Expect(err).ToNot(HaveOccurred())
+			vfs = []*types.InterfaceInfo{vf(1)}
+			present = present[1:]
+			watcher.sync()
+			resources := p.Resources()
+			Expect(resources).To(HaveLen(2))
+			Expect(resources[0].Degraded).To(BeTrue())
+
+			p.Release(res.InterfaceInfo.InterfaceName)
+			Expect(poolNames()).To(ConsistOf("ens801f0v1"))
+		})
+		var _ = It("release the degraded VF which cannot be moved back to the host", func() {
+			_, err := p.Get()
+			Expect(err).ToNot(HaveOccurred())
+			vfs = []*types.InterfaceInfo{vf(1)}
+			present = present[1:]
+			watcher.sync()
+			readInterfaceConf = func(dataDir, refid, podIface string) (*types.InterfaceInfo, error) {
+				return vf(0), nil
+			}
+			movePodInterfaceToHostNetnsFunc = fakeMovePodInterfaceToHostNetnsErr
+			pi := &sriovPodInterface{log: logrus.NewEntry(logrus.New()), pool: p}
+			Expect(pi.ReleasePodInterface(&proto.DelRequest{Netns: "/var/run/netns/cni-1", InterfaceName: "eth0"})).To(Succeed())
+			Expect(poolNames()).To(ConsistOf("ens801f0v1"))
+
+			_, err = p.Get()
+			Expect(err).ToNot(HaveOccurred())
+			readInterfaceConf = func(dataDir, refid, podIface string) (*types.InterfaceInfo, error) {
+				return vf(1), nil
+			}
+			Expect(pi.ReleasePodInterface(&proto.DelRequest{Netns: "/var/run/netns/cni-1", InterfaceName: "eth0"})).ToNot(Succeed())
+		})
+		var _ = It("sync the pool once the link updates settle", func() {
+			poolSyncDelay = 10 * time.Millisecond
+			defer func() { poolSyncDelay = time.Second }()
+			updates := make(chan netlink.LinkUpdate)
+			go watcher.run(updates)
+			defer watcher.stop()
+
+			vfs = append(vfs, vf(2), vf(3))
+			present = append(present, vf(2).PciAddr, vf(3).PciAddr)
+			updates <- netlink.LinkUpdate{}
+			updates <- netlink.LinkUpdate{}
+			Eventually(poolNames).Should(HaveLen(4))
+		})
+		var _ = It("publish the size of the pool", func() {
+			_, err := p.Get()
+			Expect(err).ToNot(HaveOccurred())
+			publishPoolSizes(types.SriovPodInterface, p)
+			Expect(poolSizes.Get(types.SriovPodInterface).String()).To(MatchJSON(`{"total": 2, "inUse": 1, "degraded": 0}`))
+		})
+	})
+	var _ = Context("vethPodInterface", func() {
+		var (
+			links   map[string]netlink.Link
+			deleted []string
+			portMac = net.HardwareAddr{0, 0, 0, 0xaa, 0xaa, 0xaa}
+			peerMac = net.HardwareAddr{0, 0, 0, 0xbb, 0xbb, 0xbb}
+			newVeth = func(name string, mac net.HardwareAddr) netlink.Link {
+				return &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: name, HardwareAddr: mac}}
+			}
+			newVethPi = func() *vethPodInterface {
+				return &vethPodInterface{log: logrus.NewEntry(logrus.New())}
+			}
+		)
+
+		var _ = BeforeEach(func() {
+			links = map[string]netlink.Link{}
+			deleted = nil
+			linkByName = func(name string) (netlink.Link, error) {
+				if link, ok := links[name]; ok {
+					return link, nil
+				}
+				return nil, netlink.LinkNotFoundError{}
+			}
+			linkAdd = func(link netlink.Link) error {
+				veth := link.(*netlink.Veth)
+				links[veth.Name] = newVeth(veth.Name, portMac)
+				links[veth.PeerName] = newVeth(veth.PeerName, peerMac)
+				return nil
+			}
+			delLinkByName = func(name string) error {
+				deleted = append(deleted, name)
+				return nil
+			}
+			linkSetUp = fakeLinkSet
+			getHostIPfromPodCIDRFunc = fakeGetHostIPfromPodCIDR
+			configureHostInterfaceFunc = fakeConfigureHostInterface
+			sendSetupHostInterfaceFunc = fakeSendSetupHostInterface
+			setHostInterfaceInPodNetnsFunc = fakeSetHostInterfaceInPodNetns
+			createVethPairFunc = createVethPair
+			freeVethPortFunc = freeVethPort
+		})
+
+		var _ = It("be created by NewPodInterface", func() {
+			intf, err := NewPodInterface(types.VethPodInterface, logrus.NewEntry(logrus.New()))
+			Expect(err).ToNot(HaveOccurred())
+			_, ok := intf.(*vethPodInterface)
+			Expect(ok).To(BeTrue())
+		})
+		var _ = It("connect the host to the first port", func() {
+			sendSetupHostInterfaceFunc = func(request *proto.SetupHostInterfaceRequest) error {
+				Expect(request.IfName).To(Equal("P4VETH_0"))
+				Expect(request.MacAddr).To(Equal(peerMac.String()))
+				return nil
+			}
+			Expect(newVethPi().setup()).To(Succeed())
+			Expect(links).To(HaveKey("P4VETH_0"))
+			Expect(links).To(HaveKey(types.InfraHost))
+			Expect(types.NodeInfraHostInterfaceName).To(Equal(types.InfraHost))
+		})
+		var _ = It("keep the pair of the host left by the previous run", func() {
+			links[types.InfraHost] = newVeth(types.InfraHost, portMac)
+			linkAdd = fakeLinkSetErr
+			Expect(newVethPi().setup()).To(Succeed())
+		})
+		var _ = It("return error if the host cannot get an address", func() {
+			getHostIPfromPodCIDRFunc = fakeGetHostIPfromPodCIDRErr
+			Expect(newVethPi().setup()).ToNot(Succeed())
+		})
+		var _ = It("return error if the host interface cannot be registered", func() {
+			sendSetupHostInterfaceFunc = fakeSendSetupHostInterfaceErr
+			Expect(newVethPi().setup()).ToNot(Succeed())
+		})
+		var _ = It("give the pod the first free port", func() {
+			links["P4VETH_1"] = newVeth("P4VETH_1", portMac)
+			saveInterfaceConf = func(dataDir, refid, podIface string, conf *types.InterfaceInfo) error {
+				Expect(refid).To(Equal("cni-1"))
+				Expect(podIface).To(Equal("eth0"))
+				return nil
+			}
+			setHostInterfaceInPodNetnsFunc = func(in *proto.AddRequest, res *types.InterfaceInfo) error {
+				Expect(res.InterfaceName).To(Equal("P4VETH_2p"))
+				return nil
+			}
+			intfInfo, err := newVethPi().CreatePodInterface(&proto.AddRequest{
+				Netns:         "/var/run/netns/cni-1",
+				InterfaceName: "eth0",
+				Settings:      &proto.ContainerSettings{},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(intfInfo.InterfaceName).To(Equal("P4VETH_2"))
+			Expect(intfInfo.MacAddr).To(Equal(peerMac.String()))
+		})
+		var _ = It("delete the pair if it cannot be moved to the pod", func() {
+			setHostInterfaceInPodNetnsFunc = fakeSetHostInterfaceInPodNetnsErr
+			_, err := newVethPi().CreatePodInterface(&proto.AddRequest{Settings: &proto.ContainerSettings{}})
+			Expect(err).To(HaveOccurred())
+			Expect(deleted).To(Equal([]string{"P4VETH_1"}))
+		})
+		var _ = It("delete the pair if the cache cannot be saved", func() {
+			saveInterfaceConf = fakeSaveInterfaceConfErr
+			_, err := newVethPi().CreatePodInterface(&proto.AddRequest{Settings: &proto.ContainerSettings{}})
+			Expect(err).To(HaveOccurred())
+			Expect(deleted).To(Equal([]string{"P4VETH_1"}))
+		})
+		var _ = It("return error if the pair cannot be created", func() {
+			linkAdd = fakeLinkSetErr
+			_, err := newVethPi().CreatePodInterface(&proto.AddRequest{Settings: &proto.ContainerSettings{}})
+			Expect(err).To(HaveOccurred())
+		})
+		var _ = It("delete the pair if it cannot be set up", func() {
+			linkSetUp = fakeLinkSetErr
+			_, err := createVethPair("P4VETH_1", "P4VETH_1p", 1500)
+			Expect(err).To(HaveOccurred())
+			Expect(deleted).To(Equal([]string{"P4VETH_1"}))
+		})
+		var _ = It("return error if the ports cannot be listed", func() {
+			linkByName = fakeLinkByNameErr
+			_, err := freeVethPort(1)
+			Expect(err).To(HaveOccurred())
+		})
+		var _ = It("delete the pair of the released pod", func() {
+			readInterfaceConf = func(dataDir, refid, podIface string) (*types.InterfaceInfo, error) {
+				return &types.InterfaceInfo{InterfaceName: "P4VETH_1"}, nil
+			}
+			Expect(newVethPi().ReleasePodInterface(&proto.DelRequest{})).To(Succeed())
+			Expect(deleted).To(Equal([]string{"P4VETH_1"}))
+		})
+		var _ = It("return no error if the released pod is not in the cache", func() {
+			readInterfaceConf = fakeReadInterfaceConfNotExist
+			Expect(newVethPi().ReleasePodInterface(&proto.DelRequest{})).To(Succeed())
+			Expect(deleted).To(BeEmpty())
+		})
+		var _ = It("return error if the cache of the released pod cannot be read", func() {
+			readInterfaceConf = fakeReadInterfaceConfErr
+			Expect(newVethPi().ReleasePodInterface(&proto.DelRequest{})).ToNot(Succeed())
+		})
+		var _ = It("create the network of the pod on its port", func() {
+			gomock.InOrder(mockClient.EXPECT().CreateNetwork(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, in *proto.CreateNetworkRequest, _ ...grpc.CallOption) (*proto.AddReply, error) {
+					Expect(in.HostIfName).To(Equal("P4VETH_1"))
+					Expect(in.MacAddr).To(Equal(peerMac.String()))
+					return &proto.AddReply{Successful: true}, nil
+				}))
+			_, err := newVethPi().SetupNetwork(context.TODO(), mockClient, &types.InterfaceInfo{MacAddr: peerMac.String()},
+				&proto.AddRequest{DesiredHostInterfaceName: "P4VETH_1"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+		var _ = It("delete the network of the pod on its port", func() {
+			withNetNSPath = fakeWithNetNSPath
+			links["eth0"] = newVeth("eth0", peerMac)
+			addrList = func(link netlink.Link, family int) ([]netlink.Addr, error) {
+				return []netlink.Addr{{IPNet: &net.IPNet{IP: net.ParseIP("10.10.10.1"), Mask: net.CIDRMask(32, 32)}}}, nil
+			}
+			readInterfaceConf = func(dataDir, refid, podIface string) (*types.InterfaceInfo, error) {
+				return &types.InterfaceInfo{InterfaceName: "P4VETH_1", MacAddr: peerMac.String()}, nil
+			}
+			gomock.InOrder(mockClient.EXPECT().DeleteNetwork(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, in *proto.DeleteNetworkRequest, _ ...grpc.CallOption) (*proto.DelReply, error) {
+					Expect(in.HostIfName).To(Equal("P4VETH_1"))
+					Expect(in.MacAddr).To(Equal(peerMac.String()))
+					Expect(in.Ipv4Addr).To(Equal("10.10.10.1/32"))
+					return &proto.DelReply{Successful: true}, nil
+				}))
+			out, err := newVethPi().ReleaseNetwork(context.TODO(), mockClient, &proto.DelRequest{InterfaceName: "eth0"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out.Successful).To(BeTrue())
+		})
+		var _ = It("return error and not delete the network if the pod interface has no address", func() {
+			withNetNSPath = fakeWithNetNSPath
+			links["eth0"] = newVeth("eth0", peerMac)
+			addrList = func(link netlink.Link, family int) ([]netlink.Addr, error) {
+				return nil, nil
+			}
+			readInterfaceConf = func(dataDir, refid, podIface string) (*types.InterfaceInfo, error) {
+				return &types.InterfaceInfo{InterfaceName: "P4VETH_1", MacAddr: peerMac.String()}, nil
+			}
+			mockClient.EXPECT().DeleteNetwork(gomock.Any(), gomock.Any()).Times(0)
+			out, err := newVethPi().ReleaseNetwork(context.TODO(), mockClient, &proto.DelRequest{InterfaceName: "eth0"})
+			Expect(err).To(HaveOccurred())
+			Expect(out.Successful).To(BeFalse())
+		})
+		var _ = It("return no error if the network of a pod not in the cache is deleted", func() {
+			readInterfaceConf = fakeReadInterfaceConfNotExist
+			out, err := newVethPi().ReleaseNetwork(context.TODO(), mockClient, &proto.DelRequest{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out.Successful).To(BeTrue())
+		})
+	})
+	var _ = Context("linkAddresses() should", func() {
+		var _ = It("return the IPv4 and the global IPv6 address of the link", func() {
+			addrList = func(link netlink.Link, family int) ([]netlink.Addr, error) {
+				Expect(family).To(Equal(netlink.FAMILY_ALL))
+				var l []netlink.Addr
+				for _, a := range []string{"fe80::1/64", "10.10.10.1/32", "fd00::1/128"} {
+					addr, err := netlink.ParseAddr(a)
+					Expect(err).ToNot(HaveOccurred())
+					l = append(l, *addr)
+				}
+				return l, nil
+			}
+			ipv4, ipv6, err := linkAddresses(&fakeLink{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ipv4).To(Equal("10.10.10.1/32"))
+			Expect(ipv6).To(Equal("fd00::1/128"))
+		})
+		var _ = It("return error if the addresses cannot be listed", func() {
+			addrList = fakeAddrListErr
+			_, _, err := linkAddresses(&fakeLink{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
 
 type fakeLink struct{}
@@ -1732,6 +2440,10 @@ func fakeMovePodInterfaceToHostNetns(netNSPath, interfaceName string, ifInfo *ty
 	return nil
 }
 
+func fakeLinkSubscribe(ch chan<- netlink.LinkUpdate, done <-chan struct{}) error {
+	return nil
+}
+
 func fakeMovePodInterfaceToHostNetnsErr(netNSPath, interfaceName string, ifInfo *types.InterfaceInfo) error {
 	return errors.New("Fake error on movePodInterfaceToHostNetns")
 }
@@ -1842,6 +2554,38 @@ func (fp *fakePoolErr) Save(path string) error {
 	return nil
 }
 
+func (fp *fakePoolErr) Allocate(owner *pool.Allocation) (*pool.Resource, error) {
+	return nil, errors.New("Fake error on pool allocate")
+}
+
+func (fp *fakePoolErr) Allocations() []pool.Resource {
+	return nil
+}
+
+func (fp *fakePoolErr) Reclaim(res string, owner *pool.Allocation) bool {
+	return false
+}
+
+func (fp *fakePoolErr) Add(resources []*types.InterfaceInfo) int {
+	return 0
+}
+
+func (fp *fakePoolErr) Remove(res string) bool {
+	return false
+}
+
+func (fp *fakePoolErr) Degrade(res string) bool {
+	return false
+}
+
+func (fp *fakePoolErr) Resources() []pool.Resource {
+	return nil
+}
+
+func (fp *fakePoolErr) Usage() (int, int) {
+	return 0, 0
+}
+
 func fakeSysctl(name string, params ...string) (string, error) {
 	return "", nil
 }
@@ -1862,12 +2606,12 @@ func fakeSendSetupHostInterfaceErr(request *proto.SetupHostInterfaceRequest) err
 	return errors.New("Fake error on sendSetupHostInterface")
 }
 
-func fakeGrpcDial(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+func fakeGetManagerConn() (*grpc.ClientConn, error) {
 	return grpc.DialContext(context.TODO(), "", grpc.WithTransportCredentials(insecure.NewCredentials()))
 }
 
-func fakeGrpcDialErr(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
-	return nil, errors.New("Fake error on grpcDial")
+func fakeGetManagerConnErr() (*grpc.ClientConn, error) {
+	return nil, errors.New("Fake error on getManagerConn")
 }
 
 func newFakeClient(cc *grpc.ClientConn) proto.InfraAgentClient {
@@ -21,14 +21,13 @@ import (
 
 	"github.com/containernetworking/plugins/pkg/ip"
 	"github.com/containernetworking/plugins/pkg/ns"
+	managerclient "github.com/ipdk-io/k8s-infra-offload/pkg/manager_client"
 	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
 	"github.com/ipdk-io/k8s-infra-offload/pkg/utils"
 	pb "github.com/ipdk-io/k8s-infra-offload/proto"
 	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 const nonTargetIP = "0.0.0.0/0"
@@ -40,8 +39,8 @@ var (
 	configureRoutingFunc            = configureRouting
 	getCurrentNS                    = ns.GetCurrentNS
 	getIPFromIPAM                   = utils.GetIPFromIPAM
+	getManagerConn                  = managerclient.GetConn
 	getNS                           = ns.GetNS
-	grpcDial                        = grpc.Dial
 	ipAddRoute                      = ip.AddRoute
 	linkByName                      = netlink.LinkByName
 	linkSetDown                     = netlink.LinkSetDown
@@ -79,12 +78,13 @@ func setupContainerRoutes(link netlink.Link, gw net.IP, containerRoutes []string
 			continue
 		}
 		rn := rip.IPNet
+		routeGw := gw
 		if rn.IP.To4() == nil {
-			logger.WithField("route", rip).Debug("Skipping non-IPv4 route")
-			continue
+			// The IPv6 routes go through the link, the gateway being IPv4
+			routeGw = nil
 		}
-		if err = ipAddRoute(rn, gw, link); err != nil {
-			return fmt.Errorf("failed to add IPv4 route for %v via %v: %v", r, gw, err)
+		if err = ipAddRoute(rn, routeGw, link); err != nil {
+			return fmt.Errorf("failed to add route for %v via %v: %v", r, routeGw, err)
 		}
 	}
 	return nil
@@ -99,6 +99,8 @@ func setLinkAddress(link netlink.Link, containerIps []*pb.IPConfig) error {
 		}
 		if addr.IP.To4() != nil {
 			addr.Mask = net.CIDRMask(32, 32)
+		} else {
+			addr.Mask = net.CIDRMask(128, 128)
 		}
 
 		logger.Infof("Address to set %+v", addr)
@@ -110,6 +112,29 @@ func setLinkAddress(link netlink.Link, containerIps []*pb.IPConfig) error {
 	return nil
 }
 
+// Returns the IPv4 and the global IPv6 address of the link, in CIDR
+// notation. Either is empty when the link has no address of the family.
+func linkAddresses(link netlink.Link) (string, string, error) {
+	l, err := addrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return "", "", err
+	}
+	var ipv4, ipv6 string
+	for _, a := range l {
+		switch {
+		case a.IP.To4() != nil:
+			if ipv4 == "" {
+				ipv4 = a.IPNet.String()
+			}
+		case a.IP.IsGlobalUnicast():
+			if ipv6 == "" {
+				ipv6 = a.IPNet.String()
+			}
+		}
+	}
+	return ipv4, ipv6, nil
+}
+
 func setupPodRoute(link netlink.Link, containerRoutes []string, gateway string) error {
 	// we need to setup default route via eth0
 	gw, err := netlink.ParseAddr(gateway)
@@ -190,20 +215,14 @@ func configureRouting(link netlink.Link, log *logrus.Entry) error {
 }
 
 func sendSetupHostInterface(request *pb.SetupHostInterfaceRequest) error {
-	managerAddr := fmt.Sprintf("%s:%s", types.InfraManagerAddr, types.InfraManagerPort)
-	conn, err := grpcDial(managerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := getManagerConn()
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
-	// defer func() {
-	// 	if conn == nil {
-	// 		return
-	// 	}
-	// 	_ = conn.Close()
-	// }()
 	c := newInfraAgentClient(conn)
-	r, err := c.SetupHostInterface(context.TODO(), request)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	r, err := c.SetupHostInterface(ctx, request)
 	if err != nil {
 		return err
 	}
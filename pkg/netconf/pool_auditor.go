@@ -0,0 +1,190 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netconf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ipdk-io/k8s-infra-offload/pkg/pool"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/utils"
+	pb "github.com/ipdk-io/k8s-infra-offload/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	getK8sClientFunc = getK8sClient
+	statNetns        = os.Stat
+	timeNow          = time.Now
+)
+
+func getK8sClient() (kubernetes.Interface, error) {
+	config, err := utils.GetK8sConfig()
+	if err != nil {
+		return nil, err
+	}
+	return utils.GetK8sClient(config)
+}
+
+// The record of the pod the resource of the pool is allocated to
+func podAllocation(in *pb.AddRequest) *pool.Allocation {
+	return &pool.Allocation{
+		Netns:        in.Netns,
+		PodInterface: in.InterfaceName,
+		PodNamespace: in.GetWorkload().GetNamespace(),
+		PodName:      in.GetWorkload().GetPod(),
+		Endpoint:     in.GetWorkload().GetEndpoint(),
+		Time:         timeNow(),
+	}
+}
+
+// poolAuditor periodically reclaims the resources of a pool which are
+// still allocated to pods gone from the node, when the deletion of the
+// pod never reached the agent
+type poolAuditor struct {
+	log     *logrus.Entry
+	pool    pool.ResourcePool
+	dataDir string
+	period  time.Duration
+	client  kubernetes.Interface
+	done    chan struct{}
+}
+
+// startPoolAuditor starts auditing the pool, unless the audit period is
+// not set
+func startPoolAuditor(log *logrus.Entry, p pool.ResourcePool, dataDir string) *poolAuditor {
+	period := time.Duration(viper.GetInt("poolAuditPeriod")) * time.Second
+	if period <= 0 {
+		return nil
+	}
+	a := &poolAuditor{
+		log:     log.WithField("func", "poolAuditor"),
+		pool:    p,
+		dataDir: dataDir,
+		period:  period,
+		done:    make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *poolAuditor) run() {
+	ticker := time.NewTicker(a.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+			a.audit()
+		}
+	}
+}
+
+func (a *poolAuditor) stop() {
+	if a != nil {
+		close(a.done)
+	}
+}
+
+// Returns the pods of the node, nil when they cannot be listed
+func (a *poolAuditor) nodePods() map[string]bool {
+	if types.NodeName == "" {
+		return nil
+	}
+	if a.client == nil {
+		client, err := getK8sClientFunc()
+		if err != nil {
+			a.log.WithError(err).Warn("Cannot get k8s client, auditing the network namespaces only")
+			return nil
+		}
+		a.client = client
+	}
+	pods, err := a.client.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "spec.nodeName=" + types.NodeName})
+	if err != nil {
+		a.log.WithError(err).Warn("Cannot list the pods of the node, auditing the network namespaces only")
+		return nil
+	}
+	res := make(map[string]bool, len(pods.Items))
+	for _, pod := range pods.Items {
+		res[pod.Namespace+"/"+pod.Name] = true
+	}
+	return res
+}
+
+// audit reclaims the leaked resources and returns how many of them
+func (a *poolAuditor) audit() int {
+	allocations := a.pool.Allocations()
+	if len(allocations) == 0 {
+		return 0
+	}
+	pods := a.nodePods()
+	reclaimed := 0
+	for _, res := range allocations {
+		if a.leaked(res.Allocation, pods) && a.reclaim(res) {
+			reclaimed++
+		}
+	}
+	if reclaimed > 0 {
+		inUse, total := a.pool.Usage()
+		a.log.Infof("Reclaimed %d leaked interfaces, %d of %d in use", reclaimed, inUse, total)
+	}
+	return reclaimed
+}
+
+// A resource is leaked once the network namespace of its pod is gone,
+// or once its pod is not on the node anymore. A pod just set up may not
+// be listed yet, it is given an audit period.
+func (a *poolAuditor) leaked(alloc *pool.Allocation, pods map[string]bool) bool {
+	if _, err := statNetns(alloc.Netns); os.IsNotExist(err) {
+		return true
+	}
+	if pods == nil || alloc.PodName == "" {
+		return false
+	}
+	return !pods[alloc.PodNamespace+"/"+alloc.PodName] && timeNow().Sub(alloc.Time) > a.period
+}
+
+func (a *poolAuditor) reclaim(res pool.Resource) bool {
+	alloc := res.Allocation
+	name := res.InterfaceInfo.InterfaceName
+	if _, err := statNetns(alloc.Netns); err == nil {
+		// the interface has to be back on the host to be allocated again
+		if err := movePodInterfaceToHostNetnsFunc(alloc.Netns, alloc.PodInterface, res.InterfaceInfo); err != nil {
+			a.log.WithError(err).Warnf("Cannot move interface %s leaked by pod %s/%s back to the host", name, alloc.PodNamespace, alloc.PodName)
+			return false
+		}
+	}
+	// the pod may have been deleted meanwhile, or another one got it
+	if !a.pool.Reclaim(name, alloc) {
+		return false
+	}
+	_ = os.Remove(filepath.Join(a.dataDir, filepath.Base(alloc.Netns)+"-"+alloc.PodInterface))
+	a.log.Warnf("Reclaimed interface %s leaked by pod %s/%s in %s", name, alloc.PodNamespace, alloc.PodName, alloc.Netns)
+
+	// a virtual interface goes away with the network namespace it is in
+	if _, err := linkByName(name); err != nil {
+		a.pool.Remove(name)
+		a.log.Warnf("Interface %s does not exist anymore, it is removed from the pool", name)
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netconf
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/ipdk-io/k8s-infra-offload/pkg/pool"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+var (
+	linkSubscribe     = netlink.LinkSubscribe
+	getVFPciAddrsFunc = utils.GetVFPciAddrs
+	// The link updates come in bursts, like when sriov_numvfs is
+	// changed, the pool is synced once they settle
+	poolSyncDelay = time.Second
+)
+
+var (
+	// The sizes of the resource pools, by pod interface type
+	poolSizes = expvar.NewMap("resourcePools")
+	// The resources added to, removed from and degraded in the pools
+	poolChanges = expvar.NewMap("resourcePoolChanges")
+)
+
+// publishPoolSizes exposes the size of the pool, the health server
+// serves it at /pools
+func publishPoolSizes(name string, p pool.ResourcePool) {
+	poolSizes.Set(name, expvar.Func(func() interface{} {
+		sizes := map[string]int{"total": 0, "inUse": 0, "degraded": 0}
+		for _, r := range p.Resources() {
+			sizes["total"]++
+			if r.InUse {
+				sizes["inUse"]++
+			}
+			if r.Degraded {
+				sizes["degraded"]++
+			}
+		}
+		return sizes
+	}))
+}
+
+// poolWatcher syncs the pool of VFs with the ones of the node interface
+// whenever links come and go, as VFs are created and removed through
+// sriov_numvfs while the agent runs
+type poolWatcher struct {
+	log  *logrus.Entry
+	pool pool.ResourcePool
+	done chan struct{}
+}
+
+// startPoolWatcher subscribes to the link updates, the pool keeps the
+// VFs found at startup when they cannot be received
+func startPoolWatcher(log *logrus.Entry, p pool.ResourcePool) *poolWatcher {
+	w := &poolWatcher{
+		log:  log.WithField("func", "poolWatcher"),
+		pool: p,
+		done: make(chan struct{}),
+	}
+	updates := make(chan netlink.LinkUpdate)
+	if err := linkSubscribe(updates, w.done); err != nil {
+		w.log.WithError(err).Warn("Cannot subscribe to link updates, VFs created or removed from now on will not be reflected in the pool")
+		return nil
+	}
+	go w.run(updates)
+	return w
+}
+
+func (w *poolWatcher) run(updates <-chan netlink.LinkUpdate) {
+	var sync <-chan time.Time
+	for {
+		select {
+		case <-w.done:
+			return
+		case _, ok := <-updates:
+			if !ok {
+				w.log.Warn("Link updates stopped, VFs created or removed from now on will not be reflected in the pool")
+				return
+			}
+			if sync == nil {
+				sync = time.After(poolSyncDelay)
+			}
+		case <-sync:
+			sync = nil
+			w.sync()
+		}
+	}
+}
+
+func (w *poolWatcher) stop() {
+	if w != nil {
+		close(w.done)
+	}
+}
+
+// sync adds the VFs the pool does not have yet and takes out the ones
+// which are gone. A VF gone while in use is marked as degraded, it
+// leaves the pool once its pod releases it.
+func (w *poolWatcher) sync() {
+	vfs, err := getVFList(types.NodeInterfaceName, utils.SysClassNet)
+	if err != nil {
+		w.log.WithError(err).Errorf("Failed to list the VFs of interface %s", types.NodeInterfaceName)
+		return
+	}
+	// The VFs moved into the pods are only found by their PCI address
+	pciAddrs, err := getVFPciAddrsFunc(types.NodeInterfaceName, utils.SysClassNet)
+	if err != nil {
+		w.log.WithError(err).Errorf("Failed to list the VFs of interface %s", types.NodeInterfaceName)
+		return
+	}
+	present := make(map[string]bool, len(pciAddrs))
+	for _, addr := range pciAddrs {
+		present[addr] = true
+	}
+
+	added := w.pool.Add(vfs)
+	removed, degraded := 0, 0
+	for _, r := range w.pool.Resources() {
+		name := r.InterfaceInfo.InterfaceName
+		if present[r.InterfaceInfo.PciAddr] {
+			continue
+		}
+		if w.pool.Remove(name) {
+			removed++
+		} else if w.pool.Degrade(name) {
+			w.log.Warnf("VF %s is gone while in use by pod %s", name, allocationOwner(r.Allocation))
+			degraded++
+		}
+	}
+	if added+removed+degraded == 0 {
+		return
+	}
+
+	poolChanges.Add(types.SriovPodInterface+".added", int64(added))
+	poolChanges.Add(types.SriovPodInterface+".removed", int64(removed))
+	poolChanges.Add(types.SriovPodInterface+".degraded", int64(degraded))
+	inUse, total := w.pool.Usage()
+	w.log.Infof("VFs of interface %s changed: %d added, %d removed, %d degraded, %d of %d in use",
+		types.NodeInterfaceName, added, removed, degraded, inUse, total)
+}
+
+func allocationOwner(a *pool.Allocation) string {
+	if a == nil || a.PodName == "" {
+		return "unknown"
+	}
+	return a.PodNamespace + "/" + a.PodName
+}
@@ -36,8 +36,10 @@ var (
 )
 
 type sriovPodInterface struct {
-	log  *logrus.Entry
-	pool pool.ResourcePool
+	log     *logrus.Entry
+	pool    pool.ResourcePool
+	auditor *poolAuditor
+	watcher *poolWatcher
 }
 
 func NewSriovPodInterface(log *logrus.Entry) (types.PodInterface, error) {
@@ -46,9 +48,19 @@ func NewSriovPodInterface(log *logrus.Entry) (types.PodInterface, error) {
 		log.WithError(err).Error("failed to setup SRIOV interface")
 		return nil, err
 	}
+	pi.auditor = startPoolAuditor(log, pi.pool, utilsGetDataDirPath(types.SriovPodInterface))
+	pi.watcher = startPoolWatcher(log, pi.pool)
+	publishPoolSizes(types.SriovPodInterface, pi.pool)
 	return pi, nil
 }
 
+// Close stops the audit of the pool and the watch of the VFs
+func (pi *sriovPodInterface) Close() error {
+	pi.auditor.stop()
+	pi.watcher.stop()
+	return nil
+}
+
 func (pi *sriovPodInterface) setup() error {
 	if err := pi.initializePool(); err != nil {
 		pi.log.WithError(err).Error("Cannot initialize sriov resource pool")
@@ -161,7 +173,7 @@ func (pi *sriovPodInterface) perepareInterface() (netlink.Link, *pool.Resource,
 }
 
 func (pi *sriovPodInterface) CreatePodInterface(in *pb.AddRequest) (*types.InterfaceInfo, error) {
-	res, err := pi.pool.Get()
+	res, err := pi.pool.Allocate(podAllocation(in))
 	if err != nil {
 		pi.log.Errorf("failed to get VF for pod error: %v", err)
 		return nil, err
@@ -196,7 +208,10 @@ func (pi *sriovPodInterface) ReleasePodInterface(in *pb.DelRequest) error {
 		return err
 	}
 	if err := movePodInterfaceToHostNetnsFunc(in.Netns, in.InterfaceName, conf); err != nil {
-		return err
+		// a degraded VF is gone, there is nothing to move back
+		if !pi.isDegraded(conf.InterfaceName) {
+			return err
+		}
 	}
 	pi.pool.Release(conf.InterfaceName)
 	// remove cache, ignore error
@@ -205,6 +220,15 @@ func (pi *sriovPodInterface) ReleasePodInterface(in *pb.DelRequest) error {
 	return nil
 }
 
+func (pi *sriovPodInterface) isDegraded(intfName string) bool {
+	for _, r := range pi.pool.Resources() {
+		if r.InterfaceInfo.InterfaceName == intfName {
+			return r.Degraded
+		}
+	}
+	return false
+}
+
 func (pi *sriovPodInterface) SetupNetwork(ctx context.Context, c pb.InfraAgentClient, intfInfo *types.InterfaceInfo, in *pb.AddRequest) (*pb.AddReply, error) {
 	request := &pb.CreateNetworkRequest{
 		AddRequest: in,
@@ -232,20 +256,19 @@ func (pi *sriovPodInterface) ReleaseNetwork(ctx context.Context, c pb.InfraAgent
 		out.ErrorMessage = err.Error()
 		return out, err
 	}
-	var ip string = ""
-	// fetch interface IP
+	var ip, ip6 string
+	// fetch interface IPs
 	err = withNetNSPath(in.Netns, func(_ ns.NetNS) error {
 		linkObj, err := linkByName(in.InterfaceName)
 		if err != nil {
 			pi.log.WithError(err).Errorf("failed to find netlink device with name %s", in.InterfaceName)
 			return err
 		}
-		l, err := addrList(linkObj, netlink.FAMILY_V4)
-		if err != nil || len(l) == 0 {
+		ip, ip6, err = linkAddresses(linkObj)
+		if err != nil || (ip == "" && ip6 == "") {
 			pi.log.WithError(err).Error("Failed to fetch IP address from Pod interface or IP not set")
 			return err
 		}
-		ip = l[0].IPNet.String()
 		return nil
 	})
 	if err != nil {
@@ -263,6 +286,7 @@ func (pi *sriovPodInterface) ReleaseNetwork(ctx context.Context, c pb.InfraAgent
 		HostIfName: conf.InterfaceName,
 		MacAddr:    conf.MacAddr,
 		Ipv4Addr:   ip,
+		Ipv6Addr:   ip6,
 	}
 
 	return c.DeleteNetwork(ctx, request)
@@ -16,6 +16,7 @@ package netconf
 
 import (
 	"fmt"
+	"os/exec"
 
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
@@ -26,8 +27,58 @@ import (
 
 var (
 	setLinkAddressFunc = setLinkAddress
+	runGnmiCliFunc     = runGnmiCli
 )
 
+// The TAP ports of the pipeline are DPDK virtual devices, configured
+// through the gNMI server of the target like scripts/create_interfaces.sh
+// does. They are only part of the pipeline when set before it.
+const (
+	gnmiCli          = "gnmi-cli"
+	tapPortConfigFmt = "device:virtual-device,name:%s,pipeline-name:pipe,mempool-name:MEMPOOL0,mtu:1500,port-type:TAP"
+	tapPortDeleteFmt = "device:virtual-device,name:%s"
+)
+
+func runGnmiCli(args ...string) error {
+	out, err := exec.Command(gnmiCli, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %v: %w: %s", gnmiCli, args, err, out)
+	}
+	return nil
+}
+
+func tapName(prefix string, index int) string {
+	return fmt.Sprintf("%s%d", prefix, index)
+}
+
+// createTapPorts creates the TAP ports of the first indexes the host
+// does not have yet and returns their names
+func createTapPorts(prefix string, count int) ([]string, error) {
+	logger := log.WithField("func", "createTapPorts").WithField("pkg", "netconf")
+	var created []string
+	for i := 0; i < count; i++ {
+		name := tapName(prefix, i)
+		_, err := linkByName(name)
+		if err == nil {
+			continue
+		}
+		if _, ok := err.(netlink.LinkNotFoundError); !ok {
+			return created, err
+		}
+		if err := runGnmiCliFunc("set", fmt.Sprintf(tapPortConfigFmt, name)); err != nil {
+			return created, fmt.Errorf("failed to create TAP port %s: %w", name, err)
+		}
+		logger.Infof("Created TAP port %s", name)
+		created = append(created, name)
+	}
+	return created, nil
+}
+
+// deleteTapPort deletes a TAP port created by createTapPorts
+func deleteTapPort(name string) error {
+	return runGnmiCliFunc("del", fmt.Sprintf(tapPortDeleteFmt, name))
+}
+
 func setHostInterfaceInPodNetns(in *pb.AddRequest, res *types.InterfaceInfo) error {
 	logger := log.WithField("func", "setHostInterfaceInPodNetns").WithField("pkg", "netconf")
 	logger.Infof("Configuring pod interface %s for Pod network", res.InterfaceName)
@@ -37,15 +37,27 @@ var (
 	configureHostInterfaceFunc     = configureHostInterface
 	getHostIPfromPodCIDRFunc       = getHostIPfromPodCIDR
 	setHostInterfaceInPodNetnsFunc = setHostInterfaceInPodNetns
+	createTapPortsFunc             = createTapPorts
+	deleteTapPortFunc              = deleteTapPort
 )
 
 type tapPodInterface struct {
 	log  *logrus.Entry
 	pool pool.ResourcePool
+	// The agent creates the missing TAP ports when their count is set.
+	// The pipeline takes its ports when it is set, so they are created
+	// once at startup and the pool does not grow afterwards. They are
+	// kept when the agent stops, the ports created after the pipeline
+	// is set would not be part of it.
+	count   int
+	auditor *poolAuditor
 }
 
 func NewTapPodInterface(log *logrus.Entry) (types.PodInterface, error) {
-	pi := &tapPodInterface{log: log}
+	pi := &tapPodInterface{
+		log:   log,
+		count: viper.GetInt("tapCount"),
+	}
 	intfs, err := pi.configurePool()
 	if err != nil {
 		log.WithError(err).Error("failed to configure pool")
@@ -55,11 +67,23 @@ func NewTapPodInterface(log *logrus.Entry) (types.PodInterface, error) {
 		log.WithError(err).Error("failed to setup tap interface")
 		return nil, err
 	}
+	pi.auditor = startPoolAuditor(log, pi.pool, utilsGetDataDirPath(types.TapInterface))
+	publishPoolSizes(types.TapInterface, pi.pool)
 	return pi, nil
 }
 
 func (pi *tapPodInterface) configurePool() ([]*types.InterfaceInfo, error) {
 	tapPrefix := viper.GetString("tapPrefix")
+	if pi.count > 0 {
+		pi.log.Infof("Creating %d TAP ports using prefix %s", pi.count, tapPrefix)
+		created, err := createTapPortsFunc(tapPrefix, pi.count)
+		if err != nil {
+			// The pipeline is not set yet, the ports can go
+			pi.log.WithError(err).Error("failed to create TAP ports")
+			pi.deletePorts(created)
+			return nil, err
+		}
+	}
 	pi.log.Infof("Scanning for Host Tap for interfaces using prefix %s", tapPrefix)
 	intfs, err := getTapInterfaces(tapPrefix)
 	if err != nil {
@@ -81,6 +105,25 @@ func (pi *tapPodInterface) configurePool() ([]*types.InterfaceInfo, error) {
 	return intfs, nil
 }
 
+// Deletes the given TAP ports, returning the last error
+func (pi *tapPodInterface) deletePorts(names []string) error {
+	var err error
+	for _, name := range names {
+		if delErr := deleteTapPortFunc(name); delErr != nil {
+			pi.log.WithError(delErr).Errorf("failed to delete TAP port %s", name)
+			err = delErr
+		}
+	}
+	return err
+}
+
+// Close stops the audit of the pool, the TAP ports are left for the next
+// start of the agent
+func (pi *tapPodInterface) Close() error {
+	pi.auditor.stop()
+	return nil
+}
+
 func (pi *tapPodInterface) setup(intfs []*types.InterfaceInfo) error {
 	var res *pool.Resource
 	// check if we have config in cache
@@ -182,7 +225,7 @@ func getHostIPfromPodCIDR(log *logrus.Entry, ec *utils.EnvConfigurer) (*net.IPNe
 }
 
 func (pi *tapPodInterface) CreatePodInterface(in *pb.AddRequest) (*types.InterfaceInfo, error) {
-	res, err := pi.pool.Get()
+	res, err := pi.pool.Allocate(podAllocation(in))
 	if err != nil {
 		pi.log.Errorf("failed to get a free interface for pod error: %v", err)
 		return nil, err
@@ -260,20 +303,19 @@ func (pi *tapPodInterface) ReleaseNetwork(ctx context.Context, c pb.InfraAgentCl
 		out.ErrorMessage = err.Error()
 		return out, err
 	}
-	var ip string = ""
-	// fetch interface IP
+	var ip, ip6 string
+	// fetch interface IPs
 	err = withNetNSPath(in.Netns, func(_ ns.NetNS) error {
 		linkObj, err := linkByName(in.InterfaceName)
 		if err != nil {
 			pi.log.WithError(err).Errorf("failed to find netlink device with name %s", in.InterfaceName)
 			return err
 		}
-		l, err := addrList(linkObj, netlink.FAMILY_V4)
-		if err != nil || len(l) == 0 {
+		ip, ip6, err = linkAddresses(linkObj)
+		if err != nil || (ip == "" && ip6 == "") {
 			pi.log.WithError(err).Error("Failed to fetch IP address from Pod interface or IP not set")
 			return err
 		}
-		ip = l[0].IPNet.String()
 		return nil
 	})
 	if err != nil {
@@ -292,6 +334,7 @@ func (pi *tapPodInterface) ReleaseNetwork(ctx context.Context, c pb.InfraAgentCl
 		HostIfName: conf.InterfaceName,
 		MacAddr:    conf.MacAddr,
 		Ipv4Addr:   ip,
+		Ipv6Addr:   ip6,
 	}
 
 	return c.DeleteNetwork(ctx, request)
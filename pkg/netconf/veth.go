@@ -0,0 +1,87 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netconf
+
+import (
+	"fmt"
+
+	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// The end of a veth pair kept on the host is named after the port of
+// the pipeline it stands for, like the TAP interfaces are, which is how
+// inframanager finds the port
+func vethName(port int) string {
+	return fmt.Sprintf("%s%d", types.VethInterfacePrefix, port)
+}
+
+// The other end is named so only until it is moved to the pod
+func vethPeerName(name string) string {
+	return name + "p"
+}
+
+// Returns the first port from the given one without a veth pair
+func freeVethPort(first int) (int, error) {
+	for port := first; ; port++ {
+		_, err := linkByName(vethName(port))
+		if err == nil {
+			continue
+		}
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return port, nil
+		}
+		return 0, err
+	}
+}
+
+// createVethPair creates the pair with its first end up and returns the
+// other end, which keeps its MAC address when moved to another namespace
+func createVethPair(name, peerName string, mtu int) (netlink.Link, error) {
+	logger := log.WithField("func", "createVethPair").WithField("pkg", "netconf")
+	veth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name: name,
+			MTU:  mtu,
+		},
+		PeerName: peerName,
+	}
+	if err := linkAdd(veth); err != nil {
+		logger.WithError(err).Errorf("Failed to create veth pair %s", name)
+		return nil, fmt.Errorf("failed to create veth pair %s: %w", name, err)
+	}
+
+	peer, err := setVethUp(name, peerName)
+	if err != nil {
+		// deleting one end deletes the other one
+		if delErr := delLinkByName(name); delErr != nil {
+			logger.WithError(delErr).Errorf("Failed to delete veth pair %s", name)
+		}
+		return nil, err
+	}
+	return peer, nil
+}
+
+func setVethUp(name, peerName string) (netlink.Link, error) {
+	link, err := linkByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := linkSetUp(link); err != nil {
+		return nil, fmt.Errorf("Cannot set link up: %w", err)
+	}
+	return linkByName(peerName)
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/containernetworking/plugins/pkg/ip"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
+	"github.com/ipdk-io/k8s-infra-offload/pkg/utils"
+	pb "github.com/ipdk-io/k8s-infra-offload/proto"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	createVethPairFunc = createVethPair
+	freeVethPortFunc   = freeVethPort
+)
+
+// vethPodInterface connects every pod with a veth pair of its own, the
+// end left on the host standing for a port of the pipeline. It does not
+// need any device, so the agent can run on any Linux box.
+type vethPodInterface struct {
+	log *logrus.Entry
+	// The ports are found from the links existing on the host
+	mutex sync.Mutex
+}
+
+func NewVethPodInterface(log *logrus.Entry) (types.PodInterface, error) {
+	pi := &vethPodInterface{log: log}
+	if err := pi.setup(); err != nil {
+		log.WithError(err).Error("failed to setup veth interface")
+		return nil, err
+	}
+	return pi, nil
+}
+
+// The host is connected to the first port, through the infra_host end
+// of its pair
+func (pi *vethPodInterface) setup() error {
+	portName := vethName(0)
+	hostLink, err := linkByName(types.InfraHost)
+	if err != nil {
+		// the pair is left in place when the agent restarts
+		if hostLink, err = createVethPairFunc(portName, types.InfraHost, 0); err != nil {
+			pi.log.WithError(err).Error("unable to create interface for host")
+			return err
+		}
+	}
+
+	varConfigurer := utils.NewOsVariableConfigurer()
+	ec := utils.NewEnvConfigurer(varConfigurer, types.DefaultCalicoConfig)
+
+	ipnet, err := getHostIPfromPodCIDRFunc(pi.log, ec)
+	if err != nil {
+		pi.log.WithError(err).Error("Failed to get IP for host interface")
+		return err
+	}
+	pi.log.Printf("Host IP address allocated: %s", ipnet)
+
+	if err := configureHostInterfaceFunc(types.InfraHost, ipnet, nil, pi.log); err != nil {
+		return err
+	}
+	types.NodeInfraHostInterfaceName = types.InfraHost
+
+	// dial inframanager and setup host interface
+	request := &pb.SetupHostInterfaceRequest{
+		IfName:   portName,
+		Ipv4Addr: ipnet.String(),
+		MacAddr:  hostLink.Attrs().HardwareAddr.String(),
+	}
+	return sendSetupHostInterfaceFunc(request)
+}
+
+// Creates the pair of the pod on the first free port
+func (pi *vethPodInterface) createVethPair(in *pb.AddRequest) (*types.InterfaceInfo, error) {
+	pi.mutex.Lock()
+	defer pi.mutex.Unlock()
+
+	port, err := freeVethPortFunc(1)
+	if err != nil {
+		return nil, err
+	}
+	name := vethName(port)
+	peer, err := createVethPairFunc(name, vethPeerName(name), int(in.GetSettings().GetMtu()))
+	if err != nil {
+		return nil, err
+	}
+	return &types.InterfaceInfo{
+		InterfaceName: name,
+		MacAddr:       peer.Attrs().HardwareAddr.String(),
+	}, nil
+}
+
+func (pi *vethPodInterface) CreatePodInterface(in *pb.AddRequest) (*types.InterfaceInfo, error) {
+	intfInfo, err := pi.createVethPair(in)
+	if err != nil {
+		pi.log.WithError(err).Error("failed to create veth pair for pod")
+		return nil, err
+	}
+	pi.log.Infof("Interface allocated for Pod: %s", intfInfo.InterfaceName)
+
+	// the end moved to the pod is set up like a TAP interface
+	peer := &types.InterfaceInfo{InterfaceName: vethPeerName(intfInfo.InterfaceName)}
+	if err := setHostInterfaceInPodNetnsFunc(in, peer); err != nil {
+		pi.log.WithError(err).Error("failed to push interface to container")
+		pi.deleteVethPair(intfInfo.InterfaceName)
+		return nil, err
+	}
+	pi.log.Infof("Host interface name: %s interface mac %s", intfInfo.InterfaceName, intfInfo.MacAddr)
+
+	refid := filepath.Base(in.Netns)
+	if err := saveInterfaceConf(utilsGetDataDirPath(types.VethPodInterface), refid, in.InterfaceName, intfInfo); err != nil {
+		pi.log.WithError(err).Error("storing cache failed")
+		pi.deleteVethPair(intfInfo.InterfaceName)
+		return nil, err
+	}
+	return intfInfo, nil
+}
+
+// Deleting the end on the host deletes the one in the pod as well
+func (pi *vethPodInterface) deleteVethPair(name string) {
+	if err := delLinkByName(name); err != nil && !errors.Is(err, ip.ErrLinkNotFound) {
+		pi.log.WithError(err).Errorf("failed to delete veth pair %s", name)
+	}
+}
+
+func (pi *vethPodInterface) ReleasePodInterface(in *pb.DelRequest) error {
+	refid := filepath.Base(in.Netns)
+	conf, err := readInterfaceConf(utilsGetDataDirPath(types.VethPodInterface), refid, in.InterfaceName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			pi.log.WithError(err).Infof("interface config cache file for refid %s is not found", refid)
+			return nil // the pod interface was not created by the agent or is already released
+		}
+		return err
+	}
+	pi.deleteVethPair(conf.InterfaceName)
+	// remove cache, ignore error
+	path := filepath.Join(utilsGetDataDirPath(types.VethPodInterface), refid+"-"+in.InterfaceName)
+	_ = os.Remove(path)
+	return nil
+}
+
+func (pi *vethPodInterface) SetupNetwork(ctx context.Context, c pb.InfraAgentClient, intfInfo *types.InterfaceInfo, in *pb.AddRequest) (*pb.AddReply, error) {
+	request := &pb.CreateNetworkRequest{
+		AddRequest: in,
+		HostIfName: in.DesiredHostInterfaceName,
+		MacAddr:    intfInfo.MacAddr,
+	}
+	out, err := c.CreateNetwork(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (pi *vethPodInterface) ReleaseNetwork(ctx context.Context, c pb.InfraAgentClient, in *pb.DelRequest) (*pb.DelReply, error) {
+	out := &pb.DelReply{
+		Successful: true,
+	}
+	// get interface config from cache
+	refid := filepath.Base(in.Netns)
+	conf, err := readInterfaceConf(utilsGetDataDirPath(types.VethPodInterface), refid, in.InterfaceName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			pi.log.WithError(err).Infof("interface config cache file for refid %s is not found", refid)
+			return out, nil // the pod interface was not created by the agent or is already released
+		}
+		pi.log.WithError(err).Errorf("error trying to read interface config from cache file using refid %s", refid)
+		out.Successful = false
+		out.ErrorMessage = err.Error()
+		return out, err
+	}
+	var ip, ip6 string
+	// fetch interface IPs
+	err = withNetNSPath(in.Netns, func(_ ns.NetNS) error {
+		linkObj, err := linkByName(in.InterfaceName)
+		if err != nil {
+			pi.log.WithError(err).Errorf("failed to find netlink device with name %s", in.InterfaceName)
+			return err
+		}
+		ip, ip6, err = linkAddresses(linkObj)
+		if err != nil {
+			pi.log.WithError(err).Error("Failed to fetch IP address from Pod interface")
+			return err
+		}
+		if ip == "" && ip6 == "" {
+			return fmt.Errorf("no IP address is set on Pod interface %s", in.InterfaceName)
+		}
+		return nil
+	})
+	if err != nil {
+		_, ok := err.(ns.NSPathNotExistErr)
+		if ok {
+			// namespace already gone do not return error
+			return out, nil
+		}
+		pi.log.WithError(err).Errorf("failed to enter Pod network nampespace with id: %s", in.Netns)
+		out.Successful = false
+		out.ErrorMessage = err.Error()
+		return out, err
+	}
+	request := &pb.DeleteNetworkRequest{
+		DelRequest: in,
+		HostIfName: conf.InterfaceName,
+		MacAddr:    conf.MacAddr,
+		Ipv4Addr:   ip,
+		Ipv6Addr:   ip6,
+	}
+
+	return c.DeleteNetwork(ctx, request)
+}
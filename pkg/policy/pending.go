@@ -0,0 +1,191 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"sort"
+
+	managerclient "github.com/ipdk-io/k8s-infra-offload/pkg/manager_client"
+	pb "github.com/ipdk-io/k8s-infra-offload/proto"
+	"github.com/pkg/errors"
+)
+
+// pendingState is the snapshot felix sends before InSync. Updates of the
+// same object replace each other and removes drop it, so that only the
+// final state is sent to inframanager.
+type pendingState struct {
+	ipSets        map[string]*pb.IPSetUpdate
+	profiles      map[string]*pb.ActiveProfileUpdate
+	policies      map[string]*pb.ActivePolicyUpdate
+	hostEndpoints map[string]*pb.HostEndpointUpdate
+	workloads     map[string]*pb.WorkloadEndpointUpdate
+	// messages which are not part of the policy state,
+	// they are sent in the order received
+	others []interface{}
+}
+
+func newPendingState() *pendingState {
+	return &pendingState{
+		ipSets:        make(map[string]*pb.IPSetUpdate),
+		profiles:      make(map[string]*pb.ActiveProfileUpdate),
+		policies:      make(map[string]*pb.ActivePolicyUpdate),
+		hostEndpoints: make(map[string]*pb.HostEndpointUpdate),
+		workloads:     make(map[string]*pb.WorkloadEndpointUpdate),
+	}
+}
+
+func policyKey(id *pb.PolicyID) string {
+	return id.GetTier() + "/" + id.GetName()
+}
+
+func workloadKey(id *pb.WorkloadEndpointID) string {
+	return id.GetOrchestratorId() + "/" + id.GetWorkloadId() + "/" + id.GetEndpointId()
+}
+
+func applyIpsetDelta(members []string, delta *pb.IPSetDeltaUpdate) []string {
+	removed := make(map[string]bool)
+	for _, m := range delta.RemovedMembers {
+		removed[m] = true
+	}
+	res := make([]string, 0, len(members)+len(delta.AddedMembers))
+	for _, m := range members {
+		if !removed[m] {
+			res = append(res, m)
+		}
+	}
+	return append(res, delta.AddedMembers...)
+}
+
+func (p *pendingState) add(msg interface{}) {
+	switch m := msg.(type) {
+	case *pb.IPSetUpdate:
+		p.ipSets[m.Id] = m
+	case *pb.IPSetDeltaUpdate:
+		ipSet, ok := p.ipSets[m.Id]
+		if !ok {
+			p.others = append(p.others, m)
+			return
+		}
+		p.ipSets[m.Id] = &pb.IPSetUpdate{
+			Id:      ipSet.Id,
+			Type:    ipSet.Type,
+			Members: applyIpsetDelta(ipSet.Members, m),
+		}
+	case *pb.IPSetRemove:
+		delete(p.ipSets, m.Id)
+	case *pb.ActiveProfileUpdate:
+		p.profiles[m.GetId().GetName()] = m
+	case *pb.ActiveProfileRemove:
+		delete(p.profiles, m.GetId().GetName())
+	case *pb.ActivePolicyUpdate:
+		p.policies[policyKey(m.Id)] = m
+	case *pb.ActivePolicyRemove:
+		delete(p.policies, policyKey(m.Id))
+	case *pb.HostEndpointUpdate:
+		p.hostEndpoints[m.GetId().GetEndpointId()] = m
+	case *pb.HostEndpointRemove:
+		delete(p.hostEndpoints, m.GetId().GetEndpointId())
+	case *pb.WorkloadEndpointUpdate:
+		p.workloads[workloadKey(m.Id)] = m
+	case *pb.WorkloadEndpointRemove:
+		delete(p.workloads, workloadKey(m.Id))
+	default:
+		p.others = append(p.others, m)
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Returns the snapshot in dependency order, the ip sets and profiles
+// come before the policies referring to them and the policies come
+// before the endpoints they are applied to.
+func (p *pendingState) messages() []interface{} {
+	msgs := make([]interface{}, 0)
+	for _, k := range sortedKeys(p.ipSets) {
+		msgs = append(msgs, p.ipSets[k])
+	}
+	for _, k := range sortedKeys(p.profiles) {
+		msgs = append(msgs, p.profiles[k])
+	}
+	for _, k := range sortedKeys(p.policies) {
+		msgs = append(msgs, p.policies[k])
+	}
+	for _, k := range sortedKeys(p.hostEndpoints) {
+		msgs = append(msgs, p.hostEndpoints[k])
+	}
+	for _, k := range sortedKeys(p.workloads) {
+		msgs = append(msgs, p.workloads[k])
+	}
+	return append(msgs, p.others...)
+}
+
+func (s *PolicyServer) applyPending() error {
+	msgs := s.pending.messages()
+	s.log.Infof("Applying %d pending updates", len(msgs))
+	for _, msg := range msgs {
+		if err := s.applyMessage(msg, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Removes the state inframanager still has from before the resync,
+// in the reverse dependency order.
+func (s *PolicyServer) reconcile() error {
+	c, err := s.dialManager()
+	if err != nil {
+		return errors.Wrap(err, "cannot reconcile policy state: cannot dial manager")
+	}
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	state, err := c.GetPolicyState(ctx, &pb.PolicyStateRequest{})
+	if err != nil {
+		return errors.Wrap(err, "cannot reconcile policy state")
+	}
+
+	stale := make([]interface{}, 0)
+	for _, id := range state.WorkloadEndpointIds {
+		if _, ok := s.pending.workloads[workloadKey(id)]; !ok {
+			stale = append(stale, &pb.WorkloadEndpointRemove{Id: id})
+		}
+	}
+	for _, id := range state.PolicyIds {
+		if _, ok := s.pending.policies[policyKey(id)]; !ok {
+			stale = append(stale, &pb.ActivePolicyRemove{Id: id})
+		}
+	}
+	for _, id := range state.IpSetIds {
+		if _, ok := s.pending.ipSets[id]; !ok {
+			stale = append(stale, &pb.IPSetRemove{Id: id})
+		}
+	}
+
+	s.log.Infof("Removing %d stale objects", len(stale))
+	for _, msg := range stale {
+		if err := s.applyMessage(msg, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
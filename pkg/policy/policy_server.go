@@ -16,21 +16,22 @@ package policy
 
 import (
 	"context"
-	"fmt"
 	"net"
 	"os"
+	"reflect"
 
+	managerclient "github.com/ipdk-io/k8s-infra-offload/pkg/manager_client"
 	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
 	pb "github.com/ipdk-io/k8s-infra-offload/proto"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"gopkg.in/tomb.v2"
 )
 
 var (
-	grpcDial              = grpc.Dial
+	getManagerConn        = managerclient.GetConn
 	pbNewInfraAgentClient = pb.NewInfraAgentClient
 	cancellableListener   = getCancellableListener
 	removeSocket          = os.RemoveAll
@@ -41,6 +42,11 @@ type PolicyServer struct {
 	nextSeqNumber uint64
 	exiting       chan bool
 	name          string
+	inSync        bool
+	pending       *pendingState
+	config        map[string]string
+	// the updates inframanager failed to apply
+	failedUpdates uint64
 }
 
 func NewPolicyServer(log *logrus.Entry) (types.Server, error) {
@@ -56,6 +62,9 @@ func (s *PolicyServer) GetName() string {
 }
 
 func (s *PolicyServer) SyncPolicy(conn net.Conn) {
+	// felix sends the whole state again on every connection
+	s.inSync = false
+	s.pending = newPendingState()
 	for {
 		msg, err := s.RecvMessage(conn)
 		if err != nil {
@@ -70,7 +79,11 @@ func (s *PolicyServer) SyncPolicy(conn net.Conn) {
 		case *pb.InSync:
 			err = s.handleInSyc(m)
 		default:
-			err = s.handleMessage(msg, false)
+			if s.inSync {
+				err = s.applyMessage(msg, false)
+			} else {
+				s.pending.add(msg)
+			}
 		}
 
 		if err != nil {
@@ -82,6 +95,36 @@ func (s *PolicyServer) SyncPolicy(conn net.Conn) {
 	}
 }
 
+// Whether the update could not reach inframanager, as opposed to being
+// refused by it
+func isTransportError(err error) bool {
+	st, ok := status.FromError(errors.Cause(err))
+	if !ok {
+		return true
+	}
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return true
+	}
+	return false
+}
+
+// Sends the update to inframanager. An update it fails to apply is
+// counted and does not stop the ones after it, only the errors reaching
+// inframanager are returned.
+func (s *PolicyServer) applyMessage(msg interface{}, pending bool) error {
+	err := s.handleMessage(msg, pending)
+	if err == nil {
+		return nil
+	}
+	if isTransportError(err) {
+		return err
+	}
+	s.failedUpdates++
+	s.log.WithError(err).Warnf("Failed to apply %T, %d updates failed so far", msg, s.failedUpdates)
+	return nil
+}
+
 func (s *PolicyServer) handleMessage(msg interface{}, pending bool) error {
 	switch m := msg.(type) {
 	case *pb.IPSetUpdate:
@@ -146,15 +189,31 @@ func (s *PolicyServer) StopServer() {
 	s.exiting <- true
 }
 
-// Not needed?
 func (s *PolicyServer) handleConfigUpdate(msg *pb.ConfigUpdate) error {
 	s.log.Infof("Got config update %+v", msg)
+	if s.config != nil && !reflect.DeepEqual(s.config, msg.Config) {
+		s.log.Warn("Felix configuration changed, restart the agent to apply it")
+	}
+	s.config = msg.Config
 	return nil
 }
 
-// Not needed?
+// Applies the updates received before InSync as one batch, then removes
+// what inframanager has and felix did not send, e.g. the objects deleted
+// while the agent was down.
 func (s *PolicyServer) handleInSyc(msg *pb.InSync) error {
 	s.log.Infof("Got in sync %+v", msg)
+	if s.inSync {
+		return nil
+	}
+	if err := s.applyPending(); err != nil {
+		return errors.Wrap(err, "cannot apply pending updates")
+	}
+	if err := s.reconcile(); err != nil {
+		return err
+	}
+	s.inSync = true
+	s.pending = nil
 	return nil
 }
 
@@ -167,8 +226,9 @@ func (s *PolicyServer) handleIpsetUpdate(msg *pb.IPSetUpdate, pending bool) erro
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleIpsetUpdate: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.UpdateIPSet(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.UpdateIPSet(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleIpsetUpdate")
 	}
@@ -184,8 +244,9 @@ func (s *PolicyServer) handleIpsetDeltaUpdate(msg *pb.IPSetDeltaUpdate, pending
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleIpsetDeltaUpdate: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.UpdateIPSetDelta(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.UpdateIPSetDelta(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleIpsetDeltaUpdate")
 	}
@@ -201,8 +262,9 @@ func (s *PolicyServer) handleIpsetRemove(msg *pb.IPSetRemove, pending bool) erro
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleIpsetRemove: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.RemoveIPSet(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.RemoveIPSet(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleIpsetRemove")
 	}
@@ -218,8 +280,9 @@ func (s *PolicyServer) handleActivePolicyUpdate(msg *pb.ActivePolicyUpdate, pend
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleActivePolicyUpdate: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.ActivePolicyUpdate(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.ActivePolicyUpdate(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleActivePolicyUpdate")
 	}
@@ -235,8 +298,9 @@ func (s *PolicyServer) handleActivePolicyRemove(msg *pb.ActivePolicyRemove, pend
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleActivePolicyRemove: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.ActivePolicyRemove(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.ActivePolicyRemove(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleActivePolicyRemove")
 	}
@@ -252,8 +316,9 @@ func (s *PolicyServer) handleActiveProfileUpdate(msg *pb.ActiveProfileUpdate, pe
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleActiveProfileUpdate: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.UpdateActiveProfile(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.UpdateActiveProfile(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleActiveProfileUpdate")
 	}
@@ -269,8 +334,9 @@ func (s *PolicyServer) handleActiveProfileRemove(msg *pb.ActiveProfileRemove, pe
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleActiveProfileRemove: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.RemoveActiveProfile(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.RemoveActiveProfile(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleActiveProfileRemove")
 	}
@@ -286,8 +352,9 @@ func (s *PolicyServer) handleHostEndpointUpdate(msg *pb.HostEndpointUpdate, pend
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleHostEndpointUpdate: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.UpdateHostEndpoint(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.UpdateHostEndpoint(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleHostEndpointUpdate")
 	}
@@ -303,8 +370,9 @@ func (s *PolicyServer) handleHostEndpointRemove(msg *pb.HostEndpointRemove, pend
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleHostEndpointRemove: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.RemoveHostEndpoint(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.RemoveHostEndpoint(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleHostEndpointRemove")
 	}
@@ -320,8 +388,9 @@ func (s *PolicyServer) handleWorkloadEndpointUpdate(msg *pb.WorkloadEndpointUpda
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleWorkloadEndpointUpdate: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.UpdateLocalEndpoint(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.UpdateLocalEndpoint(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleWorkloadEndpointUpdate")
 	}
@@ -337,8 +406,9 @@ func (s *PolicyServer) handleWorkloadEndpointRemove(msg *pb.WorkloadEndpointRemo
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleWorkloadEndpointRemove: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.RemoveLocalEndpoint(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.RemoveLocalEndpoint(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleWorkloadEndpointRemove")
 	}
@@ -354,8 +424,9 @@ func (s *PolicyServer) handleHostMetadataUpdate(msg *pb.HostMetadataUpdate, pend
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleHostMetadataUpdate: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.UpdateHostMetaData(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.UpdateHostMetaData(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleHostMetadataUpdate")
 	}
@@ -371,8 +442,9 @@ func (s *PolicyServer) handleHostMetadataRemove(msg *pb.HostMetadataRemove, pend
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleHostMetadataRemove: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.RemoveHostMetaData(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.RemoveHostMetaData(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleHostMetadataRemove")
 	}
@@ -400,8 +472,9 @@ func (s *PolicyServer) handleServiceAccountUpdate(msg *pb.ServiceAccountUpdate,
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleServiceAccountUpdate: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.UpdateServiceAccount(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.UpdateServiceAccount(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleServiceAccountUpdate")
 	}
@@ -417,8 +490,9 @@ func (s *PolicyServer) handleServiceAccountRemove(msg *pb.ServiceAccountRemove,
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleServiceAccountRemove: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.RemoveServiceAccount(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.RemoveServiceAccount(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleServiceAccountRemove")
 	}
@@ -434,8 +508,9 @@ func (s *PolicyServer) handleNamespaceUpdate(msg *pb.NamespaceUpdate, pending bo
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleNamespaceUpdate: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.UpdateNamespace(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.UpdateNamespace(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleNamespaceUpdate")
 	}
@@ -451,8 +526,9 @@ func (s *PolicyServer) handleNamespaceRemove(msg *pb.NamespaceRemove, pending bo
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleNamespaceRemove: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.RemoveNamespace(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.RemoveNamespace(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleNamespaceRemove")
 	}
@@ -468,8 +544,9 @@ func (s *PolicyServer) handleRouteUpdate(msg *pb.RouteUpdate, pending bool) erro
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleRouteUpdate: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.UpdateRoute(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.UpdateRoute(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleRouteUpdate")
 	}
@@ -485,8 +562,9 @@ func (s *PolicyServer) handleRouteRemove(msg *pb.RouteRemove, pending bool) erro
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleRouteRemove: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.RemoveRoute(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.RemoveRoute(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleRouteRemove")
 	}
@@ -502,8 +580,9 @@ func (s *PolicyServer) handleVXLANTunnelEndpointUpdate(msg *pb.VXLANTunnelEndpoi
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleVXLANTunnelEndpointUpdate: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.UpdateVXLANTunnelEndpoint(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.UpdateVXLANTunnelEndpoint(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleVXLANTunnelEndpointUpdate")
 	}
@@ -519,8 +598,9 @@ func (s *PolicyServer) handleVXLANTunnelEndpointRemove(msg *pb.VXLANTunnelEndpoi
 	if err != nil {
 		return errors.Wrap(err, "cannot process handleVXLANTunnelEndpointRemove: cannot dial manager")
 	}
-	// TODO: Add pending flag
-	out, err = c.RemoveVXLANTunnelEndpoint(context.TODO(), msg)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	out, err = c.RemoveVXLANTunnelEndpoint(ctx, msg)
 	if err != nil || !out.Successful {
 		return errors.Wrap(err, "cannot process handleVXLANTunnelEndpointRemove")
 	}
@@ -543,8 +623,7 @@ func (s *PolicyServer) handleGlobalBGPConfigUpdate(msg *pb.GlobalBGPConfigUpdate
 }
 
 func (s *PolicyServer) dialManager() (pb.InfraAgentClient, error) {
-	managerAddr := fmt.Sprintf("%s:%s", types.InfraManagerAddr, types.InfraManagerPort)
-	conn, err := grpcDial(managerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := getManagerConn()
 	if err != nil {
 		s.log.WithField("func", "dialManager")
 		s.log.Errorf("unable to dial Infra Manager. err %v", err)
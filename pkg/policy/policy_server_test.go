@@ -18,6 +18,8 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 	"gopkg.in/tomb.v2"
 )
@@ -128,6 +130,15 @@ func writeTo(conn net.Conn, data []byte) error {
 	return nil
 }
 
+func writeMsg(conn net.Conn, msg interface{}) {
+	envelope, err := wrapPayloadWithEnvelope(msg, 0)
+	Expect(err).ShouldNot(HaveOccurred())
+	bs, err := envelope.Marshal()
+	Expect(err).ShouldNot(HaveOccurred())
+	err = writeTo(conn, bs)
+	Expect(err).ShouldNot(HaveOccurred())
+}
+
 func testSendMessage(msg interface{}) {
 	var t tomb.Tomb
 	srv, err := NewPolicyServer(logrus.NewEntry(logrus.StandardLogger()))
@@ -170,7 +181,7 @@ var _ = BeforeSuite(func() {
 		return mockClient
 	}
 
-	grpcDial = func(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	getManagerConn = func() (*grpc.ClientConn, error) {
 		cc := &grpc.ClientConn{}
 		return cc, nil
 	}
@@ -208,6 +219,10 @@ func testPolicyMsg(bs []byte, callExpect *gomock.Call) {
 	conn, err := socketListener.Dial()
 	Expect(err).ShouldNot(HaveOccurred())
 
+	// updates are sent right away only once in sync
+	mockClient.EXPECT().GetPolicyState(gomock.Any(), gomock.Any()).Return(&proto.PolicyStateReply{}, nil)
+	writeMsg(conn, &proto.InSync{})
+
 	err = writeTo(conn, bs)
 	Expect(err).ShouldNot(HaveOccurred())
 
@@ -486,6 +501,7 @@ var _ = Describe("policy", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 
 			// write InSync
+			mockClient.EXPECT().GetPolicyState(gomock.Any(), gomock.Any()).Return(&proto.PolicyStateReply{}, nil)
 			insync := &proto.InSync{}
 			envelope, err = wrapPayloadWithEnvelope(insync, 0)
 			Expect(err).ShouldNot(HaveOccurred())
@@ -561,6 +577,121 @@ var _ = Describe("policy", func() {
 		})
 	})
 
+	var _ = Context("InSync should", func() {
+		var (
+			t    tomb.Tomb
+			conn net.Conn
+		)
+
+		var _ = BeforeEach(func() {
+			t = tomb.Tomb{}
+			srv, err := NewPolicyServer(logrus.NewEntry(logrus.StandardLogger()))
+			Expect(err).ToNot(HaveOccurred())
+			t.Go(func() error {
+				defer GinkgoRecover()
+				srvConn, err := socketListener.Accept()
+				if err != nil {
+					return err
+				}
+				go srv.(*PolicyServer).SyncPolicy(srvConn)
+				<-t.Dying()
+				return nil
+			})
+			conn, err = socketListener.Dial()
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		var _ = AfterEach(func() {
+			t.Kill(errors.New("stop"))
+			err := t.Wait()
+			Expect(err.Error()).Should(Equal("stop"))
+			conn.Close()
+		})
+
+		var _ = It("apply the pending updates in one batch", func() {
+			done := make(chan bool)
+			var ipSet *proto.IPSetUpdate
+			gomock.InOrder(
+				mockClient.EXPECT().UpdateIPSet(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ interface{}, in *proto.IPSetUpdate, _ ...interface{}) (*proto.Reply, error) {
+						ipSet = in
+						return &proto.Reply{Successful: true}, nil
+					}),
+				mockClient.EXPECT().ActivePolicyUpdate(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+				mockClient.EXPECT().UpdateLocalEndpoint(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+				mockClient.EXPECT().GetPolicyState(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ interface{}, _ interface{}, _ ...interface{}) (*proto.PolicyStateReply, error) {
+						close(done)
+						return &proto.PolicyStateReply{}, nil
+					}),
+			)
+
+			// sent in the reverse order on purpose
+			writeMsg(conn, &proto.WorkloadEndpointUpdate{Id: &proto.WorkloadEndpointID{OrchestratorId: "k8s", WorkloadId: "default/pod", EndpointId: "eth0"},
+				Endpoint: &proto.WorkloadEndpoint{}})
+			writeMsg(conn, &proto.ActivePolicyUpdate{Id: &proto.PolicyID{Tier: "default", Name: "allow"}, Policy: &proto.Policy{}})
+			writeMsg(conn, &proto.ActivePolicyUpdate{Id: &proto.PolicyID{Tier: "default", Name: "removed"}, Policy: &proto.Policy{}})
+			writeMsg(conn, &proto.ActivePolicyRemove{Id: &proto.PolicyID{Tier: "default", Name: "removed"}})
+			writeMsg(conn, &proto.IPSetUpdate{Id: "set", Members: []string{"10.0.0.1/32", "10.0.0.2/32"}})
+			writeMsg(conn, &proto.IPSetDeltaUpdate{Id: "set", AddedMembers: []string{"10.0.0.3/32"}, RemovedMembers: []string{"10.0.0.1/32"}})
+			writeMsg(conn, &proto.InSync{})
+
+			Eventually(done, "3s").Should(BeClosed())
+			Expect(ipSet.Members).To(Equal([]string{"10.0.0.2/32", "10.0.0.3/32"}))
+		})
+
+		var _ = It("apply the rest of the batch when an update is refused", func() {
+			done := make(chan bool)
+			gomock.InOrder(
+				mockClient.EXPECT().ActivePolicyUpdate(gomock.Any(), gomock.Any()).Return(
+					&proto.Reply{Successful: false}, status.Error(codes.Unknown, "denies all the ingress traffic")),
+				mockClient.EXPECT().ActivePolicyUpdate(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+				mockClient.EXPECT().GetPolicyState(gomock.Any(), gomock.Any()).Return(&proto.PolicyStateReply{}, nil),
+				// the connection is still served once in sync
+				mockClient.EXPECT().UpdateIPSet(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ interface{}, _ interface{}, _ ...interface{}) (*proto.Reply, error) {
+						close(done)
+						return &proto.Reply{Successful: true}, nil
+					}),
+			)
+
+			writeMsg(conn, &proto.ActivePolicyUpdate{Id: &proto.PolicyID{Tier: "default", Name: "a"}, Policy: &proto.Policy{}})
+			writeMsg(conn, &proto.ActivePolicyUpdate{Id: &proto.PolicyID{Tier: "default", Name: "b"}, Policy: &proto.Policy{}})
+			writeMsg(conn, &proto.InSync{})
+			writeMsg(conn, &proto.IPSetUpdate{Id: "set"})
+
+			Eventually(done, "3s").Should(BeClosed())
+		})
+
+		var _ = It("remove the objects felix did not send", func() {
+			done := make(chan bool)
+			kept := &proto.PolicyID{Tier: "default", Name: "kept"}
+			stale := &proto.PolicyID{Tier: "default", Name: "stale"}
+			staleWorkload := &proto.WorkloadEndpointID{OrchestratorId: "k8s", WorkloadId: "default/gone", EndpointId: "eth0"}
+
+			mockClient.EXPECT().ActivePolicyUpdate(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil)
+			mockClient.EXPECT().GetPolicyState(gomock.Any(), gomock.Any()).Return(&proto.PolicyStateReply{
+				PolicyIds:           []*proto.PolicyID{kept, stale},
+				IpSetIds:            []string{"stale-set"},
+				WorkloadEndpointIds: []*proto.WorkloadEndpointID{staleWorkload},
+			}, nil)
+			gomock.InOrder(
+				mockClient.EXPECT().RemoveLocalEndpoint(gomock.Any(), &proto.WorkloadEndpointRemove{Id: staleWorkload}).Return(&proto.Reply{Successful: true}, nil),
+				mockClient.EXPECT().ActivePolicyRemove(gomock.Any(), &proto.ActivePolicyRemove{Id: stale}).Return(&proto.Reply{Successful: true}, nil),
+				mockClient.EXPECT().RemoveIPSet(gomock.Any(), &proto.IPSetRemove{Id: "stale-set"}).DoAndReturn(
+					func(_ interface{}, _ interface{}, _ ...interface{}) (*proto.Reply, error) {
+						close(done)
+						return &proto.Reply{Successful: true}, nil
+					}),
+			)
+
+			writeMsg(conn, &proto.ActivePolicyUpdate{Id: kept, Policy: &proto.Policy{}})
+			writeMsg(conn, &proto.InSync{})
+
+			Eventually(done, "3s").Should(BeClosed())
+		})
+	})
+
 	var _ = Context("SendMessage() should", func() {
 		var _ = It("return no error when sending ProcessStatusUpdate", func() {
 			msg := &proto.ProcessStatusUpdate{IsoTimestamp: "12315", Uptime: 232145123}
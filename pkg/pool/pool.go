@@ -19,68 +19,300 @@ import (
 	"errors"
 	"os"
 	"path"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
 )
 
+// The pool keeps its state next to the cache of the interface configs
+const stateSuffix = "-pool.json"
+
 type ResourcePool interface {
 	Get() (*Resource, error)
+	Allocate(owner *Allocation) (*Resource, error)
+	Allocations() []Resource
 	Release(res string)
+	Reclaim(res string, owner *Allocation) bool
 	Save(path string) error
+	Add(resources []*types.InterfaceInfo) int
+	Remove(res string) bool
+	Degrade(res string) bool
+	Resources() []Resource
+	Usage() (inUse int, total int)
+}
+
+// Allocation records the pod a resource is allocated to. The requests of
+// the CNI backend do not carry the container ID, the network namespace
+// tells the sandbox of the pod apart instead.
+type Allocation struct {
+	Netns        string    `json:"netns"`
+	PodInterface string    `json:"podinterface"`
+	PodNamespace string    `json:"podnamespace,omitempty"`
+	PodName      string    `json:"podname,omitempty"`
+	Endpoint     string    `json:"endpoint,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+func (a *Allocation) owns(owner *Allocation) bool {
+	return a != nil && owner != nil && a.Netns == owner.Netns && a.PodInterface == owner.PodInterface
 }
 
 type Resource struct {
 	InterfaceInfo *types.InterfaceInfo `json:"interfaceinfo"`
 	InUse         bool                 `json:"inuse"`
+	Allocation    *Allocation          `json:"allocation,omitempty"`
+	// The device is gone while in use, it leaves the pool once released
+	Degraded bool `json:"degraded,omitempty"`
 }
 
 type resourcePool struct {
 	Pool []*Resource `json:"pool"`
 	sync.Mutex
+	// Saved on every change when set
+	statePath string
 }
 
+// NewResourcePool creates the pool of the resources, the ones in use
+// being found from the state the pool saved last, or from the cache of
+// the interface configs without it
 func NewResourcePool(resources []*types.InterfaceInfo, cachePath string) ResourcePool {
-	pool := make([]*Resource, 0)
-	cached := getCachedResources(cachePath)
+	p := &resourcePool{Pool: make([]*Resource, 0)}
+	if cachePath != "" {
+		p.statePath = cachePath + stateSuffix
+	}
+	state := p.loadState()
+	var cached []*types.InterfaceInfo
+	if state == nil {
+		cached = getCachedResources(cachePath)
+	}
 	for _, r := range resources {
-		inUse := getInUse(cached, r)
-		res := &Resource{InterfaceInfo: r, InUse: inUse}
-		pool = append(pool, res)
+		res := &Resource{InterfaceInfo: r}
+		if state != nil {
+			if saved := state.find(r.InterfaceName); saved != nil {
+				res.InUse = saved.InUse
+				res.Allocation = saved.Allocation
+			}
+		} else {
+			res.InUse = getInUse(cached, r)
+		}
+		p.Pool = append(p.Pool, res)
 	}
-	return &resourcePool{Pool: pool}
+	_ = p.persist()
+	return p
 }
 
+// Get allocates a resource to no pod, like the one of the host
 func (p *resourcePool) Get() (*Resource, error) {
+	return p.Allocate(nil)
+}
+
+// Allocate allocates a free resource to the owner, it is left free when
+// the pool cannot save its state
+func (p *resourcePool) Allocate(owner *Allocation) (*Resource, error) {
 	p.Lock()
 	defer p.Unlock()
 	for _, r := range p.Pool {
 		if !r.InUse {
 			r.InUse = true
+			r.Allocation = owner
+			if err := p.persist(); err != nil {
+				r.InUse = false
+				r.Allocation = nil
+				return nil, err
+			}
 			return r, nil
 		}
 	}
 	return nil, errors.New("no free resources left")
 }
 
+// Allocations returns copies of the resources allocated to pods
+func (p *resourcePool) Allocations() []Resource {
+	p.Lock()
+	defer p.Unlock()
+	var res []Resource
+	for _, r := range p.Pool {
+		if r.InUse && r.Allocation != nil {
+			res = append(res, *r)
+		}
+	}
+	return res
+}
+
 func (p *resourcePool) Release(intfName string) {
 	p.Lock()
 	defer p.Unlock()
+	if r := p.find(intfName); r != nil {
+		p.release(r)
+		_ = p.persist()
+	}
+}
+
+func (p *resourcePool) release(r *Resource) {
+	if r.Degraded {
+		p.delete(r)
+		return
+	}
+	r.InUse = false
+	r.Allocation = nil
+}
+
+func (p *resourcePool) delete(r *Resource) {
+	for i := range p.Pool {
+		if p.Pool[i] == r {
+			p.Pool = append(p.Pool[:i], p.Pool[i+1:]...)
+			return
+		}
+	}
+}
+
+// Reclaim releases the resource only if it is still allocated to the
+// owner, and not to a pod it was allocated to since
+func (p *resourcePool) Reclaim(intfName string, owner *Allocation) bool {
+	p.Lock()
+	defer p.Unlock()
+	r := p.find(intfName)
+	if r == nil || !r.InUse || !r.Allocation.owns(owner) {
+		return false
+	}
+	p.release(r)
+	_ = p.persist()
+	return true
+}
+
+// Add appends the resources the pool does not have yet, as free ones,
+// and returns how many of them
+func (p *resourcePool) Add(resources []*types.InterfaceInfo) int {
+	p.Lock()
+	defer p.Unlock()
+	added := 0
+	for _, r := range resources {
+		if p.find(r.InterfaceName) == nil {
+			p.Pool = append(p.Pool, &Resource{InterfaceInfo: r})
+			added++
+		}
+	}
+	if added > 0 {
+		_ = p.persist()
+	}
+	return added
+}
+
+// Remove takes the resource out of the pool, unless it is in use
+func (p *resourcePool) Remove(intfName string) bool {
+	p.Lock()
+	defer p.Unlock()
+	r := p.find(intfName)
+	if r == nil || r.InUse {
+		return false
+	}
+	p.delete(r)
+	_ = p.persist()
+	return true
+}
+
+// Degrade marks the resource in use whose device is gone
+func (p *resourcePool) Degrade(intfName string) bool {
+	p.Lock()
+	defer p.Unlock()
+	r := p.find(intfName)
+	if r == nil || !r.InUse || r.Degraded {
+		return false
+	}
+	r.Degraded = true
+	_ = p.persist()
+	return true
+}
+
+// Resources returns copies of all the resources of the pool
+func (p *resourcePool) Resources() []Resource {
+	p.Lock()
+	defer p.Unlock()
+	res := make([]Resource, 0, len(p.Pool))
+	for _, r := range p.Pool {
+		res = append(res, *r)
+	}
+	return res
+}
+
+func (p *resourcePool) Usage() (int, int) {
+	p.Lock()
+	defer p.Unlock()
+	inUse := 0
 	for _, r := range p.Pool {
-		if intfName == r.InterfaceInfo.InterfaceName {
-			r.InUse = false
-			break
+		if r.InUse {
+			inUse++
 		}
 	}
+	return inUse, len(p.Pool)
+}
+
+func (p *resourcePool) find(intfName string) *Resource {
+	for _, r := range p.Pool {
+		if r.InterfaceInfo != nil && intfName == r.InterfaceInfo.InterfaceName {
+			return r
+		}
+	}
+	return nil
 }
 
 func (p *resourcePool) Save(path string) error {
+	p.Lock()
+	defer p.Unlock()
+	return p.save(path)
+}
+
+// The file is replaced at once, it is never left half written
+func (p *resourcePool) save(path string) error {
 	bs, err := json.Marshal(p)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, bs, 0644)
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(bs); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (p *resourcePool) persist() error {
+	if p.statePath == "" {
+		return nil
+	}
+	return p.save(p.statePath)
+}
+
+func (p *resourcePool) loadState() *resourcePool {
+	if p.statePath == "" {
+		return nil
+	}
+	state, err := Load(p.statePath)
+	if err != nil {
+		return nil
+	}
+	return state.(*resourcePool)
 }
 
 func Load(path string) (ResourcePool, error) {
@@ -149,6 +149,109 @@ var _ = Describe("pool", func() {
 		})
 	})
 
+	var _ = Context("Add() should", func() {
+		var _ = It("append the new resources as free ones only", func() {
+			rp := resourcePool{Pool: testPool}
+			_, err := rp.Get()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rp.Add([]*types.InterfaceInfo{{InterfaceName: "iface0"}, {InterfaceName: "iface2"}})).To(Equal(1))
+			Expect(rp.Pool).To(HaveLen(3))
+			Expect(rp.Pool[0].InUse).To(BeTrue())
+			Expect(rp.Pool[2].InterfaceInfo.InterfaceName).To(Equal("iface2"))
+			Expect(rp.Pool[2].InUse).To(BeFalse())
+		})
+	})
+
+	var _ = Context("Remove() should", func() {
+		var _ = It("take the free resources out of the pool only", func() {
+			rp := resourcePool{Pool: testPool}
+			r, err := rp.Get()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rp.Remove(r.InterfaceInfo.InterfaceName)).To(BeFalse())
+			Expect(rp.Remove("iface1")).To(BeTrue())
+			Expect(rp.Remove("iface2")).To(BeFalse())
+			Expect(rp.Pool).To(HaveLen(1))
+		})
+	})
+
+	var _ = Context("Degrade() should", func() {
+		var _ = It("mark the resources in use only, which leave the pool once released", func() {
+			rp := resourcePool{Pool: testPool}
+			r, err := rp.Get()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rp.Degrade("iface1")).To(BeFalse())
+			Expect(rp.Degrade(r.InterfaceInfo.InterfaceName)).To(BeTrue())
+			Expect(rp.Degrade(r.InterfaceInfo.InterfaceName)).To(BeFalse())
+			Expect(rp.Resources()[0].Degraded).To(BeTrue())
+
+			rp.Release(r.InterfaceInfo.InterfaceName)
+			Expect(rp.Resources()).To(HaveLen(1))
+			Expect(rp.Resources()[0].InterfaceInfo.InterfaceName).To(Equal("iface1"))
+		})
+	})
+
+	var _ = Context("Usage() should", func() {
+		var _ = It("count the resources in use and all of them", func() {
+			rp := resourcePool{Pool: testPool}
+			_, err := rp.Get()
+			Expect(err).ToNot(HaveOccurred())
+
+			inUse, total := rp.Usage()
+			Expect(inUse).To(Equal(1))
+			Expect(total).To(Equal(2))
+		})
+	})
+
+	var _ = Context("Allocate() should", func() {
+		var _ = It("record the owner of the resource and save the pool", func() {
+			statePath := path.Join(testTempDir, "allocate"+stateSuffix)
+			rp := &resourcePool{Pool: testPool, statePath: statePath}
+			owner := &Allocation{Netns: "/var/run/netns/cni-1", PodInterface: "eth0", PodNamespace: "default", PodName: "pod1"}
+			r, err := rp.Allocate(owner)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.Allocation).To(Equal(owner))
+			Expect(rp.Allocations()).To(ConsistOf(*r))
+
+			saved, err := Load(statePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(saved.Allocations()).To(HaveLen(1))
+			Expect(saved.Allocations()[0].Allocation.PodName).To(Equal("pod1"))
+			entries, err := os.ReadDir(testTempDir)
+			Expect(err).ToNot(HaveOccurred())
+			for _, e := range entries {
+				Expect(e.Name()).ToNot(ContainSubstring(".tmp"))
+			}
+		})
+
+		var _ = It("leave the resource free if the pool cannot be saved", func() {
+			file := path.Join(testTempDir, "not-a-dir")
+			Expect(os.WriteFile(file, nil, 0600)).To(Succeed())
+			rp := &resourcePool{Pool: testPool, statePath: path.Join(file, "state")}
+			_, err := rp.Allocate(&Allocation{Netns: "/var/run/netns/cni-1"})
+			Expect(err).To(HaveOccurred())
+			inUse, _ := rp.Usage()
+			Expect(inUse).To(Equal(0))
+		})
+	})
+
+	var _ = Context("Reclaim() should", func() {
+		var _ = It("release the resource still allocated to the owner only", func() {
+			rp := resourcePool{Pool: testPool}
+			owner := &Allocation{Netns: "/var/run/netns/cni-1", PodInterface: "eth0"}
+			r, err := rp.Allocate(owner)
+			Expect(err).ToNot(HaveOccurred())
+			name := r.InterfaceInfo.InterfaceName
+
+			Expect(rp.Reclaim(name, &Allocation{Netns: "/var/run/netns/cni-2", PodInterface: "eth0"})).To(BeFalse())
+			Expect(rp.Reclaim(name, owner)).To(BeTrue())
+			Expect(rp.Reclaim(name, owner)).To(BeFalse())
+			Expect(rp.Allocations()).To(BeEmpty())
+		})
+	})
+
 	var _ = Context("NewResourcePool() should", func() {
 		var _ = It("return ResourcePool with one element", func() {
 			t := []*types.InterfaceInfo{{
@@ -184,5 +287,26 @@ var _ = Describe("pool", func() {
 			res := rp.(*resourcePool).Pool[1]
 			Expect(res.InUse).To(BeTrue())
 		})
+
+		var _ = It("restore the allocations from the state it saved", func() {
+			t := []*types.InterfaceInfo{
+				{InterfaceName: "iface0"},
+				{InterfaceName: "iface1"},
+			}
+			p := path.Join(testTempDir, "test-cache-2")
+			rp := NewResourcePool(t, p)
+			_, err := rp.Get()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rp.Allocate(&Allocation{Netns: "/var/run/netns/cni-1", PodInterface: "eth0"})
+			Expect(err).ToNot(HaveOccurred())
+			rp.Release("iface0")
+
+			rp = NewResourcePool(t, p)
+			Expect(rp.(*resourcePool).Pool[0].InUse).To(BeFalse())
+			allocations := rp.Allocations()
+			Expect(allocations).To(HaveLen(1))
+			Expect(allocations[0].InterfaceInfo.InterfaceName).To(Equal("iface1"))
+			Expect(allocations[0].Allocation.Netns).To(Equal("/var/run/netns/cni-1"))
+		})
 	})
 })
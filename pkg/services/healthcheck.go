@@ -0,0 +1,171 @@
+// Copyright (c) 2022 Intel Corporation.  All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+)
+
+var listen = net.Listen
+
+// healthCheckServer serves the health check node ports of the load
+// balancer services with the Local external traffic policy, in place
+// of kube-proxy. The load balancers only send traffic to the nodes
+// answering that they have local endpoints.
+type healthCheckServer struct {
+	log      *logrus.Entry
+	mutex    sync.Mutex
+	services map[string]*healthCheckService
+}
+
+type healthCheckService struct {
+	namespace      string
+	name           string
+	port           int32
+	localEndpoints int
+	server         *http.Server
+}
+
+// The body of the reply, the same as the one of kube-proxy
+type healthCheckReply struct {
+	Service struct {
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+	} `json:"service"`
+	LocalEndpoints int `json:"localEndpoints"`
+}
+
+func newHealthCheckServer(log *logrus.Entry) *healthCheckServer {
+	return &healthCheckServer{
+		log:      log,
+		services: make(map[string]*healthCheckService),
+	}
+}
+
+// Counts the ready endpoints of the service on the node
+func countLocalEndpoints(slices []*discovery.EndpointSlice) int {
+	count := 0
+	for _, slice := range slices {
+		for i := range slice.Endpoints {
+			endpoint := &slice.Endpoints[i]
+			if isEndpointLocal(endpoint) && isEndpointReady(endpoint) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// syncService serves the health check node port of the service, if it
+// has any, along with the number of its local endpoints. The port is
+// closed once the service does not have it anymore.
+func (h *healthCheckServer) syncService(service *v1.Service, slices []*discovery.EndpointSlice) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	serviceID := serviceID(&service.ObjectMeta)
+	port := service.Spec.HealthCheckNodePort
+	hs, found := h.services[serviceID]
+	if found && hs.port != port {
+		h.closeService(serviceID, hs)
+		found = false
+	}
+	if port == 0 {
+		return nil
+	}
+	if !found {
+		l, err := listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			return err
+		}
+		hs = &healthCheckService{
+			namespace: service.Namespace,
+			name:      service.Name,
+			port:      port,
+		}
+		hs.server = &http.Server{Handler: h.handler(serviceID)}
+		go func() {
+			if err := hs.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				h.log.WithError(err).Errorf("Health check of service %s stopped", serviceID)
+			}
+		}()
+		h.services[serviceID] = hs
+		h.log.Infof("Serving health check of service %s on port %d", serviceID, port)
+	}
+	hs.localEndpoints = countLocalEndpoints(slices)
+	return nil
+}
+
+func (h *healthCheckServer) deleteService(serviceID string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if hs, found := h.services[serviceID]; found {
+		h.closeService(serviceID, hs)
+	}
+}
+
+// Closes the health check node ports of all the services
+func (h *healthCheckServer) stop() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for serviceID, hs := range h.services {
+		h.closeService(serviceID, hs)
+	}
+}
+
+func (h *healthCheckServer) closeService(serviceID string, hs *healthCheckService) {
+	if err := hs.server.Close(); err != nil {
+		h.log.WithError(err).Errorf("Failed to close health check of service %s", serviceID)
+	}
+	delete(h.services, serviceID)
+}
+
+// The node is healthy for the service as long as it has local
+// endpoints, the load balancer has to avoid it otherwise
+func (h *healthCheckServer) handler(serviceID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.mutex.Lock()
+		hs, found := h.services[serviceID]
+		var reply healthCheckReply
+		if found {
+			reply.Service.Namespace = hs.namespace
+			reply.Service.Name = hs.name
+			reply.LocalEndpoints = hs.localEndpoints
+		}
+		h.mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if reply.LocalEndpoints == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		if err := json.NewEncoder(w).Encode(&reply); err != nil {
+			h.log.WithError(err).Errorf("Failed to reply to health check of service %s", serviceID)
+		}
+	}
+}
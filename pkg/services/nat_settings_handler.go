@@ -17,18 +17,14 @@ package services
 import (
 	"context"
 	"errors"
-	"fmt"
-
-	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
 
+	managerclient "github.com/ipdk-io/k8s-infra-offload/pkg/manager_client"
 	pb "github.com/ipdk-io/k8s-infra-offload/proto"
 	"github.com/sirupsen/logrus"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 var (
-	grpcDial            = grpc.Dial
+	getManagerConn      = managerclient.GetConn
 	newInfraAgentClient = pb.NewInfraAgentClient
 )
 
@@ -40,25 +36,24 @@ func NewNatServiceHandler(log *logrus.Entry) *ServiceHandler {
 	return &ServiceHandler{log: log}
 }
 
-func (s *ServiceHandler) dialManager() (pb.InfraAgentClient, *grpc.ClientConn, error) {
-	managerAddr := fmt.Sprintf("%s:%s", types.InfraManagerAddr, types.InfraManagerPort)
-	s.log.Info("dialer using manager address: ", managerAddr)
-	conn, err := grpcDial(managerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func (s *ServiceHandler) dialManager() (pb.InfraAgentClient, error) {
+	conn, err := getManagerConn()
 	if err != nil {
 		s.log.Errorf("unable to dial Infra Manager. err %v", err)
-		return nil, nil, err
+		return nil, err
 	}
-	return newInfraAgentClient(conn), conn, nil
+	return newInfraAgentClient(conn), nil
 }
 
 func (s *ServiceHandler) NatTranslationAdd(translation *pb.NatTranslation) error {
 	s.log.Infof("NatTranslationAdd endpoint %v", translation)
-	c, conn, err := s.dialManager()
+	c, err := s.dialManager()
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
-	reply, err := c.NatTranslationAdd(context.TODO(), translation)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	reply, err := c.NatTranslationAdd(ctx, translation)
 	if err != nil {
 		s.log.Errorf("Error calling  infra manager NatTranslationAdd serivce: %v", err)
 		return err
@@ -69,14 +64,19 @@ func (s *ServiceHandler) NatTranslationAdd(translation *pb.NatTranslation) error
 	return nil
 }
 
-func (s *ServiceHandler) SetSnatAddress(ip string) error {
+func (s *ServiceHandler) SetSnatAddress(ipv4, ipv6 string) error {
 	s.log.Info("SetSnatAddress")
-	c, conn, err := s.dialManager()
+	c, err := s.dialManager()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	reply, err := c.SetSnatAddress(ctx, &pb.SetSnatAddressRequest{SnatIpv4: ipv4, SnatIpv6: ipv6})
 	if err != nil {
+		s.log.Errorf("Error calling infra manager SetSnatAddress service: %v", err)
 		return err
 	}
-	defer conn.Close()
-	reply, _ := c.SetSnatAddress(context.TODO(), &pb.SetSnatAddressRequest{SnatIpv4: ip, SnatIpv6: ""})
 	if !reply.Successful {
 		return errors.New(reply.ErrorMessage)
 	}
@@ -85,12 +85,17 @@ func (s *ServiceHandler) SetSnatAddress(ip string) error {
 
 func (s *ServiceHandler) AddDelSnatPrefix(ip string, isAdd bool) error {
 	s.log.Info("AddDelSnatPrefix")
-	c, conn, err := s.dialManager()
+	c, err := s.dialManager()
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
-	reply, _ := c.AddDelSnatPrefix(context.TODO(), &pb.AddDelSnatPrefixRequest{IsAdd: isAdd, Prefix: ip})
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	reply, err := c.AddDelSnatPrefix(ctx, &pb.AddDelSnatPrefixRequest{IsAdd: isAdd, Prefix: ip})
+	if err != nil {
+		s.log.Errorf("Error calling infra manager AddDelSnatPrefix service: %v", err)
+		return err
+	}
 	if !reply.Successful {
 		return errors.New(reply.ErrorMessage)
 	}
@@ -99,14 +104,38 @@ func (s *ServiceHandler) AddDelSnatPrefix(ip string, isAdd bool) error {
 
 func (s *ServiceHandler) NatTranslationDelete(translation *pb.NatTranslation) error {
 	s.log.Infof("NatTranslationDelete %v", translation)
-	c, conn, err := s.dialManager()
+	c, err := s.dialManager()
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
-	reply, _ := c.NatTranslationDelete(context.TODO(), translation)
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	reply, err := c.NatTranslationDelete(ctx, translation)
+	if err != nil {
+		s.log.Errorf("Error calling infra manager NatTranslationDelete service: %v", err)
+		return err
+	}
 	if !reply.Successful {
 		return errors.New(reply.ErrorMessage)
 	}
 	return nil
 }
+
+func (s *ServiceHandler) NatTranslationUpdate(update *pb.NatTranslationUpdateRequest) error {
+	s.log.Infof("NatTranslationUpdate %v", update)
+	c, err := s.dialManager()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := managerclient.NewCallContext(context.Background())
+	defer cancel()
+	reply, err := c.NatTranslationUpdate(ctx, update)
+	if err != nil {
+		s.log.Errorf("Error calling infra manager NatTranslationUpdate service: %v", err)
+		return err
+	}
+	if reply != nil && !reply.Successful {
+		return errors.New(reply.ErrorMessage)
+	}
+	return nil
+}
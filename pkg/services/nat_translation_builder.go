@@ -20,6 +20,7 @@ import (
 	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
 	"github.com/ipdk-io/k8s-infra-offload/proto"
 	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -27,21 +28,27 @@ type NatTranslationBuilder interface {
 	ForServicePort(*v1.ServicePort) NatTranslationBuilder
 	WithServiceIP(serviceIP net.IP) NatTranslationBuilder
 	WithIsNodePort(isNodePort bool) NatTranslationBuilder
+	WithIsExternal(isExternal bool) NatTranslationBuilder
 	Build() *proto.NatTranslation
 }
 
 type natTranslationBuilder struct {
 	servicePort *v1.ServicePort
 	service     *v1.Service
-	ep          *v1.Endpoints
+	slices      []*discovery.EndpointSlice
+	nodeZone    string
 	serviceIP   net.IP
 	isNodePort  bool
+	isExternal  bool
 }
 
-func NewNatTranslationBuilder(service *v1.Service, ep *v1.Endpoints) NatTranslationBuilder {
+// The endpoints of the service are merged from all of its slices, the
+// zone of the node is used for the topology hints
+func NewNatTranslationBuilder(service *v1.Service, slices []*discovery.EndpointSlice, nodeZone string) NatTranslationBuilder {
 	return &natTranslationBuilder{
-		service: service,
-		ep:      ep,
+		service:  service,
+		slices:   slices,
+		nodeZone: nodeZone,
 	}
 }
 
@@ -54,6 +61,14 @@ func (b *natTranslationBuilder) WithIsNodePort(isNodePort bool) NatTranslationBu
 	return b
 }
 
+// The external addresses of the service, its node ports, external IPs
+// and load balancer ingress IPs, follow the external traffic policy,
+// the cluster IPs the internal one
+func (b *natTranslationBuilder) WithIsExternal(isExternal bool) NatTranslationBuilder {
+	b.isExternal = isExternal
+	return b
+}
+
 func (b *natTranslationBuilder) WithServiceIP(serviceIP net.IP) NatTranslationBuilder {
 	b.serviceIP = serviceIP
 	return b
@@ -65,62 +80,183 @@ func (b *natTranslationBuilder) ForServicePort(servicePort *v1.ServicePort) NatT
 }
 
 func (b *natTranslationBuilder) buildNatEntryForServicePort() *proto.NatTranslation {
+	// The pipeline does not translate the source of the service
+	// traffic, the address of the client is preserved
 	backends := make([]*proto.NatEndpointTuple, 0)
-	isLocal := isLocalOnly(b.service)
-	if b.isNodePort {
-		isLocal = false
-	}
-	for _, endpointSubset := range b.ep.Subsets {
-		for _, endpointPort := range endpointSubset.Ports {
-			if b.servicePort.Name == endpointPort.Name {
-				for _, endpointAddress := range endpointSubset.Addresses {
-					if !isEndpointAddressLocal(&endpointAddress) && isLocal {
-						continue
-					}
-					// set dst addr
-					backend := &proto.NatEndpointTuple{
-						DstEp: &proto.NatEndpoint{
-							// set port
-							Port:     getDstPort(b.servicePort, &endpointPort),
-							Ipv4Addr: endpointAddress.IP,
-						},
-					}
-					if b.isNodePort {
-						// add snat for nodeports
-						backend.SrcEp = &proto.NatEndpoint{
-							Ipv4Addr: b.serviceIP.String(),
-						}
-					}
-					backends = append(backends, backend)
-				}
-
-			}
+	for _, ep := range b.serviceEndpoints() {
+		// set dst addr
+		backend := &proto.NatEndpointTuple{
+			// set port
+			DstEp: newNatEndpoint(ep.ip, getDstPort(b.servicePort, ep.port)),
 		}
+		backends = append(backends, backend)
 	}
 	return &proto.NatTranslation{
 		Proto: string(b.servicePort.Protocol),
-		Endpoint: &proto.NatEndpoint{
-			// set port
-			Port:     getVipDstPort(b.servicePort, b.isNodePort),
-			Ipv4Addr: b.serviceIP.String(),
-		},
+		// set port
+		Endpoint: newNatEndpoint(b.serviceIP.String(), getVipDstPort(b.servicePort, b.isNodePort)),
 		Backends: backends,
 		IsRealIp: b.isNodePort,
+
+		SessionAffinityTimeout: sessionAffinityTimeout(b.service),
 	}
 }
 
-func isLocalOnly(service *v1.Service) bool {
-	return service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal
+// Returns the timeout of the ClientIP affinity of the service, the
+// default one of Kubernetes when it is not set
+func sessionAffinityTimeout(service *v1.Service) uint32 {
+	if service.Spec.SessionAffinity != v1.ServiceAffinityClientIP {
+		return 0
+	}
+	config := service.Spec.SessionAffinityConfig
+	if config != nil && config.ClientIP != nil && config.ClientIP.TimeoutSeconds != nil {
+		return uint32(*config.ClientIP.TimeoutSeconds)
+	}
+	return uint32(v1.DefaultClientIPServiceAffinitySeconds)
+}
+
+// An address of an endpoint of the service, with the port of its slice
+// matching the service port
+type sliceEndpoint struct {
+	ip       string
+	port     *discovery.EndpointPort
+	endpoint *discovery.Endpoint
 }
 
-func isEndpointAddressLocal(endpointAddress *v1.EndpointAddress) bool {
-	if endpointAddress != nil && endpointAddress.NodeName != nil && *endpointAddress.NodeName != types.NodeName {
-		return false
+// Returns the addresses the service port is load balanced to, once
+// from all the slices. The ready endpoints are used when there are
+// any, otherwise the terminating ones which are still serving are, so
+// that connections are not dropped while a deployment rolls out.
+func (b *natTranslationBuilder) serviceEndpoints() []sliceEndpoint {
+	isLocal := b.isLocalOnly()
+	ready := make([]sliceEndpoint, 0)
+	terminating := make([]sliceEndpoint, 0)
+	seen := make(map[string]bool)
+	for _, slice := range b.slices {
+		if slice.AddressType == discovery.AddressTypeFQDN {
+			continue
+		}
+		port := findSlicePort(slice, b.servicePort.Name)
+		if port == nil {
+			continue
+		}
+		for i := range slice.Endpoints {
+			endpoint := &slice.Endpoints[i]
+			if !isEndpointLocal(endpoint) && isLocal {
+				continue
+			}
+			isReady := isEndpointReady(endpoint)
+			if !isReady && !isEndpointServingTerminating(endpoint) {
+				continue
+			}
+			for _, ip := range endpoint.Addresses {
+				// the translation does not change the address family
+				if seen[ip] || !isSameFamily(net.ParseIP(ip), b.serviceIP) {
+					continue
+				}
+				seen[ip] = true
+				ep := sliceEndpoint{ip: ip, port: port, endpoint: endpoint}
+				if isReady {
+					ready = append(ready, ep)
+				} else {
+					terminating = append(terminating, ep)
+				}
+			}
+		}
+	}
+	if len(ready) == 0 {
+		return terminating
 	}
-	return true
+	return filterByZone(b.service, ready, b.nodeZone)
 }
 
-func getDstPort(servicePort *v1.ServicePort, endpointPort *v1.EndpointPort) uint32 {
+func findSlicePort(slice *discovery.EndpointSlice, name string) *discovery.EndpointPort {
+	for i := range slice.Ports {
+		port := &slice.Ports[i]
+		if port.Name == nil && name == "" || port.Name != nil && *port.Name == name {
+			return port
+		}
+	}
+	return nil
+}
+
+// An unknown readiness has to be taken as ready
+func isEndpointReady(endpoint *discovery.Endpoint) bool {
+	return endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+}
+
+func isEndpointServingTerminating(endpoint *discovery.Endpoint) bool {
+	c := endpoint.Conditions
+	return c.Serving != nil && *c.Serving && c.Terminating != nil && *c.Terminating
+}
+
+// Keeps the endpoints hinted for the zone of the node, like kube-proxy
+// does. The hints are only used when the service asks for them and all
+// of the endpoints have some, and never leave the service without
+// endpoints.
+func filterByZone(service *v1.Service, endpoints []sliceEndpoint, zone string) []sliceEndpoint {
+	hints := service.Annotations[v1.AnnotationTopologyAwareHints]
+	if zone == "" || (hints != "Auto" && hints != "auto") {
+		return endpoints
+	}
+	filtered := make([]sliceEndpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if ep.endpoint.Hints == nil || len(ep.endpoint.Hints.ForZones) == 0 {
+			return endpoints
+		}
+		for _, z := range ep.endpoint.Hints.ForZones {
+			if z.Name == zone {
+				filtered = append(filtered, ep)
+				break
+			}
+		}
+	}
+	if len(filtered) == 0 {
+		return endpoints
+	}
+	return filtered
+}
+
+// Returns the endpoint with the address set in the field of its family
+func newNatEndpoint(ip string, port uint32) *proto.NatEndpoint {
+	ep := &proto.NatEndpoint{Port: port}
+	if addr := net.ParseIP(ip); addr != nil && addr.To4() == nil {
+		ep.Ipv6Addr = ip
+	} else {
+		ep.Ipv4Addr = ip
+	}
+	return ep
+}
+
+func isSameFamily(a, b net.IP) bool {
+	return a != nil && b != nil && (a.To4() == nil) == (b.To4() == nil)
+}
+
+// Returns whether the traffic to the address being built is only sent
+// to the endpoints on the node
+func (b *natTranslationBuilder) isLocalOnly() bool {
+	if b.isExternal {
+		return isExternalLocalOnly(b.service)
+	}
+	return isInternalLocalOnly(b.service)
+}
+
+func isExternalLocalOnly(service *v1.Service) bool {
+	return service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal
+}
+
+func isInternalLocalOnly(service *v1.Service) bool {
+	policy := service.Spec.InternalTrafficPolicy
+	return policy != nil && *policy == v1.ServiceInternalTrafficPolicyLocal
+}
+
+// An endpoint is local when it names the node, one without a node name
+// may run anywhere
+func isEndpointLocal(endpoint *discovery.Endpoint) bool {
+	return endpoint != nil && endpoint.NodeName != nil && *endpoint.NodeName == types.NodeName
+}
+
+func getDstPort(servicePort *v1.ServicePort, endpointPort *discovery.EndpointPort) uint32 {
 	targetPort := servicePort.TargetPort
 	if targetPort.Type == intstr.Int {
 		if targetPort.IntVal == 0 {
@@ -129,13 +265,14 @@ func getDstPort(servicePort *v1.ServicePort, endpointPort *v1.EndpointPort) uint
 		} else {
 			return uint32(targetPort.IntVal)
 		}
-	} else {
-		return uint32(endpointPort.Port)
+	} else if endpointPort.Port != nil {
+		return uint32(*endpointPort.Port)
 	}
+	return 0
 }
 
 func getVipDstPort(servicePort *v1.ServicePort, isNodePort bool) uint32 {
-	if !isNodePort {
+	if isNodePort {
 		return uint32(servicePort.NodePort)
 	}
 	return uint32(servicePort.Port)
@@ -15,11 +15,11 @@
 package services
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"net"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,71 +29,35 @@ import (
 	"github.com/sirupsen/logrus"
 	"gopkg.in/tomb.v2"
 	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
 )
 
 var (
 	newForConfig = utils.GetK8sClient
 	getK8sConfig = utils.GetK8sConfig
+	getNodeZone  = utils.GetNodeZone
+	getNodeIPs   = utils.GetNodeIPs
 )
 
-type ServicesListWatchType int
-
-const (
-	SERVICES_LIST_WATCH ServicesListWatchType = iota
-	ENDPOINTS_LIST_WATCH
-)
-
-type ServicesListWatch struct {
-	kubernetes.Interface
-	watchType ServicesListWatchType
-}
-
-func NewServiceListWatch(client kubernetes.Interface, watchType ServicesListWatchType) *ServicesListWatch {
-	return &ServicesListWatch{Interface: client, watchType: watchType}
-}
-
-func (w ServicesListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
-	switch w.watchType {
-	case SERVICES_LIST_WATCH:
-		return w.Interface.CoreV1().Services("").List(context.TODO(), options)
-	case ENDPOINTS_LIST_WATCH:
-		return w.Interface.CoreV1().Endpoints("").List(context.TODO(), options)
-	default:
-		return nil, fmt.Errorf("invalid watch list type")
-	}
-}
-
-func (w ServicesListWatch) Watch(options metav1.ListOptions) (watch.Interface, error) {
-	switch w.watchType {
-	case SERVICES_LIST_WATCH:
-		return w.Interface.CoreV1().Services("").Watch(context.TODO(), options)
-	case ENDPOINTS_LIST_WATCH:
-		return w.Interface.CoreV1().Endpoints("").Watch(context.TODO(), options)
-	default:
-		return nil, fmt.Errorf("invalid watch list type")
-	}
-}
-
-// Assert ServicesListWatch implements watch.ListerWatcher
-var _ cache.ListerWatcher = (*ServicesListWatch)(nil)
+// Indexes the endpoint slices by the service they belong to
+const serviceIndex = "service"
 
 type ServiceServer struct {
-	log                *logrus.Entry
-	endpointStore      cache.Store
-	serviceStore       cache.Store
-	endpointController cache.Controller
-	serviceController  cache.Controller
-	t                  tomb.Tomb
-	handler            NatSettingsHandler
-	nodeAddress        string
-	stateMap           map[string]ServiceEntries
-	name               string
-	mutex              sync.Mutex
+	log             *logrus.Entry
+	informerFactory informers.SharedInformerFactory
+	sliceStore      cache.Indexer
+	serviceStore    cache.Store
+	t               tomb.Tomb
+	handler         NatSettingsHandler
+	healthCheck     *healthCheckServer
+	nodeAddresses   []string
+	nodeZone        string
+	stateMap        map[string]ServiceEntries
+	name            string
+	mutex           sync.Mutex
 }
 
 type ServiceEntries struct {
@@ -103,33 +67,55 @@ type ServiceEntries struct {
 
 type NatSettingsHandler interface {
 	NatTranslationAdd(translation *proto.NatTranslation) error
-	SetSnatAddress(ip string) error
+	SetSnatAddress(ipv4, ipv6 string) error
 	AddDelSnatPrefix(ip string, isAdd bool) error
 	NatTranslationDelete(translation *proto.NatTranslation) error
+	NatTranslationUpdate(update *proto.NatTranslationUpdateRequest) error
 }
 
-func (s *ServiceServer) findMatchingEndpoints(service *v1.Service) *v1.Endpoints {
-	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(service)
+// Returns the key of the service an endpoint slice belongs to, the
+// slices not managed for a service have none
+func sliceServiceKey(slice *discovery.EndpointSlice) (string, bool) {
+	name, ok := slice.Labels[discovery.LabelServiceName]
+	if !ok || name == "" {
+		return "", false
+	}
+	return slice.Namespace + "/" + name, true
+}
+
+func indexByService(obj interface{}) ([]string, error) {
+	slice, ok := obj.(*discovery.EndpointSlice)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T", obj)
+	}
+	if key, ok := sliceServiceKey(slice); ok {
+		return []string{key}, nil
+	}
+	return nil, nil
+}
+
+func (s *ServiceServer) findMatchingSlices(service *v1.Service) []*discovery.EndpointSlice {
+	key, err := cache.MetaNamespaceKeyFunc(service)
 	if err != nil {
 		s.log.Errorf("Error getting service %+v key: %v", service, err)
 		return nil
 	}
-	ep, found, err := s.endpointStore.GetByKey(key)
+	objs, err := s.sliceStore.ByIndex(serviceIndex, key)
 	if err != nil {
-		s.log.Errorf("Error getting endpoint %s: %v", key, err)
+		s.log.Errorf("Error getting endpoint slices of %s: %v", key, err)
 		return nil
 	}
-	if !found {
-		s.log.Debugf("Endpoint %s not found", key)
-		return nil
+	slices := make([]*discovery.EndpointSlice, 0, len(objs))
+	for _, obj := range objs {
+		slices = append(slices, obj.(*discovery.EndpointSlice))
 	}
-	return ep.(*v1.Endpoints)
+	return slices
 }
 
-func (s *ServiceServer) findMatchingService(ep *v1.Endpoints) *v1.Service {
-	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(ep)
-	if err != nil {
-		s.log.Errorf("Error getting endpoint %+v key: %v", ep, err)
+func (s *ServiceServer) findMatchingService(slice *discovery.EndpointSlice) *v1.Service {
+	key, ok := sliceServiceKey(slice)
+	if !ok {
+		s.log.Debugf("Endpoint slice %s/%s has no service", slice.Namespace, slice.Name)
 		return nil
 	}
 	service, found, err := s.serviceStore.GetByKey(key)
@@ -144,37 +130,53 @@ func (s *ServiceServer) findMatchingService(ep *v1.Endpoints) *v1.Service {
 	return service.(*v1.Service)
 }
 
-func (s *ServiceServer) handleServiceEndpointEvent(service *v1.Service, endpoint *v1.Endpoints, isDel bool) {
+func (s *ServiceServer) handleServiceEvent(service *v1.Service, isDel bool) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if service != nil && endpoint == nil {
-		endpoint = s.findMatchingEndpoints(service)
-	}
-	if endpoint != nil && service == nil {
-		service = s.findMatchingService(endpoint)
+	s.log.Infof("update service %v namespace %v", service.Name, service.Namespace)
+	if isDel {
+		s.delServicePort(service)
+		return
 	}
-	if service == nil || endpoint == nil {
+	s.addServicePort(service, s.findMatchingSlices(service))
+}
+
+// The informer drops a slice from its store before it is handed over
+// as deleted, so the service is programmed with the slices remaining
+func (s *ServiceServer) handleSliceEvent(slice *discovery.EndpointSlice) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	service := s.findMatchingService(slice)
+	if service == nil {
 		return
 	}
-	s.log.Infof("update service %v namespace %v", service.Name, service.Namespace)
-	s.log.Infof("update endpoint name %v namespace %v", endpoint.Name, endpoint.Namespace)
+	s.log.Infof("update endpoint slice name %v namespace %v", slice.Name, slice.Namespace)
+	s.addServicePort(service, s.findMatchingSlices(service))
+}
 
-	if isDel {
-		s.delServicePort(service, endpoint)
-	} else {
-		s.addServicePort(service, endpoint)
+// A deleted object may come wrapped when its deletion was missed by
+// the watch
+func deletedObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
 	}
+	return obj
 }
 
-func buildNatTranslations(s *v1.Service, e *v1.Endpoints, nodeIP string) []*proto.NatTranslation {
+// The node ports of the service are reachable on every address of the
+// node
+func buildNatTranslations(s *v1.Service, slices []*discovery.EndpointSlice, nodeIPs []string, nodeZone string) []*proto.NatTranslation {
 	entries := make([]*proto.NatTranslation, 0)
-	clusterIP := net.ParseIP(s.Spec.ClusterIP)
-	builder := NewNatTranslationBuilder(s, e)
+	builder := NewNatTranslationBuilder(s, slices, nodeZone)
 	for _, servicePort := range s.Spec.Ports {
-		if clusterIP != nil && !clusterIP.IsUnspecified() {
-			entry := builder.ForServicePort(&servicePort).WithServiceIP(clusterIP).WithIsNodePort(false).Build()
-			entries = append(entries, entry)
+		for _, ip := range serviceClusterIPs(s) {
+			clusterIP := net.ParseIP(ip)
+			if clusterIP != nil && !clusterIP.IsUnspecified() {
+				entry := builder.ForServicePort(&servicePort).WithServiceIP(clusterIP).WithIsNodePort(false).WithIsExternal(false).Build()
+				entries = append(entries, entry)
+			}
 		}
 
 		externalIPsEntries := processExternalIPs(servicePort, s.Spec.ExternalIPs, builder)
@@ -183,24 +185,51 @@ func buildNatTranslations(s *v1.Service, e *v1.Endpoints, nodeIP string) []*prot
 		lbIngressEntries := processLBIngress(servicePort, s.Status.LoadBalancer.Ingress, builder)
 		entries = append(entries, lbIngressEntries...)
 
-		if s.Spec.Type == v1.ServiceTypeNodePort {
-			nip := net.ParseIP(nodeIP)
-			if nip != nil && !nip.IsUnspecified() {
-				entry := builder.ForServicePort(&servicePort).WithServiceIP(nip).WithIsNodePort(true).Build()
-				entries = append(entries, entry)
-			}
-		}
+		nodePortEntries := processNodePorts(s, servicePort, nodeIPs, builder)
+		entries = append(entries, nodePortEntries...)
+	}
+	return entries
+}
+
+// The load balancer services have node ports as well, unless they are
+// not allocated for them
+func hasNodePort(s *v1.Service, servicePort v1.ServicePort) bool {
+	if s.Spec.Type != v1.ServiceTypeNodePort && s.Spec.Type != v1.ServiceTypeLoadBalancer {
+		return false
+	}
+	return servicePort.NodePort != 0
+}
 
+func processNodePorts(s *v1.Service, servicePort v1.ServicePort, nodeIPs []string, builder NatTranslationBuilder) []*proto.NatTranslation {
+	entries := make([]*proto.NatTranslation, 0)
+	if !hasNodePort(s, servicePort) {
+		return entries
+	}
+	for _, ip := range nodeIPs {
+		nip := net.ParseIP(ip)
+		if nip != nil && !nip.IsUnspecified() {
+			entry := builder.ForServicePort(&servicePort).WithServiceIP(nip).WithIsNodePort(true).WithIsExternal(true).Build()
+			entries = append(entries, entry)
+		}
 	}
 	return entries
 }
 
+// Returns the cluster IPs of the service, one per family for a
+// dual-stack service. Older API servers only set ClusterIP.
+func serviceClusterIPs(s *v1.Service) []string {
+	if len(s.Spec.ClusterIPs) > 0 {
+		return s.Spec.ClusterIPs
+	}
+	return []string{s.Spec.ClusterIP}
+}
+
 func processExternalIPs(servicePort v1.ServicePort, externalIPs []string, builder NatTranslationBuilder) []*proto.NatTranslation {
 	entries := make([]*proto.NatTranslation, 0)
 	for _, eip := range externalIPs {
 		extIP := net.ParseIP(eip)
 		if extIP != nil && !extIP.IsUnspecified() {
-			entry := builder.ForServicePort(&servicePort).WithServiceIP(extIP).WithIsNodePort(false).Build()
+			entry := builder.ForServicePort(&servicePort).WithServiceIP(extIP).WithIsNodePort(false).WithIsExternal(true).Build()
 			entries = append(entries, entry)
 		}
 	}
@@ -212,7 +241,7 @@ func processLBIngress(servicePort v1.ServicePort, ingress []v1.LoadBalancerIngre
 	for _, ingress := range ingress {
 		ingressIP := net.ParseIP(ingress.IP)
 		if ingressIP != nil && !ingressIP.IsUnspecified() {
-			entry := builder.ForServicePort(&servicePort).WithServiceIP(ingressIP).WithIsNodePort(false).Build()
+			entry := builder.ForServicePort(&servicePort).WithServiceIP(ingressIP).WithIsNodePort(false).WithIsExternal(true).Build()
 			entries = append(entries, entry)
 		}
 	}
@@ -223,9 +252,10 @@ func serviceID(meta *metav1.ObjectMeta) string {
 	return meta.Namespace + "/" + meta.Name
 }
 
-func (s *ServiceServer) delServicePort(service *v1.Service, ep *v1.Endpoints) {
+func (s *ServiceServer) delServicePort(service *v1.Service) {
 	serviceID := serviceID(&service.ObjectMeta)
 	s.log.Infof("Del: got service id %s", serviceID)
+	s.healthCheck.deleteService(serviceID)
 	if entry, ok := s.stateMap[serviceID]; ok {
 		s.log.Infof("Delete entry %s from state", serviceID)
 		for _, nt := range entry.Entries {
@@ -240,44 +270,194 @@ func (s *ServiceServer) delServicePort(service *v1.Service, ep *v1.Endpoints) {
 	}
 }
 
-func (s *ServiceServer) addServicePort(service *v1.Service, ep *v1.Endpoints) {
+func natTranslationKey(nt *proto.NatTranslation) string {
+	if nt.Endpoint == nil {
+		return nt.Proto
+	}
+	ip := nt.Endpoint.Ipv4Addr
+	if ip == "" {
+		return fmt.Sprintf("%s/[%s]:%d", nt.Proto, nt.Endpoint.Ipv6Addr, nt.Endpoint.Port)
+	}
+	return fmt.Sprintf("%s/%s:%d", nt.Proto, ip, nt.Endpoint.Port)
+}
+
+func natBackendKey(backend *proto.NatEndpointTuple) string {
+	return backend.String()
+}
+
+// diffBackends returns the backends of newEntry missing in oldEntry and
+// the backends of oldEntry missing in newEntry
+func diffBackends(oldEntry, newEntry *proto.NatTranslation) ([]*proto.NatEndpointTuple, []*proto.NatEndpointTuple) {
+	oldBackends := make(map[string]bool, len(oldEntry.Backends))
+	for _, backend := range oldEntry.Backends {
+		oldBackends[natBackendKey(backend)] = true
+	}
+	newBackends := make(map[string]bool, len(newEntry.Backends))
+	for _, backend := range newEntry.Backends {
+		newBackends[natBackendKey(backend)] = true
+	}
+
+	added := make([]*proto.NatEndpointTuple, 0)
+	for _, backend := range newEntry.Backends {
+		if !oldBackends[natBackendKey(backend)] {
+			added = append(added, backend)
+		}
+	}
+	removed := make([]*proto.NatEndpointTuple, 0)
+	for _, backend := range oldEntry.Backends {
+		if !newBackends[natBackendKey(backend)] {
+			removed = append(removed, backend)
+		}
+	}
+	return added, removed
+}
+
+func (s *ServiceServer) addServicePort(service *v1.Service, slices []*discovery.EndpointSlice) {
 	serviceID := serviceID(&service.ObjectMeta)
 	s.log.Infof("Add: got service id %s", serviceID)
+	if err := s.healthCheck.syncService(service, slices); err != nil {
+		s.log.WithError(err).Errorf("Failed to serve health check of service %s", serviceID)
+	}
 	se := ServiceEntries{
-		Entries:   buildNatTranslations(service, ep, s.nodeAddress),
+		Entries:   buildNatTranslations(service, slices, s.nodeAddresses, s.nodeZone),
 		ServiceID: serviceID,
 	}
-	if oldEntry, found := s.stateMap[serviceID]; found {
-		if reflect.DeepEqual(se.Entries, oldEntry.Entries) {
-			s.log.Infof("No change in entry %s, do not update anything", serviceID)
-			return
+	oldEntry, found := s.stateMap[serviceID]
+	if found && reflect.DeepEqual(se.Entries, oldEntry.Entries) {
+		s.log.Infof("No change in entry %s, do not update anything", serviceID)
+		return
+	}
+	oldEntries := make(map[string]*proto.NatTranslation)
+	for _, nt := range oldEntry.Entries {
+		oldEntries[natTranslationKey(nt)] = nt
+	}
+
+	newEntries := make(map[string]*proto.NatTranslation)
+	for _, nt := range se.Entries {
+		newEntries[natTranslationKey(nt)] = nt
+	}
+
+	// Only the translations programmed successfully are recorded, the
+	// others differ from the state on the next sync and are retried
+	state := ServiceEntries{ServiceID: serviceID}
+
+	// remove the translations which do not exist anymore first
+	var stale []*proto.NatTranslation
+	for _, nt := range oldEntry.Entries {
+		if _, found := newEntries[natTranslationKey(nt)]; found {
+			continue
 		}
-		for _, nt := range oldEntry.Entries {
-			// if backends are empty we did not send message to inframanager
-			if len(nt.Backends) > 0 {
-				if err := s.handler.NatTranslationDelete(nt); err != nil {
-					s.log.WithError(err).Errorf("Failed to delete entry for %v", nt)
-				}
+		// if backends are empty we did not send message to inframanager
+		if len(nt.Backends) > 0 {
+			if err := s.handler.NatTranslationDelete(nt); err != nil {
+				s.log.WithError(err).Errorf("Failed to delete entry for %v", nt)
+				stale = append(stale, nt)
 			}
 		}
-		delete(s.stateMap, serviceID)
 	}
+
 	for _, nt := range se.Entries {
+		oldNt, found := oldEntries[natTranslationKey(nt)]
+		if err := s.syncTranslation(nt, oldNt, found); err != nil {
+			if found {
+				state.Entries = append(state.Entries, oldNt)
+			}
+			continue
+		}
+		state.Entries = append(state.Entries, nt)
+	}
+	state.Entries = append(state.Entries, stale...)
+	s.stateMap[serviceID] = state
+}
+
+// syncTranslation programs the changes from the old translation to the
+// new one
+func (s *ServiceServer) syncTranslation(nt, oldNt *proto.NatTranslation, found bool) error {
+	switch {
+	case !found || len(oldNt.Backends) == 0:
 		// do not send if there are no backends available
 		if len(nt.Backends) > 0 {
 			if err := s.handler.NatTranslationAdd(nt); err != nil {
-				s.log.WithError(err).Errorf("Failed to delete entry for %v", nt)
+				s.log.WithError(err).Errorf("Failed to add entry for %v", nt)
+				return err
 			}
 		}
+	case len(nt.Backends) == 0:
+		if err := s.handler.NatTranslationDelete(oldNt); err != nil {
+			s.log.WithError(err).Errorf("Failed to delete entry for %v", oldNt)
+			return err
+		}
+	case nt.SessionAffinityTimeout != oldNt.SessionAffinityTimeout:
+		// the affinity is set along with the translation only
+		if err := s.handler.NatTranslationDelete(oldNt); err != nil {
+			s.log.WithError(err).Errorf("Failed to delete entry for %v", oldNt)
+			return err
+		}
+		if err := s.handler.NatTranslationAdd(nt); err != nil {
+			s.log.WithError(err).Errorf("Failed to add entry for %v", nt)
+			return err
+		}
+	default:
+		added, removed := diffBackends(oldNt, nt)
+		if len(added) == 0 && len(removed) == 0 {
+			return nil
+		}
+		update := &proto.NatTranslationUpdateRequest{
+			Endpoint:        nt.Endpoint,
+			Proto:           nt.Proto,
+			IsRealIp:        nt.IsRealIp,
+			AddedBackends:   added,
+			RemovedBackends: removed,
+
+			SessionAffinityTimeout: nt.SessionAffinityTimeout,
+		}
+		if err := s.handler.NatTranslationUpdate(update); err != nil {
+			s.log.WithError(err).Errorf("Failed to update entry for %v", nt)
+			return err
+		}
 	}
-	s.stateMap[serviceID] = se
+	return nil
 }
 
-// func (s *ServiceServer) SetSnatAddress() {
-// 	if err := s.handler.SetSnatAddress(s.nodeAddress); err != nil {
-// 		s.log.Infof("set snat address reply %v", err)
-// 	}
-// }
+// The pod and service CIDRs of the cluster, which the pods reach with
+// their own address. Each lists one CIDR per family in a dual-stack
+// cluster.
+func snatExemptPrefixes() []string {
+	prefixes := make([]string, 0)
+	for _, cidrs := range []string{types.ClusterPodsCIDR, types.ClusterServicesSubnet} {
+		for _, cidr := range strings.Split(cidrs, ",") {
+			if cidr = strings.TrimSpace(cidr); cidr != "" {
+				prefixes = append(prefixes, cidr)
+			}
+		}
+	}
+	return prefixes
+}
+
+// Masquerades the traffic of the pods leaving the cluster behind the
+// first address of the node of each family, the internal one
+func (s *ServiceServer) configureSnat() {
+	var ipv4, ipv6 string
+	for _, ip := range s.nodeAddresses {
+		addr := net.ParseIP(ip)
+		if addr == nil {
+			continue
+		}
+		if addr.To4() != nil && ipv4 == "" {
+			ipv4 = ip
+		} else if addr.To4() == nil && ipv6 == "" {
+			ipv6 = ip
+		}
+	}
+	if err := s.handler.SetSnatAddress(ipv4, ipv6); err != nil {
+		s.log.WithError(err).Errorf("Failed to set the SNAT addresses %s %s", ipv4, ipv6)
+	}
+	for _, prefix := range snatExemptPrefixes() {
+		if err := s.handler.AddDelSnatPrefix(prefix, true); err != nil {
+			s.log.WithError(err).Errorf("Failed to add the SNAT prefix %s", prefix)
+		}
+	}
+}
 
 func NewServiceServer(log *logrus.Entry, handler NatSettingsHandler, refreshTime uint32) (types.Server, error) {
 	clusterConfig, err := getK8sConfig()
@@ -290,34 +470,50 @@ func NewServiceServer(log *logrus.Entry, handler NatSettingsHandler, refreshTime
 		return nil, err
 	}
 	srv := ServiceServer{
-		log:      log,
-		handler:  handler,
-		stateMap: make(map[string]ServiceEntries),
-		name:     "services-server",
+		log:         log,
+		handler:     handler,
+		healthCheck: newHealthCheckServer(log),
+		stateMap:    make(map[string]ServiceEntries),
+		name:        "services-server",
 	}
-	nodeIP, err := utils.GetNodeIP(k8sc, types.NodeName)
+	nodeIPs, err := getNodeIPs(k8sc, types.NodeName)
 	if err != nil {
 		return nil, err
 	}
-	srv.nodeAddress = nodeIP
-	//k8sc.CoreV1().RESTClient(), "services", "", fields.Everything()
-	serviceListWatch := NewServiceListWatch(k8sc, SERVICES_LIST_WATCH)
-	serviceStore, serviceController := cache.NewInformer(serviceListWatch, &v1.Service{}, time.Duration(refreshTime)*time.Second,
-		cache.ResourceEventHandlerFuncs{
-			AddFunc:    func(obj interface{}) { srv.handleServiceEndpointEvent(obj.(*v1.Service), nil, false) },
-			DeleteFunc: func(obj interface{}) { srv.handleServiceEndpointEvent(obj.(*v1.Service), nil, true) },
-			UpdateFunc: func(_, newObj interface{}) { srv.handleServiceEndpointEvent(newObj.(*v1.Service), nil, false) }})
-	srv.serviceStore = serviceStore
-	srv.serviceController = serviceController
-	endpointsListWatch := NewServiceListWatch(k8sc, ENDPOINTS_LIST_WATCH)
-	endpointStore, endpointController := cache.NewInformer(endpointsListWatch, &v1.Endpoints{}, time.Duration(refreshTime)*time.Second,
-		cache.ResourceEventHandlerFuncs{
-			AddFunc:    func(obj interface{}) { srv.handleServiceEndpointEvent(nil, obj.(*v1.Endpoints), false) },
-			DeleteFunc: func(obj interface{}) { srv.handleServiceEndpointEvent(nil, obj.(*v1.Endpoints), true) },
-			UpdateFunc: func(_, newObj interface{}) { srv.handleServiceEndpointEvent(nil, newObj.(*v1.Endpoints), false) },
-		})
-	srv.endpointStore = endpointStore
-	srv.endpointController = endpointController
+	srv.nodeAddresses = nodeIPs
+	nodeZone, err := getNodeZone(k8sc, types.NodeName)
+	if err != nil {
+		return nil, err
+	}
+	srv.nodeZone = nodeZone
+
+	srv.informerFactory = informers.NewSharedInformerFactory(k8sc, time.Duration(refreshTime)*time.Second)
+	serviceInformer := srv.informerFactory.Core().V1().Services().Informer()
+	serviceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { srv.handleServiceEvent(obj.(*v1.Service), false) },
+		DeleteFunc: func(obj interface{}) {
+			if service, ok := deletedObject(obj).(*v1.Service); ok {
+				srv.handleServiceEvent(service, true)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) { srv.handleServiceEvent(newObj.(*v1.Service), false) },
+	})
+	srv.serviceStore = serviceInformer.GetStore()
+
+	sliceInformer := srv.informerFactory.Discovery().V1().EndpointSlices().Informer()
+	if err := sliceInformer.AddIndexers(cache.Indexers{serviceIndex: indexByService}); err != nil {
+		return nil, err
+	}
+	sliceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { srv.handleSliceEvent(obj.(*discovery.EndpointSlice)) },
+		DeleteFunc: func(obj interface{}) {
+			if slice, ok := deletedObject(obj).(*discovery.EndpointSlice); ok {
+				srv.handleSliceEvent(slice)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) { srv.handleSliceEvent(newObj.(*discovery.EndpointSlice)) },
+	})
+	srv.sliceStore = sliceInformer.GetIndexer()
 	return &srv, nil
 }
 
@@ -326,8 +522,13 @@ func (s *ServiceServer) GetName() string {
 }
 
 func (s *ServiceServer) serve() error {
-	s.t.Go(func() error { s.serviceController.Run(s.t.Dying()); return nil })
-	s.t.Go(func() error { s.endpointController.Run(s.t.Dying()); return nil })
+	s.configureSnat()
+	s.t.Go(func() error {
+		// the informers run until the server dies
+		s.informerFactory.Start(s.t.Dying())
+		<-s.t.Dying()
+		return nil
+	})
 	types.ServiceServerStatus = types.ServerStatusOK
 	<-s.t.Dying()
 	s.log.Info("Service server returned")
@@ -339,6 +540,7 @@ func (s *ServiceServer) StopServer() {
 	// stop internal goroutines
 	s.t.Kill(errors.New("GracefulStop"))
 	_ = s.t.Wait()
+	s.healthCheck.stop()
 }
 
 func (s *ServiceServer) Start(t *tomb.Tomb) error {
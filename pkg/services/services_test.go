@@ -19,6 +19,9 @@ import (
 	"encoding/json"
 	"errors"
 	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -27,43 +30,64 @@ import (
 	"github.com/ipdk-io/k8s-infra-offload/pkg/types"
 	"github.com/ipdk-io/k8s-infra-offload/proto"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/test/bufconn"
 	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
 const (
-	nodeListString = `{"metadata":{"resourceVersion":"3188299"},"items":[{"metadata":{"name":"dummyNode","uid":"4c1f6487-99e8-4860-8534-7df51b6a682c","resourceVersion":"3188070","creationTimestamp":"2022-07-08T13:44:51Z","labels":{"beta.kubernetes.io/arch":"amd64","beta.kubernetes.io/os":"linux","kubernetes.io/arch":"amd64","kubernetes.io/hostname":"dummyNode","kubernetes.io/os":"linux","node-role.kubernetes.io/control-plane":"","node.kubernetes.io/exclude-from-external-load-balancers":""},"annotations":{"kubeadm.alpha.kubernetes.io/cri-socket":"unix:///var/run/containerd/containerd.sock","node.alpha.kubernetes.io/ttl":"0","projectcalico.org/IPv4Address":"10.244.0.7/24","projectcalico.org/IPv4IPIPTunnelAddr":"10.244.0.1","volumes.kubernetes.io/controller-managed-attach-detach":"true"},"managedFields":[{"manager":"kubelet","operation":"Update","apiVersion":"v1","time":"2022-07-08T13:44:51Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:annotations":{".":{},"f:volumes.kubernetes.io/controller-managed-attach-detach":{}},"f:labels":{".":{},"f:beta.kubernetes.io/arch":{},"f:beta.kubernetes.io/os":{},"f:kubernetes.io/arch":{},"f:kubernetes.io/hostname":{},"f:kubernetes.io/os":{}}}}},{"manager":"kubeadm","operation":"Update","apiVersion":"v1","time":"2022-07-08T13:44:56Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:annotations":{"f:kubeadm.alpha.kubernetes.io/cri-socket":{}},"f:labels":{"f:node-role.kubernetes.io/control-plane":{},"f:node.kubernetes.io/exclude-from-external-load-balancers":{}}}}},{"manager":"kube-controller-manager","operation":"Update","apiVersion":"v1","time":"2022-07-08T13:45:10Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:annotations":{"f:node.alpha.kubernetes.io/ttl":{}}},"f:spec":{"f:podCIDR":{},"f:podCIDRs":{".":{},"v:\"10.244.0.0/24\"":{}}}}},{"manager":"Go-http-client","operation":"Update","apiVersion":"v1","time":"2022-07-08T13:46:55Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:annotations":{"f:projectcalico.org/IPv4Address":{},"f:projectcalico.org/IPv4IPIPTunnelAddr":{}}}},"subresource":"status"},{"manager":"kubelet","operation":"Update","apiVersion":"v1","time":"2022-07-11T07:17:49Z","fieldsType":"FieldsV1","fieldsV1":{"f:status":{"f:conditions":{"k:{\"type\":\"DiskPressure\"}":{"f:lastHeartbeatTime":{}},"k:{\"type\":\"MemoryPressure\"}":{"f:lastHeartbeatTime":{}},"k:{\"type\":\"PIDPressure\"}":{"f:lastHeartbeatTime":{}},"k:{\"type\":\"Ready\"}":{"f:lastHeartbeatTime":{},"f:lastTransitionTime":{},"f:message":{},"f:reason":{},"f:status":{}}},"f:images":{}}},"subresource":"status"}]},"spec":{"podCIDR":"10.244.0.0/24","podCIDRs":["10.244.0.0/24"]},"status":{"capacity":{"cpu":"88","ephemeral-storage":"960847604Ki","hugepages-1Gi":"0","hugepages-2Mi":"2Gi","memory":"131695828Ki","pods":"110"},"allocatable":{"cpu":"88","ephemeral-storage":"885517150381","hugepages-1Gi":"0","hugepages-2Mi":"2Gi","memory":"129496276Ki","pods":"110"},"conditions":[{"type":"MemoryPressure","status":"False","lastHeartbeatTime":"2022-07-25T12:21:02Z","lastTransitionTime":"2022-07-08T13:44:49Z","reason":"KubeletHasSufficientMemory","message":"kubelet has sufficient memory available"},{"type":"DiskPressure","status":"False","lastHeartbeatTime":"2022-07-25T12:21:02Z","lastTransitionTime":"2022-07-08T13:44:49Z","reason":"KubeletHasNoDiskPressure","message":"kubelet has no disk pressure"},{"type":"PIDPressure","status":"False","lastHeartbeatTime":"2022-07-25T12:21:02Z","lastTransitionTime":"2022-07-08T13:44:49Z","reason":"KubeletHasSufficientPID","message":"kubelet has sufficient PID available"},{"type":"Ready","status":"True","lastHeartbeatTime":"2022-07-25T12:21:02Z","lastTransitionTime":"2022-07-08T13:44:57Z","reason":"KubeletReady","message":"kubelet is posting ready status. AppArmor enabled"}],"addresses":[{"type":"InternalIP","address":"192.168.111.66"},{"type":"Hostname","address":"dummyNode"}],"daemonEndpoints":{"kubeletEndpoint":{"Port":10250}},"nodeInfo":{"machineID":"77094b51073843e5acb5c3cdd16c909e","systemUUID":"30726035-e8ed-ea11-ba6b-a4bf01644732","bootID":"de767a54-9900-4e24-8514-8c4cbf817213","kernelVersion":"5.4.0-121-generic","osImage":"Ubuntu 20.04.4 LTS","containerRuntimeVersion":"containerd://1.5.11","kubeletVersion":"v1.24.0","kubeProxyVersion":"v1.24.0","operatingSystem":"linux","architecture":"amd64"},"images":[{"names":["docker.io/calico/cni@sha256:26802bb7714fda18b93765e908f2d48b0230fd1c620789ba2502549afcde4338","docker.io/calico/cni:v3.23.1"],"sizeBytes":110500425},{"names":["k8s.gcr.io/etcd@sha256:13f53ed1d91e2e11aac476ee9a0269fdda6cc4874eba903efd40daf50c55eee5","k8s.gcr.io/etcd:3.5.3-0"],"sizeBytes":102143581},{"names":["docker.io/calico/node@sha256:d2c1613ef26c9ad43af40527691db1f3ad640291d5e4655ae27f1dd9222cc380","docker.io/calico/node:v3.23.1"],"sizeBytes":76574475},{"names":["docker.io/calico/apiserver@sha256:231b782c7d464bd59b416033e28eae8b3ec2ff90d38ca718558430f67f3203fa","docker.io/calico/apiserver:v3.23.1"],"sizeBytes":76516308},{"names":["quay.io/tigera/operator@sha256:526c06f827200856fb1f5594cc3f7d23935674cf20c22330e8ab9a6ddc484c8d","quay.io/tigera/operator:v1.27.1"],"sizeBytes":60267159},{"names":["docker.io/library/nginx@sha256:10f14ffa93f8dedf1057897b745e5ac72ac5655c299dade0aa434c71557697ea","docker.io/library/nginx:latest"],"sizeBytes":56748232},{"names":["docker.io/calico/kube-controllers@sha256:e8b2af28f2c283a38b4d80436e2d2a25e70f2820d97d1a8684609d42c3973afb","docker.io/calico/kube-controllers:v3.23.1"],"sizeBytes":56361853},{"names":["docker.io/calico/typha@sha256:d58558013bce1387f40969f483f65b5178b4574a8c383c3e997768d6a0ffff34","docker.io/calico/typha:v3.23.1"],"sizeBytes":54003239},{"names":["docker.io/library/nginx@sha256:6fff55753e3b34e36e24e37039ee9eae1fe38a6420d8ae16ef37c92d1eb26699","docker.io/library/nginx:1.17"],"sizeBytes":51030575},{"names":["k8s.gcr.io/kube-proxy@sha256:c957d602267fa61082ab8847914b2118955d0739d592cc7b01e278513478d6a8","k8s.gcr.io/kube-proxy:v1.24.0"],"sizeBytes":39515042},{"names":["k8s.gcr.io/kube-apiserver@sha256:a04522b882e919de6141b47d72393fb01226c78e7388400f966198222558c955","k8s.gcr.io/kube-apiserver:v1.24.0"],"sizeBytes":33796127},{"names":["10.55.129.85:5000/infra-agent@sha256:f9f2ef413a30e37ea5f3ca8a5affbeb41c58b56b4a3f36ac22cf85143e5148a0","10.55.129.85:5000/k8s-p4-dataplane@sha256:f9f2ef413a30e37ea5f3ca8a5affbeb41c58b56b4a3f36ac22cf85143e5148a0","10.55.129.85:5000/infra-agent:latest","10.55.129.85:5000/k8s-p4-dataplane:latest"],"sizeBytes":32681228},{"names":["k8s.gcr.io/kube-controller-manager@sha256:df044a154e79a18f749d3cd9d958c3edde2b6a00c815176472002b7bbf956637","k8s.gcr.io/kube-controller-manager:v1.24.0"],"sizeBytes":31032816},{"names":["docker.io/wbitt/network-multitool@sha256:82a5ea955024390d6b438ce22ccc75c98b481bf00e57c13e9a9cc1458eb92652","docker.io/wbitt/network-multitool:latest"],"sizeBytes":24236758},{"names":["k8s.gcr.io/kube-scheduler@sha256:db842a7c431fd51db7e1911f6d1df27a7b6b6963ceda24852b654d2cd535b776","k8s.gcr.io/kube-scheduler:v1.24.0"],"sizeBytes":15488642},{"names":["k8s.gcr.io/coredns/coredns@sha256:5b6ec0d6de9baaf3e92d0f66cd96a25b9edbce8716f5f15dcd1a616b3abd590e","k8s.gcr.io/coredns/coredns:v1.8.6"],"sizeBytes":13585107},{"names":["docker.io/calico/pod2daemon-flexvol@sha256:5d5759fc6de1f6c09b95d36334d968fa074779120024c067a770cfb2af579670","docker.io/calico/pod2daemon-flexvol:v3.23.1"],"sizeBytes":8671600},{"names":["docker.io/leannet/k8s-netperf@sha256:dd79ca1b6ecefc1e5bd9301abff0cfdec25dce9cd4fb9a09ddf4e117aa5550cd","docker.io/leannet/k8s-netperf:latest"],"sizeBytes":6732296},{"names":["docker.io/library/busybox@sha256:3614ca5eacf0a3a1bcc361c939202a974b4902b9334ff36eb29ffe9011aaad83","docker.io/library/busybox:latest"],"sizeBytes":777536},{"names":["docker.io/library/busybox@sha256:ebadf81a7f2146e95f8c850ad7af8cf9755d31cdba380a8ffd5930fba5996095"],"sizeBytes":777101},{"names":["docker.io/library/busybox@sha256:d2b53584f580310186df7a2055ce3ff83cc0df6caacf1e3489bff8cf5d0af5d8"],"sizeBytes":777091},{"names":["k8s.gcr.io/pause@sha256:bb6ed397957e9ca7c65ada0db5c5d1c707c9c8afc80a94acbe69f3ae76988f0c","k8s.gcr.io/pause:3.7"],"sizeBytes":311278},{"names":["k8s.gcr.io/pause@sha256:927d98197ec1141a368550822d18fa1c60bdae27b78b0c004f705f548c07814f","k8s.gcr.io/pause:3.2"],"sizeBytes":299513}]}}]}`
-	srvListString  = `{"metadata":{"resourceVersion":"3102"},"items":[{"metadata":{"name":"kubernetes","namespace":"default","uid":"5e1695b8-ca90-41af-ad7c-610efec9b7cb","resourceVersion":"210","creationTimestamp":"2022-07-26T12:30:51Z","labels":{"component":"apiserver","provider":"kubernetes"},"managedFields":[{"manager":"kube-apiserver","operation":"Update","apiVersion":"v1","time":"2022-07-26T12:30:51Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:labels":{".":{},"f:component":{},"f:provider":{}}},"f:spec":{"f:clusterIP":{},"f:internalTrafficPolicy":{},"f:ipFamilyPolicy":{},"f:ports":{".":{},"k:{\"port\":443,\"protocol\":\"TCP\"}":{".":{},"f:name":{},"f:port":{},"f:protocol":{},"f:targetPort":{}}},"f:sessionAffinity":{},"f:type":{}}}}]},"spec":{"ports":[{"name":"https","protocol":"TCP","port":443,"targetPort":6443}],"clusterIP":"10.96.0.1","clusterIPs":["10.96.0.1"],"type":"ClusterIP","sessionAffinity":"None","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{}}},{"metadata":{"name":"nginx-service","namespace":"default","uid":"f0ab5958-03c8-459b-84fe-148e35a8ad8d","resourceVersion":"2460","creationTimestamp":"2022-07-26T12:49:46Z","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"v1\",\"kind\":\"Service\",\"metadata\":{\"annotations\":{},\"name\":\"nginx-service\",\"namespace\":\"default\"},\"spec\":{\"ports\":[{\"name\":\"name-of-service-port\",\"port\":80,\"protocol\":\"TCP\",\"targetPort\":\"http-web-svc\"}],\"selector\":{\"app.kubernetes.io/name\":\"proxy\"}}}\n"},"managedFields":[{"manager":"kubectl-client-side-apply","operation":"Update","apiVersion":"v1","time":"2022-07-26T12:49:46Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:annotations":{".":{},"f:kubectl.kubernetes.io/last-applied-configuration":{}}},"f:spec":{"f:internalTrafficPolicy":{},"f:ports":{".":{},"k:{\"port\":80,\"protocol\":\"TCP\"}":{".":{},"f:name":{},"f:port":{},"f:protocol":{},"f:targetPort":{}}},"f:selector":{},"f:sessionAffinity":{},"f:type":{}}}}]},"spec":{"ports":[{"name":"name-of-service-port","protocol":"TCP","port":80,"targetPort":"http-web-svc"}],"selector":{"app.kubernetes.io/name":"proxy"},"clusterIP":"10.96.30.210","clusterIPs":["10.96.30.210"],"type":"ClusterIP","sessionAffinity":"None","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{}}},{"kind":"Service","apiVersion":"v1","metadata":{"name":"nginx-svc-cl","namespace":"default","uid":"99b626ee-1a89-44e7-91ae-6a702eb1add6","resourceVersion":"2378","creationTimestamp":"2022-08-01T08:36:55Z","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"v1\",\"kind\":\"Service\",\"metadata\":{\"annotations\":{},\"name\":\"nginx-svc-cl\",\"namespace\":\"default\"},\"spec\":{\"externalIPs\":[\"1.2.3.42\"],\"ports\":[{\"name\":\"http\",\"port\":80,\"protocol\":\"TCP\",\"targetPort\":80}],\"selector\":{\"app\":\"nginx-cl\"}}}\n"}},"spec":{"ports":[{"name":"http","protocol":"TCP","port":80,"targetPort":80}],"selector":{"app":"nginx-cl"},"clusterIP":"10.98.247.229","clusterIPs":["10.98.247.229"],"type":"ClusterIP","externalIPs":["1.2.3.42"],"sessionAffinity":"None","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{}}},{"kind":"Service","apiVersion":"v1","metadata":{"name":"nginx-svc-np","namespace":"default","uid":"18524032-4e56-4d14-800a-29ca2476dc14","resourceVersion":"13002","creationTimestamp":"2022-08-01T09:58:00Z","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"v1\",\"kind\":\"Service\",\"metadata\":{\"annotations\":{},\"name\":\"nginx-svc-np\",\"namespace\":\"default\"},\"spec\":{\"ports\":[{\"nodePort\":30080,\"port\":80,\"targetPort\":80}],\"selector\":{\"app\":\"nginx-np\"},\"type\":\"NodePort\"}}\n"}},"spec":{"ports":[{"protocol":"TCP","port":80,"targetPort":80,"nodePort":30080}],"selector":{"app":"nginx-np"},"clusterIP":"10.109.14.106","clusterIPs":["10.109.14.106"],"type":"NodePort","sessionAffinity":"None","externalTrafficPolicy":"Cluster","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{}}},{"kind":"Service","apiVersion":"v1","metadata":{"name":"nginx-svc-lb","namespace":"default","uid":"47392643-d502-4728-a62c-9e613e83a9a8","resourceVersion":"39797","creationTimestamp":"2022-08-01T13:22:49Z","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"v1\",\"kind\":\"Service\",\"metadata\":{\"annotations\":{},\"name\":\"nginx-svc-lb\",\"namespace\":\"default\"},\"spec\":{\"clusterIP\":\"10.96.100.100\",\"ports\":[{\"port\":80,\"protocol\":\"TCP\",\"targetPort\":80}],\"selector\":{\"app\":\"nginx-lb\"},\"type\":\"LoadBalancer\"},\"status\":{\"loadBalancer\":{\"ingress\":[{\"ip\":\"192.0.2.127\"}]}}}\n"}},"spec":{"ports":[{"protocol":"TCP","port":80,"targetPort":80,"nodePort":30126}],"selector":{"app":"nginx-lb"},"clusterIP":"10.96.100.100","clusterIPs":["10.96.100.100"],"type":"LoadBalancer","sessionAffinity":"None","externalTrafficPolicy":"Cluster","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","allocateLoadBalancerNodePorts":true,"internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{"ingress":[{"ip":"127.0.0.1"}]}}},{"kind":"Service","apiVersion":"v1","metadata":{"name":"nginx-svc-npB","namespace":"default","uid":"18524032-4e56-4d14-800a-29ca2476dc15","resourceVersion":"13002","creationTimestamp":"2022-08-01T09:58:00Z","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"v1\",\"kind\":\"Service\",\"metadata\":{\"annotations\":{},\"name\":\"nginx-svc-np\",\"namespace\":\"default\"},\"spec\":{\"ports\":[{\"nodePort\":30080,\"port\":80,\"targetPort\":80}],\"selector\":{\"app\":\"nginx-np\"},\"type\":\"NodePort\"}}\n"}},"spec":{"ports":[{"protocol":"TCP","port":80,"targetPort":80,"nodePort":30080}],"selector":{"app":"nginx-np"},"clusterIP":"10.109.14.106","clusterIPs":["10.109.14.106"],"type":"NodePort","sessionAffinity":"None","externalTrafficPolicy":"Cluster","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{}}}]}`
-	epListString   = `{"metadata":{"resourceVersion":"3102"},"items":[{"metadata":{"name":"kubernetes","namespace":"default","uid":"e5df8985-8b70-440d-97ae-83a8a38c6c50","resourceVersion":"212","creationTimestamp":"2022-07-26T12:30:51Z","labels":{"endpointslice.kubernetes.io/skip-mirror":"true"},"managedFields":[{"manager":"kube-apiserver","operation":"Update","apiVersion":"v1","time":"2022-07-26T12:30:51Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:labels":{".":{},"f:endpointslice.kubernetes.io/skip-mirror":{}}},"f:subsets":{}}}]},"subsets":[{"addresses":[{"ip":"192.168.111.66"}],"ports":[{"name":"https","port":6443,"protocol":"TCP"}]}]},{"metadata":{"name":"nginx-service","namespace":"default","uid":"bd00dd32-64c8-4a36-8d9c-fa1743100587","resourceVersion":"2500","creationTimestamp":"2022-07-26T12:49:46Z","annotations":{"endpoints.kubernetes.io/last-change-trigger-time":"2022-07-26T12:50:00Z"},"managedFields":[{"manager":"kube-controller-manager","operation":"Update","apiVersion":"v1","time":"2022-07-26T12:50:00Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:annotations":{".":{},"f:endpoints.kubernetes.io/last-change-trigger-time":{}}},"f:subsets":{}}}]},"subsets":[{"addresses":[{"ip":"10.244.0.28","nodeName":"dummyNode","targetRef":{"kind":"Pod","namespace":"default","name":"nginx","uid":"46b11a7f-bb3c-4c2c-9780-04da3fea7e0e"}}],"ports":[{"name":"name-of-service-port","port":80,"protocol":"TCP"}]}]},{"apiVersion": "v1","kind": "Endpoints","metadata": {"annotations": {"endpoints.kubernetes.io/last-change-trigger-time": "2022-08-01T08:36:55Z"},"creationTimestamp": "2022-08-01T08:36:55Z","name": "nginx-svc-cl","namespace": "default","resourceVersion": "2380","uid": "8de7b90c-5e02-45da-b8e1-e4dfdebfe04f"},"subsets": [{"addresses": [{"ip": "10.210.193.70","nodeName": "infratest","targetRef": {"kind": "Pod","name": "nginx-cl","namespace": "default","uid": "1b4aaa88-0d8a-4a1e-a4d9-3f5b2e14ac35"}}],"ports": [{"name": "http","port": 80,"protocol": "TCP"}]}]},{"apiVersion": "v1","kind": "Endpoints","metadata": {"annotations": {"endpoints.kubernetes.io/last-change-trigger-time": "2022-08-01T09:58:00Z"},"creationTimestamp": "2022-08-01T09:58:00Z","name": "nginx-svc-np","namespace": "default","resourceVersion": "13004","uid": "2ef245e6-7ca8-4dff-a3b3-ed228f7410fa"},"subsets": [{"addresses": [{"ip": "10.210.193.71","nodeName": "infratest","targetRef": {"kind": "Pod","name": "nginx-np","namespace": "default","uid": "9db2c098-283a-4827-83c0-961dd8345497"}}],"ports": [{"port": 80,"protocol": "TCP"}]}]},{"apiVersion": "v1","kind": "Endpoints","metadata": {"annotations": {"endpoints.kubernetes.io/last-change-trigger-time": "2022-08-01T13:22:49Z"},"creationTimestamp": "2022-08-01T13:22:49Z","name": "nginx-svc-lb","namespace": "default","resourceVersion": "39798","uid": "e794042d-3357-4c33-84a7-fa1fa6770c6a"},"subsets": [{"addresses": [{"ip": "10.210.193.72","nodeName": "infratest","targetRef": {"kind": "Pod","name": "nginx-lb","namespace": "default","uid": "8959ad46-cbe5-4450-97a7-813c0dec8509"}}],"ports": [{"port": 80,"protocol": "TCP"}]}]},{"apiVersion": "v1","kind": "Endpoints","metadata": {"annotations": {"endpoints.kubernetes.io/last-change-trigger-time": "2022-08-01T09:58:00Z"},"creationTimestamp": "2022-08-01T09:58:00Z","name": "nginx-svc-npA","namespace": "default","resourceVersion": "13004","uid": "2ef245e6-7ca8-4dff-a3b3-ed228f7410fb"},"subsets": [{"addresses": [{"ip": "10.210.193.71","nodeName": "infratest","targetRef": {"kind": "Pod","name": "nginx-np","namespace": "default","uid": "9db2c098-283a-4827-83c0-961dd8345497"}}],"ports": [{"port": 80,"protocol": "TCP"}]}]}]}`
-	bufSize        = 1024 * 1024
+	nodeListString  = `{"metadata":{"resourceVersion":"3188299"},"items":[{"metadata":{"name":"dummyNode","uid":"4c1f6487-99e8-4860-8534-7df51b6a682c","resourceVersion":"3188070","creationTimestamp":"2022-07-08T13:44:51Z","labels":{"beta.kubernetes.io/arch":"amd64","beta.kubernetes.io/os":"linux","kubernetes.io/arch":"amd64","kubernetes.io/hostname":"dummyNode","kubernetes.io/os":"linux","node-role.kubernetes.io/control-plane":"","node.kubernetes.io/exclude-from-external-load-balancers":""},"annotations":{"kubeadm.alpha.kubernetes.io/cri-socket":"unix:///var/run/containerd/containerd.sock","node.alpha.kubernetes.io/ttl":"0","projectcalico.org/IPv4Address":"10.244.0.7/24","projectcalico.org/IPv4IPIPTunnelAddr":"10.244.0.1","volumes.kubernetes.io/controller-managed-attach-detach":"true"},"managedFields":[{"manager":"kubelet","operation":"Update","apiVersion":"v1","time":"2022-07-08T13:44:51Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:annotations":{".":{},"f:volumes.kubernetes.io/controller-managed-attach-detach":{}},"f:labels":{".":{},"f:beta.kubernetes.io/arch":{},"f:beta.kubernetes.io/os":{},"f:kubernetes.io/arch":{},"f:kubernetes.io/hostname":{},"f:kubernetes.io/os":{}}}}},{"manager":"kubeadm","operation":"Update","apiVersion":"v1","time":"2022-07-08T13:44:56Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:annotations":{"f:kubeadm.alpha.kubernetes.io/cri-socket":{}},"f:labels":{"f:node-role.kubernetes.io/control-plane":{},"f:node.kubernetes.io/exclude-from-external-load-balancers":{}}}}},{"manager":"kube-controller-manager","operation":"Update","apiVersion":"v1","time":"2022-07-08T13:45:10Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:annotations":{"f:node.alpha.kubernetes.io/ttl":{}}},"f:spec":{"f:podCIDR":{},"f:podCIDRs":{".":{},"v:\"10.244.0.0/24\"":{}}}}},{"manager":"Go-http-client","operation":"Update","apiVersion":"v1","time":"2022-07-08T13:46:55Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:annotations":{"f:projectcalico.org/IPv4Address":{},"f:projectcalico.org/IPv4IPIPTunnelAddr":{}}}},"subresource":"status"},{"manager":"kubelet","operation":"Update","apiVersion":"v1","time":"2022-07-11T07:17:49Z","fieldsType":"FieldsV1","fieldsV1":{"f:status":{"f:conditions":{"k:{\"type\":\"DiskPressure\"}":{"f:lastHeartbeatTime":{}},"k:{\"type\":\"MemoryPressure\"}":{"f:lastHeartbeatTime":{}},"k:{\"type\":\"PIDPressure\"}":{"f:lastHeartbeatTime":{}},"k:{\"type\":\"Ready\"}":{"f:lastHeartbeatTime":{},"f:lastTransitionTime":{},"f:message":{},"f:reason":{},"f:status":{}}},"f:images":{}}},"subresource":"status"}]},"spec":{"podCIDR":"10.244.0.0/24","podCIDRs":["10.244.0.0/24"]},"status":{"capacity":{"cpu":"88","ephemeral-storage":"960847604Ki","hugepages-1Gi":"0","hugepages-2Mi":"2Gi","memory":"131695828Ki","pods":"110"},"allocatable":{"cpu":"88","ephemeral-storage":"885517150381","hugepages-1Gi":"0","hugepages-2Mi":"2Gi","memory":"129496276Ki","pods":"110"},"conditions":[{"type":"MemoryPressure","status":"False","lastHeartbeatTime":"2022-07-25T12:21:02Z","lastTransitionTime":"2022-07-08T13:44:49Z","reason":"KubeletHasSufficientMemory","message":"kubelet has sufficient memory available"},{"type":"DiskPressure","status":"False","lastHeartbeatTime":"2022-07-25T12:21:02Z","lastTransitionTime":"2022-07-08T13:44:49Z","reason":"KubeletHasNoDiskPressure","message":"kubelet has no disk pressure"},{"type":"PIDPressure","status":"False","lastHeartbeatTime":"2022-07-25T12:21:02Z","lastTransitionTime":"2022-07-08T13:44:49Z","reason":"KubeletHasSufficientPID","message":"kubelet has sufficient PID available"},{"type":"Ready","status":"True","lastHeartbeatTime":"2022-07-25T12:21:02Z","lastTransitionTime":"2022-07-08T13:44:57Z","reason":"KubeletReady","message":"kubelet is posting ready status. AppArmor enabled"}],"addresses":[{"type":"InternalIP","address":"192.168.111.66"},{"type":"Hostname","address":"dummyNode"}],"daemonEndpoints":{"kubeletEndpoint":{"Port":10250}},"nodeInfo":{"machineID":"77094b51073843e5acb5c3cdd16c909e","systemUUID":"30726035-e8ed-ea11-ba6b-a4bf01644732","bootID":"de767a54-9900-4e24-8514-8c4cbf817213","kernelVersion":"5.4.0-121-generic","osImage":"Ubuntu 20.04.4 LTS","containerRuntimeVersion":"containerd://1.5.11","kubeletVersion":"v1.24.0","kubeProxyVersion":"v1.24.0","operatingSystem":"linux","architecture":"amd64"},"images":[{"names":["docker.io/calico/cni@sha256:26802bb7714fda18b93765e908f2d48b0230fd1c620789ba2502549afcde4338","docker.io/calico/cni:v3.23.1"],"sizeBytes":110500425},{"names":["k8s.gcr.io/etcd@sha256:13f53ed1d91e2e11aac476ee9a0269fdda6cc4874eba903efd40daf50c55eee5","k8s.gcr.io/etcd:3.5.3-0"],"sizeBytes":102143581},{"names":["docker.io/calico/node@sha256:d2c1613ef26c9ad43af40527691db1f3ad640291d5e4655ae27f1dd9222cc380","docker.io/calico/node:v3.23.1"],"sizeBytes":76574475},{"names":["docker.io/calico/apiserver@sha256:231b782c7d464bd59b416033e28eae8b3ec2ff90d38ca718558430f67f3203fa","docker.io/calico/apiserver:v3.23.1"],"sizeBytes":76516308},{"names":["quay.io/tigera/operator@sha256:526c06f827200856fb1f5594cc3f7d23935674cf20c22330e8ab9a6ddc484c8d","quay.io/tigera/operator:v1.27.1"],"sizeBytes":60267159},{"names":["docker.io/library/nginx@sha256:10f14ffa93f8dedf1057897b745e5ac72ac5655c299dade0aa434c71557697ea","docker.io/library/nginx:latest"],"sizeBytes":56748232},{"names":["docker.io/calico/kube-controllers@sha256:e8b2af28f2c283a38b4d80436e2d2a25e70f2820d97d1a8684609d42c3973afb","docker.io/calico/kube-controllers:v3.23.1"],"sizeBytes":56361853},{"names":["docker.io/calico/typha@sha256:d58558013bce1387f40969f483f65b5178b4574a8c383c3e997768d6a0ffff34","docker.io/calico/typha:v3.23.1"],"sizeBytes":54003239},{"names":["docker.io/library/nginx@sha256:6fff55753e3b34e36e24e37039ee9eae1fe38a6420d8ae16ef37c92d1eb26699","docker.io/library/nginx:1.17"],"sizeBytes":51030575},{"names":["k8s.gcr.io/kube-proxy@sha256:c957d602267fa61082ab8847914b2118955d0739d592cc7b01e278513478d6a8","k8s.gcr.io/kube-proxy:v1.24.0"],"sizeBytes":39515042},{"names":["k8s.gcr.io/kube-apiserver@sha256:a04522b882e919de6141b47d72393fb01226c78e7388400f966198222558c955","k8s.gcr.io/kube-apiserver:v1.24.0"],"sizeBytes":33796127},{"names":["10.55.129.85:5000/infra-agent@sha256:f9f2ef413a30e37ea5f3ca8a5affbeb41c58b56b4a3f36ac22cf85143e5148a0","10.55.129.85:5000/k8s-p4-dataplane@sha256:f9f2ef413a30e37ea5f3ca8a5affbeb41c58b56b4a3f36ac22cf85143e5148a0","10.55.129.85:5000/infra-agent:latest","10.55.129.85:5000/k8s-p4-dataplane:latest"],"sizeBytes":32681228},{"names":["k8s.gcr.io/kube-controller-manager@sha256:df044a154e79a18f749d3cd9d958c3edde2b6a00c815176472002b7bbf956637","k8s.gcr.io/kube-controller-manager:v1.24.0"],"sizeBytes":31032816},{"names":["docker.io/wbitt/network-multitool@sha256:82a5ea955024390d6b438ce22ccc75c98b481bf00e57c13e9a9cc1458eb92652","docker.io/wbitt/network-multitool:latest"],"sizeBytes":24236758},{"names":["k8s.gcr.io/kube-scheduler@sha256:db842a7c431fd51db7e1911f6d1df27a7b6b6963ceda24852b654d2cd535b776","k8s.gcr.io/kube-scheduler:v1.24.0"],"sizeBytes":15488642},{"names":["k8s.gcr.io/coredns/coredns@sha256:5b6ec0d6de9baaf3e92d0f66cd96a25b9edbce8716f5f15dcd1a616b3abd590e","k8s.gcr.io/coredns/coredns:v1.8.6"],"sizeBytes":13585107},{"names":["docker.io/calico/pod2daemon-flexvol@sha256:5d5759fc6de1f6c09b95d36334d968fa074779120024c067a770cfb2af579670","docker.io/calico/pod2daemon-flexvol:v3.23.1"],"sizeBytes":8671600},{"names":["docker.io/leannet/k8s-netperf@sha256:dd79ca1b6ecefc1e5bd9301abff0cfdec25dce9cd4fb9a09ddf4e117aa5550cd","docker.io/leannet/k8s-netperf:latest"],"sizeBytes":6732296},{"names":["docker.io/library/busybox@sha256:3614ca5eacf0a3a1bcc361c939202a974b4902b9334ff36eb29ffe9011aaad83","docker.io/library/busybox:latest"],"sizeBytes":777536},{"names":["docker.io/library/busybox@sha256:ebadf81a7f2146e95f8c850ad7af8cf9755d31cdba380a8ffd5930fba5996095"],"sizeBytes":777101},{"names":["docker.io/library/busybox@sha256:d2b53584f580310186df7a2055ce3ff83cc0df6caacf1e3489bff8cf5d0af5d8"],"sizeBytes":777091},{"names":["k8s.gcr.io/pause@sha256:bb6ed397957e9ca7c65ada0db5c5d1c707c9c8afc80a94acbe69f3ae76988f0c","k8s.gcr.io/pause:3.7"],"sizeBytes":311278},{"names":["k8s.gcr.io/pause@sha256:927d98197ec1141a368550822d18fa1c60bdae27b78b0c004f705f548c07814f","k8s.gcr.io/pause:3.2"],"sizeBytes":299513}]}}]}`
+	srvListString   = `{"metadata":{"resourceVersion":"3102"},"items":[{"metadata":{"name":"kubernetes","namespace":"default","uid":"5e1695b8-ca90-41af-ad7c-610efec9b7cb","resourceVersion":"210","creationTimestamp":"2022-07-26T12:30:51Z","labels":{"component":"apiserver","provider":"kubernetes"},"managedFields":[{"manager":"kube-apiserver","operation":"Update","apiVersion":"v1","time":"2022-07-26T12:30:51Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:labels":{".":{},"f:component":{},"f:provider":{}}},"f:spec":{"f:clusterIP":{},"f:internalTrafficPolicy":{},"f:ipFamilyPolicy":{},"f:ports":{".":{},"k:{\"port\":443,\"protocol\":\"TCP\"}":{".":{},"f:name":{},"f:port":{},"f:protocol":{},"f:targetPort":{}}},"f:sessionAffinity":{},"f:type":{}}}}]},"spec":{"ports":[{"name":"https","protocol":"TCP","port":443,"targetPort":6443}],"clusterIP":"10.96.0.1","clusterIPs":["10.96.0.1"],"type":"ClusterIP","sessionAffinity":"None","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{}}},{"metadata":{"name":"nginx-service","namespace":"default","uid":"f0ab5958-03c8-459b-84fe-148e35a8ad8d","resourceVersion":"2460","creationTimestamp":"2022-07-26T12:49:46Z","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"v1\",\"kind\":\"Service\",\"metadata\":{\"annotations\":{},\"name\":\"nginx-service\",\"namespace\":\"default\"},\"spec\":{\"ports\":[{\"name\":\"name-of-service-port\",\"port\":80,\"protocol\":\"TCP\",\"targetPort\":\"http-web-svc\"}],\"selector\":{\"app.kubernetes.io/name\":\"proxy\"}}}\n"},"managedFields":[{"manager":"kubectl-client-side-apply","operation":"Update","apiVersion":"v1","time":"2022-07-26T12:49:46Z","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:annotations":{".":{},"f:kubectl.kubernetes.io/last-applied-configuration":{}}},"f:spec":{"f:internalTrafficPolicy":{},"f:ports":{".":{},"k:{\"port\":80,\"protocol\":\"TCP\"}":{".":{},"f:name":{},"f:port":{},"f:protocol":{},"f:targetPort":{}}},"f:selector":{},"f:sessionAffinity":{},"f:type":{}}}}]},"spec":{"ports":[{"name":"name-of-service-port","protocol":"TCP","port":80,"targetPort":"http-web-svc"}],"selector":{"app.kubernetes.io/name":"proxy"},"clusterIP":"10.96.30.210","clusterIPs":["10.96.30.210"],"type":"ClusterIP","sessionAffinity":"None","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{}}},{"kind":"Service","apiVersion":"v1","metadata":{"name":"nginx-svc-cl","namespace":"default","uid":"99b626ee-1a89-44e7-91ae-6a702eb1add6","resourceVersion":"2378","creationTimestamp":"2022-08-01T08:36:55Z","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"v1\",\"kind\":\"Service\",\"metadata\":{\"annotations\":{},\"name\":\"nginx-svc-cl\",\"namespace\":\"default\"},\"spec\":{\"externalIPs\":[\"1.2.3.42\"],\"ports\":[{\"name\":\"http\",\"port\":80,\"protocol\":\"TCP\",\"targetPort\":80}],\"selector\":{\"app\":\"nginx-cl\"}}}\n"}},"spec":{"ports":[{"name":"http","protocol":"TCP","port":80,"targetPort":80}],"selector":{"app":"nginx-cl"},"clusterIP":"10.98.247.229","clusterIPs":["10.98.247.229"],"type":"ClusterIP","externalIPs":["1.2.3.42"],"sessionAffinity":"None","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{}}},{"kind":"Service","apiVersion":"v1","metadata":{"name":"nginx-svc-np","namespace":"default","uid":"18524032-4e56-4d14-800a-29ca2476dc14","resourceVersion":"13002","creationTimestamp":"2022-08-01T09:58:00Z","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"v1\",\"kind\":\"Service\",\"metadata\":{\"annotations\":{},\"name\":\"nginx-svc-np\",\"namespace\":\"default\"},\"spec\":{\"ports\":[{\"nodePort\":30080,\"port\":80,\"targetPort\":80}],\"selector\":{\"app\":\"nginx-np\"},\"type\":\"NodePort\"}}\n"}},"spec":{"ports":[{"protocol":"TCP","port":80,"targetPort":80,"nodePort":30080}],"selector":{"app":"nginx-np"},"clusterIP":"10.109.14.106","clusterIPs":["10.109.14.106"],"type":"NodePort","sessionAffinity":"None","externalTrafficPolicy":"Cluster","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{}}},{"kind":"Service","apiVersion":"v1","metadata":{"name":"nginx-svc-lb","namespace":"default","uid":"47392643-d502-4728-a62c-9e613e83a9a8","resourceVersion":"39797","creationTimestamp":"2022-08-01T13:22:49Z","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"v1\",\"kind\":\"Service\",\"metadata\":{\"annotations\":{},\"name\":\"nginx-svc-lb\",\"namespace\":\"default\"},\"spec\":{\"clusterIP\":\"10.96.100.100\",\"ports\":[{\"port\":80,\"protocol\":\"TCP\",\"targetPort\":80}],\"selector\":{\"app\":\"nginx-lb\"},\"type\":\"LoadBalancer\"},\"status\":{\"loadBalancer\":{\"ingress\":[{\"ip\":\"192.0.2.127\"}]}}}\n"}},"spec":{"ports":[{"protocol":"TCP","port":80,"targetPort":80,"nodePort":30126}],"selector":{"app":"nginx-lb"},"clusterIP":"10.96.100.100","clusterIPs":["10.96.100.100"],"type":"LoadBalancer","sessionAffinity":"None","externalTrafficPolicy":"Cluster","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","allocateLoadBalancerNodePorts":true,"internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{"ingress":[{"ip":"127.0.0.1"}]}}},{"kind":"Service","apiVersion":"v1","metadata":{"name":"nginx-svc-npB","namespace":"default","uid":"18524032-4e56-4d14-800a-29ca2476dc15","resourceVersion":"13002","creationTimestamp":"2022-08-01T09:58:00Z","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"v1\",\"kind\":\"Service\",\"metadata\":{\"annotations\":{},\"name\":\"nginx-svc-np\",\"namespace\":\"default\"},\"spec\":{\"ports\":[{\"nodePort\":30080,\"port\":80,\"targetPort\":80}],\"selector\":{\"app\":\"nginx-np\"},\"type\":\"NodePort\"}}\n"}},"spec":{"ports":[{"protocol":"TCP","port":80,"targetPort":80,"nodePort":30080}],"selector":{"app":"nginx-np"},"clusterIP":"10.109.14.106","clusterIPs":["10.109.14.106"],"type":"NodePort","sessionAffinity":"None","externalTrafficPolicy":"Cluster","ipFamilies":["IPv4"],"ipFamilyPolicy":"SingleStack","internalTrafficPolicy":"Cluster"},"status":{"loadBalancer":{}}}]}`
+	sliceListString = `{"metadata":{"resourceVersion":"3102"},"items":[{"metadata":{"name":"kubernetes","namespace":"default","labels":{"kubernetes.io/service-name":"kubernetes"}},"addressType":"IPv4","endpoints":[{"addresses":["192.168.111.66"],"conditions":{"ready":true}}],"ports":[{"name":"https","port":6443,"protocol":"TCP"}]},{"metadata":{"name":"nginx-service-x7k2p","namespace":"default","labels":{"kubernetes.io/service-name":"nginx-service"}},"addressType":"IPv4","endpoints":[{"addresses":["10.244.0.28"],"conditions":{"ready":true},"nodeName":"dummyNode"}],"ports":[{"name":"name-of-service-port","port":80,"protocol":"TCP"}]},{"metadata":{"name":"nginx-svc-cl-4bq9d","namespace":"default","labels":{"kubernetes.io/service-name":"nginx-svc-cl"}},"addressType":"IPv4","endpoints":[{"addresses":["10.210.193.70"],"conditions":{"ready":true},"nodeName":"infratest"}],"ports":[{"name":"http","port":80,"protocol":"TCP"}]},{"metadata":{"name":"nginx-svc-np-m2c8w","namespace":"default","labels":{"kubernetes.io/service-name":"nginx-svc-np"}},"addressType":"IPv4","endpoints":[{"addresses":["10.210.193.71"],"conditions":{"ready":true},"nodeName":"infratest"}],"ports":[{"name":"","port":80,"protocol":"TCP"}]},{"metadata":{"name":"nginx-svc-lb-z5r7t","namespace":"default","labels":{"kubernetes.io/service-name":"nginx-svc-lb"}},"addressType":"IPv4","endpoints":[{"addresses":["10.210.193.72"],"conditions":{"ready":true},"nodeName":"infratest"}],"ports":[{"name":"","port":80,"protocol":"TCP"}]},{"metadata":{"name":"nginx-svc-npA-h6j3v","namespace":"default","labels":{"kubernetes.io/service-name":"nginx-svc-npA"}},"addressType":"IPv4","endpoints":[{"addresses":["10.210.193.71"],"conditions":{"ready":true},"nodeName":"infratest"}],"ports":[{"name":"","port":80,"protocol":"TCP"}]}]}`
+	bufSize         = 1024 * 1024
 
-	unsupportedWatchType = 42
-	nomberOfNatAddCalls  = 8
+	nomberOfNatAddCalls = 9
 )
 
 var (
-	servicesList  *v1.ServiceList
-	endpointsList *v1.EndpointsList
-	nodeList      *v1.NodeList
-	mockCrtl      *gomock.Controller
-	mockClient    *mock_proto.MockInfraAgentClient
-	listener      *bufconn.Listener
-	fakeClient    *fake.Clientset
+	servicesList *v1.ServiceList
+	slicesList   *discovery.EndpointSliceList
+	nodeList     *v1.NodeList
+	mockCrtl     *gomock.Controller
+	mockClient   *mock_proto.MockInfraAgentClient
+	listener     *bufconn.Listener
+	fakeClient   *fake.Clientset
 )
 
 func bufDialer(context.Context, string) (net.Conn, error) {
 	return listener.Dial()
 }
 
+// Returns a slice of the dummy service with the ready endpoints
+func newSlice(addressType discovery.AddressType, ips ...string) *discovery.EndpointSlice {
+	name, port, protocol := "http", int32(80), v1.ProtocolTCP
+	endpoints := make([]discovery.Endpoint, 0)
+	for _, ip := range ips {
+		endpoints = append(endpoints, discovery.Endpoint{Addresses: []string{ip}})
+	}
+	return &discovery.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dummy-" + strings.ToLower(string(addressType)),
+			Namespace: "default",
+			Labels:    map[string]string{discovery.LabelServiceName: "dummy"},
+		},
+		AddressType: addressType,
+		Endpoints:   endpoints,
+		Ports:       []discovery.EndpointPort{{Name: &name, Port: &port, Protocol: &protocol}},
+	}
+}
+
 func TestServices(t *testing.T) {
 	mockCrtl = gomock.NewController(t)
 	mockClient = mock_proto.NewMockInfraAgentClient(mockCrtl)
@@ -75,8 +99,8 @@ var _ = BeforeSuite(func() {
 	servicesList = &v1.ServiceList{}
 	err := json.Unmarshal([]byte(srvListString), servicesList)
 	Expect(err).ShouldNot(HaveOccurred())
-	endpointsList = &v1.EndpointsList{}
-	err = json.Unmarshal([]byte(epListString), endpointsList)
+	slicesList = &discovery.EndpointSliceList{}
+	err = json.Unmarshal([]byte(sliceListString), slicesList)
 	Expect(err).ShouldNot(HaveOccurred())
 	nodeList = &v1.NodeList{}
 	err = json.Unmarshal([]byte(nodeListString), nodeList)
@@ -91,13 +115,13 @@ var _ = AfterSuite(func() {
 
 var _ = Describe("services", func() {
 	var _ = BeforeEach(func() {
-		grpcDial = func(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+		getManagerConn = func() (*grpc.ClientConn, error) {
 			return grpc.DialContext(context.TODO(), "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
 		}
 		newInfraAgentClient = func(cc *grpc.ClientConn) proto.InfraAgentClient {
 			return mockClient
 		}
-		fakeClient = fake.NewSimpleClientset(nodeList, servicesList, endpointsList)
+		fakeClient = fake.NewSimpleClientset(nodeList, servicesList, slicesList)
 		newForConfig = func(c *rest.Config) (kubernetes.Interface, error) {
 			return fakeClient, nil
 		}
@@ -110,7 +134,7 @@ var _ = Describe("services", func() {
 			server, err := NewServiceServer(logrus.NewEntry(logrus.StandardLogger()), NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger())), types.ServiceRefreshTimeInSeconds)
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(server).NotTo(BeNil())
-			Expect(server.(*ServiceServer).nodeAddress).NotTo(BeEmpty())
+			Expect(server.(*ServiceServer).nodeAddresses).NotTo(BeEmpty())
 			Expect(server.GetName()).To(Equal("services-server"))
 		})
 	})
@@ -119,16 +143,19 @@ var _ = Describe("services", func() {
 		var _ = It("run ServiceServer without error", func() {
 			// for given input we should create six gRPC calls
 			var calls [](*gomock.Call)
+			var callsMade int32
 			for i := 0; i < nomberOfNatAddCalls; i++ {
-				calls = append(calls, mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil))
+				calls = append(calls, mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil).Do(
+					func(interface{}, interface{}, ...interface{}) { atomic.AddInt32(&callsMade, 1) }))
 			}
 
 			gomock.InOrder(calls...)
+			mockClient.EXPECT().SetSnatAddress(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil)
 			types.NodeName = "dummyNode"
 			server, err := NewServiceServer(logrus.NewEntry(logrus.StandardLogger()), NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger())), types.ServiceRefreshTimeInSeconds)
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(server).NotTo(BeNil())
-			Expect(server.(*ServiceServer).nodeAddress).NotTo(BeEmpty())
+			Expect(server.(*ServiceServer).nodeAddresses).NotTo(BeEmpty())
 
 			go func() {
 				defer GinkgoRecover()
@@ -139,6 +166,10 @@ var _ = Describe("services", func() {
 			Eventually(func() string {
 				return types.ServiceServerStatus
 			}).Should(Equal(types.ServerStatusOK))
+			// the events are handled asynchronously, wait for all of them
+			Eventually(func() int32 {
+				return atomic.LoadInt32(&callsMade)
+			}).Should(Equal(int32(nomberOfNatAddCalls)))
 			// stop service eventually
 			server.StopServer()
 			Eventually(func() string {
@@ -146,30 +177,17 @@ var _ = Describe("services", func() {
 			}).Should(Equal(types.ServerStatusStopped))
 		})
 	})
-
-	var _ = Context("NewServiceListWatch created for unsupported type ", func() {
-		var _ = It("should return error when List is called", func() {
-			w := NewServiceListWatch(fakeClient, unsupportedWatchType)
-			_, err := w.List(metav1.ListOptions{})
-			Expect(err).To(HaveOccurred())
-		})
-		var _ = It("should return error when Watch is called", func() {
-			w := NewServiceListWatch(fakeClient, unsupportedWatchType)
-			_, err := w.Watch(metav1.ListOptions{})
-			Expect(err).To(HaveOccurred())
-		})
-	})
 })
 
 var _ = Describe("NAT settings handler", func() {
 	var _ = BeforeEach(func() {
-		grpcDial = func(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+		getManagerConn = func() (*grpc.ClientConn, error) {
 			return grpc.DialContext(context.TODO(), "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
 		}
 		newInfraAgentClient = func(cc *grpc.ClientConn) proto.InfraAgentClient {
 			return mockClient
 		}
-		fakeClient = fake.NewSimpleClientset(nodeList, servicesList, endpointsList)
+		fakeClient = fake.NewSimpleClientset(nodeList, servicesList, slicesList)
 		newForConfig = func(c *rest.Config) (kubernetes.Interface, error) {
 			return fakeClient, nil
 		}
@@ -189,7 +207,7 @@ var _ = Describe("NAT settings handler", func() {
 			grpcCall := mockClient.EXPECT().SetSnatAddress(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil)
 			gomock.InOrder(grpcCall)
 			h := NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger()))
-			err := h.SetSnatAddress("127.0.0.1")
+			err := h.SetSnatAddress("127.0.0.1", "")
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -208,6 +226,14 @@ var _ = Describe("NAT settings handler", func() {
 			err := h.NatTranslationDelete(&proto.NatTranslation{})
 			Expect(err).ToNot(HaveOccurred())
 		})
+
+		var _ = It("NatTranslationUpdate", func() {
+			grpcCall := mockClient.EXPECT().NatTranslationUpdate(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil)
+			gomock.InOrder(grpcCall)
+			h := NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger()))
+			err := h.NatTranslationUpdate(&proto.NatTranslationUpdateRequest{})
+			Expect(err).ToNot(HaveOccurred())
+		})
 	})
 
 	var _ = Context("Methods should return error", func() {
@@ -231,7 +257,7 @@ var _ = Describe("NAT settings handler", func() {
 			grpcCall := mockClient.EXPECT().SetSnatAddress(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: false}, nil)
 			gomock.InOrder(grpcCall)
 			h := NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger()))
-			err := h.SetSnatAddress("127.0.0.1")
+			err := h.SetSnatAddress("127.0.0.1", "")
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -250,12 +276,28 @@ var _ = Describe("NAT settings handler", func() {
 			err := h.NatTranslationDelete(&proto.NatTranslation{})
 			Expect(err).To(HaveOccurred())
 		})
+
+		var _ = It("NatTranslationUpdate - error reply from Manager", func() {
+			grpcCall := mockClient.EXPECT().NatTranslationUpdate(gomock.Any(), gomock.Any()).Return(nil, errors.New("Fake error"))
+			gomock.InOrder(grpcCall)
+			h := NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger()))
+			err := h.NatTranslationUpdate(&proto.NatTranslationUpdateRequest{})
+			Expect(err).To(HaveOccurred())
+		})
+
+		var _ = It("NatTranslationUpdate - failure reply from Manager", func() {
+			grpcCall := mockClient.EXPECT().NatTranslationUpdate(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: false}, nil)
+			gomock.InOrder(grpcCall)
+			h := NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger()))
+			err := h.NatTranslationUpdate(&proto.NatTranslationUpdateRequest{})
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
 
 var _ = Describe("NAT settings handler connection", func() {
 	var _ = BeforeEach(func() {
-		grpcDial = func(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+		getManagerConn = func() (*grpc.ClientConn, error) {
 			return nil, errors.New("Connection error")
 		}
 	})
@@ -269,7 +311,7 @@ var _ = Describe("NAT settings handler connection", func() {
 
 		var _ = It("SetSnatAddress", func() {
 			h := NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger()))
-			err := h.SetSnatAddress("127.0.0.1")
+			err := h.SetSnatAddress("127.0.0.1", "")
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -284,6 +326,12 @@ var _ = Describe("NAT settings handler connection", func() {
 			err := h.NatTranslationDelete(&proto.NatTranslation{})
 			Expect(err).To(HaveOccurred())
 		})
+
+		var _ = It("NatTranslationUpdate", func() {
+			h := NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger()))
+			err := h.NatTranslationUpdate(&proto.NatTranslationUpdateRequest{})
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
 
@@ -301,17 +349,322 @@ var _ = Describe("NAT translation builter utilities", func() {
 			Expect(port).To(Equal(uint32(sp.Port)))
 		})
 	})
+
+	var _ = Context("buildNatTranslations should", func() {
+		var _ = It("build a translation per cluster IP of a dual-stack service", func() {
+			service := &v1.Service{
+				Spec: v1.ServiceSpec{
+					Type:       v1.ServiceTypeClusterIP,
+					ClusterIP:  "10.96.0.20",
+					ClusterIPs: []string{"10.96.0.20", "fd00:96::20"},
+					Ports:      []v1.ServicePort{{Name: "http", Port: 80, Protocol: v1.ProtocolTCP}},
+				},
+			}
+			slices := []*discovery.EndpointSlice{
+				newSlice(discovery.AddressTypeIPv4, "10.10.10.1"),
+				newSlice(discovery.AddressTypeIPv6, "fd00::1"),
+			}
+
+			entries := buildNatTranslations(service, slices, nil, "")
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Endpoint.Ipv4Addr).To(Equal("10.96.0.20"))
+			Expect(entries[0].Backends).To(HaveLen(1))
+			Expect(entries[0].Backends[0].DstEp.Ipv4Addr).To(Equal("10.10.10.1"))
+			Expect(entries[1].Endpoint.Ipv4Addr).To(BeEmpty())
+			Expect(entries[1].Endpoint.Ipv6Addr).To(Equal("fd00:96::20"))
+			Expect(entries[1].Backends).To(HaveLen(1))
+			Expect(entries[1].Backends[0].DstEp.Ipv6Addr).To(Equal("fd00::1"))
+			Expect(natTranslationKey(entries[1])).To(Equal("TCP/[fd00:96::20]:80"))
+		})
+	})
+})
+
+var _ = Describe("service types", func() {
+	nodeIPs := []string{"192.168.111.66", "203.0.113.10", "fd00:10::66"}
+	slices := []*discovery.EndpointSlice{newSlice(discovery.AddressTypeIPv4, "10.10.10.1")}
+
+	newService := func(serviceType v1.ServiceType, nodePort int32) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "dummy", Namespace: "default"},
+			Spec: v1.ServiceSpec{
+				Type:      serviceType,
+				ClusterIP: "10.96.0.20",
+				Ports:     []v1.ServicePort{{Name: "http", Port: 80, NodePort: nodePort, Protocol: v1.ProtocolTCP}},
+			},
+		}
+	}
+
+	translationKeys := func(entries []*proto.NatTranslation) []string {
+		keys := make([]string, 0, len(entries))
+		for _, nt := range entries {
+			keys = append(keys, natTranslationKey(nt))
+		}
+		return keys
+	}
+
+	DescribeTable("buildNatTranslations() should program",
+		func(service *v1.Service, keys ...string) {
+			entries := buildNatTranslations(service, slices, nodeIPs, "")
+			Expect(translationKeys(entries)).To(Equal(keys))
+			for _, nt := range entries {
+				// the backends keep the target port, whatever the port
+				// the service is reached on
+				if len(nt.Backends) > 0 {
+					Expect(nt.Backends[0].DstEp.Port).To(Equal(uint32(80)))
+				}
+			}
+		},
+		Entry("the cluster IP of a ClusterIP service",
+			newService(v1.ServiceTypeClusterIP, 0),
+			"TCP/10.96.0.20:80"),
+		Entry("the external IPs of a ClusterIP service on the service port",
+			func() *v1.Service {
+				s := newService(v1.ServiceTypeClusterIP, 0)
+				s.Spec.ExternalIPs = []string{"1.2.3.42"}
+				return s
+			}(),
+			"TCP/10.96.0.20:80", "TCP/1.2.3.42:80"),
+		Entry("the node port of a NodePort service on every node address",
+			newService(v1.ServiceTypeNodePort, 30080),
+			"TCP/10.96.0.20:80", "TCP/192.168.111.66:30080", "TCP/203.0.113.10:30080", "TCP/[fd00:10::66]:30080"),
+		Entry("the ingress IPs and the node port of a LoadBalancer service",
+			func() *v1.Service {
+				s := newService(v1.ServiceTypeLoadBalancer, 30126)
+				s.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "192.0.2.127"}}
+				return s
+			}(),
+			"TCP/10.96.0.20:80", "TCP/192.0.2.127:80",
+			"TCP/192.168.111.66:30126", "TCP/203.0.113.10:30126", "TCP/[fd00:10::66]:30126"),
+		Entry("no node port for a LoadBalancer service without allocated node ports",
+			func() *v1.Service {
+				s := newService(v1.ServiceTypeLoadBalancer, 0)
+				s.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "192.0.2.127"}}
+				return s
+			}(),
+			"TCP/10.96.0.20:80", "TCP/192.0.2.127:80"),
+		Entry("no node port for a ClusterIP service which had one",
+			newService(v1.ServiceTypeClusterIP, 30080),
+			"TCP/10.96.0.20:80"),
+		Entry("no entries for an ExternalName service",
+			&v1.Service{Spec: v1.ServiceSpec{Type: v1.ServiceTypeExternalName, ExternalName: "example.com"}}),
+	)
+})
+
+var _ = Describe("endpoint slices", func() {
+	var service *v1.Service
+
+	backendIPs := func(slices ...*discovery.EndpointSlice) []string {
+		entries := buildNatTranslations(service, slices, nil, "zone-a")
+		Expect(entries).To(HaveLen(1))
+		ips := make([]string, 0)
+		for _, backend := range entries[0].Backends {
+			ips = append(ips, backend.DstEp.Ipv4Addr)
+		}
+		return ips
+	}
+
+	var _ = BeforeEach(func() {
+		service = &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "dummy", Namespace: "default"},
+			Spec: v1.ServiceSpec{
+				Type:      v1.ServiceTypeClusterIP,
+				ClusterIP: "10.96.0.20",
+				Ports:     []v1.ServicePort{{Name: "http", Port: 80, Protocol: v1.ProtocolTCP}},
+			},
+		}
+	})
+
+	var _ = Context("buildNatTranslations() should", func() {
+		var _ = It("merge the endpoints of all the slices of a service", func() {
+			first := newSlice(discovery.AddressTypeIPv4, "10.10.10.1", "10.10.10.2")
+			second := newSlice(discovery.AddressTypeIPv4, "10.10.10.2", "10.10.10.3")
+			second.Name = "dummy-ipv4-2"
+			Expect(backendIPs(first, second)).To(Equal([]string{"10.10.10.1", "10.10.10.2", "10.10.10.3"}))
+		})
+
+		var _ = It("use the ready endpoints only", func() {
+			notReady := false
+			slice := newSlice(discovery.AddressTypeIPv4, "10.10.10.1", "10.10.10.2")
+			slice.Endpoints[1].Conditions.Ready = &notReady
+			Expect(backendIPs(slice)).To(Equal([]string{"10.10.10.1"}))
+		})
+
+		var _ = It("fall back to the terminating endpoints still serving", func() {
+			notReady, serving, terminating := false, true, true
+			slice := newSlice(discovery.AddressTypeIPv4, "10.10.10.1", "10.10.10.2")
+			slice.Endpoints[0].Conditions = discovery.EndpointConditions{Ready: &notReady, Serving: &serving, Terminating: &terminating}
+			slice.Endpoints[1].Conditions = discovery.EndpointConditions{Ready: &notReady, Terminating: &terminating}
+			Expect(backendIPs(slice)).To(Equal([]string{"10.10.10.1"}))
+		})
+
+		var _ = It("keep the endpoints hinted for the zone of the node", func() {
+			slice := newSlice(discovery.AddressTypeIPv4, "10.10.10.1", "10.10.10.2")
+			slice.Endpoints[0].Hints = &discovery.EndpointHints{ForZones: []discovery.ForZone{{Name: "zone-a"}}}
+			slice.Endpoints[1].Hints = &discovery.EndpointHints{ForZones: []discovery.ForZone{{Name: "zone-b"}}}
+			// the hints are used only when the service asks for them
+			Expect(backendIPs(slice)).To(HaveLen(2))
+
+			service.Annotations = map[string]string{v1.AnnotationTopologyAwareHints: "Auto"}
+			Expect(backendIPs(slice)).To(Equal([]string{"10.10.10.1"}))
+
+			// and when every endpoint has some
+			slice.Endpoints[1].Hints = nil
+			Expect(backendIPs(slice)).To(HaveLen(2))
+		})
+
+		var _ = It("carry the timeout of the ClientIP affinity", func() {
+			slice := newSlice(discovery.AddressTypeIPv4, "10.10.10.1")
+			Expect(buildNatTranslations(service, []*discovery.EndpointSlice{slice}, nil, "")[0].SessionAffinityTimeout).To(BeZero())
+
+			service.Spec.SessionAffinity = v1.ServiceAffinityClientIP
+			Expect(buildNatTranslations(service, []*discovery.EndpointSlice{slice}, nil, "")[0].SessionAffinityTimeout).To(
+				Equal(uint32(v1.DefaultClientIPServiceAffinitySeconds)))
+
+			timeout := int32(60)
+			service.Spec.SessionAffinityConfig = &v1.SessionAffinityConfig{ClientIP: &v1.ClientIPConfig{TimeoutSeconds: &timeout}}
+			Expect(buildNatTranslations(service, []*discovery.EndpointSlice{slice}, nil, "")[0].SessionAffinityTimeout).To(Equal(uint32(60)))
+		})
+
+		var _ = It("skip the slices without the port of the service", func() {
+			slice := newSlice(discovery.AddressTypeIPv4, "10.10.10.1")
+			other := "metrics"
+			slice.Ports[0].Name = &other
+			Expect(backendIPs(slice)).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("traffic policies", func() {
+	var (
+		service *v1.Service
+		slices  []*discovery.EndpointSlice
+	)
+
+	backendIPs := func(entry *proto.NatTranslation) []string {
+		ips := make([]string, 0)
+		for _, backend := range entry.Backends {
+			ips = append(ips, backend.DstEp.Ipv4Addr)
+		}
+		return ips
+	}
+
+	var _ = BeforeEach(func() {
+		types.NodeName = "dummyNode"
+		service = &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "dummy", Namespace: "default"},
+			Spec: v1.ServiceSpec{
+				Type:        v1.ServiceTypeNodePort,
+				ClusterIP:   "10.96.0.20",
+				ExternalIPs: []string{"1.2.3.42"},
+				Ports:       []v1.ServicePort{{Name: "http", Port: 80, NodePort: 30080, Protocol: v1.ProtocolTCP}},
+			},
+		}
+		local, remote := "dummyNode", "otherNode"
+		slice := newSlice(discovery.AddressTypeIPv4, "10.10.10.1", "10.10.10.2")
+		slice.Endpoints[0].NodeName = &local
+		slice.Endpoints[1].NodeName = &remote
+		slices = []*discovery.EndpointSlice{slice}
+	})
+
+	var _ = Context("buildNatTranslations() should", func() {
+		var _ = It("send the external traffic to the local endpoints for the Local policy", func() {
+			service.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeLocal
+			entries := buildNatTranslations(service, slices, []string{"192.168.111.66"}, "")
+			Expect(entries).To(HaveLen(3))
+			// cluster IP, external IP and node port
+			Expect(backendIPs(entries[0])).To(Equal([]string{"10.10.10.1", "10.10.10.2"}))
+			Expect(backendIPs(entries[1])).To(Equal([]string{"10.10.10.1"}))
+			Expect(backendIPs(entries[2])).To(Equal([]string{"10.10.10.1"}))
+			for _, entry := range entries {
+				Expect(entry.Backends[0].SrcEp).To(BeNil())
+			}
+		})
+
+		var _ = It("not take the endpoints without a node name for local ones", func() {
+			slices[0].Endpoints[1].NodeName = nil
+			service.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeLocal
+			entries := buildNatTranslations(service, slices, []string{"192.168.111.66"}, "")
+			Expect(backendIPs(entries[2])).To(Equal([]string{"10.10.10.1"}))
+			Expect(countLocalEndpoints(slices)).To(Equal(1))
+		})
+
+		var _ = It("send the cluster IP traffic to the local endpoints for the Local internal policy", func() {
+			policy := v1.ServiceInternalTrafficPolicyLocal
+			service.Spec.InternalTrafficPolicy = &policy
+			entries := buildNatTranslations(service, slices, []string{"192.168.111.66"}, "")
+			Expect(entries).To(HaveLen(3))
+			Expect(backendIPs(entries[0])).To(Equal([]string{"10.10.10.1"}))
+			Expect(backendIPs(entries[1])).To(Equal([]string{"10.10.10.1", "10.10.10.2"}))
+			Expect(backendIPs(entries[2])).To(Equal([]string{"10.10.10.1", "10.10.10.2"}))
+		})
+	})
+
+	var _ = Context("healthCheckServer should", func() {
+		var (
+			hc *healthCheckServer
+			l  net.Listener
+		)
+
+		get := func() (int, healthCheckReply) {
+			var reply healthCheckReply
+			resp, err := http.Get("http://" + l.Addr().String())
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(json.NewDecoder(resp.Body).Decode(&reply)).To(Succeed())
+			return resp.StatusCode, reply
+		}
+
+		var _ = BeforeEach(func() {
+			hc = newHealthCheckServer(logrus.NewEntry(logrus.StandardLogger()))
+			listen = func(network, _ string) (net.Listener, error) {
+				var err error
+				l, err = net.Listen(network, "127.0.0.1:0")
+				return l, err
+			}
+			service.Spec.Type = v1.ServiceTypeLoadBalancer
+			service.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeLocal
+			service.Spec.HealthCheckNodePort = 32000
+		})
+
+		var _ = AfterEach(func() {
+			hc.stop()
+			listen = net.Listen
+		})
+
+		var _ = It("report the local endpoints of the service", func() {
+			Expect(hc.syncService(service, slices)).To(Succeed())
+			code, reply := get()
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(reply.Service.Name).To(Equal("dummy"))
+			Expect(reply.LocalEndpoints).To(Equal(1))
+
+			slices[0].Endpoints = slices[0].Endpoints[1:]
+			Expect(hc.syncService(service, slices)).To(Succeed())
+			code, reply = get()
+			Expect(code).To(Equal(http.StatusServiceUnavailable))
+			Expect(reply.LocalEndpoints).To(Equal(0))
+		})
+
+		var _ = It("close the port once the service does not have it anymore", func() {
+			Expect(hc.syncService(service, slices)).To(Succeed())
+			service.Spec.HealthCheckNodePort = 0
+			Expect(hc.syncService(service, slices)).To(Succeed())
+			Expect(hc.services).To(BeEmpty())
+			_, err := http.Get("http://" + l.Addr().String())
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
 
 var _ = Describe("service deletion", func() {
 	var _ = BeforeEach(func() {
-		grpcDial = func(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+		getManagerConn = func() (*grpc.ClientConn, error) {
 			return grpc.DialContext(context.TODO(), "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
 		}
 		newInfraAgentClient = func(cc *grpc.ClientConn) proto.InfraAgentClient {
 			return mockClient
 		}
-		fakeClient = fake.NewSimpleClientset(nodeList, servicesList, endpointsList)
+		fakeClient = fake.NewSimpleClientset(nodeList, servicesList, slicesList)
 		newForConfig = func(c *rest.Config) (kubernetes.Interface, error) {
 			return fakeClient, nil
 		}
@@ -322,18 +675,21 @@ var _ = Describe("service deletion", func() {
 		var _ = It("be successfull", func() {
 			// for given input we should create six gRPC calls
 			var calls [](*gomock.Call)
+			var callsMade int32
 			for i := 0; i < nomberOfNatAddCalls; i++ {
-				calls = append(calls, mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil))
+				calls = append(calls, mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil).Do(
+					func(interface{}, interface{}, ...interface{}) { atomic.AddInt32(&callsMade, 1) }))
 			}
 
 			calls = append(calls, mockClient.EXPECT().NatTranslationDelete(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil))
 
 			gomock.InOrder(calls...)
+			mockClient.EXPECT().SetSnatAddress(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil)
 			types.NodeName = "dummyNode"
 			server, err := NewServiceServer(logrus.NewEntry(logrus.StandardLogger()), NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger())), 1)
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(server).NotTo(BeNil())
-			Expect(server.(*ServiceServer).nodeAddress).NotTo(BeEmpty())
+			Expect(server.(*ServiceServer).nodeAddresses).NotTo(BeEmpty())
 
 			go func() {
 				defer GinkgoRecover()
@@ -345,18 +701,22 @@ var _ = Describe("service deletion", func() {
 				return types.ServiceServerStatus
 			}).Should(Equal(types.ServerStatusOK))
 
-			serviceListWatch := NewServiceListWatch(fakeClient, SERVICES_LIST_WATCH)
-			svc, err := serviceListWatch.List(metav1.ListOptions{})
+			svc, err := fakeClient.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{})
 			Expect(err).ToNot(HaveOccurred())
-			preDel := len(svc.(*v1.ServiceList).Items)
+			preDel := len(svc.Items)
+
+			// the service has to be programmed before it can be deleted
+			Eventually(func() int32 {
+				return atomic.LoadInt32(&callsMade)
+			}).Should(Equal(int32(nomberOfNatAddCalls)))
 
 			err = fakeClient.CoreV1().Services("default").Delete(context.TODO(), "kubernetes", metav1.DeleteOptions{})
 			Expect(err).ToNot(HaveOccurred())
 			time.Sleep(2 * time.Second)
 
-			svc, err = serviceListWatch.List(metav1.ListOptions{})
+			svc, err = fakeClient.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{})
 			Expect(err).ToNot(HaveOccurred())
-			postDel := len(svc.(*v1.ServiceList).Items)
+			postDel := len(svc.Items)
 
 			Expect(preDel - postDel).To(Equal(1))
 
@@ -368,3 +728,217 @@ var _ = Describe("service deletion", func() {
 		})
 	})
 })
+
+var _ = Describe("SNAT configuration", func() {
+	var _ = BeforeEach(func() {
+		getManagerConn = func() (*grpc.ClientConn, error) {
+			return grpc.DialContext(context.TODO(), "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		}
+		newInfraAgentClient = func(cc *grpc.ClientConn) proto.InfraAgentClient {
+			return mockClient
+		}
+		types.ClusterPodsCIDR = "10.244.0.0/16,fd00:244::/56"
+		types.ClusterServicesSubnet = "10.96.0.0/12"
+	})
+
+	var _ = AfterEach(func() {
+		types.ClusterPodsCIDR = ""
+		types.ClusterServicesSubnet = ""
+	})
+
+	var _ = Context("configureSnat() should", func() {
+		var _ = It("masquerade behind the internal node addresses except for the cluster CIDRs", func() {
+			var snat *proto.SetSnatAddressRequest
+			prefixes := make([]string, 0)
+			gomock.InOrder(
+				mockClient.EXPECT().SetSnatAddress(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, in *proto.SetSnatAddressRequest, _ ...grpc.CallOption) (*proto.Reply, error) {
+						snat = in
+						return &proto.Reply{Successful: true}, nil
+					}),
+				mockClient.EXPECT().AddDelSnatPrefix(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, in *proto.AddDelSnatPrefixRequest, _ ...grpc.CallOption) (*proto.Reply, error) {
+						Expect(in.IsAdd).To(BeTrue())
+						prefixes = append(prefixes, in.Prefix)
+						return &proto.Reply{Successful: true}, nil
+					}).Times(3),
+			)
+			server := &ServiceServer{
+				log:           logrus.NewEntry(logrus.StandardLogger()),
+				handler:       NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger())),
+				nodeAddresses: []string{"192.168.111.66", "fd00:10::66", "203.0.113.10"},
+			}
+			server.configureSnat()
+
+			Expect(snat.SnatIpv4).To(Equal("192.168.111.66"))
+			Expect(snat.SnatIpv6).To(Equal("fd00:10::66"))
+			Expect(prefixes).To(Equal([]string{"10.244.0.0/16", "fd00:244::/56", "10.96.0.0/12"}))
+		})
+	})
+})
+
+var _ = Describe("service backends update", func() {
+	var (
+		server  *ServiceServer
+		service *v1.Service
+	)
+
+	newSlices := func(ips ...string) []*discovery.EndpointSlice {
+		return []*discovery.EndpointSlice{newSlice(discovery.AddressTypeIPv4, ips...)}
+	}
+
+	var _ = BeforeEach(func() {
+		getManagerConn = func() (*grpc.ClientConn, error) {
+			return grpc.DialContext(context.TODO(), "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		}
+		newInfraAgentClient = func(cc *grpc.ClientConn) proto.InfraAgentClient {
+			return mockClient
+		}
+		server = &ServiceServer{
+			log:         logrus.NewEntry(logrus.StandardLogger()),
+			handler:     NewNatServiceHandler(logrus.NewEntry(logrus.StandardLogger())),
+			healthCheck: newHealthCheckServer(logrus.NewEntry(logrus.StandardLogger())),
+			stateMap:    make(map[string]ServiceEntries),
+		}
+		service = &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "dummy", Namespace: "default"},
+			Spec: v1.ServiceSpec{
+				Type:      v1.ServiceTypeClusterIP,
+				ClusterIP: "10.96.0.20",
+				Ports: []v1.ServicePort{{
+					Name:       "http",
+					Port:       80,
+					Protocol:   v1.ProtocolTCP,
+					TargetPort: intstr.FromInt(80),
+				}},
+			},
+		}
+	})
+
+	var _ = Context("addServicePort() should", func() {
+		var _ = It("send only the changed backends", func() {
+			var update *proto.NatTranslationUpdateRequest
+			gomock.InOrder(
+				mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+				mockClient.EXPECT().NatTranslationUpdate(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, in *proto.NatTranslationUpdateRequest, _ ...grpc.CallOption) (*proto.Reply, error) {
+						update = in
+						return &proto.Reply{Successful: true}, nil
+					}),
+			)
+
+			server.addServicePort(service, newSlices("10.10.10.1", "10.10.10.2"))
+			server.addServicePort(service, newSlices("10.10.10.2", "10.10.10.3"))
+
+			Expect(update).NotTo(BeNil())
+			Expect(update.Endpoint.Ipv4Addr).To(Equal("10.96.0.20"))
+			Expect(update.AddedBackends).To(HaveLen(1))
+			Expect(update.AddedBackends[0].DstEp.Ipv4Addr).To(Equal("10.10.10.3"))
+			Expect(update.RemovedBackends).To(HaveLen(1))
+			Expect(update.RemovedBackends[0].DstEp.Ipv4Addr).To(Equal("10.10.10.1"))
+		})
+
+		var _ = It("delete the translation when no backends are left", func() {
+			gomock.InOrder(
+				mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+				mockClient.EXPECT().NatTranslationDelete(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+			)
+
+			server.addServicePort(service, newSlices("10.10.10.1"))
+			server.addServicePort(service, newSlices())
+		})
+
+		var _ = It("program the service again when one of its slices is deleted", func() {
+			var update *proto.NatTranslationUpdateRequest
+			gomock.InOrder(
+				mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+				mockClient.EXPECT().NatTranslationUpdate(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, in *proto.NatTranslationUpdateRequest, _ ...grpc.CallOption) (*proto.Reply, error) {
+						update = in
+						return &proto.Reply{Successful: true}, nil
+					}),
+			)
+			server.serviceStore = cache.NewStore(cache.MetaNamespaceKeyFunc)
+			server.sliceStore = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{serviceIndex: indexByService})
+			first := newSlice(discovery.AddressTypeIPv4, "10.10.10.1")
+			second := newSlice(discovery.AddressTypeIPv4, "10.10.10.2")
+			second.Name = "dummy-ipv4-2"
+			Expect(server.serviceStore.Add(service)).To(Succeed())
+			Expect(server.sliceStore.Add(first)).To(Succeed())
+			Expect(server.sliceStore.Add(second)).To(Succeed())
+
+			server.handleSliceEvent(second)
+			Expect(server.sliceStore.Delete(second)).To(Succeed())
+			server.handleSliceEvent(second)
+
+			Expect(update).NotTo(BeNil())
+			Expect(update.AddedBackends).To(BeEmpty())
+			Expect(update.RemovedBackends).To(HaveLen(1))
+			Expect(update.RemovedBackends[0].DstEp.Ipv4Addr).To(Equal("10.10.10.2"))
+		})
+
+		var _ = It("program the translation again when the session affinity changes", func() {
+			var added *proto.NatTranslation
+			gomock.InOrder(
+				mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+				mockClient.EXPECT().NatTranslationDelete(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+				mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, in *proto.NatTranslation, _ ...grpc.CallOption) (*proto.Reply, error) {
+						added = in
+						return &proto.Reply{Successful: true}, nil
+					}),
+			)
+
+			server.addServicePort(service, newSlices("10.10.10.1"))
+			service.Spec.SessionAffinity = v1.ServiceAffinityClientIP
+			server.addServicePort(service, newSlices("10.10.10.1"))
+
+			Expect(added).NotTo(BeNil())
+			Expect(added.SessionAffinityTimeout).To(Equal(uint32(v1.DefaultClientIPServiceAffinitySeconds)))
+			Expect(added.Backends).To(HaveLen(1))
+		})
+
+		var _ = It("not send anything when backends did not change", func() {
+			gomock.InOrder(
+				mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+			)
+
+			server.addServicePort(service, newSlices("10.10.10.1"))
+			server.addServicePort(service, newSlices("10.10.10.1"))
+		})
+
+		var _ = It("send the failed update again on the next sync", func() {
+			var update *proto.NatTranslationUpdateRequest
+			gomock.InOrder(
+				mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+				mockClient.EXPECT().NatTranslationUpdate(gomock.Any(), gomock.Any()).Return(nil, errors.New("Fake error")),
+				mockClient.EXPECT().NatTranslationUpdate(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, in *proto.NatTranslationUpdateRequest, _ ...grpc.CallOption) (*proto.Reply, error) {
+						update = in
+						return &proto.Reply{Successful: true}, nil
+					}),
+			)
+
+			server.addServicePort(service, newSlices("10.10.10.1"))
+			server.addServicePort(service, newSlices("10.10.10.1", "10.10.10.2"))
+			server.addServicePort(service, newSlices("10.10.10.1", "10.10.10.2"))
+
+			Expect(update).NotTo(BeNil())
+			Expect(update.AddedBackends).To(HaveLen(1))
+			Expect(update.AddedBackends[0].DstEp.Ipv4Addr).To(Equal("10.10.10.2"))
+			Expect(server.stateMap["default/dummy"].Entries[0].Backends).To(HaveLen(2))
+		})
+
+		var _ = It("send the failed translation again on the next sync", func() {
+			gomock.InOrder(
+				mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: false, ErrorMessage: "Fake error"}, nil),
+				mockClient.EXPECT().NatTranslationAdd(gomock.Any(), gomock.Any()).Return(&proto.Reply{Successful: true}, nil),
+			)
+
+			server.addServicePort(service, newSlices("10.10.10.1"))
+			Expect(server.stateMap["default/dummy"].Entries).To(BeEmpty())
+			server.addServicePort(service, newSlices("10.10.10.1"))
+			Expect(server.stateMap["default/dummy"].Entries).To(HaveLen(1))
+		})
+	})
+})
@@ -35,6 +35,11 @@ const (
 	IpvlanPodInterface          = "ipvlan"
 	TapInterface                = "tap"
 	TapInterfacePrefix          = "P4TAP_"
+	VethPodInterface            = "veth"
+	VethInterfacePrefix         = "P4VETH_"
+	IPVlanModeL2                = "l2"
+	IPVlanModeL3                = "l3"
+	IPVlanModeL3S               = "l3s"
 	InfraHostDummyContainerId   = "60e2aea2_2d40_44ac_b9b1_ace4ceda528e"
 	InfraDummyNetNS             = "/var/run/netns/cni-60e2aea2-2d40-44ac-b9b1-ace4ceda528e"
 	DefaultCNIBinPath           = "/opt/cni/bin"
@@ -59,6 +64,7 @@ var (
 	ServiceServerStatus        = ""
 	CNIServerStatus            = ""
 	InfraManagerServerStatus   = ""
+	InfraManagerConnStatus     = ""
 )
 
 type PodInterface interface {
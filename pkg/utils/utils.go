@@ -122,6 +122,38 @@ func GetNodeIP(client kubernetes.Interface, nodeName string) (string, error) {
 	return internalIP, nil
 }
 
+// Returns the internal and external addresses of the node, of both
+// families, on which its node ports can be reached. The internal ones
+// come first.
+func GetNodeIPs(client kubernetes.Interface, nodeName string) ([]string, error) {
+	nodes, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "metadata.name=" + nodeName})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodes.Items) == 0 {
+		return nil, errors.New("unable to get K8s node from API")
+	}
+
+	ips := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, addrType := range []v1.NodeAddressType{v1.NodeInternalIP, v1.NodeExternalIP} {
+		for _, adr := range nodes.Items[0].Status.Addresses {
+			if adr.Type != addrType || net.ParseIP(adr.Address) == nil || seen[adr.Address] {
+				continue
+			}
+			seen[adr.Address] = true
+			ips = append(ips, adr.Address)
+		}
+	}
+
+	if len(ips) == 0 {
+		return nil, errors.New("no node InternalIP or ExternalIP")
+	}
+	return ips, nil
+}
+
 type InterfaceAddressGetter interface {
 	GetAddr(net.Interface) ([]net.Addr, error)
 }
@@ -246,6 +278,28 @@ func GetVFList(pf string, prefix string) ([]*types.InterfaceInfo, error) {
 	return out, nil
 }
 
+// GetVFPciAddrs returns the PCI addresses of all the VFs of the PF,
+// including the ones whose interface was moved out of the root netns
+func GetVFPciAddrs(pf string, prefix string) ([]string, error) {
+	devicePath := path.Join(prefix, pf, "device")
+	de, err := os.ReadDir(devicePath)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0)
+	for _, entry := range de {
+		if strings.Contains(entry.Name(), "virtfn") {
+			realPath, err := os.Readlink(path.Join(devicePath, entry.Name()))
+			if err != nil {
+				continue
+			}
+			out = append(out, path.Base(realPath))
+		}
+	}
+	return out, nil
+}
+
 // GetTapInterfaces returns a list of host Tap interance info matching a naming prefix using "prefix"
 func GetTapInterfaces(prefix string) ([]*types.InterfaceInfo, error) {
 	links, err := netlink.LinkList()
@@ -278,6 +332,19 @@ func GetNodePodsCIDR(k8sclient kubernetes.Interface, nodeName string) (string, e
 	return ns.Items[0].Spec.PodCIDR, nil
 }
 
+// Returns the topology zone of the node, empty when it has none
+func GetNodeZone(k8sclient kubernetes.Interface, nodeName string) (string, error) {
+	ns, err := k8sclient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "metadata.name=" + nodeName})
+	if err != nil {
+		return "", err
+	}
+	if len(ns.Items) == 0 {
+		return "", fmt.Errorf("empty node list for %s", nodeName)
+	}
+	return ns.Items[0].Labels[v1.LabelTopologyZone], nil
+}
+
 func GetK8sClient(config *rest.Config) (kubernetes.Interface, error) {
 	return kubernetes.NewForConfig(config)
 }
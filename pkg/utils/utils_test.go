@@ -183,6 +183,30 @@ var _ = Describe("utils", func() {
 		})
 	})
 
+	var _ = Context("GetNodeIPs() should", func() {
+		var _ = It("return the internal and external addresses of the node", func() {
+			nodeList := &v1.NodeList{}
+			err := json.Unmarshal([]byte(nodeListString), nodeList)
+			Expect(err).ToNot(HaveOccurred())
+			nodeList.Items[0].Status.Addresses = append(nodeList.Items[0].Status.Addresses,
+				v1.NodeAddress{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+				v1.NodeAddress{Type: v1.NodeInternalIP, Address: "fd00:10::66"})
+			client := fake.NewSimpleClientset(nodeList)
+			ips, err := GetNodeIPs(client, nodeName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]string{"192.168.111.66", "fd00:10::66", "203.0.113.10"}))
+		})
+
+		var _ = It("return error if no internal or external IP is available", func() {
+			nodeList := &v1.NodeList{}
+			err := json.Unmarshal([]byte(noInternalIPNodeListString), nodeList)
+			Expect(err).ToNot(HaveOccurred())
+			client := fake.NewSimpleClientset(nodeList)
+			_, err = GetNodeIPs(client, nodeName)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	var _ = Context("GetNodeNetInterface() should", func() {
 		var _ = It("return no error", func() {
 			nodeList := &v1.NodeList{}
@@ -301,6 +325,40 @@ var _ = Describe("utils", func() {
 		})
 	})
 
+	var _ = Context("GetVFPciAddrs() should", func() {
+		var _ = It("return the VFs out of the root netns as well", func() {
+			fs := &FakeFilesystem{
+				Dirs: []string{
+					"sys/class/net/dummyPf/device/",
+					"sys/class/net/dummyPf/0000:02:06.0/net/enp2s0",
+					"sys/class/net/dummyPf/0000:02:06.1/net",
+				},
+				Symlinks: map[string]string{
+					"sys/class/net/dummyPf/device/virtfn0": "../0000:02:06.0",
+					"sys/class/net/dummyPf/device/virtfn1": "../0000:02:06.1",
+				},
+			}
+			tempRoot, tearDown, err := fs.Use(tempDir)
+			Expect(err).ToNot(HaveOccurred())
+			defer tearDown()
+			fakeSysFs := filepath.Join(tempRoot, SysClassNet)
+			result, err := GetVFPciAddrs("dummyPf", fakeSysFs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ConsistOf("0000:02:06.0", "0000:02:06.1"))
+		})
+		var _ = It("return error if device does not exist", func() {
+			fs := &FakeFilesystem{
+				Dirs: []string{"sys/class/net/"},
+			}
+			tempRoot, tearDown, err := fs.Use(tempDir)
+			Expect(err).ToNot(HaveOccurred())
+			defer tearDown()
+			fakeSysFs := filepath.Join(tempRoot, SysClassNet)
+			_, err = GetVFPciAddrs("dummyPf", fakeSysFs)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	var _ = Context("GetTapInterfaces() should", func() {
 		var _ = It("return no error", func() {
 			links, err := netlink.LinkList()
@@ -338,6 +396,28 @@ var _ = Describe("utils", func() {
 		})
 	})
 
+	var _ = Context("GetNodeZone() should", func() {
+		var _ = It("return the zone label of the node", func() {
+			nodeList := &v1.NodeList{}
+			err := json.Unmarshal([]byte(nodeListString), nodeList)
+			Expect(err).ToNot(HaveOccurred())
+			nodeList.Items[0].Labels[v1.LabelTopologyZone] = "zone-a"
+			client := fake.NewSimpleClientset(nodeList)
+			zone, err := GetNodeZone(client, "dummyNode")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(zone).To(Equal("zone-a"))
+		})
+
+		var _ = It("return error if list of nodes is empty", func() {
+			nodeList := &v1.NodeList{}
+			err := json.Unmarshal([]byte(emptyNodeListString), nodeList)
+			Expect(err).ToNot(HaveOccurred())
+			client := fake.NewSimpleClientset(nodeList)
+			_, err = GetNodeZone(client, "dummyNode")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	var _ = Context("GetK8sClient() should", func() {
 		var _ = It("return client for valid config", func() {
 			client, err := GetK8sClient(&rest.Config{})
@@ -26,9 +26,11 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+// Only the address of the family of the endpoint is set
 type NatEndpoint struct {
 	Ipv4Addr             string   `protobuf:"bytes,1,opt,name=ipv4_addr,json=ipv4Addr,proto3" json:"ipv4_addr,omitempty"`
 	Port                 uint32   `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	Ipv6Addr             string   `protobuf:"bytes,3,opt,name=ipv6_addr,json=ipv6Addr,proto3" json:"ipv6_addr,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -81,6 +83,13 @@ func (m *NatEndpoint) GetPort() uint32 {
 	return 0
 }
 
+func (m *NatEndpoint) GetIpv6Addr() string {
+	if m != nil {
+		return m.Ipv6Addr
+	}
+	return ""
+}
+
 type NatEndpointTuple struct {
 	DstEp                *NatEndpoint `protobuf:"bytes,1,opt,name=dst_ep,json=dstEp,proto3" json:"dst_ep,omitempty"`
 	SrcEp                *NatEndpoint `protobuf:"bytes,2,opt,name=src_ep,json=srcEp,proto3" json:"src_ep,omitempty"`
@@ -137,13 +146,16 @@ func (m *NatEndpointTuple) GetSrcEp() *NatEndpoint {
 }
 
 type NatTranslation struct {
-	Endpoint             *NatEndpoint        `protobuf:"bytes,2,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
-	Proto                string              `protobuf:"bytes,3,opt,name=proto,proto3" json:"proto,omitempty"`
-	IsRealIp             bool                `protobuf:"varint,4,opt,name=is_real_ip,json=isRealIp,proto3" json:"is_real_ip,omitempty"`
-	Backends             []*NatEndpointTuple `protobuf:"bytes,6,rep,name=backends,proto3" json:"backends,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
-	XXX_unrecognized     []byte              `json:"-"`
-	XXX_sizecache        int32               `json:"-"`
+	Endpoint *NatEndpoint        `protobuf:"bytes,2,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Proto    string              `protobuf:"bytes,3,opt,name=proto,proto3" json:"proto,omitempty"`
+	IsRealIp bool                `protobuf:"varint,4,opt,name=is_real_ip,json=isRealIp,proto3" json:"is_real_ip,omitempty"`
+	Backends []*NatEndpointTuple `protobuf:"bytes,6,rep,name=backends,proto3" json:"backends,omitempty"`
+	// Idle timeout in seconds of the ClientIP session affinity,
+	// zero when the service has none
+	SessionAffinityTimeout uint32   `protobuf:"varint,7,opt,name=session_affinity_timeout,json=sessionAffinityTimeout,proto3" json:"session_affinity_timeout,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{} `json:"-"`
+	XXX_unrecognized       []byte   `json:"-"`
+	XXX_sizecache          int32    `json:"-"`
 }
 
 func (m *NatTranslation) Reset()         { *m = NatTranslation{} }
@@ -207,6 +219,100 @@ func (m *NatTranslation) GetBackends() []*NatEndpointTuple {
 	return nil
 }
 
+func (m *NatTranslation) GetSessionAffinityTimeout() uint32 {
+	if m != nil {
+		return m.SessionAffinityTimeout
+	}
+	return 0
+}
+
+type NatTranslationUpdateRequest struct {
+	Endpoint               *NatEndpoint        `protobuf:"bytes,1,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Proto                  string              `protobuf:"bytes,2,opt,name=proto,proto3" json:"proto,omitempty"`
+	IsRealIp               bool                `protobuf:"varint,3,opt,name=is_real_ip,json=isRealIp,proto3" json:"is_real_ip,omitempty"`
+	AddedBackends          []*NatEndpointTuple `protobuf:"bytes,4,rep,name=added_backends,json=addedBackends,proto3" json:"added_backends,omitempty"`
+	RemovedBackends        []*NatEndpointTuple `protobuf:"bytes,5,rep,name=removed_backends,json=removedBackends,proto3" json:"removed_backends,omitempty"`
+	SessionAffinityTimeout uint32              `protobuf:"varint,6,opt,name=session_affinity_timeout,json=sessionAffinityTimeout,proto3" json:"session_affinity_timeout,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{}            `json:"-"`
+	XXX_unrecognized       []byte              `json:"-"`
+	XXX_sizecache          int32               `json:"-"`
+}
+
+func (m *NatTranslationUpdateRequest) Reset()         { *m = NatTranslationUpdateRequest{} }
+func (m *NatTranslationUpdateRequest) String() string { return proto.CompactTextString(m) }
+func (*NatTranslationUpdateRequest) ProtoMessage()    {}
+func (*NatTranslationUpdateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cd059abf8f713b80, []int{3}
+}
+func (m *NatTranslationUpdateRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *NatTranslationUpdateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_NatTranslationUpdateRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *NatTranslationUpdateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NatTranslationUpdateRequest.Merge(m, src)
+}
+func (m *NatTranslationUpdateRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *NatTranslationUpdateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NatTranslationUpdateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NatTranslationUpdateRequest proto.InternalMessageInfo
+
+func (m *NatTranslationUpdateRequest) GetEndpoint() *NatEndpoint {
+	if m != nil {
+		return m.Endpoint
+	}
+	return nil
+}
+
+func (m *NatTranslationUpdateRequest) GetProto() string {
+	if m != nil {
+		return m.Proto
+	}
+	return ""
+}
+
+func (m *NatTranslationUpdateRequest) GetIsRealIp() bool {
+	if m != nil {
+		return m.IsRealIp
+	}
+	return false
+}
+
+func (m *NatTranslationUpdateRequest) GetAddedBackends() []*NatEndpointTuple {
+	if m != nil {
+		return m.AddedBackends
+	}
+	return nil
+}
+
+func (m *NatTranslationUpdateRequest) GetRemovedBackends() []*NatEndpointTuple {
+	if m != nil {
+		return m.RemovedBackends
+	}
+	return nil
+}
+
+func (m *NatTranslationUpdateRequest) GetSessionAffinityTimeout() uint32 {
+	if m != nil {
+		return m.SessionAffinityTimeout
+	}
+	return 0
+}
+
 type Reply struct {
 	Successful           bool     `protobuf:"varint,1,opt,name=successful,proto3" json:"successful,omitempty"`
 	ErrorMessage         string   `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
@@ -219,7 +325,7 @@ func (m *Reply) Reset()         { *m = Reply{} }
 func (m *Reply) String() string { return proto.CompactTextString(m) }
 func (*Reply) ProtoMessage()    {}
 func (*Reply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cd059abf8f713b80, []int{3}
+	return fileDescriptor_cd059abf8f713b80, []int{4}
 }
 func (m *Reply) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -262,6 +368,108 @@ func (m *Reply) GetErrorMessage() string {
 	return ""
 }
 
+type PolicyStateRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PolicyStateRequest) Reset()         { *m = PolicyStateRequest{} }
+func (m *PolicyStateRequest) String() string { return proto.CompactTextString(m) }
+func (*PolicyStateRequest) ProtoMessage()    {}
+func (*PolicyStateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cd059abf8f713b80, []int{5}
+}
+func (m *PolicyStateRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *PolicyStateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_PolicyStateRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *PolicyStateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PolicyStateRequest.Merge(m, src)
+}
+func (m *PolicyStateRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *PolicyStateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PolicyStateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PolicyStateRequest proto.InternalMessageInfo
+
+type PolicyStateReply struct {
+	PolicyIds            []*PolicyID           `protobuf:"bytes,1,rep,name=policy_ids,json=policyIds,proto3" json:"policy_ids,omitempty"`
+	IpSetIds             []string              `protobuf:"bytes,2,rep,name=ip_set_ids,json=ipSetIds,proto3" json:"ip_set_ids,omitempty"`
+	WorkloadEndpointIds  []*WorkloadEndpointID `protobuf:"bytes,3,rep,name=workload_endpoint_ids,json=workloadEndpointIds,proto3" json:"workload_endpoint_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *PolicyStateReply) Reset()         { *m = PolicyStateReply{} }
+func (m *PolicyStateReply) String() string { return proto.CompactTextString(m) }
+func (*PolicyStateReply) ProtoMessage()    {}
+func (*PolicyStateReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cd059abf8f713b80, []int{6}
+}
+func (m *PolicyStateReply) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *PolicyStateReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_PolicyStateReply.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *PolicyStateReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PolicyStateReply.Merge(m, src)
+}
+func (m *PolicyStateReply) XXX_Size() int {
+	return m.Size()
+}
+func (m *PolicyStateReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_PolicyStateReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PolicyStateReply proto.InternalMessageInfo
+
+func (m *PolicyStateReply) GetPolicyIds() []*PolicyID {
+	if m != nil {
+		return m.PolicyIds
+	}
+	return nil
+}
+
+func (m *PolicyStateReply) GetIpSetIds() []string {
+	if m != nil {
+		return m.IpSetIds
+	}
+	return nil
+}
+
+func (m *PolicyStateReply) GetWorkloadEndpointIds() []*WorkloadEndpointID {
+	if m != nil {
+		return m.WorkloadEndpointIds
+	}
+	return nil
+}
+
 type SetSnatAddressRequest struct {
 	SnatIpv4             string   `protobuf:"bytes,1,opt,name=snat_ipv4,json=snatIpv4,proto3" json:"snat_ipv4,omitempty"`
 	SnatIpv6             string   `protobuf:"bytes,2,opt,name=snat_ipv6,json=snatIpv6,proto3" json:"snat_ipv6,omitempty"`
@@ -274,7 +482,7 @@ func (m *SetSnatAddressRequest) Reset()         { *m = SetSnatAddressRequest{} }
 func (m *SetSnatAddressRequest) String() string { return proto.CompactTextString(m) }
 func (*SetSnatAddressRequest) ProtoMessage()    {}
 func (*SetSnatAddressRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cd059abf8f713b80, []int{4}
+	return fileDescriptor_cd059abf8f713b80, []int{7}
 }
 func (m *SetSnatAddressRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -329,7 +537,7 @@ func (m *AddDelSnatPrefixRequest) Reset()         { *m = AddDelSnatPrefixRequest
 func (m *AddDelSnatPrefixRequest) String() string { return proto.CompactTextString(m) }
 func (*AddDelSnatPrefixRequest) ProtoMessage()    {}
 func (*AddDelSnatPrefixRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cd059abf8f713b80, []int{5}
+	return fileDescriptor_cd059abf8f713b80, []int{8}
 }
 func (m *AddDelSnatPrefixRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -385,7 +593,7 @@ func (m *CreateNetworkRequest) Reset()         { *m = CreateNetworkRequest{} }
 func (m *CreateNetworkRequest) String() string { return proto.CompactTextString(m) }
 func (*CreateNetworkRequest) ProtoMessage()    {}
 func (*CreateNetworkRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cd059abf8f713b80, []int{6}
+	return fileDescriptor_cd059abf8f713b80, []int{9}
 }
 func (m *CreateNetworkRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -440,6 +648,7 @@ type DeleteNetworkRequest struct {
 	HostIfName           string      `protobuf:"bytes,2,opt,name=host_if_name,json=hostIfName,proto3" json:"host_if_name,omitempty"`
 	MacAddr              string      `protobuf:"bytes,3,opt,name=mac_addr,json=macAddr,proto3" json:"mac_addr,omitempty"`
 	Ipv4Addr             string      `protobuf:"bytes,4,opt,name=ipv4_addr,json=ipv4Addr,proto3" json:"ipv4_addr,omitempty"`
+	Ipv6Addr             string      `protobuf:"bytes,5,opt,name=ipv6_addr,json=ipv6Addr,proto3" json:"ipv6_addr,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
 	XXX_unrecognized     []byte      `json:"-"`
 	XXX_sizecache        int32       `json:"-"`
@@ -449,7 +658,7 @@ func (m *DeleteNetworkRequest) Reset()         { *m = DeleteNetworkRequest{} }
 func (m *DeleteNetworkRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteNetworkRequest) ProtoMessage()    {}
 func (*DeleteNetworkRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cd059abf8f713b80, []int{7}
+	return fileDescriptor_cd059abf8f713b80, []int{10}
 }
 func (m *DeleteNetworkRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -506,6 +715,13 @@ func (m *DeleteNetworkRequest) GetIpv4Addr() string {
 	return ""
 }
 
+func (m *DeleteNetworkRequest) GetIpv6Addr() string {
+	if m != nil {
+		return m.Ipv6Addr
+	}
+	return ""
+}
+
 type SetupHostInterfaceRequest struct {
 	IfName               string   `protobuf:"bytes,1,opt,name=if_name,json=ifName,proto3" json:"if_name,omitempty"`
 	Ipv4Addr             string   `protobuf:"bytes,2,opt,name=ipv4_addr,json=ipv4Addr,proto3" json:"ipv4_addr,omitempty"`
@@ -519,7 +735,7 @@ func (m *SetupHostInterfaceRequest) Reset()         { *m = SetupHostInterfaceReq
 func (m *SetupHostInterfaceRequest) String() string { return proto.CompactTextString(m) }
 func (*SetupHostInterfaceRequest) ProtoMessage()    {}
 func (*SetupHostInterfaceRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cd059abf8f713b80, []int{8}
+	return fileDescriptor_cd059abf8f713b80, []int{11}
 }
 func (m *SetupHostInterfaceRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -573,7 +789,10 @@ func init() {
 	proto.RegisterType((*NatEndpoint)(nil), "infra.NatEndpoint")
 	proto.RegisterType((*NatEndpointTuple)(nil), "infra.NatEndpointTuple")
 	proto.RegisterType((*NatTranslation)(nil), "infra.NatTranslation")
+	proto.RegisterType((*NatTranslationUpdateRequest)(nil), "infra.NatTranslationUpdateRequest")
 	proto.RegisterType((*Reply)(nil), "infra.Reply")
+	proto.RegisterType((*PolicyStateRequest)(nil), "infra.PolicyStateRequest")
+	proto.RegisterType((*PolicyStateReply)(nil), "infra.PolicyStateReply")
 	proto.RegisterType((*SetSnatAddressRequest)(nil), "infra.SetSnatAddressRequest")
 	proto.RegisterType((*AddDelSnatPrefixRequest)(nil), "infra.AddDelSnatPrefixRequest")
 	proto.RegisterType((*CreateNetworkRequest)(nil), "infra.CreateNetworkRequest")
@@ -584,71 +803,84 @@ func init() {
 func init() { proto.RegisterFile("infra.proto", fileDescriptor_cd059abf8f713b80) }
 
 var fileDescriptor_cd059abf8f713b80 = []byte{
-	// 1017 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x96, 0xdd, 0x4e, 0x1b, 0x47,
-	0x14, 0xc7, 0x63, 0x02, 0x8e, 0x39, 0x86, 0x40, 0x27, 0x26, 0x06, 0x42, 0x2d, 0x6b, 0x7b, 0x43,
-	0x6f, 0xac, 0x86, 0x44, 0x91, 0xd2, 0x2a, 0x89, 0x16, 0x4c, 0x13, 0x4b, 0x80, 0xe8, 0x42, 0x9b,
-	0xaa, 0x37, 0xd6, 0xb0, 0x73, 0x4c, 0x46, 0x8c, 0x77, 0xb7, 0x33, 0x63, 0x1a, 0x1e, 0xa0, 0xef,
-	0xd0, 0xeb, 0xde, 0xf5, 0x4d, 0x7a, 0xd9, 0x47, 0xa8, 0xe8, 0x75, 0xdf, 0xa1, 0xda, 0x9d, 0xd9,
-	0xc5, 0x8b, 0x3d, 0x40, 0xd4, 0x2b, 0x76, 0xcf, 0x9c, 0xf3, 0x3b, 0xff, 0x39, 0x1f, 0x8b, 0xa1,
-	0xce, 0xa3, 0x81, 0xa4, 0x9d, 0x44, 0xc6, 0x3a, 0x26, 0x73, 0xd9, 0xcb, 0xfa, 0x72, 0x18, 0xf1,
-	0x13, 0x1a, 0x9e, 0x61, 0xc4, 0xcc, 0xc1, 0x3a, 0x19, 0xa0, 0xe0, 0x1f, 0x4b, 0x36, 0xef, 0x35,
-	0xd4, 0x0f, 0xa8, 0xde, 0x8d, 0x58, 0x12, 0xf3, 0x48, 0x93, 0x27, 0x30, 0xcf, 0x93, 0xf3, 0xe7,
-	0x7d, 0xca, 0x98, 0x5c, 0xad, 0xb4, 0x2b, 0x9b, 0xf3, 0x41, 0x2d, 0x35, 0xf8, 0x8c, 0x49, 0x42,
-	0x60, 0x36, 0x89, 0xa5, 0x5e, 0x9d, 0x69, 0x57, 0x36, 0x17, 0x83, 0xec, 0xd9, 0xfb, 0x00, 0xcb,
-	0x63, 0xf1, 0xc7, 0xa3, 0x44, 0x20, 0xf9, 0x12, 0xaa, 0x4c, 0xe9, 0x3e, 0x26, 0x19, 0xa1, 0xbe,
-	0x45, 0x3a, 0x46, 0xde, 0x98, 0x63, 0x30, 0xc7, 0x94, 0xde, 0x4d, 0x52, 0x57, 0x25, 0xc3, 0xd4,
-	0x75, 0xc6, 0xed, 0xaa, 0x64, 0xb8, 0x9b, 0x78, 0x7f, 0x54, 0xe0, 0xe1, 0x01, 0xd5, 0xc7, 0x92,
-	0x46, 0x4a, 0x50, 0xcd, 0xe3, 0x88, 0x74, 0xa0, 0x86, 0xd6, 0xeb, 0x86, 0xf8, 0xc2, 0x87, 0x34,
-	0x60, 0x2e, 0xbb, 0xf5, 0xea, 0xfd, 0xec, 0x66, 0xe6, 0x85, 0x6c, 0x00, 0x70, 0xd5, 0x97, 0x48,
-	0x45, 0x9f, 0x27, 0xab, 0xb3, 0xed, 0xca, 0x66, 0x2d, 0xa8, 0x71, 0x15, 0x20, 0x15, 0xbd, 0x84,
-	0x3c, 0x83, 0x9a, 0xad, 0x98, 0x5a, 0xad, 0xb6, 0xef, 0x6f, 0xd6, 0xb7, 0x9a, 0x93, 0x39, 0xb2,
-	0x7b, 0x07, 0x85, 0xa3, 0xb7, 0x07, 0x73, 0x01, 0x26, 0xe2, 0x82, 0xb4, 0x00, 0xd4, 0x28, 0x0c,
-	0x51, 0xa9, 0xc1, 0x48, 0x64, 0xe5, 0xa8, 0x05, 0x63, 0x16, 0xf2, 0x05, 0x2c, 0xa2, 0x94, 0xb1,
-	0xec, 0x0f, 0x51, 0x29, 0x7a, 0x8a, 0xd9, 0x35, 0xe6, 0x83, 0x85, 0xcc, 0xb8, 0x6f, 0x6c, 0xde,
-	0x77, 0xb0, 0x72, 0x84, 0xfa, 0x28, 0xa2, 0x3a, 0x6d, 0x03, 0x2a, 0x15, 0xe0, 0xcf, 0x23, 0x54,
-	0x59, 0xb7, 0x54, 0x44, 0x75, 0x3f, 0xed, 0x50, 0xde, 0xad, 0xd4, 0xd0, 0x4b, 0xce, 0x9f, 0x8f,
-	0x1f, 0xbe, 0xb0, 0xd8, 0xfc, 0xf0, 0x85, 0xf7, 0x0e, 0x9a, 0x3e, 0x63, 0x5d, 0x14, 0x29, 0xf5,
-	0x50, 0xe2, 0x80, 0x7f, 0xcc, 0xa1, 0x2b, 0x50, 0xe5, 0x2a, 0x1d, 0x00, 0x2b, 0x77, 0x8e, 0x2b,
-	0x9f, 0x31, 0xf2, 0x18, 0xaa, 0x49, 0xe6, 0x67, 0x59, 0xf6, 0xcd, 0xfb, 0xb5, 0x02, 0x8d, 0x1d,
-	0x89, 0x54, 0xe3, 0x01, 0xea, 0x5f, 0x62, 0x79, 0x96, 0x73, 0xbe, 0x82, 0x3a, 0x65, 0xac, 0x2f,
-	0xcd, 0xab, 0x1d, 0x85, 0xa5, 0x4e, 0x18, 0xf1, 0x8e, 0xcf, 0x98, 0xf5, 0x0a, 0x80, 0x16, 0xcf,
-	0xa4, 0x0d, 0x0b, 0x1f, 0x62, 0xa5, 0xfb, 0x7c, 0xd0, 0x8f, 0xe8, 0x30, 0xaf, 0x05, 0xa4, 0xb6,
-	0xde, 0xe0, 0x80, 0x0e, 0x91, 0xac, 0x41, 0x6d, 0x48, 0x43, 0x33, 0x9d, 0xa6, 0x87, 0x0f, 0x86,
-	0x34, 0x4c, 0xab, 0xe2, 0xfd, 0x5e, 0x81, 0x46, 0x17, 0x05, 0x4e, 0xd3, 0xc1, 0x50, 0x4c, 0xd5,
-	0xd1, 0x45, 0x51, 0xe8, 0x60, 0xc5, 0xf3, 0xff, 0xd2, 0x51, 0xde, 0xa0, 0xd9, 0xf2, 0x06, 0x79,
-	0x02, 0xd6, 0x8e, 0x50, 0x8f, 0x92, 0x77, 0x29, 0x2a, 0xd2, 0x28, 0x07, 0x34, 0xc4, 0x3c, 0x6d,
-	0x13, 0x1e, 0xe4, 0x19, 0x4d, 0x2f, 0xab, 0xdc, 0x64, 0x2b, 0x21, 0x67, 0xae, 0x2d, 0xa5, 0x5b,
-	0xca, 0xd6, 0xbf, 0x4b, 0x00, 0xbd, 0x74, 0x54, 0xfd, 0x53, 0x8c, 0x34, 0x79, 0x05, 0x8b, 0xa5,
-	0x46, 0x91, 0x27, 0x76, 0x90, 0xa7, 0xb5, 0x6f, 0x7d, 0xf1, 0xaa, 0x53, 0x89, 0xb8, 0xf0, 0xee,
-	0xa5, 0xe1, 0xa5, 0xfa, 0x16, 0xe1, 0xd3, 0xaa, 0x6e, 0xc3, 0xb3, 0x02, 0x9b, 0xf0, 0x6f, 0x81,
-	0x4c, 0x5e, 0x9d, 0xb4, 0x2d, 0xc3, 0x59, 0x95, 0xf5, 0x05, 0xeb, 0x91, 0x73, 0xbe, 0x86, 0xcf,
-	0xca, 0x5f, 0x81, 0x74, 0x38, 0x57, 0xae, 0x56, 0x72, 0xec, 0x64, 0x22, 0xf6, 0x35, 0x3c, 0x2c,
-	0x2f, 0x12, 0xd9, 0xb8, 0xca, 0x3f, 0xb9, 0x5f, 0x13, 0xf1, 0xdb, 0xb0, 0x7c, 0x7d, 0x6b, 0x48,
-	0xcb, 0xfa, 0x38, 0xd6, 0x69, 0x82, 0xf1, 0x0a, 0x1a, 0x65, 0x95, 0xa6, 0x7c, 0x77, 0xbd, 0xc2,
-	0x1b, 0x20, 0x7e, 0xa8, 0xf9, 0x39, 0x1e, 0xc6, 0x82, 0x87, 0x17, 0xdf, 0x27, 0x8c, 0x6a, 0x24,
-	0x6b, 0x9d, 0xec, 0xd3, 0xde, 0x99, 0x3c, 0xba, 0x0d, 0x10, 0xe0, 0x30, 0x3e, 0x9f, 0x0e, 0x30,
-	0x47, 0x13, 0x80, 0xa7, 0x50, 0x37, 0xe8, 0xde, 0xe1, 0x11, 0x6a, 0x42, 0x6c, 0x64, 0xf6, 0xe6,
-	0xc8, 0xf9, 0x0d, 0x2c, 0x8f, 0x85, 0x74, 0x51, 0x68, 0x4a, 0x9a, 0xe3, 0x71, 0x99, 0xc9, 0x11,
-	0xfc, 0x14, 0xea, 0x46, 0xc9, 0x94, 0x7c, 0x0e, 0x89, 0x3e, 0x3c, 0x32, 0x30, 0x7b, 0x1d, 0x19,
-	0x0f, 0xb8, 0x40, 0xb2, 0x5e, 0xbe, 0xa4, 0xb1, 0x3a, 0xb2, 0xfa, 0xf0, 0xc8, 0xc0, 0xef, 0x80,
-	0x70, 0xa8, 0x78, 0x03, 0xc4, 0xc0, 0xd3, 0xb9, 0x2e, 0xfe, 0xc3, 0xe6, 0x95, 0x1e, 0x37, 0xba,
-	0x5b, 0x65, 0xd0, 0xb7, 0x02, 0x1c, 0x0a, 0xba, 0x79, 0x1d, 0xf6, 0xe2, 0x90, 0x8a, 0x82, 0xf0,
-	0xb9, 0x25, 0xbc, 0x8f, 0xe5, 0x99, 0x88, 0x29, 0xbb, 0x45, 0x46, 0x37, 0x2f, 0xc5, 0xdd, 0x28,
-	0x77, 0xa9, 0xc6, 0x3e, 0x6a, 0xda, 0xa5, 0x9a, 0x96, 0x2e, 0x93, 0x1a, 0x19, 0x75, 0xce, 0x41,
-	0xa9, 0x1a, 0x37, 0x02, 0x1c, 0x0a, 0x76, 0xa0, 0x61, 0xd0, 0x47, 0x28, 0xcf, 0x79, 0x88, 0x7e,
-	0x18, 0xc6, 0xa3, 0xf4, 0x37, 0x8f, 0x45, 0x94, 0xcd, 0x0e, 0x15, 0x3b, 0xd0, 0x30, 0xf8, 0x3b,
-	0x41, 0x1c, 0x4a, 0x5e, 0xc2, 0x92, 0xc1, 0xa7, 0x9f, 0x77, 0x95, 0xa4, 0x1f, 0xc2, 0xc7, 0x36,
-	0xbe, 0xb0, 0x38, 0xf2, 0xbf, 0x84, 0x25, 0x03, 0xbd, 0x21, 0xf4, 0xb6, 0xc5, 0x0d, 0xe2, 0x91,
-	0xc6, 0x62, 0x91, 0xb2, 0xb7, 0xdb, 0x76, 0x6f, 0x4a, 0x88, 0x23, 0xcb, 0x3e, 0xac, 0x19, 0xd8,
-	0x0f, 0x3f, 0xee, 0xf9, 0x07, 0xc7, 0xa3, 0x28, 0xc2, 0xab, 0x99, 0x69, 0x5b, 0xc0, 0x94, 0x33,
-	0x87, 0x82, 0x7d, 0x58, 0x33, 0x89, 0x3e, 0x11, 0xe7, 0x50, 0xd7, 0x83, 0xa6, 0x49, 0xf4, 0x9e,
-	0x4b, 0x3c, 0x1d, 0x51, 0x59, 0x8c, 0x2d, 0x69, 0xe5, 0xf3, 0x7c, 0xfd, 0xc4, 0xa1, 0xac, 0x07,
-	0x4d, 0x93, 0xe4, 0x13, 0x50, 0x0e, 0x55, 0xbb, 0xb0, 0x62, 0x92, 0xbc, 0x15, 0xf1, 0x09, 0x15,
-	0xdb, 0x6f, 0x0f, 0x77, 0xe2, 0x68, 0xc0, 0x4f, 0xc9, 0x86, 0x05, 0x5d, 0xb3, 0x4f, 0x57, 0xb4,
-	0xdd, 0xfc, 0xf3, 0xb2, 0x55, 0xf9, 0xeb, 0xb2, 0x55, 0xf9, 0xfb, 0xb2, 0x55, 0xf9, 0xed, 0x9f,
-	0xd6, 0xbd, 0x9f, 0xcc, 0x2f, 0xdc, 0x93, 0x6a, 0xf6, 0xe7, 0xd9, 0x7f, 0x01, 0x00, 0x00, 0xff,
-	0xff, 0x02, 0x2a, 0x97, 0x47, 0x27, 0x0c, 0x00, 0x00,
+	// 1232 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0xcd, 0x6e, 0xdb, 0x46,
+	0x10, 0x0e, 0x65, 0x4b, 0x91, 0x47, 0xf1, 0x4f, 0x36, 0x72, 0x24, 0xcb, 0xa9, 0x20, 0xa8, 0x17,
+	0xf7, 0x22, 0x34, 0x3f, 0x30, 0x9a, 0x16, 0x49, 0x20, 0x5b, 0x4e, 0xa2, 0x22, 0x36, 0x5c, 0xca,
+	0x6d, 0x8a, 0xf6, 0x40, 0x6c, 0xb8, 0x23, 0x67, 0x11, 0x8a, 0x64, 0xb9, 0x2b, 0x27, 0x3e, 0xf6,
+	0xd0, 0x77, 0xe8, 0x5b, 0xf4, 0x11, 0x72, 0xed, 0xb1, 0x8f, 0x50, 0x24, 0x2f, 0x52, 0x90, 0xbb,
+	0xa4, 0x45, 0x49, 0x6b, 0xcb, 0x40, 0x4f, 0xd6, 0xce, 0xcf, 0x37, 0xdf, 0xcc, 0xec, 0xcc, 0xd2,
+	0x50, 0xe1, 0xfe, 0x30, 0xa2, 0x9d, 0x30, 0x0a, 0x64, 0x40, 0x8a, 0xc9, 0xa1, 0xb1, 0xe1, 0xfa,
+	0xfc, 0x0d, 0x75, 0xdf, 0xa1, 0xcf, 0x94, 0xa2, 0x41, 0x86, 0xe8, 0xf1, 0x0f, 0x39, 0x59, 0xfb,
+	0x57, 0xa8, 0x1c, 0x51, 0x79, 0xe0, 0xb3, 0x30, 0xe0, 0xbe, 0x24, 0xdb, 0xb0, 0xc2, 0xc3, 0xb3,
+	0x47, 0x0e, 0x65, 0x2c, 0xaa, 0x5b, 0x2d, 0x6b, 0x67, 0xc5, 0x2e, 0xc7, 0x82, 0x2e, 0x63, 0x11,
+	0x21, 0xb0, 0x1c, 0x06, 0x91, 0xac, 0x17, 0x5a, 0xd6, 0xce, 0xaa, 0x9d, 0xfc, 0xd6, 0x0e, 0xbb,
+	0xca, 0x61, 0x29, 0x73, 0xd8, 0x8d, 0x1d, 0xda, 0x6f, 0x61, 0x63, 0x02, 0xfc, 0x64, 0x1c, 0x7a,
+	0x48, 0xbe, 0x82, 0x12, 0x13, 0xd2, 0xc1, 0x30, 0x81, 0xaf, 0x3c, 0x20, 0x1d, 0xc5, 0x7d, 0xc2,
+	0xd0, 0x2e, 0x32, 0x21, 0x0f, 0xc2, 0xd8, 0x54, 0x44, 0x6e, 0x6c, 0x5a, 0x30, 0x9b, 0x8a, 0xc8,
+	0x3d, 0x08, 0xdb, 0x9f, 0x2d, 0x58, 0x3b, 0xa2, 0xf2, 0x24, 0xa2, 0xbe, 0xf0, 0xa8, 0xe4, 0x81,
+	0x4f, 0x3a, 0x50, 0x46, 0x6d, 0x75, 0x89, 0x7f, 0x66, 0x43, 0xaa, 0x50, 0x4c, 0x4a, 0xa2, 0xb3,
+	0x50, 0x07, 0x72, 0x0f, 0x80, 0x0b, 0x27, 0x42, 0xea, 0x39, 0x3c, 0xac, 0x2f, 0xb7, 0xac, 0x9d,
+	0xb2, 0x5d, 0xe6, 0xc2, 0x46, 0xea, 0xf5, 0x43, 0xf2, 0x10, 0xca, 0xba, 0x9c, 0xa2, 0x5e, 0x6a,
+	0x2d, 0xed, 0x54, 0x1e, 0xd4, 0x66, 0x63, 0x24, 0x79, 0xdb, 0x99, 0x21, 0xf9, 0x06, 0xea, 0x02,
+	0x85, 0xe0, 0x81, 0xef, 0xd0, 0xe1, 0x90, 0xfb, 0x5c, 0x9e, 0x3b, 0x92, 0x8f, 0x30, 0x18, 0xcb,
+	0xfa, 0xcd, 0xa4, 0xb4, 0x77, 0xb5, 0xbe, 0xab, 0xd5, 0x27, 0x4a, 0xdb, 0xfe, 0x58, 0x80, 0xed,
+	0x7c, 0x96, 0x3f, 0x86, 0x8c, 0x4a, 0xb4, 0xf1, 0xb7, 0x31, 0x0a, 0x99, 0x4b, 0xd9, 0xba, 0x4e,
+	0xca, 0x05, 0x73, 0xca, 0x4b, 0x53, 0x29, 0x3f, 0x85, 0x35, 0xca, 0x18, 0x32, 0x27, 0x4b, 0x7c,
+	0xf9, 0xf2, 0xc4, 0x57, 0x13, 0xf3, 0xbd, 0x34, 0xfb, 0x3d, 0xd8, 0x88, 0x70, 0x14, 0x9c, 0x4d,
+	0x22, 0x14, 0x2f, 0x47, 0x58, 0xd7, 0x0e, 0x7b, 0x8b, 0x54, 0xb0, 0x74, 0x69, 0x05, 0x5f, 0x41,
+	0xd1, 0xc6, 0xd0, 0x3b, 0x27, 0x4d, 0x00, 0x31, 0x76, 0x5d, 0x14, 0x62, 0x38, 0xf6, 0x92, 0x62,
+	0x95, 0xed, 0x09, 0x09, 0xf9, 0x12, 0x56, 0x31, 0x8a, 0x82, 0xc8, 0x19, 0xa1, 0x10, 0xf4, 0x14,
+	0x75, 0x89, 0x6e, 0x25, 0xc2, 0x43, 0x25, 0x6b, 0x57, 0x81, 0x1c, 0x07, 0x1e, 0x77, 0xcf, 0x07,
+	0xf2, 0xa2, 0x0b, 0xed, 0xbf, 0x2c, 0xd8, 0xc8, 0x89, 0xe3, 0x78, 0x1d, 0x80, 0x30, 0x91, 0x39,
+	0x9c, 0x89, 0xba, 0x95, 0x24, 0xbc, 0xde, 0x49, 0x06, 0xb2, 0xa3, 0x8c, 0xfb, 0x3d, 0x7b, 0x45,
+	0x99, 0xf4, 0x99, 0x48, 0x9a, 0x10, 0x3a, 0x02, 0x65, 0x62, 0x5f, 0x68, 0x2d, 0xa9, 0xc1, 0x1a,
+	0xa0, 0x8c, 0xb5, 0x87, 0xb0, 0xf9, 0x3e, 0x88, 0xde, 0x79, 0x01, 0x65, 0x4e, 0xda, 0xcd, 0xc4,
+	0x70, 0x29, 0x01, 0xde, 0xd2, 0xc0, 0xaf, 0xb5, 0x4d, 0x5a, 0xce, 0x7e, 0xcf, 0xbe, 0xf3, 0x7e,
+	0x5a, 0xc6, 0x44, 0xfb, 0x07, 0xd8, 0x1c, 0xa0, 0x1c, 0xf8, 0x54, 0xc6, 0x63, 0x8b, 0x42, 0xa4,
+	0x17, 0x6a, 0x1b, 0x56, 0x84, 0x4f, 0xa5, 0x13, 0xaf, 0x80, 0x74, 0x1d, 0xc4, 0x82, 0x7e, 0x78,
+	0xf6, 0x68, 0x52, 0xb9, 0x5b, 0x2f, 0xe4, 0x94, 0xbb, 0xed, 0x97, 0x50, 0xeb, 0x32, 0xd6, 0x43,
+	0x2f, 0x46, 0x3d, 0x8e, 0x70, 0xc8, 0x3f, 0xa4, 0xa0, 0x9b, 0x50, 0xe2, 0x22, 0x5e, 0x18, 0xba,
+	0xec, 0x45, 0x2e, 0xba, 0x8c, 0x91, 0xbb, 0x50, 0x0a, 0x13, 0x3b, 0x8d, 0xa5, 0x4f, 0xed, 0x3f,
+	0x2c, 0xa8, 0xee, 0x47, 0x48, 0x25, 0x1e, 0xa1, 0x8c, 0xd9, 0xa7, 0x38, 0x5f, 0x43, 0x85, 0x32,
+	0xe6, 0x44, 0xea, 0xa8, 0x2f, 0xfc, 0x7a, 0xc7, 0xf5, 0x79, 0xa7, 0xcb, 0x98, 0xb6, 0xb2, 0x81,
+	0x66, 0xbf, 0x49, 0x0b, 0x6e, 0xbd, 0x0d, 0x84, 0x74, 0xf8, 0xd0, 0xf1, 0xe9, 0x28, 0xed, 0x29,
+	0xc4, 0xb2, 0xfe, 0xf0, 0x88, 0x8e, 0x90, 0x6c, 0x41, 0x79, 0x44, 0xdd, 0xc9, 0x6d, 0x76, 0x73,
+	0x44, 0xdd, 0x64, 0x99, 0x7d, 0xb4, 0xa0, 0xda, 0x43, 0x0f, 0xe7, 0xf1, 0x60, 0xe8, 0xcd, 0xe5,
+	0xd1, 0x43, 0x2f, 0xe3, 0xc1, 0xd0, 0xfb, 0x3f, 0x78, 0xe4, 0x57, 0xf4, 0xf2, 0xd4, 0x8a, 0xce,
+	0xad, 0xe3, 0xe2, 0xd4, 0x3a, 0xf6, 0x60, 0x6b, 0x80, 0x72, 0x1c, 0xbe, 0x8c, 0xe3, 0xf8, 0x12,
+	0xa3, 0x21, 0x75, 0xb3, 0xdd, 0x51, 0x83, 0x9b, 0x29, 0x1d, 0xd5, 0xe8, 0x12, 0x57, 0x54, 0x72,
+	0xf1, 0x0a, 0x53, 0xf1, 0xcc, 0x3c, 0x1f, 0xfc, 0x7e, 0x1b, 0xa0, 0x1f, 0x0f, 0x74, 0xf7, 0x14,
+	0x7d, 0x49, 0x9e, 0xc0, 0x6a, 0xae, 0x8b, 0x64, 0x5b, 0x8f, 0xfb, 0xbc, 0xde, 0x36, 0x56, 0x2f,
+	0xda, 0x18, 0x7a, 0xe7, 0xed, 0x1b, 0xb1, 0x7b, 0xae, 0xf8, 0x99, 0xfb, 0xbc, 0x96, 0x34, 0x56,
+	0x2f, 0xaa, 0xaf, 0xdc, 0x9f, 0x03, 0x99, 0x4d, 0x9d, 0xb4, 0x34, 0x86, 0xb1, 0x2a, 0x8d, 0x5b,
+	0xda, 0x22, 0xc5, 0xf9, 0x16, 0x6e, 0xe7, 0x17, 0x70, 0x7c, 0x73, 0x37, 0x2f, 0x16, 0xd7, 0x84,
+	0x66, 0xc6, 0xf7, 0x29, 0xac, 0xe5, 0xa7, 0x8c, 0xdc, 0xbb, 0x88, 0x3f, 0x3b, 0x7c, 0x33, 0xfe,
+	0x7b, 0xb0, 0x31, 0x3d, 0x52, 0xa4, 0xa9, 0x6d, 0x0c, 0xb3, 0x36, 0x83, 0xf1, 0x04, 0xaa, 0x79,
+	0x96, 0xaa, 0x7c, 0x8b, 0xa6, 0xf0, 0x3d, 0x54, 0xe7, 0xbd, 0x3f, 0xa4, 0x3d, 0xd7, 0x3d, 0xf7,
+	0x38, 0xcd, 0x60, 0x3d, 0x03, 0xd2, 0x75, 0x25, 0x3f, 0x43, 0xb5, 0xfe, 0x34, 0x52, 0xba, 0xba,
+	0x66, 0x55, 0x57, 0x01, 0xd8, 0xc9, 0x23, 0x31, 0x17, 0x40, 0xa9, 0x66, 0x00, 0xee, 0x43, 0x45,
+	0x41, 0xf7, 0x8f, 0x07, 0x28, 0x09, 0xd1, 0x9e, 0xc9, 0xc9, 0x10, 0xf3, 0x3b, 0xd8, 0x98, 0x70,
+	0xe9, 0xa1, 0x27, 0x29, 0xa9, 0x4d, 0xfa, 0x25, 0x22, 0x83, 0xf3, 0x7d, 0xa8, 0x28, 0x26, 0x73,
+	0xe2, 0x19, 0x28, 0x76, 0xe1, 0x8e, 0x02, 0xd3, 0xe9, 0x44, 0xc1, 0x90, 0x7b, 0x48, 0x1a, 0xf9,
+	0x24, 0x95, 0xd4, 0x10, 0xb5, 0x0b, 0x77, 0x14, 0xf8, 0x02, 0x10, 0x06, 0x16, 0xcf, 0x80, 0x28,
+	0xf0, 0x78, 0x46, 0xb2, 0x6f, 0xc5, 0xb4, 0xd2, 0x93, 0x42, 0x73, 0xab, 0x14, 0xf4, 0x95, 0x00,
+	0x06, 0x06, 0xbd, 0xb4, 0x0e, 0xaf, 0x02, 0x97, 0x7a, 0x19, 0xc2, 0x17, 0x86, 0x87, 0xce, 0x40,
+	0xa3, 0x97, 0x96, 0x62, 0x31, 0x94, 0x45, 0xaa, 0x71, 0x88, 0x92, 0xf6, 0xa8, 0xa4, 0xb9, 0x64,
+	0x62, 0x21, 0xa3, 0x92, 0x2e, 0x52, 0x8d, 0x4b, 0x01, 0x0c, 0x0c, 0xf6, 0xa1, 0xaa, 0xa0, 0x07,
+	0x18, 0x9d, 0x71, 0x17, 0xbb, 0xae, 0x1b, 0x8c, 0xe3, 0xaf, 0x77, 0x0d, 0x91, 0x17, 0x1b, 0x58,
+	0xec, 0x43, 0x55, 0xc1, 0x2f, 0x04, 0x62, 0x60, 0xf2, 0x18, 0xd6, 0x15, 0x7c, 0xfc, 0x54, 0x88,
+	0x30, 0x5e, 0xaa, 0x77, 0xb5, 0x7f, 0x26, 0x31, 0xc4, 0x7f, 0x0c, 0xeb, 0x0a, 0xf4, 0x12, 0xd7,
+	0xab, 0x06, 0xd7, 0x0e, 0xc6, 0x12, 0xb3, 0x41, 0x4a, 0x4e, 0x57, 0xcd, 0xde, 0x1c, 0x17, 0x43,
+	0x94, 0x43, 0xd8, 0x52, 0x60, 0x3f, 0xfd, 0xfc, 0xaa, 0x7b, 0x74, 0x32, 0xf6, 0x7d, 0xbc, 0xb8,
+	0x33, 0x2d, 0x0d, 0x30, 0x47, 0x67, 0x60, 0x70, 0x08, 0x5b, 0x2a, 0xd0, 0x35, 0xe1, 0x0c, 0xec,
+	0xfa, 0x50, 0x53, 0x81, 0x5e, 0xf3, 0x08, 0x4f, 0xc7, 0x34, 0xca, 0xae, 0x2d, 0x69, 0xa6, 0xf7,
+	0x79, 0x5a, 0x63, 0x60, 0xd6, 0x87, 0x9a, 0x0a, 0x72, 0x0d, 0x28, 0x03, 0xab, 0x03, 0xd8, 0x54,
+	0x41, 0x5e, 0x78, 0xc1, 0x1b, 0xea, 0xed, 0xbd, 0x38, 0xde, 0x0f, 0xfc, 0x21, 0x3f, 0x25, 0xf7,
+	0x34, 0xd0, 0x94, 0xdc, 0xc0, 0xe8, 0x39, 0xac, 0xbd, 0x40, 0x39, 0xf1, 0x11, 0x4d, 0xb6, 0xb4,
+	0xc5, 0xec, 0xf7, 0x76, 0xa3, 0x36, 0x4f, 0x95, 0xe0, 0xec, 0xd5, 0xfe, 0xfe, 0xd4, 0xb4, 0xfe,
+	0xf9, 0xd4, 0xb4, 0xfe, 0xfd, 0xd4, 0xb4, 0xfe, 0xfc, 0xdc, 0xbc, 0xf1, 0x8b, 0xfa, 0x1f, 0xe7,
+	0x4d, 0x29, 0xf9, 0xf3, 0xf0, 0xbf, 0x01, 0x00, 0x77, 0x61, 0x7a, 0x6c, 0x39, 0x0f, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -670,6 +902,7 @@ type InfraAgentClient interface {
 	SetSnatAddress(ctx context.Context, in *SetSnatAddressRequest, opts ...grpc.CallOption) (*Reply, error)
 	AddDelSnatPrefix(ctx context.Context, in *AddDelSnatPrefixRequest, opts ...grpc.CallOption) (*Reply, error)
 	NatTranslationDelete(ctx context.Context, in *NatTranslation, opts ...grpc.CallOption) (*Reply, error)
+	NatTranslationUpdate(ctx context.Context, in *NatTranslationUpdateRequest, opts ...grpc.CallOption) (*Reply, error)
 	ActivePolicyUpdate(ctx context.Context, in *ActivePolicyUpdate, opts ...grpc.CallOption) (*Reply, error)
 	ActivePolicyRemove(ctx context.Context, in *ActivePolicyRemove, opts ...grpc.CallOption) (*Reply, error)
 	UpdateIPSet(ctx context.Context, in *IPSetUpdate, opts ...grpc.CallOption) (*Reply, error)
@@ -694,6 +927,7 @@ type InfraAgentClient interface {
 	UpdateWireguardEndpoint(ctx context.Context, in *WireguardEndpointUpdate, opts ...grpc.CallOption) (*Reply, error)
 	RemoveWireguardEndpoint(ctx context.Context, in *WireguardEndpointRemove, opts ...grpc.CallOption) (*Reply, error)
 	UpdateGlobalBGPConfig(ctx context.Context, in *GlobalBGPConfigUpdate, opts ...grpc.CallOption) (*Reply, error)
+	GetPolicyState(ctx context.Context, in *PolicyStateRequest, opts ...grpc.CallOption) (*PolicyStateReply, error)
 }
 
 type infraAgentClient struct {
@@ -767,6 +1001,15 @@ func (c *infraAgentClient) NatTranslationDelete(ctx context.Context, in *NatTran
 	return out, nil
 }
 
+func (c *infraAgentClient) NatTranslationUpdate(ctx context.Context, in *NatTranslationUpdateRequest, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/infra.InfraAgent/NatTranslationUpdate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *infraAgentClient) ActivePolicyUpdate(ctx context.Context, in *ActivePolicyUpdate, opts ...grpc.CallOption) (*Reply, error) {
 	out := new(Reply)
 	err := c.cc.Invoke(ctx, "/infra.InfraAgent/ActivePolicyUpdate", in, out, opts...)
@@ -983,6 +1226,15 @@ func (c *infraAgentClient) UpdateGlobalBGPConfig(ctx context.Context, in *Global
 	return out, nil
 }
 
+func (c *infraAgentClient) GetPolicyState(ctx context.Context, in *PolicyStateRequest, opts ...grpc.CallOption) (*PolicyStateReply, error) {
+	out := new(PolicyStateReply)
+	err := c.cc.Invoke(ctx, "/infra.InfraAgent/GetPolicyState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // InfraAgentServer is the server API for InfraAgent service.
 type InfraAgentServer interface {
 	CreateNetwork(context.Context, *CreateNetworkRequest) (*AddReply, error)
@@ -992,6 +1244,7 @@ type InfraAgentServer interface {
 	SetSnatAddress(context.Context, *SetSnatAddressRequest) (*Reply, error)
 	AddDelSnatPrefix(context.Context, *AddDelSnatPrefixRequest) (*Reply, error)
 	NatTranslationDelete(context.Context, *NatTranslation) (*Reply, error)
+	NatTranslationUpdate(context.Context, *NatTranslationUpdateRequest) (*Reply, error)
 	ActivePolicyUpdate(context.Context, *ActivePolicyUpdate) (*Reply, error)
 	ActivePolicyRemove(context.Context, *ActivePolicyRemove) (*Reply, error)
 	UpdateIPSet(context.Context, *IPSetUpdate) (*Reply, error)
@@ -1016,6 +1269,7 @@ type InfraAgentServer interface {
 	UpdateWireguardEndpoint(context.Context, *WireguardEndpointUpdate) (*Reply, error)
 	RemoveWireguardEndpoint(context.Context, *WireguardEndpointRemove) (*Reply, error)
 	UpdateGlobalBGPConfig(context.Context, *GlobalBGPConfigUpdate) (*Reply, error)
+	GetPolicyState(context.Context, *PolicyStateRequest) (*PolicyStateReply, error)
 }
 
 // UnimplementedInfraAgentServer can be embedded to have forward compatible implementations.
@@ -1043,6 +1297,9 @@ func (*UnimplementedInfraAgentServer) AddDelSnatPrefix(ctx context.Context, req
 func (*UnimplementedInfraAgentServer) NatTranslationDelete(ctx context.Context, req *NatTranslation) (*Reply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method NatTranslationDelete not implemented")
 }
+func (*UnimplementedInfraAgentServer) NatTranslationUpdate(ctx context.Context, req *NatTranslationUpdateRequest) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NatTranslationUpdate not implemented")
+}
 func (*UnimplementedInfraAgentServer) ActivePolicyUpdate(ctx context.Context, req *ActivePolicyUpdate) (*Reply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ActivePolicyUpdate not implemented")
 }
@@ -1115,6 +1372,9 @@ func (*UnimplementedInfraAgentServer) RemoveWireguardEndpoint(ctx context.Contex
 func (*UnimplementedInfraAgentServer) UpdateGlobalBGPConfig(ctx context.Context, req *GlobalBGPConfigUpdate) (*Reply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateGlobalBGPConfig not implemented")
 }
+func (*UnimplementedInfraAgentServer) GetPolicyState(ctx context.Context, req *PolicyStateRequest) (*PolicyStateReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPolicyState not implemented")
+}
 
 func RegisterInfraAgentServer(s *grpc.Server, srv InfraAgentServer) {
 	s.RegisterService(&_InfraAgent_serviceDesc, srv)
@@ -1246,6 +1506,24 @@ func _InfraAgent_NatTranslationDelete_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _InfraAgent_NatTranslationUpdate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NatTranslationUpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InfraAgentServer).NatTranslationUpdate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/infra.InfraAgent/NatTranslationUpdate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InfraAgentServer).NatTranslationUpdate(ctx, req.(*NatTranslationUpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _InfraAgent_ActivePolicyUpdate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ActivePolicyUpdate)
 	if err := dec(in); err != nil {
@@ -1678,6 +1956,24 @@ func _InfraAgent_UpdateGlobalBGPConfig_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _InfraAgent_GetPolicyState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PolicyStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InfraAgentServer).GetPolicyState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/infra.InfraAgent/GetPolicyState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InfraAgentServer).GetPolicyState(ctx, req.(*PolicyStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _InfraAgent_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "infra.InfraAgent",
 	HandlerType: (*InfraAgentServer)(nil),
@@ -1710,6 +2006,10 @@ var _InfraAgent_serviceDesc = grpc.ServiceDesc{
 			MethodName: "NatTranslationDelete",
 			Handler:    _InfraAgent_NatTranslationDelete_Handler,
 		},
+		{
+			MethodName: "NatTranslationUpdate",
+			Handler:    _InfraAgent_NatTranslationUpdate_Handler,
+		},
 		{
 			MethodName: "ActivePolicyUpdate",
 			Handler:    _InfraAgent_ActivePolicyUpdate_Handler,
@@ -1806,6 +2106,10 @@ var _InfraAgent_serviceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateGlobalBGPConfig",
 			Handler:    _InfraAgent_UpdateGlobalBGPConfig_Handler,
 		},
+		{
+			MethodName: "GetPolicyState",
+			Handler:    _InfraAgent_GetPolicyState_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "infra.proto",
@@ -1835,6 +2139,13 @@ func (m *NatEndpoint) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if len(m.Ipv6Addr) > 0 {
+		i -= len(m.Ipv6Addr)
+		copy(dAtA[i:], m.Ipv6Addr)
+		i = encodeVarintInfra(dAtA, i, uint64(len(m.Ipv6Addr)))
+		i--
+		dAtA[i] = 0x1a
+	}
 	if m.Port != 0 {
 		i = encodeVarintInfra(dAtA, i, uint64(m.Port))
 		i--
@@ -1925,6 +2236,11 @@ func (m *NatTranslation) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if m.SessionAffinityTimeout != 0 {
+		i = encodeVarintInfra(dAtA, i, uint64(m.SessionAffinityTimeout))
+		i--
+		dAtA[i] = 0x38
+	}
 	if len(m.Backends) > 0 {
 		for iNdEx := len(m.Backends) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -1971,7 +2287,7 @@ func (m *NatTranslation) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *Reply) Marshal() (dAtA []byte, err error) {
+func (m *NatTranslationUpdateRequest) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -1981,12 +2297,12 @@ func (m *Reply) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Reply) MarshalTo(dAtA []byte) (int, error) {
+func (m *NatTranslationUpdateRequest) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Reply) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *NatTranslationUpdateRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -1995,27 +2311,72 @@ func (m *Reply) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.ErrorMessage) > 0 {
-		i -= len(m.ErrorMessage)
-		copy(dAtA[i:], m.ErrorMessage)
-		i = encodeVarintInfra(dAtA, i, uint64(len(m.ErrorMessage)))
+	if m.SessionAffinityTimeout != 0 {
+		i = encodeVarintInfra(dAtA, i, uint64(m.SessionAffinityTimeout))
 		i--
-		dAtA[i] = 0x12
+		dAtA[i] = 0x30
 	}
-	if m.Successful {
+	if len(m.RemovedBackends) > 0 {
+		for iNdEx := len(m.RemovedBackends) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.RemovedBackends[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintInfra(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x2a
+		}
+	}
+	if len(m.AddedBackends) > 0 {
+		for iNdEx := len(m.AddedBackends) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.AddedBackends[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintInfra(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if m.IsRealIp {
 		i--
-		if m.Successful {
+		if m.IsRealIp {
 			dAtA[i] = 1
 		} else {
 			dAtA[i] = 0
 		}
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0x18
+	}
+	if len(m.Proto) > 0 {
+		i -= len(m.Proto)
+		copy(dAtA[i:], m.Proto)
+		i = encodeVarintInfra(dAtA, i, uint64(len(m.Proto)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Endpoint != nil {
+		{
+			size, err := m.Endpoint.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintInfra(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *SetSnatAddressRequest) Marshal() (dAtA []byte, err error) {
+func (m *Reply) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -2025,12 +2386,12 @@ func (m *SetSnatAddressRequest) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *SetSnatAddressRequest) MarshalTo(dAtA []byte) (int, error) {
+func (m *Reply) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *SetSnatAddressRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Reply) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -2039,13 +2400,148 @@ func (m *SetSnatAddressRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.SnatIpv6) > 0 {
-		i -= len(m.SnatIpv6)
-		copy(dAtA[i:], m.SnatIpv6)
-		i = encodeVarintInfra(dAtA, i, uint64(len(m.SnatIpv6)))
-		i--
-		dAtA[i] = 0x12
-	}
+	if len(m.ErrorMessage) > 0 {
+		i -= len(m.ErrorMessage)
+		copy(dAtA[i:], m.ErrorMessage)
+		i = encodeVarintInfra(dAtA, i, uint64(len(m.ErrorMessage)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Successful {
+		i--
+		if m.Successful {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *PolicyStateRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *PolicyStateRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *PolicyStateRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *PolicyStateReply) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *PolicyStateReply) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *PolicyStateReply) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.WorkloadEndpointIds) > 0 {
+		for iNdEx := len(m.WorkloadEndpointIds) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.WorkloadEndpointIds[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintInfra(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if len(m.IpSetIds) > 0 {
+		for iNdEx := len(m.IpSetIds) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.IpSetIds[iNdEx])
+			copy(dAtA[i:], m.IpSetIds[iNdEx])
+			i = encodeVarintInfra(dAtA, i, uint64(len(m.IpSetIds[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.PolicyIds) > 0 {
+		for iNdEx := len(m.PolicyIds) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.PolicyIds[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintInfra(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *SetSnatAddressRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SetSnatAddressRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SetSnatAddressRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.SnatIpv6) > 0 {
+		i -= len(m.SnatIpv6)
+		copy(dAtA[i:], m.SnatIpv6)
+		i = encodeVarintInfra(dAtA, i, uint64(len(m.SnatIpv6)))
+		i--
+		dAtA[i] = 0x12
+	}
 	if len(m.SnatIpv4) > 0 {
 		i -= len(m.SnatIpv4)
 		copy(dAtA[i:], m.SnatIpv4)
@@ -2177,6 +2673,13 @@ func (m *DeleteNetworkRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if len(m.Ipv6Addr) > 0 {
+		i -= len(m.Ipv6Addr)
+		copy(dAtA[i:], m.Ipv6Addr)
+		i = encodeVarintInfra(dAtA, i, uint64(len(m.Ipv6Addr)))
+		i--
+		dAtA[i] = 0x2a
+	}
 	if len(m.Ipv4Addr) > 0 {
 		i -= len(m.Ipv4Addr)
 		copy(dAtA[i:], m.Ipv4Addr)
@@ -2285,6 +2788,10 @@ func (m *NatEndpoint) Size() (n int) {
 	if m.Port != 0 {
 		n += 1 + sovInfra(uint64(m.Port))
 	}
+	l = len(m.Ipv6Addr)
+	if l > 0 {
+		n += 1 + l + sovInfra(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -2334,6 +2841,47 @@ func (m *NatTranslation) Size() (n int) {
 			n += 1 + l + sovInfra(uint64(l))
 		}
 	}
+	if m.SessionAffinityTimeout != 0 {
+		n += 1 + sovInfra(uint64(m.SessionAffinityTimeout))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *NatTranslationUpdateRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Endpoint != nil {
+		l = m.Endpoint.Size()
+		n += 1 + l + sovInfra(uint64(l))
+	}
+	l = len(m.Proto)
+	if l > 0 {
+		n += 1 + l + sovInfra(uint64(l))
+	}
+	if m.IsRealIp {
+		n += 2
+	}
+	if len(m.AddedBackends) > 0 {
+		for _, e := range m.AddedBackends {
+			l = e.Size()
+			n += 1 + l + sovInfra(uint64(l))
+		}
+	}
+	if len(m.RemovedBackends) > 0 {
+		for _, e := range m.RemovedBackends {
+			l = e.Size()
+			n += 1 + l + sovInfra(uint64(l))
+		}
+	}
+	if m.SessionAffinityTimeout != 0 {
+		n += 1 + sovInfra(uint64(m.SessionAffinityTimeout))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -2359,6 +2907,48 @@ func (m *Reply) Size() (n int) {
 	return n
 }
 
+func (m *PolicyStateRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *PolicyStateReply) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.PolicyIds) > 0 {
+		for _, e := range m.PolicyIds {
+			l = e.Size()
+			n += 1 + l + sovInfra(uint64(l))
+		}
+	}
+	if len(m.IpSetIds) > 0 {
+		for _, s := range m.IpSetIds {
+			l = len(s)
+			n += 1 + l + sovInfra(uint64(l))
+		}
+	}
+	if len(m.WorkloadEndpointIds) > 0 {
+		for _, e := range m.WorkloadEndpointIds {
+			l = e.Size()
+			n += 1 + l + sovInfra(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
 func (m *SetSnatAddressRequest) Size() (n int) {
 	if m == nil {
 		return 0
@@ -2444,6 +3034,10 @@ func (m *DeleteNetworkRequest) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovInfra(uint64(l))
 	}
+	l = len(m.Ipv6Addr)
+	if l > 0 {
+		n += 1 + l + sovInfra(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -2560,6 +3154,38 @@ func (m *NatEndpoint) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ipv6Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthInfra
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Ipv6Addr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipInfra(dAtA[iNdEx:])
@@ -2856,6 +3482,25 @@ func (m *NatTranslation) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SessionAffinityTimeout", wireType)
+			}
+			m.SessionAffinityTimeout = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SessionAffinityTimeout |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipInfra(dAtA[iNdEx:])
@@ -2878,7 +3523,7 @@ func (m *NatTranslation) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Reply) Unmarshal(dAtA []byte) error {
+func (m *NatTranslationUpdateRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2901,17 +3546,17 @@ func (m *Reply) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Reply: wiretype end group for non-group")
+			return fmt.Errorf("proto: NatTranslationUpdateRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Reply: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: NatTranslationUpdateRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Successful", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Endpoint", wireType)
 			}
-			var v int
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowInfra
@@ -2921,16 +3566,242 @@ func (m *Reply) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Successful = bool(v != 0)
-		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ErrorMessage", wireType)
-			}
+			if msglen < 0 {
+				return ErrInvalidLengthInfra
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Endpoint == nil {
+				m.Endpoint = &NatEndpoint{}
+			}
+			if err := m.Endpoint.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Proto", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthInfra
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Proto = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IsRealIp", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.IsRealIp = bool(v != 0)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AddedBackends", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthInfra
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.AddedBackends = append(m.AddedBackends, &NatEndpointTuple{})
+			if err := m.AddedBackends[len(m.AddedBackends)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RemovedBackends", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthInfra
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.RemovedBackends = append(m.RemovedBackends, &NatEndpointTuple{})
+			if err := m.RemovedBackends[len(m.RemovedBackends)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SessionAffinityTimeout", wireType)
+			}
+			m.SessionAffinityTimeout = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SessionAffinityTimeout |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipInfra(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Reply) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowInfra
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Reply: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Reply: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Successful", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Successful = bool(v != 0)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ErrorMessage", wireType)
+			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
@@ -2981,6 +3852,208 @@ func (m *Reply) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *PolicyStateRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowInfra
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PolicyStateRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PolicyStateRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipInfra(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *PolicyStateReply) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowInfra
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PolicyStateReply: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PolicyStateReply: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PolicyIds", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthInfra
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PolicyIds = append(m.PolicyIds, &PolicyID{})
+			if err := m.PolicyIds[len(m.PolicyIds)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IpSetIds", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthInfra
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.IpSetIds = append(m.IpSetIds, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field WorkloadEndpointIds", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthInfra
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.WorkloadEndpointIds = append(m.WorkloadEndpointIds, &WorkloadEndpointID{})
+			if err := m.WorkloadEndpointIds[len(m.WorkloadEndpointIds)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipInfra(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func (m *SetSnatAddressRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
@@ -3511,6 +4584,38 @@ func (m *DeleteNetworkRequest) Unmarshal(dAtA []byte) error {
 			}
 			m.Ipv4Addr = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ipv6Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowInfra
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthInfra
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthInfra
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Ipv6Addr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipInfra(dAtA[iNdEx:])
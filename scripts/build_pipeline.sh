@@ -0,0 +1,36 @@
+#!/bin/bash
+#Copyright (C) 2022 Intel Corporation
+#SPDX-License-Identifier: Apache-2.0
+
+# This script compiles k8s_dp.p4 with p4c-dpdk and builds the pipeline
+# binary loaded by the inframanager. It regenerates k8s_dp.pb.bin and
+# p4Info.txt, which must be committed along with any change to the P4
+# source so that the three stay in sync.
+set -e
+
+if [[ -z "${OVS_INSTALL}" ]]; then
+    echo "OVS_INSTALL env is undefined" && exit 1
+fi
+
+P4C=${P4C:-p4c-dpdk}
+K8S_DP_DIR=$(cd "$(dirname "$0")/../k8s_dp" && pwd)
+OUT_DIR=$(mktemp -d)
+trap 'rm -rf "$OUT_DIR"' EXIT
+
+cp "$K8S_DP_DIR/k8s_dp.conf" "$OUT_DIR"
+mkdir -p "$OUT_DIR/pipe"
+
+$P4C --arch pna --target dpdk \
+    --p4runtime-files "$OUT_DIR/p4Info.txt" \
+    --bf-rt-schema "$OUT_DIR/bfrt.json" \
+    --context "$OUT_DIR/pipe/context.json" \
+    -o "$OUT_DIR/pipe/k8s_dp.spec" \
+    "$K8S_DP_DIR/k8s_dp.p4"
+
+cd "$OUT_DIR"
+"$OVS_INSTALL/bin/ovs_pipeline_builder" \
+    --p4c_conf_file=k8s_dp.conf \
+    --bf_pipeline_config_binary_file=k8s_dp.pb.bin
+
+cp "$OUT_DIR/p4Info.txt" "$OUT_DIR/k8s_dp.pb.bin" "$K8S_DP_DIR"
+echo "updated $K8S_DP_DIR/k8s_dp.pb.bin and $K8S_DP_DIR/p4Info.txt"